@@ -0,0 +1,178 @@
+package bloom
+
+import (
+	"bytes"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/mslipper/handshake/wire"
+	"math"
+	"sync"
+)
+
+const seedMultiplier = 0xfba4c795
+
+// Filter is a BIP37 bloom filter over the data elements of Handshake
+// transactions: transaction IDs, output address hashes, the name hashes of
+// name covenants and spent outpoints.
+type Filter struct {
+	mtx     sync.Mutex
+	filter  []byte
+	hashFns uint32
+	tweak   uint32
+	update  uint8
+}
+
+// NewFilter returns a filter sized to hold elements items with the given
+// false positive rate, within the protocol's size limits.
+func NewFilter(elements int, fpRate float64, tweak uint32, update uint8) *Filter {
+	if elements < 1 {
+		elements = 1
+	}
+	fpRate = math.Max(1e-9, math.Min(fpRate, 1))
+	size := math.Floor(-1 / (math.Ln2 * math.Ln2) * float64(elements) * math.Log(fpRate) / 8)
+	size = math.Max(1, math.Min(size, wire.MaxFilterSize))
+	hashFns := size * 8 / float64(elements) * math.Ln2
+	hashFns = math.Max(1, math.Min(hashFns, wire.MaxFilterHashFns))
+	return &Filter{
+		filter:  make([]byte, int(size)),
+		hashFns: uint32(hashFns),
+		tweak:   tweak,
+		update:  update,
+	}
+}
+
+// LoadFilter returns the filter described by a filterload message.
+func LoadFilter(msg *wire.FilterLoadMessage) *Filter {
+	filter := make([]byte, len(msg.Filter))
+	copy(filter, msg.Filter)
+	return &Filter{
+		filter:  filter,
+		hashFns: msg.HashFns,
+		tweak:   msg.Tweak,
+		update:  msg.Update,
+	}
+}
+
+// LoadMessage returns the filterload message that installs the filter on a
+// peer.
+func (f *Filter) LoadMessage() *wire.FilterLoadMessage {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	filter := make([]byte, len(f.filter))
+	copy(filter, f.filter)
+	return &wire.FilterLoadMessage{
+		Filter:  filter,
+		HashFns: f.hashFns,
+		Tweak:   f.tweak,
+		Update:  f.update,
+	}
+}
+
+func (f *Filter) Add(data []byte) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.add(data)
+}
+
+// AddAddress watches outputs paying to addr.
+func (f *Filter) AddAddress(addr *primitives.Address) {
+	f.Add(addr.Hash)
+}
+
+// AddName watches name covenants for name.
+func (f *Filter) AddName(name string) {
+	f.Add(primitives.HashName(name))
+}
+
+// AddOutpoint watches transactions spending outpoint.
+func (f *Filter) AddOutpoint(outpoint *primitives.Outpoint) {
+	f.Add(encodeOutpoint(outpoint))
+}
+
+func (f *Filter) Test(data []byte) bool {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.test(data)
+}
+
+// MatchTx reports whether tx matches the filter. The transaction ID, the
+// address hash and covenant name hash of each output, and each spent
+// outpoint are tested. Depending on the filter's update flag, matching
+// outputs are added to the filter so that their spends match as well.
+func (f *Filter) MatchTx(tx *primitives.Transaction) bool {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	hash := tx.ID()
+	matched := f.test(hash)
+	for i, output := range tx.Outputs {
+		if !f.matchOutput(output) {
+			continue
+		}
+		matched = true
+		if f.update == wire.FilterUpdateAll ||
+			(f.update == wire.FilterUpdateP2PKOnly && isPubKeyHash(output.Address)) {
+			outpoint := &primitives.Outpoint{Index: uint32(i)}
+			copy(outpoint.Hash[:], hash)
+			f.add(encodeOutpoint(outpoint))
+		}
+	}
+	if matched {
+		return true
+	}
+	for _, input := range tx.Inputs {
+		if f.test(encodeOutpoint(input.Prevout)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Filter) matchOutput(output *primitives.Output) bool {
+	if output.Address != nil && len(output.Address.Hash) > 0 && f.test(output.Address.Hash) {
+		return true
+	}
+	cov := output.Covenant
+	if cov == nil || cov.Type == primitives.CovenantNone || len(cov.Items) == 0 {
+		return false
+	}
+	return f.test(cov.Items[0])
+}
+
+func (f *Filter) add(data []byte) {
+	if len(f.filter) == 0 {
+		return
+	}
+	for i := uint32(0); i < f.hashFns; i++ {
+		bit := f.hash(i, data)
+		f.filter[bit>>3] |= 1 << (bit & 7)
+	}
+}
+
+func (f *Filter) test(data []byte) bool {
+	if len(f.filter) == 0 {
+		return false
+	}
+	for i := uint32(0); i < f.hashFns; i++ {
+		bit := f.hash(i, data)
+		if f.filter[bit>>3]&(1<<(bit&7)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *Filter) hash(n uint32, data []byte) uint32 {
+	return murmur3(data, n*seedMultiplier+f.tweak) % uint32(len(f.filter)*8)
+}
+
+func encodeOutpoint(outpoint *primitives.Outpoint) []byte {
+	buf := new(bytes.Buffer)
+	if err := outpoint.Encode(buf); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+func isPubKeyHash(addr *primitives.Address) bool {
+	return addr != nil && addr.Version == 0 && len(addr.Hash) == 20
+}
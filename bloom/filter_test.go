@@ -0,0 +1,151 @@
+package bloom
+
+import (
+	"encoding/hex"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/mslipper/handshake/primitives/primitivestest"
+	"github.com/mslipper/handshake/wire"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+func TestMurmur3(t *testing.T) {
+	tests := []struct {
+		seed     uint32
+		data     string
+		expected uint32
+	}{
+		{0x00000000, "", 0x00000000},
+		{0xfba4c795, "", 0x6a396f08},
+		{0xffffffff, "", 0x81f16f39},
+		{0x00000000, "00", 0x514e28b7},
+		{0xfba4c795, "00", 0xea3f0b17},
+		{0x00000000, "ff", 0xfd6cf10d},
+		{0x00000000, "0011", 0x16c6b7ab},
+		{0x00000000, "001122", 0x8eb51c3d},
+		{0x00000000, "00112233", 0xb4471bf8},
+		{0x00000000, "0011223344", 0xe2301fa8},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, murmur3(mustHex(t, tt.data), tt.seed), tt.data)
+	}
+}
+
+func TestFilter_BIP37Vectors(t *testing.T) {
+	tests := []struct {
+		tweak  uint32
+		filter string
+	}{
+		{0, "614e9b"},
+		{2147483649, "ce4299"},
+	}
+	for _, tt := range tests {
+		f := NewFilter(3, 0.01, tt.tweak, wire.FilterUpdateAll)
+		f.Add(mustHex(t, "99108ad8ed9bb6274d3980bab5a85c048f0950c8"))
+		require.True(t, f.Test(mustHex(t, "99108ad8ed9bb6274d3980bab5a85c048f0950c8")))
+		require.False(t, f.Test(mustHex(t, "19108ad8ed9bb6274d3980bab5a85c048f0950c8")))
+		f.Add(mustHex(t, "b5a2c786d9ef4658287ced5914b37a1b4aa32eee"))
+		f.Add(mustHex(t, "b9300670b4c5366e95b2699e8b18bc75e5f729c5"))
+
+		msg := f.LoadMessage()
+		require.Equal(t, tt.filter, hex.EncodeToString(msg.Filter))
+		require.EqualValues(t, 5, msg.HashFns)
+		require.Equal(t, tt.tweak, msg.Tweak)
+		require.Equal(t, wire.FilterUpdateAll, msg.Update)
+		require.True(t, LoadFilter(msg).Test(mustHex(t, "99108ad8ed9bb6274d3980bab5a85c048f0950c8")))
+	}
+}
+
+func TestFilter_MatchTx(t *testing.T) {
+	block := primitivestest.LoadBlock(t, primitivestest.GoldenBlock)
+	// Transaction 6 opens a name; transaction 11 only moves coins.
+	open, payment := block.Transactions[6], block.Transactions[11]
+	require.Equal(t, primitives.CovenantOpen, open.Outputs[0].Covenant.Type)
+	var paymentID [32]byte
+	copy(paymentID[:], payment.ID())
+	spend := &primitives.Transaction{
+		Inputs: []*primitives.Input{{
+			Prevout: &primitives.Outpoint{Hash: paymentID, Index: 1},
+		}},
+	}
+
+	tests := []struct {
+		name    string
+		update  uint8
+		watch   func(f *Filter)
+		tx      *primitives.Transaction
+		matches bool
+		spend   bool
+	}{
+		{
+			"nothing",
+			wire.FilterUpdateAll,
+			func(f *Filter) {},
+			payment,
+			false,
+			false,
+		},
+		{
+			"txid",
+			wire.FilterUpdateAll,
+			func(f *Filter) { f.Add(payment.ID()) },
+			payment,
+			true,
+			false,
+		},
+		{
+			"address",
+			wire.FilterUpdateAll,
+			func(f *Filter) { f.AddAddress(payment.Outputs[1].Address) },
+			payment,
+			true,
+			true,
+		},
+		{
+			"address without update",
+			wire.FilterUpdateNone,
+			func(f *Filter) { f.AddAddress(payment.Outputs[1].Address) },
+			payment,
+			true,
+			false,
+		},
+		{
+			"address with pubkeyhash update",
+			wire.FilterUpdateP2PKOnly,
+			func(f *Filter) { f.AddAddress(payment.Outputs[1].Address) },
+			payment,
+			true,
+			true,
+		},
+		{
+			"name hash",
+			wire.FilterUpdateAll,
+			func(f *Filter) { f.Add(open.Outputs[0].Covenant.Items[0]) },
+			open,
+			true,
+			false,
+		},
+		{
+			"outpoint",
+			wire.FilterUpdateAll,
+			func(f *Filter) { f.AddOutpoint(spend.Inputs[0].Prevout) },
+			spend,
+			true,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFilter(10, 0.000001, 0, tt.update)
+			tt.watch(f)
+			require.Equal(t, tt.matches, f.MatchTx(tt.tx))
+			require.Equal(t, tt.spend, f.MatchTx(spend))
+		})
+	}
+}
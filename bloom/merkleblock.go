@@ -0,0 +1,185 @@
+package bloom
+
+import (
+	"errors"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/mslipper/handshake/wire"
+	"golang.org/x/crypto/blake2b"
+)
+
+var (
+	ErrInvalidMerkleBlock = errors.New("bloom: invalid partial merkle tree")
+	ErrMerkleRootMismatch = errors.New("bloom: merkle root mismatch")
+)
+
+// merkleSentinel pairs with a node that has no sibling, as in
+// primitives.MerkleRoot.
+var merkleSentinel = blake2b.Sum256(nil)
+
+// NewMerkleBlock builds a merkleblock for block, proving the transactions
+// that match filter. The matching transactions are returned in block
+// order.
+func NewMerkleBlock(block *primitives.Block, filter *Filter) (*wire.MerkleBlockMessage, []*primitives.Transaction) {
+	header := *block
+	header.Transactions = nil
+	tree := &partialTree{
+		total: len(block.Transactions),
+	}
+	var matched []*primitives.Transaction
+	for _, tx := range block.Transactions {
+		var hash [32]byte
+		copy(hash[:], tx.ID())
+		tree.txids = append(tree.txids, hash)
+		match := filter.MatchTx(tx)
+		tree.matches = append(tree.matches, match)
+		if match {
+			matched = append(matched, tx)
+		}
+	}
+	if tree.total > 0 {
+		tree.build(tree.height(), 0)
+	}
+	return &wire.MerkleBlockMessage{
+		Header:  &header,
+		TotalTx: uint32(tree.total),
+		Hashes:  tree.hashes,
+		Flags:   tree.flags,
+	}, matched
+}
+
+// VerifyMerkleBlock checks the partial merkle tree in msg against the
+// header's merkle root and returns the IDs of the matched transactions in
+// block order.
+func VerifyMerkleBlock(msg *wire.MerkleBlockMessage) ([][32]byte, error) {
+	if msg.Header == nil || msg.TotalTx == 0 || msg.TotalTx > wire.MaxMerkleBlockTxs {
+		return nil, ErrInvalidMerkleBlock
+	}
+	if len(msg.Hashes) > int(msg.TotalTx) || len(msg.Flags)*8 < len(msg.Hashes) {
+		return nil, ErrInvalidMerkleBlock
+	}
+	tree := &partialTree{
+		total:  int(msg.TotalTx),
+		hashes: msg.Hashes,
+		flags:  msg.Flags,
+	}
+	var matched [][32]byte
+	root, err := tree.extract(tree.height(), 0, &matched)
+	if err != nil {
+		return nil, err
+	}
+	// Every hash must be used, and only padding may remain in the flags.
+	if tree.hashUsed != len(tree.hashes) || (tree.bitsUsed+7)/8 != len(tree.flags) {
+		return nil, ErrInvalidMerkleBlock
+	}
+	if root != msg.Header.MerkleRoot {
+		return nil, ErrMerkleRootMismatch
+	}
+	return matched, nil
+}
+
+type partialTree struct {
+	total    int
+	txids    [][32]byte
+	matches  []bool
+	hashes   [][32]byte
+	flags    []byte
+	bitsUsed int
+	hashUsed int
+}
+
+// width returns the number of nodes at height in the tree.
+func (t *partialTree) width(height uint) int {
+	return (t.total + (1 << height) - 1) >> height
+}
+
+func (t *partialTree) height() uint {
+	var height uint
+	for t.width(height) > 1 {
+		height++
+	}
+	return height
+}
+
+func (t *partialTree) hash(height uint, pos int) [32]byte {
+	if height == 0 {
+		return primitives.MerkleHashLeaf(t.txids[pos][:])
+	}
+	left := t.hash(height-1, pos*2)
+	right := merkleSentinel
+	if pos*2+1 < t.width(height-1) {
+		right = t.hash(height-1, pos*2+1)
+	}
+	return primitives.MerkleHashInternal(left, right)
+}
+
+// build walks the tree depth first, emitting a flag bit per node visited
+// and a hash for every node that is not expanded. Leaves are sent as
+// transaction IDs.
+func (t *partialTree) build(height uint, pos int) {
+	parent := false
+	for i := pos << height; i < (pos+1)<<height && i < t.total; i++ {
+		if t.matches[i] {
+			parent = true
+			break
+		}
+	}
+	t.pushBit(parent)
+	if height == 0 {
+		t.hashes = append(t.hashes, t.txids[pos])
+		return
+	}
+	if !parent {
+		t.hashes = append(t.hashes, t.hash(height, pos))
+		return
+	}
+	t.build(height-1, pos*2)
+	if pos*2+1 < t.width(height-1) {
+		t.build(height-1, pos*2+1)
+	}
+}
+
+func (t *partialTree) pushBit(bit bool) {
+	if t.bitsUsed%8 == 0 {
+		t.flags = append(t.flags, 0)
+	}
+	if bit {
+		t.flags[t.bitsUsed/8] |= 1 << (uint(t.bitsUsed) % 8)
+	}
+	t.bitsUsed++
+}
+
+func (t *partialTree) extract(height uint, pos int, matched *[][32]byte) ([32]byte, error) {
+	if t.bitsUsed >= len(t.flags)*8 {
+		return [32]byte{}, ErrInvalidMerkleBlock
+	}
+	parent := t.flags[t.bitsUsed/8]&(1<<(uint(t.bitsUsed)%8)) != 0
+	t.bitsUsed++
+
+	if height == 0 || !parent {
+		if t.hashUsed >= len(t.hashes) {
+			return [32]byte{}, ErrInvalidMerkleBlock
+		}
+		hash := t.hashes[t.hashUsed]
+		t.hashUsed++
+		if height != 0 {
+			return hash, nil
+		}
+		if parent {
+			*matched = append(*matched, hash)
+		}
+		return primitives.MerkleHashLeaf(hash[:]), nil
+	}
+
+	left, err := t.extract(height-1, pos*2, matched)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	right := merkleSentinel
+	if pos*2+1 < t.width(height-1) {
+		right, err = t.extract(height-1, pos*2+1, matched)
+		if err != nil {
+			return [32]byte{}, err
+		}
+	}
+	return primitives.MerkleHashInternal(left, right), nil
+}
@@ -0,0 +1,134 @@
+package bloom
+
+import (
+	"github.com/mslipper/handshake/primitives"
+	"github.com/mslipper/handshake/primitives/primitivestest"
+	"github.com/mslipper/handshake/wire"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func txIDs(txs []*primitives.Transaction) [][32]byte {
+	var ids [][32]byte
+	for _, tx := range txs {
+		var id [32]byte
+		copy(id[:], tx.ID())
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestMerkleBlock_RoundTrip(t *testing.T) {
+	block := primitivestest.LoadBlock(t, primitivestest.GoldenBlock)
+	tests := []struct {
+		name    string
+		block   *primitives.Block
+		indexes []int
+	}{
+		{"none", block, nil},
+		{"first", block, []int{0}},
+		{"last", block, []int{22}},
+		{"several", block, []int{3, 4, 11, 21}},
+		{"all", block, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22}},
+		{"single", &primitives.Block{
+			MerkleRoot:   primitives.MerkleRoot([][]byte{block.Transactions[0].ID()}),
+			Transactions: block.Transactions[:1],
+		}, []int{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := NewFilter(len(tt.indexes), 0.000001, 0, wire.FilterUpdateNone)
+			var expected []*primitives.Transaction
+			for _, i := range tt.indexes {
+				filter.Add(tt.block.Transactions[i].ID())
+				expected = append(expected, tt.block.Transactions[i])
+			}
+			msg, matched := NewMerkleBlock(tt.block, filter)
+			require.Equal(t, expected, matched)
+			require.EqualValues(t, len(tt.block.Transactions), msg.TotalTx)
+			require.Nil(t, msg.Header.Transactions)
+
+			ids, err := VerifyMerkleBlock(msg)
+			require.NoError(t, err)
+			require.Equal(t, txIDs(expected), ids)
+		})
+	}
+}
+
+func TestVerifyMerkleBlock_Errors(t *testing.T) {
+	block := primitivestest.LoadBlock(t, primitivestest.GoldenBlock)
+	filter := NewFilter(2, 0.000001, 0, wire.FilterUpdateNone)
+	filter.Add(block.Transactions[4].ID())
+	filter.Add(block.Transactions[17].ID())
+
+	tests := []struct {
+		name   string
+		mutate func(msg *wire.MerkleBlockMessage)
+		err    error
+	}{
+		{
+			"no transactions",
+			func(msg *wire.MerkleBlockMessage) {
+				msg.TotalTx = 0
+			},
+			ErrInvalidMerkleBlock,
+		},
+		{
+			"wrong total",
+			func(msg *wire.MerkleBlockMessage) {
+				msg.TotalTx = 1
+			},
+			ErrInvalidMerkleBlock,
+		},
+		{
+			"extra hash",
+			func(msg *wire.MerkleBlockMessage) {
+				msg.Hashes = append(msg.Hashes, [32]byte{})
+			},
+			ErrInvalidMerkleBlock,
+		},
+		{
+			"missing hash",
+			func(msg *wire.MerkleBlockMessage) {
+				msg.Hashes = msg.Hashes[:len(msg.Hashes)-1]
+			},
+			ErrInvalidMerkleBlock,
+		},
+		{
+			"truncated flags",
+			func(msg *wire.MerkleBlockMessage) {
+				msg.Flags = msg.Flags[:1]
+			},
+			ErrInvalidMerkleBlock,
+		},
+		{
+			"extra flags",
+			func(msg *wire.MerkleBlockMessage) {
+				msg.Flags = append(msg.Flags, 0)
+			},
+			ErrInvalidMerkleBlock,
+		},
+		{
+			"tampered hash",
+			func(msg *wire.MerkleBlockMessage) {
+				msg.Hashes[0][0] ^= 0xff
+			},
+			ErrMerkleRootMismatch,
+		},
+		{
+			"wrong root",
+			func(msg *wire.MerkleBlockMessage) {
+				msg.Header.MerkleRoot[0] ^= 0xff
+			},
+			ErrMerkleRootMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, _ := NewMerkleBlock(block, filter)
+			tt.mutate(msg)
+			_, err := VerifyMerkleBlock(msg)
+			require.Equal(t, tt.err, err)
+		})
+	}
+}
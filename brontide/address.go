@@ -0,0 +1,38 @@
+package brontide
+
+import (
+	"encoding/base32"
+	"errors"
+	"github.com/btcsuite/btcd/btcec"
+	"net"
+	"strconv"
+	"strings"
+)
+
+var keyEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// ParseIdentityAddr parses an address in hsd's "<base32 key>@host[:port]"
+// form. defaultPort is used when the address has no port.
+func ParseIdentityAddr(addr string, defaultPort int) (*btcec.PublicKey, string, error) {
+	parts := strings.SplitN(addr, "@", 2)
+	if len(parts) != 2 {
+		return nil, "", errors.New("address has no identity key")
+	}
+	keyB, err := keyEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, "", err
+	}
+	pub, err := btcec.ParsePubKey(keyB, btcec.S256())
+	if err != nil {
+		return nil, "", ErrBadKey
+	}
+	host := parts[1]
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), strconv.Itoa(defaultPort))
+	}
+	return pub, host, nil
+}
+
+func FormatIdentityAddr(pub *btcec.PublicKey, hostPort string) string {
+	return keyEncoding.EncodeToString(pub.SerializeCompressed()) + "@" + hostPort
+}
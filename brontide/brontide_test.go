@@ -0,0 +1,268 @@
+package brontide
+
+import (
+	"bytes"
+	"encoding/hex"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testKey(b byte) *btcec.PrivateKey {
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat([]byte{b}, 32))
+	return key
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+// Apart from its prologue and length framing, brontide's handshake is the
+// one specified in BOLT 8, so the BOLT 8 vectors apply to the acts.
+func TestMachine_Handshake(t *testing.T) {
+	initiator := newMachine(true, testKey(0x11), testKey(0x21).PubKey())
+	initiator.prologue = "lightning"
+	initiator.genEphemeral = func() (*btcec.PrivateKey, error) {
+		return testKey(0x12), nil
+	}
+	responder := newMachine(false, testKey(0x21), nil)
+	responder.prologue = "lightning"
+	responder.genEphemeral = func() (*btcec.PrivateKey, error) {
+		return testKey(0x22), nil
+	}
+
+	actOne, err := initiator.genActOne()
+	require.NoError(t, err)
+	require.Equal(t, "00036360e856310ce5d294e8be33fc807077dc56ac80d95d9cd4ddbd21325eff73f70df6086551151f58b8afe6c195782c6a", hex.EncodeToString(actOne[:]))
+	require.NoError(t, responder.recvActOne(actOne))
+
+	actTwo, err := responder.genActTwo()
+	require.NoError(t, err)
+	require.Equal(t, "0002466d7fcae563e5cb09a0d1870bb580344804617879a14949cf22285f1bae3f276e2470b93aac583c9ef6eafca3f730ae", hex.EncodeToString(actTwo[:]))
+	require.NoError(t, initiator.recvActTwo(actTwo))
+
+	actThree := initiator.genActThree()
+	require.Equal(t, "00b9e3a702e93e3a9948c2ed6e5fd7590a6e1c3a0344cfc9d5b57357049aa22355361aa02e55a8fc28fef5bd6d71ad0c38228dc68b1c466263b47fdf31e560e139ba", hex.EncodeToString(actThree[:]))
+	require.NoError(t, responder.recvActThree(actThree))
+
+	require.Equal(t, mustHex(t, "969ab31b4d288cedf6218839b27a3e2140827047f2c0f01bf5c04435d43511a9"), initiator.sendCipher.key[:])
+	require.Equal(t, mustHex(t, "bb9020b8965f4df047e07f955f3c4b88418984aadc5cdb35096b9ea8fa5c3442"), initiator.recvCipher.key[:])
+	require.Equal(t, initiator.sendCipher.key, responder.recvCipher.key)
+	require.Equal(t, initiator.recvCipher.key, responder.sendCipher.key)
+	require.True(t, responder.remoteStatic.IsEqual(testKey(0x11).PubKey()))
+}
+
+func TestMachine_BadActs(t *testing.T) {
+	responder := newMachine(false, testKey(0x21), nil)
+	initiator := newMachine(true, testKey(0x11), testKey(0x21).PubKey())
+	actOne, err := initiator.genActOne()
+	require.NoError(t, err)
+
+	badVersion := actOne
+	badVersion[0] = 1
+	require.Equal(t, ErrBadVersion, responder.recvActOne(badVersion))
+
+	badTag := actOne
+	badTag[ActOneSize-1] ^= 0x01
+	require.Equal(t, ErrBadTag, responder.recvActOne(badTag))
+
+	badKey := actOne
+	badKey[1] = 0x05
+	require.Equal(t, ErrBadKey, responder.recvActOne(badKey))
+
+	wrongResponder := newMachine(false, testKey(0x22), nil)
+	require.Equal(t, ErrBadTag, wrongResponder.recvActOne(actOne))
+}
+
+func pipe(t *testing.T) (*Conn, *Conn) {
+	clientRaw, serverRaw := net.Pipe()
+	type result struct {
+		conn *Conn
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		conn, err := NewServerConn(serverRaw, testKey(0x21))
+		ch <- result{conn, err}
+	}()
+	client, err := NewClientConn(clientRaw, testKey(0x11), testKey(0x21).PubKey())
+	require.NoError(t, err)
+	res := <-ch
+	require.NoError(t, res.err)
+	return client, res.conn
+}
+
+func TestConn_Pipe(t *testing.T) {
+	client, server := pipe(t)
+	defer client.Close()
+	defer server.Close()
+	require.True(t, server.RemotePub().IsEqual(testKey(0x11).PubKey()))
+	require.True(t, client.RemotePub().IsEqual(testKey(0x21).PubKey()))
+
+	go func() {
+		_, _ = client.Write([]byte("hello, "))
+		_, _ = client.Write([]byte("world"))
+	}()
+	buf := make([]byte, 12)
+	_, err := io.ReadFull(server, buf)
+	require.NoError(t, err)
+	require.Equal(t, "hello, world", string(buf))
+
+	// Push both directions past a key rotation.
+	msgs := RotationInterval*2 + 10
+	errCh := make(chan error, 1)
+	go func() {
+		for i := 0; i < msgs; i++ {
+			msg, err := server.ReadMessage()
+			if err != nil {
+				errCh <- err
+				return
+			}
+			if err := server.WriteMessage(msg); err != nil {
+				errCh <- err
+				return
+			}
+		}
+		errCh <- nil
+	}()
+	initialKey := client.m.sendCipher.key
+	for i := 0; i < msgs; i++ {
+		msg := []byte{byte(i), byte(i >> 8)}
+		require.NoError(t, client.WriteMessage(msg))
+		echo, err := client.ReadMessage()
+		require.NoError(t, err)
+		require.Equal(t, msg, echo)
+	}
+	require.NoError(t, <-errCh)
+	require.NotEqual(t, initialKey, client.m.sendCipher.key)
+}
+
+func TestConn_WrongRemoteKey(t *testing.T) {
+	clientRaw, serverRaw := net.Pipe()
+	defer clientRaw.Close()
+	defer serverRaw.Close()
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := NewServerConn(serverRaw, testKey(0x21))
+		serverRaw.Close()
+		errCh <- err
+	}()
+	_, err := NewClientConn(clientRaw, testKey(0x11), testKey(0x33).PubKey())
+	require.Error(t, err)
+	require.Equal(t, ErrBadTag, <-errCh)
+}
+
+func TestConn_TamperedMessage(t *testing.T) {
+	clientRaw, serverRaw := net.Pipe()
+	go func() {
+		conn, err := NewServerConn(serverRaw, testKey(0x21))
+		if err == nil {
+			_ = conn.WriteMessage([]byte("payload"))
+		}
+	}()
+	m := newMachine(true, testKey(0x11), testKey(0x21).PubKey())
+	client, err := handshake(clientRaw, m)
+	require.NoError(t, err)
+	defer client.Close()
+
+	raw := make([]byte, HeaderSize+len("payload")+MACSize)
+	_, err = io.ReadFull(clientRaw, raw)
+	require.NoError(t, err)
+	raw[HeaderSize] ^= 0x01
+	_, err = m.readMessage(bytes.NewReader(raw))
+	require.Equal(t, ErrBadTag, err)
+}
+
+func TestListener(t *testing.T) {
+	raw, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	l := NewListener(raw, testKey(0x21))
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(conn, conn)
+	}()
+	conn, err := Dial(testKey(0x11), testKey(0x21).PubKey(), raw.Addr().String(), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	require.Equal(t, "ping", string(buf))
+}
+
+func TestListener_StalledHandshake(t *testing.T) {
+	raw, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	l := NewListener(raw, testKey(0x21))
+
+	// Neither a client that never sends act one nor one that sends garbage
+	// holds up the next connection.
+	stalled, err := net.Dial("tcp", raw.Addr().String())
+	require.NoError(t, err)
+	defer stalled.Close()
+	garbage, err := net.Dial("tcp", raw.Addr().String())
+	require.NoError(t, err)
+	defer garbage.Close()
+	_, err = garbage.Write(make([]byte, ActOneSize))
+	require.NoError(t, err)
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+	conn, err := Dial(testKey(0x11), testKey(0x21).PubKey(), raw.Addr().String(), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	select {
+	case sConn := <-accepted:
+		defer sConn.Close()
+		require.True(t, sConn.(*Conn).RemotePub().IsEqual(testKey(0x11).PubKey()))
+	case <-time.After(5 * time.Second):
+		t.Fatal("accept blocked on a stalled handshake")
+	}
+
+	require.NoError(t, l.Close())
+	_, err = l.Accept()
+	require.Error(t, err)
+}
+
+func TestParseIdentityAddr(t *testing.T) {
+	pub := testKey(0x21).PubKey()
+	addr := FormatIdentityAddr(pub, "127.0.0.1:44806")
+	require.Len(t, strings.Split(addr, "@")[0], 53)
+
+	parsed, host, err := ParseIdentityAddr(addr, 1)
+	require.NoError(t, err)
+	require.True(t, pub.IsEqual(parsed))
+	require.Equal(t, "127.0.0.1:44806", host)
+
+	_, host, err = ParseIdentityAddr(strings.Split(addr, ":")[0], 44806)
+	require.NoError(t, err)
+	require.Equal(t, "127.0.0.1:44806", host)
+
+	_, host, err = ParseIdentityAddr(strings.Split(addr, "@")[0]+"@::1", 44806)
+	require.NoError(t, err)
+	require.Equal(t, "[::1]:44806", host)
+
+	_, _, err = ParseIdentityAddr("127.0.0.1:44806", 44806)
+	require.Error(t, err)
+	_, _, err = ParseIdentityAddr("aaaa@127.0.0.1", 44806)
+	require.Error(t, err)
+}
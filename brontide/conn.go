@@ -0,0 +1,289 @@
+package brontide
+
+import (
+	"bytes"
+	"github.com/btcsuite/btcd/btcec"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const HandshakeTimeout = 15 * time.Second
+
+// Conn is a net.Conn that encrypts everything written to it with brontide.
+// Writes larger than MaxMessageSize are split over several messages; reads
+// are served from the current decrypted message until it is used up.
+type Conn struct {
+	conn net.Conn
+	m    *machine
+
+	readMtx sync.Mutex
+	readBuf bytes.Reader
+
+	writeMtx sync.Mutex
+}
+
+var _ net.Conn = (*Conn)(nil)
+
+// Dial connects to a peer whose identity key is known and runs the
+// initiator side of the handshake.
+func Dial(localPriv *btcec.PrivateKey, remotePub *btcec.PublicKey, addr string, dialer func(network, addr string) (net.Conn, error)) (*Conn, error) {
+	if dialer == nil {
+		dialer = net.Dial
+	}
+	conn, err := dialer("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	bConn, err := NewClientConn(conn, localPriv, remotePub)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return bConn, nil
+}
+
+// NewClientConn runs the initiator side of the handshake over an existing
+// connection. The underlying connection is not closed on failure.
+func NewClientConn(conn net.Conn, localPriv *btcec.PrivateKey, remotePub *btcec.PublicKey) (*Conn, error) {
+	return handshake(conn, newMachine(true, localPriv, remotePub))
+}
+
+// NewServerConn runs the responder side of the handshake over an existing
+// connection. The initiator's identity key is available from RemotePub
+// afterwards.
+func NewServerConn(conn net.Conn, localPriv *btcec.PrivateKey) (*Conn, error) {
+	return handshake(conn, newMachine(false, localPriv, nil))
+}
+
+func handshake(conn net.Conn, m *machine) (*Conn, error) {
+	if err := conn.SetDeadline(time.Now().Add(HandshakeTimeout)); err != nil {
+		return nil, err
+	}
+	var err error
+	if m.initiator {
+		err = initiatorHandshake(conn, m)
+	} else {
+		err = responderHandshake(conn, m)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+	return &Conn{
+		conn: conn,
+		m:    m,
+	}, nil
+}
+
+func initiatorHandshake(conn net.Conn, m *machine) error {
+	actOne, err := m.genActOne()
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(actOne[:]); err != nil {
+		return err
+	}
+	var actTwo [ActTwoSize]byte
+	if _, err := io.ReadFull(conn, actTwo[:]); err != nil {
+		return err
+	}
+	if err := m.recvActTwo(actTwo); err != nil {
+		return err
+	}
+	actThree := m.genActThree()
+	_, err = conn.Write(actThree[:])
+	return err
+}
+
+func responderHandshake(conn net.Conn, m *machine) error {
+	var actOne [ActOneSize]byte
+	if _, err := io.ReadFull(conn, actOne[:]); err != nil {
+		return err
+	}
+	if err := m.recvActOne(actOne); err != nil {
+		return err
+	}
+	actTwo, err := m.genActTwo()
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(actTwo[:]); err != nil {
+		return err
+	}
+	var actThree [ActThreeSize]byte
+	if _, err := io.ReadFull(conn, actThree[:]); err != nil {
+		return err
+	}
+	return m.recvActThree(actThree)
+}
+
+func (c *Conn) RemotePub() *btcec.PublicKey {
+	return c.m.remoteStatic
+}
+
+func (c *Conn) LocalPub() *btcec.PublicKey {
+	return c.m.localStatic.PubKey()
+}
+
+// ReadMessage returns the next whole brontide message. It must not be mixed
+// with Read while a partially read message is buffered.
+func (c *Conn) ReadMessage() ([]byte, error) {
+	c.readMtx.Lock()
+	defer c.readMtx.Unlock()
+	return c.m.readMessage(c.conn)
+}
+
+func (c *Conn) WriteMessage(p []byte) error {
+	c.writeMtx.Lock()
+	defer c.writeMtx.Unlock()
+	return c.m.writeMessage(c.conn, p)
+}
+
+func (c *Conn) Read(p []byte) (int, error) {
+	c.readMtx.Lock()
+	defer c.readMtx.Unlock()
+	for c.readBuf.Len() == 0 {
+		msg, err := c.m.readMessage(c.conn)
+		if err != nil {
+			return 0, err
+		}
+		c.readBuf.Reset(msg)
+	}
+	return c.readBuf.Read(p)
+}
+
+func (c *Conn) Write(p []byte) (int, error) {
+	c.writeMtx.Lock()
+	defer c.writeMtx.Unlock()
+	var n int
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > MaxMessageSize {
+			chunk = chunk[:MaxMessageSize]
+		}
+		if err := c.m.writeMessage(c.conn, chunk); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+		p = p[len(chunk):]
+	}
+	return n, nil
+}
+
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// maxPendingHandshakes bounds the handshakes a Listener runs at once.
+// Connections beyond it are closed immediately.
+const maxPendingHandshakes = 64
+
+type acceptResult struct {
+	conn net.Conn
+	err  error
+}
+
+// Listener accepts brontide connections. Handshakes run in the background,
+// so a peer that stalls mid-handshake does not hold up others, and Accept
+// only returns connections whose handshake has completed. Failed
+// handshakes are closed without being reported.
+type Listener struct {
+	net.Listener
+	localPriv *btcec.PrivateKey
+	accepted  chan acceptResult
+	pending   chan struct{}
+	done      chan struct{}
+	err       error
+}
+
+func NewListener(l net.Listener, localPriv *btcec.PrivateKey) *Listener {
+	bl := &Listener{
+		Listener:  l,
+		localPriv: localPriv,
+		accepted:  make(chan acceptResult),
+		pending:   make(chan struct{}, maxPendingHandshakes),
+		done:      make(chan struct{}),
+	}
+	go bl.acceptLoop()
+	return bl
+}
+
+// Accept waits for the next connection to complete its handshake.
+// Temporary errors from the underlying listener are passed through; after
+// any other error the listener is done and Accept keeps returning it.
+func (l *Listener) Accept() (net.Conn, error) {
+	select {
+	case res := <-l.accepted:
+		return res.conn, res.err
+	case <-l.done:
+		return nil, l.err
+	}
+}
+
+func (l *Listener) acceptLoop() {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				l.deliver(nil, err)
+				continue
+			}
+			l.err = err
+			close(l.done)
+			return
+		}
+		select {
+		case l.pending <- struct{}{}:
+			go l.handshake(conn)
+		default:
+			conn.Close()
+		}
+	}
+}
+
+func (l *Listener) handshake(conn net.Conn) {
+	defer func() {
+		<-l.pending
+	}()
+	bConn, err := NewServerConn(conn, l.localPriv)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	if !l.deliver(bConn, nil) {
+		bConn.Close()
+	}
+}
+
+func (l *Listener) deliver(conn net.Conn, err error) bool {
+	select {
+	case l.accepted <- acceptResult{conn, err}:
+		return true
+	case <-l.done:
+		return false
+	}
+}
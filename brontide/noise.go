@@ -0,0 +1,337 @@
+package brontide
+
+import (
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+	"io"
+)
+
+const (
+	ProtocolName     = "Noise_XK_secp256k1_ChaChaPoly_SHA256"
+	Prologue         = "hns"
+	RotationInterval = 1000
+	Version          = 0
+	ActOneSize       = 50
+	ActTwoSize       = 50
+	ActThreeSize     = 66
+	MACSize          = 16
+	LengthSize       = 4
+	HeaderSize       = LengthSize + MACSize
+	MaxMessageSize   = 8000000 + 9
+)
+
+var (
+	ErrBadVersion      = errors.New("brontide: bad handshake version")
+	ErrBadKey          = errors.New("brontide: bad public key")
+	ErrBadTag          = errors.New("brontide: bad tag")
+	ErrMessageTooLarge = errors.New("brontide: message too large")
+)
+
+// cipherState is a ChaCha20-Poly1305 key with a counter nonce. The key is
+// ratcheted forward through HKDF every RotationInterval messages.
+type cipherState struct {
+	nonce uint32
+	key   [32]byte
+	salt  [32]byte
+	aead  cipher.AEAD
+}
+
+func (c *cipherState) initKey(key [32]byte) {
+	c.key = key
+	c.nonce = 0
+	aead, err := chacha20poly1305.New(key[:])
+	if err != nil {
+		panic(err)
+	}
+	c.aead = aead
+}
+
+func (c *cipherState) initSalt(key [32]byte, salt [32]byte) {
+	c.salt = salt
+	c.initKey(key)
+}
+
+func (c *cipherState) iv() []byte {
+	var iv [chacha20poly1305.NonceSize]byte
+	binary.LittleEndian.PutUint32(iv[4:], c.nonce)
+	return iv[:]
+}
+
+func (c *cipherState) advance() {
+	c.nonce++
+	if c.nonce == RotationInterval {
+		salt, next := expand(c.key[:], c.salt[:], nil)
+		c.salt = salt
+		c.initKey(next)
+	}
+}
+
+func (c *cipherState) encrypt(dst, pt, ad []byte) []byte {
+	out := c.aead.Seal(dst, c.iv(), pt, ad)
+	c.advance()
+	return out
+}
+
+func (c *cipherState) decrypt(dst, ct, ad []byte) ([]byte, error) {
+	out, err := c.aead.Open(dst, c.iv(), ct, ad)
+	if err != nil {
+		return nil, ErrBadTag
+	}
+	c.advance()
+	return out, nil
+}
+
+type symmetricState struct {
+	cipherState
+	chaining [32]byte
+	digest   [32]byte
+}
+
+func (s *symmetricState) initSymmetric(protocolName string) {
+	s.digest = sha256.Sum256([]byte(protocolName))
+	s.chaining = s.digest
+	s.initKey([32]byte{})
+}
+
+func (s *symmetricState) mixKey(input []byte) {
+	chaining, temp := expand(input, s.chaining[:], nil)
+	s.chaining = chaining
+	s.initKey(temp)
+}
+
+func (s *symmetricState) mixHash(data []byte) {
+	h := sha256.New()
+	h.Write(s.digest[:])
+	h.Write(data)
+	copy(s.digest[:], h.Sum(nil))
+}
+
+func (s *symmetricState) encryptHash(pt []byte) []byte {
+	ct := s.encrypt(nil, pt, s.digest[:])
+	s.mixHash(ct)
+	return ct
+}
+
+func (s *symmetricState) decryptHash(ct []byte) ([]byte, error) {
+	pt, err := s.decrypt(nil, ct, s.digest[:])
+	if err != nil {
+		return nil, err
+	}
+	s.mixHash(ct)
+	return pt, nil
+}
+
+// machine runs the Noise_XK handshake and, once complete, frames and
+// encrypts transport messages. The initiator must know the responder's
+// static key up front.
+type machine struct {
+	symmetricState
+
+	initiator       bool
+	prologue        string
+	localStatic     *btcec.PrivateKey
+	localEphemeral  *btcec.PrivateKey
+	remoteStatic    *btcec.PublicKey
+	remoteEphemeral *btcec.PublicKey
+	genEphemeral    func() (*btcec.PrivateKey, error)
+
+	sendCipher cipherState
+	recvCipher cipherState
+}
+
+func newMachine(initiator bool, localStatic *btcec.PrivateKey, remoteStatic *btcec.PublicKey) *machine {
+	return &machine{
+		initiator:    initiator,
+		prologue:     Prologue,
+		localStatic:  localStatic,
+		remoteStatic: remoteStatic,
+		genEphemeral: func() (*btcec.PrivateKey, error) {
+			return btcec.NewPrivateKey(btcec.S256())
+		},
+	}
+}
+
+func (m *machine) initState() {
+	m.initSymmetric(ProtocolName)
+	m.mixHash([]byte(m.prologue))
+	if m.initiator {
+		m.mixHash(m.remoteStatic.SerializeCompressed())
+	} else {
+		m.mixHash(m.localStatic.PubKey().SerializeCompressed())
+	}
+}
+
+func (m *machine) genActOne() ([ActOneSize]byte, error) {
+	var act [ActOneSize]byte
+	m.initState()
+	ephemeral, err := m.genEphemeral()
+	if err != nil {
+		return act, err
+	}
+	m.localEphemeral = ephemeral
+	ephemeralPub := ephemeral.PubKey().SerializeCompressed()
+	m.mixHash(ephemeralPub)
+	m.mixKey(ecdh(m.remoteStatic, m.localEphemeral))
+	tag := m.encryptHash(nil)
+	act[0] = Version
+	copy(act[1:], ephemeralPub)
+	copy(act[34:], tag)
+	return act, nil
+}
+
+func (m *machine) recvActOne(act [ActOneSize]byte) error {
+	m.initState()
+	if act[0] != Version {
+		return ErrBadVersion
+	}
+	remoteEphemeral, err := btcec.ParsePubKey(act[1:34], btcec.S256())
+	if err != nil {
+		return ErrBadKey
+	}
+	m.remoteEphemeral = remoteEphemeral
+	m.mixHash(act[1:34])
+	m.mixKey(ecdh(m.remoteEphemeral, m.localStatic))
+	_, err = m.decryptHash(act[34:])
+	return err
+}
+
+func (m *machine) genActTwo() ([ActTwoSize]byte, error) {
+	var act [ActTwoSize]byte
+	ephemeral, err := m.genEphemeral()
+	if err != nil {
+		return act, err
+	}
+	m.localEphemeral = ephemeral
+	ephemeralPub := ephemeral.PubKey().SerializeCompressed()
+	m.mixHash(ephemeralPub)
+	m.mixKey(ecdh(m.remoteEphemeral, m.localEphemeral))
+	tag := m.encryptHash(nil)
+	act[0] = Version
+	copy(act[1:], ephemeralPub)
+	copy(act[34:], tag)
+	return act, nil
+}
+
+func (m *machine) recvActTwo(act [ActTwoSize]byte) error {
+	if act[0] != Version {
+		return ErrBadVersion
+	}
+	remoteEphemeral, err := btcec.ParsePubKey(act[1:34], btcec.S256())
+	if err != nil {
+		return ErrBadKey
+	}
+	m.remoteEphemeral = remoteEphemeral
+	m.mixHash(act[1:34])
+	m.mixKey(ecdh(m.remoteEphemeral, m.localEphemeral))
+	_, err = m.decryptHash(act[34:])
+	return err
+}
+
+func (m *machine) genActThree() [ActThreeSize]byte {
+	var act [ActThreeSize]byte
+	ct := m.encryptHash(m.localStatic.PubKey().SerializeCompressed())
+	m.mixKey(ecdh(m.remoteEphemeral, m.localStatic))
+	tag := m.encryptHash(nil)
+	act[0] = Version
+	copy(act[1:], ct)
+	copy(act[50:], tag)
+	m.split()
+	return act
+}
+
+func (m *machine) recvActThree(act [ActThreeSize]byte) error {
+	if act[0] != Version {
+		return ErrBadVersion
+	}
+	pub, err := m.decryptHash(act[1:50])
+	if err != nil {
+		return err
+	}
+	remoteStatic, err := btcec.ParsePubKey(pub, btcec.S256())
+	if err != nil {
+		return ErrBadKey
+	}
+	m.remoteStatic = remoteStatic
+	m.mixKey(ecdh(m.remoteStatic, m.localEphemeral))
+	if _, err := m.decryptHash(act[50:]); err != nil {
+		return err
+	}
+	m.split()
+	return nil
+}
+
+func (m *machine) split() {
+	h1, h2 := expand(nil, m.chaining[:], nil)
+	if m.initiator {
+		m.sendCipher.initSalt(h1, m.chaining)
+		m.recvCipher.initSalt(h2, m.chaining)
+	} else {
+		m.recvCipher.initSalt(h1, m.chaining)
+		m.sendCipher.initSalt(h2, m.chaining)
+	}
+}
+
+// writeMessage encrypts p as a single transport message: an encrypted
+// little-endian length with its tag, followed by the encrypted body and
+// its tag.
+func (m *machine) writeMessage(w io.Writer, p []byte) error {
+	if len(p) > MaxMessageSize {
+		return ErrMessageTooLarge
+	}
+	var length [LengthSize]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(p)))
+	packet := make([]byte, 0, HeaderSize+len(p)+MACSize)
+	packet = m.sendCipher.encrypt(packet, length[:], nil)
+	packet = m.sendCipher.encrypt(packet, p, nil)
+	_, err := w.Write(packet)
+	return err
+}
+
+func (m *machine) readMessage(r io.Reader) ([]byte, error) {
+	var header [HeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	length, err := m.recvCipher.decrypt(nil, header[:], nil)
+	if err != nil {
+		return nil, err
+	}
+	size := binary.LittleEndian.Uint32(length)
+	if size > MaxMessageSize {
+		return nil, ErrMessageTooLarge
+	}
+	body := make([]byte, int(size)+MACSize)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return m.recvCipher.decrypt(body[:0], body, nil)
+}
+
+func ecdh(pub *btcec.PublicKey, priv *btcec.PrivateKey) []byte {
+	x, y := btcec.S256().ScalarMult(pub.X, pub.Y, priv.D.Bytes())
+	point := &btcec.PublicKey{
+		Curve: btcec.S256(),
+		X:     x,
+		Y:     y,
+	}
+	secret := sha256.Sum256(point.SerializeCompressed())
+	return secret[:]
+}
+
+func expand(secret, salt, info []byte) ([32]byte, [32]byte) {
+	var a, b [32]byte
+	r := hkdf.New(sha256.New, secret, salt, info)
+	if _, err := io.ReadFull(r, a[:]); err != nil {
+		panic(fmt.Sprintf("hkdf: %v", err))
+	}
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		panic(fmt.Sprintf("hkdf: %v", err))
+	}
+	return a, b
+}
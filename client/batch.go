@@ -0,0 +1,222 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrBatchExecuted        = errors.New("batch already executed")
+	ErrBatchMissingResponse = errors.New("no response for batched call")
+)
+
+// Batch collects JSON-RPC calls and sends them to hsd in a single POST.
+// Results are written into the values passed to Queue once Execute returns;
+// each call carries its own error.
+type Batch struct {
+	c        *Client
+	calls    []*BatchCall
+	executed bool
+}
+
+// BatchCall is a single call queued on a Batch. Err is set by Execute.
+type BatchCall struct {
+	Method string
+	Params []interface{}
+	Err    error
+	id     int64
+	result interface{}
+}
+
+func (c *Client) NewBatch() *Batch {
+	return &Batch{
+		c: c,
+	}
+}
+
+// Queue adds a call to the batch. result must be a pointer, or nil if the
+// result should be discarded.
+func (b *Batch) Queue(method string, result interface{}, params ...interface{}) *BatchCall {
+	if params == nil {
+		params = make([]interface{}, 0)
+	}
+	call := &BatchCall{
+		Method: method,
+		Params: params,
+		id:     b.c.nextRPCID(),
+		result: result,
+	}
+	b.calls = append(b.calls, call)
+	return call
+}
+
+func (b *Batch) Len() int {
+	return len(b.calls)
+}
+
+// Execute sends every queued call. The returned error is only non-nil if the
+// request as a whole failed, in which case it is also set on every call.
+// Per-call failures are reported through BatchCall.Err; use Err to get the
+// first of them.
+func (b *Batch) Execute(ctx context.Context) error {
+	if b.executed {
+		return ErrBatchExecuted
+	}
+	b.executed = true
+	if len(b.calls) == 0 {
+		return nil
+	}
+
+	reqs := make([]*rpcRequest, len(b.calls))
+	for i, call := range b.calls {
+		reqs[i] = &rpcRequest{
+			ID:     call.id,
+			Method: call.Method,
+			Params: call.Params,
+		}
+	}
+	idempotent := true
+	for _, call := range b.calls {
+		idempotent = idempotent && isIdempotentRPC(call.Method)
+	}
+	body, err := b.c.postRPC(ctx, reqs, idempotent)
+	if err != nil {
+		b.fail(err)
+		return err
+	}
+	var resBodies []*rpcResponse
+	if err := json.Unmarshal(body, &resBodies); err != nil {
+		decErr := &DecodeError{
+			Body: body,
+			Err:  err,
+		}
+		b.fail(decErr)
+		return decErr
+	}
+
+	byID := make(map[int64]*rpcResponse, len(resBodies))
+	for _, res := range resBodies {
+		byID[res.ID] = res
+	}
+	for _, call := range b.calls {
+		res, ok := byID[call.id]
+		if !ok {
+			call.Err = fmt.Errorf("%s: %w", call.Method, ErrBatchMissingResponse)
+			continue
+		}
+		call.Err = res.decode(call.Method, call.result)
+	}
+	return nil
+}
+
+// Err returns the first per-call error in queue order.
+func (b *Batch) Err() error {
+	for _, call := range b.calls {
+		if call.Err != nil {
+			return call.Err
+		}
+	}
+	return nil
+}
+
+func (b *Batch) fail(err error) {
+	for _, call := range b.calls {
+		call.Err = err
+	}
+}
+
+func (b *Batch) RPCGetBlockByHashWithoutTxs(blockHash string) (*RPCBlockWithoutTxsResponse, *BatchCall) {
+	res := new(RPCBlockWithoutTxsResponse)
+	return res, b.Queue("getblock", res, blockHash, true, false)
+}
+
+func (b *Batch) RPCGetBlockByHashWithTxs(blockHash string) (*RPCBlockWithTxsResponse, *BatchCall) {
+	res := new(RPCBlockWithTxsResponse)
+	return res, b.Queue("getblock", res, blockHash, true, true)
+}
+
+func (b *Batch) RPCGetBlockHexByHash(blockHash string) (*string, *BatchCall) {
+	res := new(string)
+	return res, b.Queue("getblock", res, blockHash, false, false)
+}
+
+func (b *Batch) RPCGetBlockByHeightWithoutTxs(blockHeight int) (*RPCBlockWithoutTxsResponse, *BatchCall) {
+	res := new(RPCBlockWithoutTxsResponse)
+	return res, b.Queue("getblockbyheight", res, blockHeight, true, false)
+}
+
+func (b *Batch) RPCGetBlockByHeightWithTxs(blockHeight int) (*RPCBlockWithTxsResponse, *BatchCall) {
+	res := new(RPCBlockWithTxsResponse)
+	return res, b.Queue("getblockbyheight", res, blockHeight, true, true)
+}
+
+func (b *Batch) RPCGetBlockHexByHeight(blockHeight int) (*string, *BatchCall) {
+	res := new(string)
+	return res, b.Queue("getblockbyheight", res, blockHeight, false, false)
+}
+
+func (b *Batch) RPCGetBlockHashByHeight(blockHeight int) (*string, *BatchCall) {
+	res := new(string)
+	return res, b.Queue("getblockhash", res, blockHeight)
+}
+
+func (b *Batch) RPCGetBlockHeaderByHash(blockHash string) (*GetBlockHeaderResult, *BatchCall) {
+	res := new(GetBlockHeaderResult)
+	return res, b.Queue("getblockheader", res, blockHash, true)
+}
+
+func (b *Batch) RPCGetBlockHeaderHexByHash(blockHash string) (*string, *BatchCall) {
+	res := new(string)
+	return res, b.Queue("getblockheader", res, blockHash, false)
+}
+
+func (b *Batch) RPCGetRawTransaction(txid string) (*RPCTx, *BatchCall) {
+	res := new(RPCTx)
+	return res, b.Queue("getrawtransaction", res, txid, true)
+}
+
+// RPCGetBlocksByHeightWithTxs fetches blocks [start, end) in one batched
+// request. It fails on the first block that could not be fetched.
+func (c *Client) RPCGetBlocksByHeightWithTxs(ctx context.Context, start int, end int) ([]*RPCBlockWithTxsResponse, error) {
+	if end < start {
+		return nil, errors.New("end must not be less than start")
+	}
+	batch := c.NewBatch()
+	res := make([]*RPCBlockWithTxsResponse, end-start)
+	for i := range res {
+		res[i], _ = batch.RPCGetBlockByHeightWithTxs(start + i)
+	}
+	if err := batch.Execute(ctx); err != nil {
+		return nil, err
+	}
+	if err := batch.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// RPCGetBlockHashesByHeight fetches the block hashes for [start, end) in one
+// batched request.
+func (c *Client) RPCGetBlockHashesByHeight(ctx context.Context, start int, end int) ([]string, error) {
+	if end < start {
+		return nil, errors.New("end must not be less than start")
+	}
+	batch := c.NewBatch()
+	hashes := make([]*string, end-start)
+	for i := range hashes {
+		hashes[i], _ = batch.RPCGetBlockHashByHeight(start + i)
+	}
+	if err := batch.Execute(ctx); err != nil {
+		return nil, err
+	}
+	if err := batch.Err(); err != nil {
+		return nil, err
+	}
+	res := make([]string, len(hashes))
+	for i, hash := range hashes {
+		res[i] = *hash
+	}
+	return res, nil
+}
@@ -0,0 +1,155 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+// batchHandler answers batched JSON-RPC requests. respond maps the decoded
+// requests to the raw responses sent back, in the order given.
+func batchHandler(t *testing.T, respond func(reqs []*rpcRequest) []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var reqs []*rpcRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqs))
+		res := respond(reqs)
+		fmt.Fprint(w, "[")
+		for i, body := range res {
+			if i > 0 {
+				fmt.Fprint(w, ",")
+			}
+			fmt.Fprint(w, body)
+		}
+		fmt.Fprint(w, "]")
+	}
+}
+
+func resultJSON(id int64, result string) string {
+	return fmt.Sprintf(`{"result":%s,"error":null,"id":%d}`, result, id)
+}
+
+func errorJSON(id int64, code int, message string) string {
+	return fmt.Sprintf(`{"result":null,"error":{"message":%q,"code":%d},"id":%d}`, message, code, id)
+}
+
+func TestBatch_MixedResults(t *testing.T) {
+	srv := newTestServer(t, batchHandler(t, func(reqs []*rpcRequest) []string {
+		return []string{
+			resultJSON(reqs[0].ID, `"0000000000000424ee6c2a5d6e0da5edfc47a4a10328c1792056ee48303c3e40"`),
+			errorJSON(reqs[1].ID, RPCInvalidParameter, "Block not found."),
+			resultJSON(reqs[2].ID, "7436"),
+		}
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL)
+
+	batch := c.NewBatch()
+	hash, hashCall := batch.RPCGetBlockHashByHeight(2016)
+	_, headerCall := batch.RPCGetBlockHeaderByHash("00")
+	var count int
+	countCall := batch.Queue("getblockcount", &count)
+	require.Equal(t, 3, batch.Len())
+	require.NoError(t, batch.Execute(context.Background()))
+
+	require.NoError(t, hashCall.Err)
+	require.Equal(t, "0000000000000424ee6c2a5d6e0da5edfc47a4a10328c1792056ee48303c3e40", *hash)
+	var rpcErr *RPCError
+	require.True(t, errors.As(headerCall.Err, &rpcErr))
+	require.Equal(t, RPCInvalidParameter, rpcErr.Code)
+	require.Equal(t, "getblockheader", rpcErr.Method)
+	require.NoError(t, countCall.Err)
+	require.Equal(t, 7436, count)
+	require.Equal(t, headerCall.Err, batch.Err())
+
+	var reqs []*rpcRequest
+	require.Len(t, srv.Requests(), 1)
+	require.NoError(t, json.Unmarshal(srv.LastRequest().Body, &reqs))
+	require.Len(t, reqs, 3)
+	require.Equal(t, []interface{}{float64(2016)}, reqs[0].Params)
+	require.Equal(t, ErrBatchExecuted, batch.Execute(context.Background()))
+}
+
+func TestBatch_OutOfOrderAndUnknownIDs(t *testing.T) {
+	srv := newTestServer(t, batchHandler(t, func(reqs []*rpcRequest) []string {
+		// Answer in reverse, drop the second call and add an unrequested
+		// id.
+		return []string{
+			resultJSON(reqs[2].ID, `"c"`),
+			resultJSON(reqs[2].ID+100, `"unknown"`),
+			resultJSON(reqs[0].ID, `"a"`),
+		}
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL)
+
+	batch := c.NewBatch()
+	var a, b, cc string
+	callA := batch.Queue("getblockhash", &a, 0)
+	callB := batch.Queue("getblockhash", &b, 1)
+	callC := batch.Queue("getblockhash", &cc, 2)
+	require.NoError(t, batch.Execute(context.Background()))
+
+	require.NoError(t, callA.Err)
+	require.Equal(t, "a", a)
+	require.True(t, errors.Is(callB.Err, ErrBatchMissingResponse))
+	require.Empty(t, b)
+	require.NoError(t, callC.Err)
+	require.Equal(t, "c", cc)
+	require.Equal(t, callB.Err, batch.Err())
+}
+
+func TestBatch_Empty(t *testing.T) {
+	srv := newTestServer(t, batchHandler(t, func(reqs []*rpcRequest) []string {
+		return nil
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL)
+
+	batch := c.NewBatch()
+	require.Equal(t, 0, batch.Len())
+	require.NoError(t, batch.Execute(context.Background()))
+	require.NoError(t, batch.Err())
+	require.Empty(t, srv.Requests())
+	require.Equal(t, ErrBatchExecuted, batch.Execute(context.Background()))
+}
+
+func TestBatch_RequestFailure(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	defer srv.Close()
+	c := NewClient(srv.URL)
+
+	batch := c.NewBatch()
+	_, call1 := batch.RPCGetBlockHashByHeight(0)
+	_, call2 := batch.RPCGetBlockHashByHeight(1)
+	err := batch.Execute(context.Background())
+	var httpErr *HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	require.Equal(t, http.StatusUnauthorized, httpErr.StatusCode)
+	require.Equal(t, err, call1.Err)
+	require.Equal(t, err, call2.Err)
+}
+
+func TestClient_RPCGetBlockHashesByHeight(t *testing.T) {
+	srv := newTestServer(t, batchHandler(t, func(reqs []*rpcRequest) []string {
+		var res []string
+		for _, req := range reqs {
+			res = append(res, resultJSON(req.ID, fmt.Sprintf(`"hash%v"`, req.Params[0])))
+		}
+		return res
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL)
+
+	hashes, err := c.RPCGetBlockHashesByHeight(context.Background(), 5, 8)
+	require.NoError(t, err)
+	require.Equal(t, []string{"hash5", "hash6", "hash7"}, hashes)
+
+	_, err = c.RPCGetBlockHashesByHeight(context.Background(), 8, 5)
+	require.Error(t, err)
+}
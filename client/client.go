@@ -2,22 +2,31 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/mslipper/handshake/primitives"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync/atomic"
+	"time"
 )
 
 type Client struct {
-	apiKey   string
-	host     string
-	port     int
-	c        *http.Client
-	rpcID    int64
-	basePath string
+	apiKey    string
+	hosts     []string
+	port      int
+	network   primitives.Network
+	c         *http.Client
+	rpcID     int64
+	basePath  string
+	retry     *RetryPolicy
+	cooldown  time.Duration
+	endpoints []*endpoint
 }
 
 type Opt func(c *Client)
@@ -34,15 +43,6 @@ type rpcResponse struct {
 	Error  *RPCError
 }
 
-type RPCError struct {
-	Message string `json:"message"`
-	Code    int    `json:"code"`
-}
-
-func (r *RPCError) Error() string {
-	return r.Message
-}
-
 func WithAPIKey(apiKey string) Opt {
 	return func(c *Client) {
 		c.apiKey = apiKey
@@ -55,9 +55,13 @@ func WithHTTPClient(client *http.Client) Opt {
 	}
 }
 
+// WithNetwork selects the network, and with it the default port of the
+// node or wallet server. Whichever of WithNetwork and WithPort comes last
+// decides the port.
 func WithNetwork(n primitives.Network) Opt {
 	return func(c *Client) {
-		c.port = n.RPCPort()
+		c.network = n
+		c.port = 0
 	}
 }
 
@@ -74,123 +78,215 @@ func WithBasePath(path string) Opt {
 }
 
 func NewClient(host string, opts ...Opt) *Client {
+	c := newClient(host, opts...)
+	if c.port == 0 {
+		c.port = c.network.RPCPort()
+	}
+	return c
+}
+
+func newClient(host string, opts ...Opt) *Client {
 	c := &Client{
-		host: host,
-		port: primitives.NetworkMainnet.RPCPort(),
-		c:    http.DefaultClient,
+		hosts:    []string{host},
+		network:  primitives.NetworkMainnet,
+		c:        http.DefaultClient,
+		retry:    noRetry,
+		cooldown: defaultCooldown,
 	}
 	for _, opt := range opts {
 		opt(c)
 	}
+	for _, h := range c.hosts {
+		c.endpoints = append(c.endpoints, &endpoint{
+			host: h,
+		})
+	}
 	return c
 }
 
-func (c *Client) getJSON(path string, result interface{}) error {
-	req, err := http.NewRequest("GET", c.makeURL(path), nil)
+func (c *Client) getJSON(ctx context.Context, path string, result interface{}) error {
+	return c.doJSON(ctx, "GET", path, nil, result)
+}
+
+func (c *Client) postJSON(ctx context.Context, path string, body interface{}, result interface{}) error {
+	return c.doJSON(ctx, "POST", path, body, result)
+}
+
+func (c *Client) putJSON(ctx context.Context, path string, body interface{}, result interface{}) error {
+	return c.doJSON(ctx, "PUT", path, body, result)
+}
+
+func (c *Client) doJSON(ctx context.Context, method string, path string, body interface{}, result interface{}) error {
+	var reqBody []byte
+	if body != nil {
+		bodyB, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bodyB
+	}
+	resBody, err := c.do(ctx, method, path, reqBody, method == "GET", false)
 	if err != nil {
 		return err
 	}
-	if c.apiKey != "" {
-		req.SetBasicAuth("x", c.apiKey)
+	if err := json.Unmarshal(resBody, result); err != nil {
+		return &DecodeError{
+			Body: resBody,
+			Err:  err,
+		}
 	}
-	res, err := c.c.Do(req)
+	return nil
+}
+
+func (c *Client) executeRPC(ctx context.Context, method string, resp interface{}, params ...interface{}) error {
+	if params == nil {
+		params = make([]interface{}, 0)
+	}
+	body, err := c.postRPC(ctx, &rpcRequest{
+		ID:     c.nextRPCID(),
+		Method: method,
+		Params: params,
+	}, isIdempotentRPC(method))
 	if err != nil {
+		var rpcErr *RPCError
+		if errors.As(err, &rpcErr) {
+			rpcErr.Method = method
+		}
 		return err
 	}
-	defer res.Body.Close()
-	if res.StatusCode != 200 {
-		return fmt.Errorf("non-200 status code: %d", res.StatusCode)
+	resBody := new(rpcResponse)
+	if err := json.Unmarshal(body, resBody); err != nil {
+		return &DecodeError{
+			Body: body,
+			Err:  err,
+		}
 	}
-	body, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return err
+	return resBody.decode(method, resp)
+}
+
+func (r *rpcResponse) decode(method string, resp interface{}) error {
+	if r.Error != nil {
+		r.Error.Method = method
+		return r.Error
 	}
-	if err := json.Unmarshal(body, result); err != nil {
-		return err
+	if resp == nil {
+		return nil
+	}
+	if err := json.Unmarshal(r.Result, resp); err != nil {
+		return &DecodeError{
+			Body: r.Result,
+			Err:  err,
+		}
 	}
 	return nil
 }
 
-func (c *Client) postJSON(path string, body interface{}, result interface{}) error {
-	bodyB, err := json.Marshal(body)
+func (c *Client) postRPC(ctx context.Context, payload interface{}, idempotent bool) ([]byte, error) {
+	reqBody, err := json.Marshal(payload)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	req, err := http.NewRequest("POST", c.makeURL(path), bytes.NewReader(bodyB))
-	if err != nil {
-		return err
+	return c.do(ctx, "POST", "", reqBody, idempotent, true)
+}
+
+// do sends a request to the first healthy endpoint. Idempotent requests are
+// retried according to the client's RetryPolicy, failing over to the next
+// endpoint whenever one fails. Other requests only fail over when the
+// connection could not be made, since the node never saw them. For JSON-RPC
+// requests, an error status whose body carries a JSON-RPC error means the
+// node handled the call, so it is neither retried nor marked unhealthy.
+func (c *Client) do(ctx context.Context, method string, path string, body []byte, idempotent bool, rpc bool) ([]byte, error) {
+	attempts := 1
+	if idempotent {
+		attempts = c.retry.MaxAttempts
 	}
-	req.Header.Set("Content-Type", "application/json")
-	if c.apiKey != "" {
-		req.SetBasicAuth("x", c.apiKey)
+	var err error
+	for attempt, failovers := 0, 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if err := sleepCtx(ctx, c.retry.backoff(attempt)); err != nil {
+				return nil, err
+			}
+		}
+		ep := c.pickEndpoint()
+		req, reqErr := c.newRequest(ctx, ep, method, path, body)
+		if reqErr != nil {
+			return nil, reqErr
+		}
+		var resBody []byte
+		resBody, err = c.send(req)
+		if err == nil {
+			ep.markHealthy()
+			return resBody, nil
+		}
+		if rpc && rpcErrorFromHTTP(err) != nil {
+			ep.markHealthy()
+			return nil, err
+		}
+		if !isRetryable(ctx, err) {
+			return nil, err
+		}
+		ep.markUnhealthy(c.cooldown)
+		if !idempotent && isDialError(err) && failovers < len(c.endpoints)-1 {
+			failovers++
+			attempts++
+		}
 	}
-	res, err := c.c.Do(req)
+	return nil, err
+}
+
+func (c *Client) sendTo(ctx context.Context, ep *endpoint, method string, path string, body []byte) ([]byte, error) {
+	req, err := c.newRequest(ctx, ep, method, path, body)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer res.Body.Close()
-	if res.StatusCode != 200 {
-		return fmt.Errorf("non-200 status code: %d", res.StatusCode)
+	return c.send(req)
+}
+
+func (c *Client) newRequest(ctx context.Context, ep *endpoint, method string, path string, body []byte) (*http.Request, error) {
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
 	}
-	resBody, err := ioutil.ReadAll(res.Body)
+	req, err := http.NewRequestWithContext(ctx, method, c.makeURL(ep.host, path), reqBody)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if err := json.Unmarshal(resBody, result); err != nil {
-		return err
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
 	}
-	return nil
+	return req, nil
 }
 
-func (c *Client) executeRPC(method string, resp interface{}, params ...interface{}) error {
-	reqBody, err := json.Marshal(&rpcRequest{
-		ID:     atomic.AddInt64(&c.rpcID, 1),
-		Method: method,
-		Params: params,
-	})
-	if err != nil {
-		return err
-	}
-	req, err := http.NewRequest("POST", c.makeURL(""), bytes.NewBuffer(reqBody))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
+func (c *Client) nextRPCID() int64 {
+	return atomic.AddInt64(&c.rpcID, 1)
+}
+
+func (c *Client) send(req *http.Request) ([]byte, error) {
 	if c.apiKey != "" {
 		req.SetBasicAuth("x", c.apiKey)
 	}
 	res, err := c.c.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer res.Body.Close()
-	if res.StatusCode != 200 {
-		return fmt.Errorf("non-200 status code: %d", res.StatusCode)
-	}
-	if resp == nil {
-		return nil
-	}
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return err
-	}
-	resBody := new(rpcResponse)
-	if err := json.Unmarshal(body, resBody); err != nil {
-		return err
+		return nil, err
 	}
-	if resBody.Error != nil {
-		return resBody.Error
-	}
-	if err := json.Unmarshal(resBody.Result, resp); err != nil {
-		return err
+	if res.StatusCode != 200 {
+		return nil, newHTTPError(res.StatusCode, body)
 	}
-	return nil
+	return body, nil
 }
 
-func (c *Client) makeURL(path string) string {
+func (c *Client) makeURL(host string, path string) string {
+	if u, err := url.Parse(host); err != nil || u.Port() == "" {
+		host = fmt.Sprintf("%s:%d", host, c.port)
+	}
 	if c.basePath != "" {
-		return fmt.Sprintf("%s:%d/%s/%s", c.host, c.port, c.basePath, path)
+		return fmt.Sprintf("%s/%s/%s", host, c.basePath, path)
 	}
 
-	return fmt.Sprintf("%s:%d/%s", c.host, c.port, path)
+	return fmt.Sprintf("%s/%s", host, path)
 }
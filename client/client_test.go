@@ -0,0 +1,186 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordedRequest is a request received by a test server.
+type recordedRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	User   string
+	Pass   string
+	Body   []byte
+}
+
+// testServer wraps an httptest server, recording every request before
+// passing it to handler.
+type testServer struct {
+	*httptest.Server
+	mtx      sync.Mutex
+	requests []*recordedRequest
+}
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) *testServer {
+	s := new(testServer)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		user, pass, _ := r.BasicAuth()
+		s.mtx.Lock()
+		s.requests = append(s.requests, &recordedRequest{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.Query(),
+			User:   user,
+			Pass:   pass,
+			Body:   body,
+		})
+		s.mtx.Unlock()
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		handler(w, r)
+	}))
+	return s
+}
+
+func (s *testServer) Requests() []*recordedRequest {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]*recordedRequest{}, s.requests...)
+}
+
+func (s *testServer) LastRequest() *recordedRequest {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if len(s.requests) == 0 {
+		return nil
+	}
+	return s.requests[len(s.requests)-1]
+}
+
+// rpcResults returns a handler answering each JSON-RPC method with the raw
+// JSON result registered for it.
+func rpcResults(t *testing.T, results map[string]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := new(rpcRequest)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		result, ok := results[req.Method]
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, `{"result":null,"error":{"message":"Method not found.","code":-32601},"id":%d}`, req.ID)
+			return
+		}
+		fmt.Fprintf(w, `{"result":%s,"error":null,"id":%d}`, result, req.ID)
+	}
+}
+
+// rpcParams decodes the params of a recorded JSON-RPC request.
+func rpcParams(t *testing.T, req *recordedRequest) (string, []interface{}) {
+	body := new(struct {
+		Method string        `json:"method"`
+		Params []interface{} `json:"params"`
+	})
+	require.NoError(t, json.Unmarshal(req.Body, body))
+	return body.Method, body.Params
+}
+
+// blockingServer holds every request until the client goes away or the
+// server is closed.
+func blockingServer(t *testing.T) (*testServer, func()) {
+	unblock := make(chan struct{})
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-unblock:
+		}
+	})
+	return srv, func() {
+		close(unblock)
+		srv.Close()
+	}
+}
+
+func TestClient_ContextDone(t *testing.T) {
+	calls := []struct {
+		name string
+		call func(ctx context.Context, c *Client) error
+	}{
+		{
+			"rest",
+			func(ctx context.Context, c *Client) error {
+				_, err := c.GetInfo(ctx)
+				return err
+			},
+		},
+		{
+			"rpc",
+			func(ctx context.Context, c *Client) error {
+				_, err := c.RPCGetBlockCount(ctx)
+				return err
+			},
+		},
+	}
+	for _, tt := range calls {
+		t.Run(tt.name+" canceled", func(t *testing.T) {
+			srv, closeSrv := blockingServer(t)
+			defer closeSrv()
+			c := NewClient(srv.URL, WithRetry(fastRetry))
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				for len(srv.Requests()) == 0 {
+					time.Sleep(time.Millisecond)
+				}
+				cancel()
+			}()
+			start := time.Now()
+			err := tt.call(ctx, c)
+			require.True(t, errors.Is(err, context.Canceled), "%v", err)
+			require.True(t, time.Since(start) < time.Second)
+			require.Len(t, srv.Requests(), 1)
+			require.Equal(t, []string{srv.URL}, c.HealthyHosts())
+		})
+
+		t.Run(tt.name+" deadline", func(t *testing.T) {
+			srv, closeSrv := blockingServer(t)
+			defer closeSrv()
+			c := NewClient(srv.URL, WithRetry(fastRetry))
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			err := tt.call(ctx, c)
+			require.True(t, errors.Is(err, context.DeadlineExceeded), "%v", err)
+			require.True(t, time.Since(start) < time.Second)
+			require.Len(t, srv.Requests(), 1)
+		})
+	}
+}
+
+func TestClient_ContextDoneDuringBackoff(t *testing.T) {
+	srv := statusServer(t, http.StatusServiceUnavailable, "")
+	defer srv.Close()
+	c := NewClient(srv.URL, WithRetry(&RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Hour,
+		MaxBackoff:  time.Hour,
+	}))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.GetInfo(ctx)
+	require.True(t, errors.Is(err, context.DeadlineExceeded), "%v", err)
+	require.True(t, time.Since(start) < time.Second)
+	require.Len(t, srv.Requests(), 1)
+}
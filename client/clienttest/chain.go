@@ -0,0 +1,176 @@
+package clienttest
+
+import (
+	"github.com/mslipper/handshake/primitives"
+)
+
+const (
+	regtestBits  = 0x207fffff
+	blockReward  = 2000 * 1000000
+	genesisTime  = 1580745078
+	blockSpacing = 600
+)
+
+// minerAddress receives the coinbase of mined blocks.
+var minerAddress = &primitives.Address{
+	Version: 0,
+	Hash:    make([]byte, 20),
+}
+
+type txLoc struct {
+	block *primitives.Block
+	index int
+}
+
+type chain struct {
+	main    []*primitives.Block
+	blocks  map[[32]byte]*primitives.Block
+	heights map[[32]byte]int
+	txs     map[[32]byte]txLoc
+	mempool []*primitives.Transaction
+	salt    uint32
+}
+
+func newChain() *chain {
+	return &chain{
+		blocks:  make(map[[32]byte]*primitives.Block),
+		heights: make(map[[32]byte]int),
+		txs:     make(map[[32]byte]txLoc),
+	}
+}
+
+func (c *chain) height() int {
+	return len(c.main) - 1
+}
+
+func (c *chain) tip() *primitives.Block {
+	if len(c.main) == 0 {
+		return nil
+	}
+	return c.main[len(c.main)-1]
+}
+
+func (c *chain) connect(block *primitives.Block) {
+	hash := toHash(block.Hash())
+	c.blocks[hash] = block
+	c.heights[hash] = len(c.main)
+	c.main = append(c.main, block)
+	for i, tx := range block.Transactions {
+		c.txs[toHash(tx.ID())] = txLoc{
+			block: block,
+			index: i,
+		}
+	}
+}
+
+// rewind disconnects blocks from height upward. Their transactions are
+// forgotten but the blocks themselves stay retrievable by hash.
+func (c *chain) rewind(height int) {
+	if height < 0 {
+		height = 0
+	}
+	for len(c.main) > height {
+		block := c.main[len(c.main)-1]
+		for _, tx := range block.Transactions {
+			delete(c.txs, toHash(tx.ID()))
+		}
+		c.main = c.main[:len(c.main)-1]
+	}
+}
+
+// mine builds a block on the tip with a valid regtest proof of work. salt
+// distinguishes blocks mined at the same height on different branches.
+func (c *chain) mine(salt uint32) *primitives.Block {
+	height := len(c.main)
+	block := &primitives.Block{
+		Time:    genesisTime,
+		Version: 0,
+		Bits:    regtestBits,
+	}
+	if tip := c.tip(); tip != nil {
+		copy(block.PrevHash[:], tip.Hash())
+		block.Time = tip.Time + blockSpacing
+		block.TreeRoot = tip.TreeRoot
+	}
+	block.Transactions = append([]*primitives.Transaction{coinbase(height, salt)}, c.mempool...)
+	c.mempool = nil
+	block.MerkleRoot = block.ComputeMerkleRoot()
+	block.WitnessRoot = block.ComputeWitnessRoot()
+	for !block.VerifyPOW() {
+		block.Nonce++
+	}
+	c.connect(block)
+	return block
+}
+
+func (c *chain) addMempool(tx *primitives.Transaction) {
+	id := toHash(tx.ID())
+	for _, existing := range c.mempool {
+		if toHash(existing.ID()) == id {
+			return
+		}
+	}
+	c.mempool = append(c.mempool, tx)
+}
+
+func (c *chain) mempoolTx(id [32]byte) *primitives.Transaction {
+	for _, tx := range c.mempool {
+		if toHash(tx.ID()) == id {
+			return tx
+		}
+	}
+	return nil
+}
+
+// isSpent reports whether an outpoint is spent on the main chain or in the
+// mempool.
+func (c *chain) isSpent(prevout *primitives.Outpoint) bool {
+	spends := func(txs []*primitives.Transaction) bool {
+		for _, tx := range txs {
+			for _, in := range tx.Inputs {
+				if *in.Prevout == *prevout {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for _, block := range c.main {
+		if spends(block.Transactions) {
+			return true
+		}
+	}
+	return spends(c.mempool)
+}
+
+func coinbase(height int, salt uint32) *primitives.Transaction {
+	return &primitives.Transaction{
+		Inputs: []*primitives.Input{
+			{
+				Prevout: &primitives.Outpoint{
+					Index: 0xffffffff,
+				},
+				Sequence: salt,
+			},
+		},
+		Outputs: []*primitives.Output{
+			{
+				Value:   blockReward,
+				Address: minerAddress,
+				Covenant: &primitives.Covenant{
+					Type: primitives.CovenantNone,
+				},
+			},
+		},
+		Locktime: uint32(height),
+		Witnesses: []*primitives.Witness{
+			new(primitives.Witness),
+		},
+	}
+}
+
+func toHash(b []byte) [32]byte {
+	var hash [32]byte
+	copy(hash[:], b)
+	return hash
+}
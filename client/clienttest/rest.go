@@ -0,0 +1,282 @@
+package clienttest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/mslipper/handshake/client"
+	"github.com/mslipper/handshake/primitives"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (s *Server) serveREST(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	key := r.Method + " /" + parts[0]
+
+	s.mtx.Lock()
+	fault := s.takeFault(key)
+	s.mtx.Unlock()
+	if fault != nil {
+		time.Sleep(fault.Latency)
+		if fault.Status != 0 {
+			writeError(w, fault.Status, "injected fault")
+			return
+		}
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	switch {
+	case key == "GET /" && len(parts) == 1 && parts[0] == "":
+		s.handleInfo(w)
+	case key == "GET /block" && len(parts) == 2:
+		s.handleBlock(w, parts[1])
+	case key == "GET /tx" && len(parts) == 2:
+		s.handleTx(w, parts[1])
+	case key == "GET /coin" && len(parts) == 3 && parts[1] == "address":
+		s.handleCoinsByAddress(w, parts[2])
+	case key == "GET /coin" && len(parts) == 3:
+		s.handleCoin(w, parts[1], parts[2])
+	case key == "GET /mempool" && len(parts) == 1:
+		s.handleMempool(w)
+	case key == "POST /broadcast" && len(parts) == 1:
+		s.handleBroadcast(w, r)
+	case key == "GET /fee" && len(parts) == 1:
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"rate": s.fee,
+		})
+	default:
+		writeError(w, http.StatusNotFound, "Not found.")
+	}
+}
+
+func (s *Server) handleInfo(w http.ResponseWriter) {
+	info := &client.NodeInfo{
+		Version: "clienttest",
+		Network: s.network.String(),
+		Chain: client.ChainInfo{
+			Height: s.chain.height(),
+		},
+		Mempool: client.MempoolInfo{
+			Tx: len(s.chain.mempool),
+		},
+	}
+	if tip := s.chain.tip(); tip != nil {
+		info.Chain.Tip = hex.EncodeToString(tip.Hash())
+		info.Chain.TreeRoot = hex.EncodeToString(tip.TreeRoot[:])
+		info.Chain.Progress = 1
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+func (s *Server) handleBlock(w http.ResponseWriter, id string) {
+	block, height := s.lookupBlock(id)
+	if block == nil {
+		writeError(w, http.StatusNotFound, "Block not found.")
+		return
+	}
+	res, err := client.NewRESTBlock(block, s.network)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	res.Height = height
+	res.Depth = s.chain.height() - height + 1
+	for i := range res.Txs {
+		res.Txs[i].Mtime = int(block.Time)
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// lookupBlock finds a block by hash or main chain height.
+func (s *Server) lookupBlock(id string) (*primitives.Block, int) {
+	if len(id) == 64 {
+		hashB, err := hex.DecodeString(id)
+		if err != nil {
+			return nil, 0
+		}
+		hash := toHash(hashB)
+		block := s.chain.blocks[hash]
+		if block == nil {
+			return nil, 0
+		}
+		return block, s.chain.heights[hash]
+	}
+	height, err := strconv.Atoi(id)
+	if err != nil || height < 0 || height > s.chain.height() {
+		return nil, 0
+	}
+	return s.chain.main[height], height
+}
+
+func (s *Server) handleTx(w http.ResponseWriter, id string) {
+	tx, loc := s.lookupTx(id)
+	if tx == nil {
+		writeError(w, http.StatusNotFound, "TX not found.")
+		return
+	}
+	res, err := client.NewTransaction(tx, s.network)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if loc != nil {
+		res.Index = loc.index
+		res.Mtime = int(loc.block.Time)
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// lookupTx finds a transaction on the main chain or in the mempool. The
+// location is nil for mempool transactions.
+func (s *Server) lookupTx(id string) (*primitives.Transaction, *txLoc) {
+	hashB, err := hex.DecodeString(id)
+	if err != nil || len(hashB) != 32 {
+		return nil, nil
+	}
+	hash := toHash(hashB)
+	if loc, ok := s.chain.txs[hash]; ok {
+		return loc.block.Transactions[loc.index], &loc
+	}
+	return s.chain.mempoolTx(hash), nil
+}
+
+func (s *Server) handleCoin(w http.ResponseWriter, id string, indexStr string) {
+	index, err := strconv.Atoi(indexStr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid index.")
+		return
+	}
+	tx, loc := s.lookupTx(id)
+	if tx == nil || index < 0 || index >= len(tx.Outputs) {
+		writeError(w, http.StatusNotFound, "Coin not found.")
+		return
+	}
+	prevout := &primitives.Outpoint{
+		Hash:  toHash(tx.ID()),
+		Index: uint32(index),
+	}
+	if s.chain.isSpent(prevout) {
+		writeError(w, http.StatusNotFound, "Coin not found.")
+		return
+	}
+	coin, err := s.newCoin(tx, loc, index)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, coin)
+}
+
+func (s *Server) handleCoinsByAddress(w http.ResponseWriter, addr string) {
+	coins := make([]*client.Coin, 0)
+	visit := func(tx *primitives.Transaction, loc *txLoc) error {
+		for i, out := range tx.Outputs {
+			outAddr, err := out.Address.ToBech32(s.network.AddressHRP())
+			if err != nil {
+				return err
+			}
+			if outAddr != addr {
+				continue
+			}
+			prevout := &primitives.Outpoint{
+				Hash:  toHash(tx.ID()),
+				Index: uint32(i),
+			}
+			if s.chain.isSpent(prevout) {
+				continue
+			}
+			coin, err := s.newCoin(tx, loc, i)
+			if err != nil {
+				return err
+			}
+			coins = append(coins, coin)
+		}
+		return nil
+	}
+	for _, block := range s.chain.main {
+		for i, tx := range block.Transactions {
+			if err := visit(tx, &txLoc{block: block, index: i}); err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+	}
+	for _, tx := range s.chain.mempool {
+		if err := visit(tx, nil); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, coins)
+}
+
+func (s *Server) newCoin(tx *primitives.Transaction, loc *txLoc, index int) (*client.Coin, error) {
+	out := tx.Outputs[index]
+	addr, err := out.Address.ToBech32(s.network.AddressHRP())
+	if err != nil {
+		return nil, err
+	}
+	restTx, err := client.NewTransaction(tx, s.network)
+	if err != nil {
+		return nil, err
+	}
+	height := -1
+	coinbase := false
+	if loc != nil {
+		height = s.chain.heights[toHash(loc.block.Hash())]
+		coinbase = loc.index == 0
+	}
+	return &client.Coin{
+		Version:  int(tx.Version),
+		Height:   height,
+		Value:    int(out.Value),
+		Address:  addr,
+		Covenant: restTx.Outputs[index].Covenant,
+		Coinbase: coinbase,
+		Hash:     restTx.Hash,
+		Index:    index,
+	}, nil
+}
+
+func (s *Server) handleMempool(w http.ResponseWriter) {
+	hashes := make([]string, 0, len(s.chain.mempool))
+	for _, tx := range s.chain.mempool {
+		hashes = append(hashes, hex.EncodeToString(tx.ID()))
+	}
+	writeJSON(w, http.StatusOK, hashes)
+}
+
+func (s *Server) handleBroadcast(w http.ResponseWriter, r *http.Request) {
+	body := new(struct {
+		Tx string `json:"tx"`
+	})
+	if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	tx, err := decodeTx(body.Tx)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.chain.addMempool(tx)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+	})
+}
+
+func decodeTx(txHex string) (*primitives.Transaction, error) {
+	txB, err := hex.DecodeString(txHex)
+	if err != nil {
+		return nil, err
+	}
+	tx := new(primitives.Transaction)
+	if err := tx.Decode(bytes.NewReader(txB)); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
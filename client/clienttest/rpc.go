@@ -0,0 +1,445 @@
+package clienttest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mslipper/handshake/client"
+	"github.com/mslipper/handshake/primitives"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+type rpcRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	ID     json.RawMessage  `json:"id"`
+	Result interface{}      `json:"result"`
+	Error  *client.RPCError `json:"error"`
+}
+
+func (s *Server) serveRPC(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	body = bytes.TrimSpace(body)
+	batch := len(body) > 0 && body[0] == '['
+
+	var reqs []*rpcRequest
+	if batch {
+		err = json.Unmarshal(body, &reqs)
+	} else {
+		req := new(rpcRequest)
+		err = json.Unmarshal(body, req)
+		reqs = append(reqs, req)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mtx.Lock()
+	faults := make([]*Fault, len(reqs))
+	for i, req := range reqs {
+		faults[i] = s.takeFault(req.Method)
+	}
+	s.mtx.Unlock()
+	for _, fault := range faults {
+		if fault == nil {
+			continue
+		}
+		time.Sleep(fault.Latency)
+		if fault.Status != 0 {
+			http.Error(w, "injected fault", fault.Status)
+			return
+		}
+	}
+
+	res := make([]*rpcResponse, len(reqs))
+	for i, req := range reqs {
+		res[i] = &rpcResponse{
+			ID: req.ID,
+		}
+		if faults[i] != nil && faults[i].RPCError != nil {
+			res[i].Error = faults[i].RPCError
+			continue
+		}
+		result, err := s.call(req.Method, req.Params)
+		if err != nil {
+			var rpcErr *client.RPCError
+			if !errors.As(err, &rpcErr) {
+				rpcErr = &client.RPCError{
+					Message: err.Error(),
+					Code:    client.RPCMiscError,
+				}
+			}
+			res[i].Error = rpcErr
+			continue
+		}
+		res[i].Result = result
+	}
+	if batch {
+		writeJSON(w, http.StatusOK, res)
+		return
+	}
+	// hsd answers a failed call with a 500, but a batch with a 200 whatever
+	// its results.
+	status := http.StatusOK
+	if res[0].Error != nil {
+		status = http.StatusInternalServerError
+	}
+	writeJSON(w, status, res[0])
+}
+
+func (s *Server) call(method string, params []json.RawMessage) (interface{}, error) {
+	s.mtx.Lock()
+	handler := s.handlers[method]
+	s.mtx.Unlock()
+	if handler != nil {
+		return handler(params)
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	switch method {
+	case "getblockcount":
+		return s.chain.height(), nil
+	case "getbestblockhash":
+		tip := s.chain.tip()
+		if tip == nil {
+			return nil, errNotFound("Block not found.")
+		}
+		return hex.EncodeToString(tip.Hash()), nil
+	case "getblockhash":
+		height, err := paramInt(params, 0)
+		if err != nil {
+			return nil, err
+		}
+		if height < 0 || height > s.chain.height() {
+			return nil, &client.RPCError{
+				Message: "Block height out of range.",
+				Code:    client.RPCInvalidParameter,
+			}
+		}
+		return hex.EncodeToString(s.chain.main[height].Hash()), nil
+	case "getblock":
+		hash, err := paramString(params, 0)
+		if err != nil {
+			return nil, err
+		}
+		return s.rpcBlock(hash, params)
+	case "getblockbyheight":
+		height, err := paramInt(params, 0)
+		if err != nil {
+			return nil, err
+		}
+		return s.rpcBlock(fmt.Sprint(height), params)
+	case "getblockheader":
+		hash, err := paramString(params, 0)
+		if err != nil {
+			return nil, err
+		}
+		return s.rpcBlockHeader(hash, params)
+	case "getrawtransaction":
+		txid, err := paramString(params, 0)
+		if err != nil {
+			return nil, err
+		}
+		return s.rpcRawTransaction(txid, params)
+	case "sendrawtransaction":
+		txHex, err := paramString(params, 0)
+		if err != nil {
+			return nil, err
+		}
+		tx, err := decodeTx(txHex)
+		if err != nil {
+			return nil, &client.RPCError{
+				Message: "TX decode failed.",
+				Code:    client.RPCDeserializationError,
+			}
+		}
+		s.chain.addMempool(tx)
+		return hex.EncodeToString(tx.ID()), nil
+	case "getrawmempool":
+		hashes := make([]string, 0, len(s.chain.mempool))
+		for _, tx := range s.chain.mempool {
+			hashes = append(hashes, hex.EncodeToString(tx.ID()))
+		}
+		return hashes, nil
+	case "getblockchaininfo":
+		res := &client.GetBlockchainInfoResult{
+			Chain:   s.network.String(),
+			Blocks:  s.chain.height(),
+			Headers: s.chain.height(),
+		}
+		if tip := s.chain.tip(); tip != nil {
+			res.BestBlockHash = hex.EncodeToString(tip.Hash())
+			res.TreeRoot = hex.EncodeToString(tip.TreeRoot[:])
+			res.VerificationProgress = 1
+		}
+		return res, nil
+	default:
+		return nil, &client.RPCError{
+			Message: "Method not found.",
+			Code:    client.RPCMethodNotFound,
+		}
+	}
+}
+
+// rpcBlock serves getblock and getblockbyheight, whose second and third
+// params select the verbose and details forms.
+func (s *Server) rpcBlock(id string, params []json.RawMessage) (interface{}, error) {
+	verbose, err := paramBool(params, 1, true)
+	if err != nil {
+		return nil, err
+	}
+	details, err := paramBool(params, 2, false)
+	if err != nil {
+		return nil, err
+	}
+	block, height := s.lookupBlock(id)
+	if block == nil {
+		return nil, errNotFound("Block not found.")
+	}
+	if !verbose {
+		buf := new(bytes.Buffer)
+		if err := block.Encode(buf); err != nil {
+			return nil, err
+		}
+		return hex.EncodeToString(buf.Bytes()), nil
+	}
+	header := s.blockHeaderJSON(block, height)
+	if !details {
+		res := &client.RPCBlockWithoutTxsResponse{
+			Hash:          header.Hash,
+			Confirmations: header.Confirmations,
+			Height:        header.Height,
+			Version:       header.Version,
+			VersionHex:    header.VersionHex,
+			MerkleRoot:    header.MerkleRoot,
+			WitnessRoot:   header.WitnessRoot,
+			TreeRoot:      header.TreeRoot,
+			ReservedRoot:  header.ReservedRoot,
+			Mask:          header.Mask,
+			Time:          header.Time,
+			MedianTime:    header.MedianTime,
+			Bits:          header.Bits,
+			NextBlockHash: header.NextBlockHash,
+		}
+		if header.PreviousBlockHash != nil {
+			res.PreviousBlockHash = *header.PreviousBlockHash
+		}
+		for _, tx := range block.Transactions {
+			res.TxHashes = append(res.TxHashes, hex.EncodeToString(tx.ID()))
+		}
+		return res, nil
+	}
+	res := &client.RPCBlockWithTxsResponse{
+		Hash:              header.Hash,
+		Confirmations:     header.Confirmations,
+		Height:            header.Height,
+		Version:           header.Version,
+		VersionHex:        header.VersionHex,
+		MerkleRoot:        header.MerkleRoot,
+		WitnessRoot:       header.WitnessRoot,
+		TreeRoot:          header.TreeRoot,
+		ReservedRoot:      header.ReservedRoot,
+		Mask:              header.Mask,
+		Time:              header.Time,
+		Mediantime:        header.MedianTime,
+		Bits:              header.Bits,
+		PreviousBlockHash: header.PreviousBlockHash,
+		NextBlockHash:     header.NextBlockHash,
+	}
+	for _, tx := range block.Transactions {
+		rpcTx, err := s.rpcTx(tx, block, height)
+		if err != nil {
+			return nil, err
+		}
+		res.Txs = append(res.Txs, *rpcTx)
+	}
+	return res, nil
+}
+
+func (s *Server) rpcBlockHeader(hash string, params []json.RawMessage) (interface{}, error) {
+	verbose, err := paramBool(params, 1, true)
+	if err != nil {
+		return nil, err
+	}
+	block, height := s.lookupBlock(hash)
+	if block == nil {
+		return nil, errNotFound("Block not found.")
+	}
+	if !verbose {
+		buf := new(bytes.Buffer)
+		if err := block.EncodeHeader(buf); err != nil {
+			return nil, err
+		}
+		return hex.EncodeToString(buf.Bytes()), nil
+	}
+	return s.blockHeaderJSON(block, height), nil
+}
+
+func (s *Server) blockHeaderJSON(block *primitives.Block, height int) *client.GetBlockHeaderResult {
+	hash := block.Hash()
+	res := &client.GetBlockHeaderResult{
+		Hash:          hex.EncodeToString(hash),
+		Confirmations: -1,
+		Height:        height,
+		Version:       int(block.Version),
+		VersionHex:    fmt.Sprintf("%08x", block.Version),
+		MerkleRoot:    hex.EncodeToString(block.MerkleRoot[:]),
+		WitnessRoot:   hex.EncodeToString(block.WitnessRoot[:]),
+		TreeRoot:      hex.EncodeToString(block.TreeRoot[:]),
+		ReservedRoot:  hex.EncodeToString(block.ReservedRoot[:]),
+		Mask:          hex.EncodeToString(block.Mask[:]),
+		Time:          int(block.Time),
+		MedianTime:    int(block.Time),
+		Bits:          int(block.Bits),
+	}
+	if height > 0 {
+		prev := hex.EncodeToString(block.PrevHash[:])
+		res.PreviousBlockHash = &prev
+	}
+	if height <= s.chain.height() && bytes.Equal(s.chain.main[height].Hash(), hash) {
+		res.Confirmations = s.chain.height() - height + 1
+		if height < s.chain.height() {
+			next := hex.EncodeToString(s.chain.main[height+1].Hash())
+			res.NextBlockHash = &next
+		}
+	}
+	return res
+}
+
+func (s *Server) rpcRawTransaction(txid string, params []json.RawMessage) (interface{}, error) {
+	verbose, err := paramBool(params, 1, false)
+	if err != nil {
+		return nil, err
+	}
+	tx, loc := s.lookupTx(txid)
+	if tx == nil {
+		return nil, &client.RPCError{
+			Message: "Transaction not found.",
+			Code:    client.RPCInvalidAddressOrKey,
+		}
+	}
+	if !verbose {
+		buf := new(bytes.Buffer)
+		if err := tx.Encode(buf); err != nil {
+			return nil, err
+		}
+		return hex.EncodeToString(buf.Bytes()), nil
+	}
+	if loc == nil {
+		return s.rpcTx(tx, nil, -1)
+	}
+	return s.rpcTx(tx, loc.block, s.chain.heights[toHash(loc.block.Hash())])
+}
+
+func (s *Server) rpcTx(tx *primitives.Transaction, block *primitives.Block, height int) (*client.RPCTx, error) {
+	buf := new(bytes.Buffer)
+	if err := tx.Encode(buf); err != nil {
+		return nil, err
+	}
+	res := &client.RPCTx{
+		ID:       hex.EncodeToString(tx.ID()),
+		Hash:     hex.EncodeToString(tx.WitnessHash()),
+		Size:     buf.Len(),
+		VSize:    buf.Len(),
+		Version:  int(tx.Version),
+		LockTime: int(tx.Locktime),
+		Hex:      hex.EncodeToString(buf.Bytes()),
+	}
+	for i, in := range tx.Inputs {
+		vin := client.RPCVin{
+			Coinbase: i == 0 && in.Prevout.Hash == [32]byte{} && in.Prevout.Index == 0xffffffff,
+			Txid:     hex.EncodeToString(in.Prevout.Hash[:]),
+			Vout:     int64(in.Prevout.Index),
+			Sequence: int(in.Sequence),
+		}
+		if i < len(tx.Witnesses) {
+			for _, item := range tx.Witnesses[i].Items {
+				vin.Txinwitness = append(vin.Txinwitness, hex.EncodeToString(item))
+			}
+		}
+		res.Vin = append(res.Vin, vin)
+	}
+	restTx, err := client.NewTransaction(tx, s.network)
+	if err != nil {
+		return nil, err
+	}
+	for i, out := range tx.Outputs {
+		res.VOut = append(res.VOut, client.RPCVOut{
+			Value: float64(out.Value) / 1e6,
+			N:     i,
+			Address: client.RPCAddress{
+				Version: int(out.Address.Version),
+				Hash:    hex.EncodeToString(out.Address.Hash),
+			},
+			Covenant: restTx.Outputs[i].Covenant,
+		})
+	}
+	if block != nil {
+		res.BlockHash = hex.EncodeToString(block.Hash())
+		res.Confirmations = s.chain.height() - height + 1
+		res.Time = int(block.Time)
+		res.BlockTime = int(block.Time)
+	}
+	return res, nil
+}
+
+func errNotFound(msg string) error {
+	return &client.RPCError{
+		Message: msg,
+		Code:    client.RPCMiscError,
+	}
+}
+
+func invalidParams(msg string) error {
+	return &client.RPCError{
+		Message: msg,
+		Code:    client.RPCInvalidParams,
+	}
+}
+
+func paramInt(params []json.RawMessage, i int) (int, error) {
+	if i >= len(params) {
+		return 0, invalidParams(fmt.Sprintf("missing param %d", i))
+	}
+	var v int
+	if err := json.Unmarshal(params[i], &v); err != nil {
+		return 0, invalidParams(fmt.Sprintf("param %d must be a number", i))
+	}
+	return v, nil
+}
+
+func paramString(params []json.RawMessage, i int) (string, error) {
+	if i >= len(params) {
+		return "", invalidParams(fmt.Sprintf("missing param %d", i))
+	}
+	var v string
+	if err := json.Unmarshal(params[i], &v); err != nil {
+		return "", invalidParams(fmt.Sprintf("param %d must be a string", i))
+	}
+	return v, nil
+}
+
+func paramBool(params []json.RawMessage, i int, def bool) (bool, error) {
+	if i >= len(params) {
+		return def, nil
+	}
+	var v bool
+	if err := json.Unmarshal(params[i], &v); err != nil {
+		return false, invalidParams(fmt.Sprintf("param %d must be a boolean", i))
+	}
+	return v, nil
+}
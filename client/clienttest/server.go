@@ -0,0 +1,237 @@
+// Package clienttest provides an in-process fake hsd node for testing code
+// that uses the client package.
+package clienttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mslipper/handshake/client"
+	"github.com/mslipper/handshake/primitives"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Fault is returned in place of a normal response. Faults are keyed by RPC
+// method name, such as "getblockhash", or by REST route, such as
+// "GET /block".
+type Fault struct {
+	// Status, if set, fails the HTTP request with this status code.
+	Status int
+	// RPCError, if set, is returned as the JSON-RPC error.
+	RPCError *client.RPCError
+	// Latency delays the response.
+	Latency time.Duration
+	// Times limits how many requests the fault applies to. Zero means
+	// until the fault is cleared.
+	Times int
+}
+
+// RPCHandler handles a JSON-RPC method. Returning a *client.RPCError sends
+// it as the call's error.
+type RPCHandler func(params []json.RawMessage) (interface{}, error)
+
+// Server is a fake hsd node serving the REST and JSON-RPC APIs from a
+// scripted in-memory chain.
+type Server struct {
+	srv      *httptest.Server
+	network  primitives.Network
+	mtx      sync.Mutex
+	chain    *chain
+	fee      uint64
+	latency  time.Duration
+	faults   map[string]*Fault
+	handlers map[string]RPCHandler
+	requests []string
+}
+
+func NewServer(network primitives.Network) *Server {
+	s := &Server{
+		network:  network,
+		chain:    newChain(),
+		fee:      1000,
+		faults:   make(map[string]*Fault),
+		handlers: make(map[string]RPCHandler),
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Client returns a client configured to talk to the server.
+func (s *Server) Client(opts ...client.Opt) *client.Client {
+	u, err := url.Parse(s.srv.URL)
+	if err != nil {
+		panic(err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		panic(err)
+	}
+	base := []client.Opt{
+		client.WithNetwork(s.network),
+		client.WithPort(port),
+	}
+	return client.NewClient(fmt.Sprintf("%s://%s", u.Scheme, u.Hostname()), append(base, opts...)...)
+}
+
+// AddBlocks appends blocks to the main chain without validating them, which
+// allows serving blocks, such as mainnet test vectors, that do not link to
+// each other.
+func (s *Server) AddBlocks(blocks ...*primitives.Block) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for _, block := range blocks {
+		s.chain.connect(block)
+	}
+}
+
+// Mine mines n blocks on the tip. The first block includes the mempool.
+func (s *Server) Mine(n int) []*primitives.Block {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	var out []*primitives.Block
+	for i := 0; i < n; i++ {
+		out = append(out, s.chain.mine(0))
+	}
+	return out
+}
+
+// Reorg replaces the main chain from height upward with n new blocks. The
+// replaced blocks can still be fetched by hash, as with a real node. The
+// mempool is left untouched.
+func (s *Server) Reorg(height int, n int) []*primitives.Block {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.chain.rewind(height)
+	s.chain.salt++
+	var out []*primitives.Block
+	for i := 0; i < n; i++ {
+		out = append(out, s.chain.mine(s.chain.salt))
+	}
+	return out
+}
+
+// Height returns the height of the tip, or -1 if the chain is empty.
+func (s *Server) Height() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.chain.height()
+}
+
+func (s *Server) BlockAt(height int) *primitives.Block {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if height < 0 || height > s.chain.height() {
+		return nil
+	}
+	return s.chain.main[height]
+}
+
+func (s *Server) AddMempoolTx(tx *primitives.Transaction) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.chain.addMempool(tx)
+}
+
+func (s *Server) Mempool() []*primitives.Transaction {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]*primitives.Transaction(nil), s.chain.mempool...)
+}
+
+func (s *Server) SetFee(rate uint64) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.fee = rate
+}
+
+// SetLatency delays every response.
+func (s *Server) SetLatency(latency time.Duration) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.latency = latency
+}
+
+func (s *Server) InjectFault(key string, fault *Fault) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	f := *fault
+	s.faults[key] = &f
+}
+
+func (s *Server) ClearFaults() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.faults = make(map[string]*Fault)
+}
+
+// HandleRPC overrides or adds a JSON-RPC method.
+func (s *Server) HandleRPC(method string, handler RPCHandler) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.handlers[method] = handler
+}
+
+// Requests returns the keys of every request served so far, in order.
+func (s *Server) Requests() []string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// takeFault records the request and returns the fault to apply to it, if
+// any. The caller must hold the lock.
+func (s *Server) takeFault(key string) *Fault {
+	s.requests = append(s.requests, key)
+	fault := s.faults[key]
+	if fault == nil {
+		return nil
+	}
+	if fault.Times > 0 {
+		fault.Times--
+		if fault.Times == 0 {
+			delete(s.faults, key)
+		}
+	}
+	f := *fault
+	return &f
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mtx.Lock()
+	latency := s.latency
+	s.mtx.Unlock()
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	if r.Method == "POST" && (r.URL.Path == "/" || r.URL.Path == "") {
+		s.serveRPC(w, r)
+		return
+	}
+	s.serveREST(w, r)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+		},
+	})
+}
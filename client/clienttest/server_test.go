@@ -0,0 +1,232 @@
+package clienttest
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/mslipper/handshake/client"
+	"github.com/mslipper/handshake/follower"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+var goldenBlocks = []string{
+	"000000000000003d56d278ef00c657de6b1fcb549f9e04e299f6a918c2573b94",
+	"0000000000000424ee6c2a5d6e0da5edfc47a4a10328c1792056ee48303c3e40",
+}
+
+func TestServer_GoldenBlocks(t *testing.T) {
+	s := NewServer(primitives.NetworkMainnet)
+	defer s.Close()
+	var raw []string
+	for _, hash := range goldenBlocks {
+		data, err := ioutil.ReadFile(fmt.Sprintf("../../primitives/testdata/block_%s.bin", hash))
+		require.NoError(t, err)
+		block := new(primitives.Block)
+		require.NoError(t, block.Decode(bytes.NewReader(data)))
+		s.AddBlocks(block)
+		raw = append(raw, hex.EncodeToString(data))
+	}
+	c := s.Client()
+	ctx := context.Background()
+
+	// The server returns blocks byte for byte.
+	for i, hash := range goldenBlocks {
+		blockHex, err := c.RPCGetBlockHexByHash(ctx, hash)
+		require.NoError(t, err)
+		require.Equal(t, raw[i], blockHex)
+	}
+
+	for height, hash := range goldenBlocks {
+		block, err := c.GetBlockPrimitiveByHash(ctx, hash)
+		require.NoError(t, err)
+		require.Equal(t, hash, hex.EncodeToString(block.Hash()))
+
+		block, err = c.RPCGetBlockPrimitiveByHash(ctx, hash)
+		require.NoError(t, err)
+		require.Equal(t, hash, hex.EncodeToString(block.Hash()))
+
+		rpcBlock, err := c.RPCGetBlockByHeightWithTxs(ctx, height)
+		require.NoError(t, err)
+		require.Equal(t, hash, rpcBlock.Hash)
+		require.Equal(t, len(block.Transactions), len(rpcBlock.Txs))
+
+		txid := hex.EncodeToString(block.Transactions[0].ID())
+		tx, err := c.GetTransactionPrimitiveByHash(ctx, txid)
+		require.NoError(t, err)
+		require.Equal(t, block.Transactions[0].ID(), tx.ID())
+	}
+
+	count, err := c.RPCGetBlockCount(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+}
+
+func TestServer_MempoolAndCoins(t *testing.T) {
+	s := NewServer(primitives.NetworkRegtest)
+	defer s.Close()
+	blocks := s.Mine(2)
+	c := s.Client()
+	ctx := context.Background()
+
+	coinbaseID := hex.EncodeToString(blocks[0].Transactions[0].ID())
+	coin, err := c.GetCoinByOutpoint(ctx, coinbaseID, 0)
+	require.NoError(t, err)
+	require.True(t, coin.Coinbase)
+	require.Equal(t, 0, coin.Height)
+
+	minerAddr, err := minerAddress.ToBech32(primitives.NetworkRegtest.AddressHRP())
+	require.NoError(t, err)
+	coins, err := c.GetCoinsByAddress(ctx, minerAddr)
+	require.NoError(t, err)
+	require.Len(t, coins, 2)
+	require.Equal(t, coinbaseID, coins[0].Hash)
+	require.Equal(t, minerAddr, coins[1].Address)
+	require.Equal(t, 1, coins[1].Height)
+
+	spend := &primitives.Transaction{
+		Inputs: []*primitives.Input{
+			{
+				Prevout: &primitives.Outpoint{
+					Hash: toHash(blocks[0].Transactions[0].ID()),
+				},
+				Sequence: 0xffffffff,
+			},
+		},
+		Outputs: []*primitives.Output{
+			{
+				Value:    1000,
+				Address:  minerAddress,
+				Covenant: new(primitives.Covenant),
+			},
+		},
+		Witnesses: []*primitives.Witness{
+			new(primitives.Witness),
+		},
+	}
+	txid, err := c.RPCSendRawTransactionPrimitive(ctx, spend)
+	require.NoError(t, err)
+	mempool, err := c.GetMempoolSnapshot(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{txid}, mempool)
+
+	coins, err = c.GetCoinsByAddress(ctx, minerAddr)
+	require.NoError(t, err)
+	require.Len(t, coins, 2)
+	require.Equal(t, txid, coins[1].Hash)
+	require.Equal(t, 1000, coins[1].Value)
+
+	_, err = c.GetCoinByOutpoint(ctx, coinbaseID, 0)
+	var httpErr *client.HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	require.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+
+	mined := s.Mine(1)
+	require.Len(t, mined[0].Transactions, 2)
+	require.Empty(t, s.Mempool())
+	rpcTx, err := c.RPCGetRawTransaction(ctx, txid)
+	require.NoError(t, err)
+	require.Equal(t, hex.EncodeToString(mined[0].Hash()), rpcTx.BlockHash)
+	require.Equal(t, 1, rpcTx.Confirmations)
+}
+
+func TestServer_Faults(t *testing.T) {
+	s := NewServer(primitives.NetworkRegtest)
+	defer s.Close()
+	s.Mine(3)
+	ctx := context.Background()
+
+	s.InjectFault("getblockcount", &Fault{
+		Status: http.StatusServiceUnavailable,
+		Times:  2,
+	})
+	_, err := s.Client().RPCGetBlockCount(ctx)
+	var httpErr *client.HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	require.Equal(t, http.StatusServiceUnavailable, httpErr.StatusCode)
+
+	c := s.Client(client.WithRetry(&client.RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  time.Millisecond,
+	}))
+	count, err := c.RPCGetBlockCount(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	s.InjectFault("getblockhash", &Fault{
+		RPCError: &client.RPCError{
+			Message: "boom",
+			Code:    client.RPCMiscError,
+		},
+		Times: 1,
+	})
+	hashes, err := c.RPCGetBlockHashesByHeight(ctx, 0, 3)
+	var rpcErr *client.RPCError
+	require.True(t, errors.As(err, &rpcErr))
+	require.Equal(t, "boom", rpcErr.Message)
+	require.Nil(t, hashes)
+	hashes, err = c.RPCGetBlockHashesByHeight(ctx, 0, 3)
+	require.NoError(t, err)
+	require.Len(t, hashes, 3)
+
+	s.InjectFault("getblockcount", &Fault{
+		RPCError: &client.RPCError{
+			Message: "boom",
+			Code:    client.RPCMiscError,
+		},
+		Times: 1,
+	})
+	res, err := http.Post(s.URL(), "application/json", strings.NewReader(`{"method":"getblockcount","params":[],"id":1}`))
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusInternalServerError, res.StatusCode)
+
+	s.InjectFault("GET /fee", &Fault{
+		Latency: 50 * time.Millisecond,
+	})
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = c.EstimateFee(timeoutCtx, 1)
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+type recordingHandler struct {
+	events []string
+}
+
+func (r *recordingHandler) Connect(ctx context.Context, height int, block *primitives.Block) error {
+	r.events = append(r.events, fmt.Sprintf("+%d", height))
+	return nil
+}
+
+func (r *recordingHandler) Disconnect(ctx context.Context, height int, block *primitives.Block) error {
+	r.events = append(r.events, fmt.Sprintf("-%d", height))
+	return nil
+}
+
+func TestServer_Reorg(t *testing.T) {
+	s := NewServer(primitives.NetworkRegtest)
+	defer s.Close()
+	old := s.Mine(4)
+	ctx := context.Background()
+	handler := new(recordingHandler)
+	f := follower.NewChainFollower(follower.NewClientSource(s.Client()), follower.NewMemoryCursorStore(), 0, handler)
+	require.NoError(t, f.Sync(ctx))
+	require.Equal(t, []string{"+0", "+1", "+2", "+3"}, handler.events)
+
+	handler.events = nil
+	replaced := s.Reorg(2, 3)
+	require.Equal(t, 4, s.Height())
+	require.NotEqual(t, old[2].Hash(), replaced[0].Hash())
+	require.True(t, bytes.Equal(old[1].Hash(), replaced[0].PrevHash[:]))
+	require.NoError(t, f.Sync(ctx))
+	require.Equal(t, []string{"-3", "-2", "+2", "+3", "+4"}, handler.events)
+}
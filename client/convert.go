@@ -0,0 +1,208 @@
+package client
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/mslipper/handshake/primitives"
+)
+
+var covenantActions = []string{
+	"NONE",
+	"CLAIM",
+	"OPEN",
+	"BID",
+	"REVEAL",
+	"REDEEM",
+	"REGISTER",
+	"UPDATE",
+	"RENEW",
+	"TRANSFER",
+	"FINALIZE",
+	"REVOKE",
+}
+
+// Primitive converts the block to its native form, checking that the
+// header hashes to the block's hash and that its transactions match the
+// header's merkle and witness roots.
+func (b *RESTBlock) Primitive() (*primitives.Block, error) {
+	block := &primitives.Block{
+		Nonce:   uint32(b.Nonce),
+		Time:    uint64(b.Time),
+		Version: uint32(b.Version),
+		Bits:    uint32(b.Bits),
+	}
+	fields := []struct {
+		dst []byte
+		src string
+	}{
+		{block.PrevHash[:], b.PrevBlock},
+		{block.TreeRoot[:], b.TreeRoot},
+		{block.ExtraNonce[:], b.ExtraNonce},
+		{block.ReservedRoot[:], b.ReservedRoot},
+		{block.WitnessRoot[:], b.WitnessRoot},
+		{block.MerkleRoot[:], b.MerkleRoot},
+		{block.Mask[:], b.Mask},
+	}
+	for _, field := range fields {
+		if err := decodeFixedHex(field.dst, field.src); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkBlockHash(block, b.Hash); err != nil {
+		return nil, err
+	}
+	for i := range b.Txs {
+		tx, err := b.Txs[i].Primitive()
+		if err != nil {
+			return nil, err
+		}
+		block.Transactions = append(block.Transactions, tx)
+	}
+	if err := checkBlockRoots(block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// Primitive converts the transaction to its native form, checking that it
+// hashes to the transaction's hash. The raw hex is used when present.
+func (t *Transaction) Primitive() (*primitives.Transaction, error) {
+	if t.Hex != "" {
+		tx, err := decodeTransactionHex(t.Hex)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkTransactionID(tx, t.Hash); err != nil {
+			return nil, err
+		}
+		return tx, nil
+	}
+
+	tx := &primitives.Transaction{
+		Version:  uint32(t.Version),
+		Locktime: uint32(t.Locktime),
+	}
+	for _, in := range t.Inputs {
+		prevout := new(primitives.Outpoint)
+		if err := decodeFixedHex(prevout.Hash[:], in.Prevout.Hash); err != nil {
+			return nil, err
+		}
+		prevout.Index = uint32(in.Prevout.Index)
+		items, err := decodeHexItems(in.Witness)
+		if err != nil {
+			return nil, err
+		}
+		tx.Inputs = append(tx.Inputs, &primitives.Input{
+			Prevout:  prevout,
+			Sequence: uint32(in.Sequence),
+		})
+		tx.Witnesses = append(tx.Witnesses, &primitives.Witness{
+			Items: items,
+		})
+	}
+	for _, out := range t.Outputs {
+		addr, _, err := primitives.NewAddressFromBech32(out.Address)
+		if err != nil {
+			return nil, err
+		}
+		items, err := decodeHexItems(out.Covenant.Items)
+		if err != nil {
+			return nil, err
+		}
+		tx.Outputs = append(tx.Outputs, &primitives.Output{
+			Value:   uint64(out.Value),
+			Address: addr,
+			Covenant: &primitives.Covenant{
+				Type:  uint8(out.Covenant.Type),
+				Items: items,
+			},
+		})
+	}
+	if err := checkTransactionID(tx, t.Hash); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// NewRESTBlock converts a block into the shape returned by the REST API.
+// Fields that depend on chain state, such as height and depth, are left
+// unset.
+func NewRESTBlock(block *primitives.Block, network primitives.Network) (*RESTBlock, error) {
+	res := &RESTBlock{
+		Hash:         hex.EncodeToString(block.Hash()),
+		Version:      int(block.Version),
+		PrevBlock:    hex.EncodeToString(block.PrevHash[:]),
+		MerkleRoot:   hex.EncodeToString(block.MerkleRoot[:]),
+		WitnessRoot:  hex.EncodeToString(block.WitnessRoot[:]),
+		TreeRoot:     hex.EncodeToString(block.TreeRoot[:]),
+		ReservedRoot: hex.EncodeToString(block.ReservedRoot[:]),
+		Time:         int(block.Time),
+		Bits:         int(block.Bits),
+		Nonce:        int(block.Nonce),
+		ExtraNonce:   hex.EncodeToString(block.ExtraNonce[:]),
+		Mask:         hex.EncodeToString(block.Mask[:]),
+		Txs:          make([]Transaction, 0, len(block.Transactions)),
+	}
+	for i, tx := range block.Transactions {
+		restTx, err := NewTransaction(tx, network)
+		if err != nil {
+			return nil, err
+		}
+		restTx.Index = i
+		res.Txs = append(res.Txs, *restTx)
+	}
+	return res, nil
+}
+
+// NewTransaction converts a transaction into the shape returned by the REST
+// API. Fields that need the spent coins, such as fees and input addresses,
+// are left unset.
+func NewTransaction(tx *primitives.Transaction, network primitives.Network) (*Transaction, error) {
+	txHex, err := encodeTransactionHex(tx)
+	if err != nil {
+		return nil, err
+	}
+	if len(tx.Witnesses) != len(tx.Inputs) {
+		return nil, fmt.Errorf("expected %d witnesses, got %d", len(tx.Inputs), len(tx.Witnesses))
+	}
+	res := &Transaction{
+		Hash:        hex.EncodeToString(tx.ID()),
+		WitnessHash: hex.EncodeToString(tx.WitnessHash()),
+		Mtime:       -1,
+		Index:       -1,
+		Version:     int(tx.Version),
+		Inputs:      make([]Input, 0, len(tx.Inputs)),
+		Outputs:     make([]Output, 0, len(tx.Outputs)),
+		Locktime:    int(tx.Locktime),
+		Hex:         txHex,
+	}
+	for i, in := range tx.Inputs {
+		res.Inputs = append(res.Inputs, Input{
+			Prevout: Prevout{
+				Hash:  hex.EncodeToString(in.Prevout.Hash[:]),
+				Index: int64(in.Prevout.Index),
+			},
+			Witness:  encodeHexItems(tx.Witnesses[i].Items),
+			Sequence: int(in.Sequence),
+		})
+	}
+	for _, out := range tx.Outputs {
+		addr, err := out.Address.ToBech32(network.AddressHRP())
+		if err != nil {
+			return nil, err
+		}
+		if int(out.Covenant.Type) >= len(covenantActions) {
+			return nil, fmt.Errorf("invalid covenant type %d", out.Covenant.Type)
+		}
+		res.Outputs = append(res.Outputs, Output{
+			Value:   int(out.Value),
+			Address: addr,
+			Covenant: Covenant{
+				Type:   int(out.Covenant.Type),
+				Action: covenantActions[out.Covenant.Type],
+				Items:  encodeHexItems(out.Covenant.Items),
+			},
+		})
+	}
+	return res, nil
+}
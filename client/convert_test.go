@@ -0,0 +1,173 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"testing"
+)
+
+// The JSON files in testdata follow the shape of hsd's /block and /tx
+// responses. They were built from the golden blocks and transactions in
+// primitives/testdata rather than recorded from a running node.
+const (
+	restBlockFixture = "block_0000000000000424ee6c2a5d6e0da5edfc47a4a10328c1792056ee48303c3e40"
+	restTxFixture    = "tx_1d0f8de2757488cbd59bea7b8f7c7ad5aa9ebd6459631e801a041062338a8630"
+)
+
+func loadRESTFixture(t *testing.T, name string, v interface{}) []byte {
+	data, err := ioutil.ReadFile("testdata/" + name + ".json")
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, v))
+	golden, err := ioutil.ReadFile("../primitives/testdata/" + name + ".bin")
+	require.NoError(t, err)
+	return golden
+}
+
+func TestRESTBlock_Primitive(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(b *RESTBlock)
+		err    string
+	}{
+		{
+			"raw transactions",
+			func(b *RESTBlock) {},
+			"",
+		},
+		{
+			"transaction fields",
+			func(b *RESTBlock) {
+				for i := range b.Txs {
+					b.Txs[i].Hex = ""
+				}
+			},
+			"",
+		},
+		{
+			"wrong block hash",
+			func(b *RESTBlock) {
+				b.Hash = "000000000000003d56d278ef00c657de6b1fcb549f9e04e299f6a918c2573b94"
+			},
+			"block hash mismatch",
+		},
+		{
+			"wrong transaction hash",
+			func(b *RESTBlock) {
+				b.Txs[0].Hash = "1d0f8de2757488cbd59bea7b8f7c7ad5aa9ebd6459631e801a041062338a8630"
+			},
+			"transaction hash mismatch",
+		},
+		{
+			"transaction not in merkle root",
+			func(b *RESTBlock) {
+				b.Txs = nil
+			},
+			"block merkle root mismatch",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restBlock := new(RESTBlock)
+			golden := loadRESTFixture(t, restBlockFixture, restBlock)
+			require.Equal(t, 2016, restBlock.Height)
+			tt.mutate(restBlock)
+			block, err := restBlock.Primitive()
+			if tt.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.err)
+				return
+			}
+			require.NoError(t, err)
+			buf := new(bytes.Buffer)
+			require.NoError(t, block.Encode(buf))
+			require.Equal(t, golden, buf.Bytes())
+		})
+	}
+}
+
+func TestTransaction_Primitive(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(tx *Transaction)
+		err    string
+	}{
+		{
+			"raw",
+			func(tx *Transaction) {},
+			"",
+		},
+		{
+			"fields",
+			func(tx *Transaction) {
+				tx.Hex = ""
+			},
+			"",
+		},
+		{
+			"raw with wrong hash",
+			func(tx *Transaction) {
+				tx.Hash = "e9c5a29b773c07cdc6dfc87008d880a150c09cc4e85545725328eb9ae3cf7e80"
+			},
+			"transaction hash mismatch",
+		},
+		{
+			"fields with wrong hash",
+			func(tx *Transaction) {
+				tx.Hex = ""
+				tx.Hash = "e9c5a29b773c07cdc6dfc87008d880a150c09cc4e85545725328eb9ae3cf7e80"
+			},
+			"transaction hash mismatch",
+		},
+		{
+			"fields not matching hash",
+			func(tx *Transaction) {
+				tx.Hex = ""
+				tx.Outputs[0].Value++
+			},
+			"transaction hash mismatch",
+		},
+		{
+			"short prevout hash",
+			func(tx *Transaction) {
+				tx.Hex = ""
+				tx.Inputs[0].Prevout.Hash = "08c4e1"
+			},
+			"invalid length",
+		},
+		{
+			"invalid witness",
+			func(tx *Transaction) {
+				tx.Hex = ""
+				tx.Inputs[0].Witness[0] = "zz"
+			},
+			"invalid byte",
+		},
+		{
+			"invalid address",
+			func(tx *Transaction) {
+				tx.Hex = ""
+				tx.Outputs[0].Address = "hs1qinvalid"
+			},
+			"invalid character",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restTx := new(Transaction)
+			golden := loadRESTFixture(t, restTxFixture, restTx)
+			tt.mutate(restTx)
+			tx, err := restTx.Primitive()
+			if tt.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.err)
+				return
+			}
+			require.NoError(t, err)
+			buf := new(bytes.Buffer)
+			require.NoError(t, tx.Encode(buf))
+			require.Equal(t, golden, buf.Bytes())
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// RPC error codes returned by hsd.
+const (
+	RPCInvalidRequest = -32600
+	RPCMethodNotFound = -32601
+	RPCInvalidParams  = -32602
+	RPCInternalError  = -32603
+	RPCParseError     = -32700
+
+	RPCMiscError            = -1
+	RPCForbiddenBySafeMode  = -2
+	RPCTypeError            = -3
+	RPCInvalidAddressOrKey  = -5
+	RPCOutOfMemory          = -7
+	RPCInvalidParameter     = -8
+	RPCDatabaseError        = -20
+	RPCDeserializationError = -22
+	RPCVerifyError          = -25
+	RPCVerifyRejected       = -26
+	RPCVerifyAlreadyInChain = -27
+	RPCInWarmup             = -28
+
+	RPCClientNotConnected      = -9
+	RPCClientInInitialDownload = -10
+	RPCClientNodeAlreadyAdded  = -23
+	RPCClientNodeNotAdded      = -24
+	RPCClientNodeNotConnected  = -29
+	RPCClientInvalidIPOrSubnet = -30
+	RPCClientP2PDisabled       = -31
+
+	RPCWalletError               = -4
+	RPCWalletInsufficientFunds   = -6
+	RPCWalletInvalidAccountName  = -11
+	RPCWalletKeypoolRanOut       = -12
+	RPCWalletUnlockNeeded        = -13
+	RPCWalletPassphraseIncorrect = -14
+	RPCWalletWrongEncState       = -15
+	RPCWalletEncryptionFailed    = -16
+	RPCWalletAlreadyUnlocked     = -17
+)
+
+// RPCError is an error returned by hsd in a JSON-RPC response.
+type RPCError struct {
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+	Method  string `json:"-"`
+}
+
+func (r *RPCError) Error() string {
+	if r.Method != "" {
+		return fmt.Sprintf("%s: rpc error %d: %s", r.Method, r.Code, r.Message)
+	}
+	return fmt.Sprintf("rpc error %d: %s", r.Code, r.Message)
+}
+
+// HTTPError is returned when hsd responds with a non-200 status code. If the
+// body carried a JSON-RPC error, it is available through errors.As.
+type HTTPError struct {
+	StatusCode int
+	Message    string
+	Body       []byte
+	Err        error
+}
+
+func (h *HTTPError) Error() string {
+	if h.Err != nil {
+		return fmt.Sprintf("non-200 status code: %d: %s", h.StatusCode, h.Err.Error())
+	}
+	if h.Message != "" {
+		return fmt.Sprintf("non-200 status code: %d: %s", h.StatusCode, h.Message)
+	}
+	return fmt.Sprintf("non-200 status code: %d", h.StatusCode)
+}
+
+func (h *HTTPError) Unwrap() error {
+	return h.Err
+}
+
+// DecodeError is returned when a response body cannot be decoded into the
+// expected result type.
+type DecodeError struct {
+	Body []byte
+	Err  error
+}
+
+func (d *DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode response: %s", d.Err.Error())
+}
+
+func (d *DecodeError) Unwrap() error {
+	return d.Err
+}
+
+func newHTTPError(statusCode int, body []byte) *HTTPError {
+	httpErr := &HTTPError{
+		StatusCode: statusCode,
+		Body:       body,
+	}
+	restErr := new(struct {
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	})
+	if err := json.Unmarshal(body, restErr); err == nil && restErr.Error != nil {
+		httpErr.Message = restErr.Error.Message
+	}
+	return httpErr
+}
+
+// rpcErrorFromHTTP attaches the JSON-RPC error carried in the body of an
+// HTTPError to it and returns it, if there is one.
+func rpcErrorFromHTTP(err error) *RPCError {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		return nil
+	}
+	res := new(rpcResponse)
+	if err := json.Unmarshal(httpErr.Body, res); err != nil || res.Error == nil {
+		return nil
+	}
+	httpErr.Err = res.Error
+	return res.Error
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRPCError_Error(t *testing.T) {
+	err := &RPCError{
+		Message: "Block not found.",
+		Code:    RPCInvalidParameter,
+	}
+	require.Equal(t, "rpc error -8: Block not found.", err.Error())
+	err.Method = "getblock"
+	require.Equal(t, "getblock: rpc error -8: Block not found.", err.Error())
+}
+
+func TestHTTPError(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		message string
+		err     string
+	}{
+		{
+			"rest error",
+			404,
+			`{"error": {"message": "Not found.", "type": "HTTPError", "code": 404}}`,
+			"Not found.",
+			"non-200 status code: 404: Not found.",
+		},
+		{
+			"plain body",
+			502,
+			"Bad Gateway",
+			"",
+			"non-200 status code: 502",
+		},
+		{
+			"empty body",
+			503,
+			"",
+			"",
+			"non-200 status code: 503",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newHTTPError(tt.status, []byte(tt.body))
+			require.Equal(t, tt.status, err.StatusCode)
+			require.Equal(t, tt.message, err.Message)
+			require.Equal(t, []byte(tt.body), err.Body)
+			require.Equal(t, tt.err, err.Error())
+			require.Nil(t, errors.Unwrap(err))
+		})
+	}
+
+	rpcErr := &RPCError{Message: "Method not found.", Code: RPCMethodNotFound, Method: "getfoo"}
+	err := &HTTPError{StatusCode: 500, Err: rpcErr}
+	require.Equal(t, "non-200 status code: 500: getfoo: rpc error -32601: Method not found.", err.Error())
+	var target *RPCError
+	require.True(t, errors.As(err, &target))
+	require.Equal(t, rpcErr, target)
+}
+
+func TestRPCErrorFromHTTP(t *testing.T) {
+	httpErr := newHTTPError(500, []byte(`{"result": null, "error": {"message": "Method not found.", "code": -32601}, "id": 1}`))
+	rpcErr := rpcErrorFromHTTP(httpErr)
+	require.Equal(t, &RPCError{Message: "Method not found.", Code: RPCMethodNotFound}, rpcErr)
+	require.Equal(t, rpcErr, httpErr.Err)
+	require.Nil(t, rpcErrorFromHTTP(newHTTPError(500, []byte("Internal Server Error"))))
+	require.Nil(t, rpcErrorFromHTTP(newHTTPError(500, []byte(`[]`))))
+	require.Nil(t, rpcErrorFromHTTP(errors.New("connection refused")))
+}
+
+func TestDecodeError(t *testing.T) {
+	var v struct{}
+	jsonErr := json.Unmarshal([]byte("{"), &v)
+	err := &DecodeError{Body: []byte("{"), Err: jsonErr}
+	require.Equal(t, "failed to decode response: "+jsonErr.Error(), err.Error())
+	require.Equal(t, jsonErr, errors.Unwrap(err))
+}
+
+func TestRPCResponse_Decode(t *testing.T) {
+	res := new(rpcResponse)
+	require.NoError(t, json.Unmarshal([]byte(`{"result": null, "error": {"message": "Invalid address.", "code": -5}, "id": 1}`), res))
+	err := res.decode("getaddressinfo", nil)
+	var rpcErr *RPCError
+	require.True(t, errors.As(err, &rpcErr))
+	require.Equal(t, RPCInvalidAddressOrKey, rpcErr.Code)
+	require.Equal(t, "getaddressinfo", rpcErr.Method)
+
+	res = new(rpcResponse)
+	require.NoError(t, json.Unmarshal([]byte(`{"result": "abc", "error": null, "id": 2}`), res))
+	var count int
+	err = res.decode("getblockcount", &count)
+	var decodeErr *DecodeError
+	require.True(t, errors.As(err, &decodeErr))
+	require.Equal(t, []byte(`"abc"`), decodeErr.Body)
+}
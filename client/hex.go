@@ -0,0 +1,111 @@
+package client
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/mslipper/handshake/primitives"
+)
+
+func decodeTransactionHex(txHex string) (*primitives.Transaction, error) {
+	txB, err := hex.DecodeString(txHex)
+	if err != nil {
+		return nil, err
+	}
+	tx := new(primitives.Transaction)
+	if err := tx.Decode(bytes.NewReader(txB)); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func encodeTransactionHex(tx *primitives.Transaction) (string, error) {
+	buf := new(bytes.Buffer)
+	if err := tx.Encode(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf.Bytes()), nil
+}
+
+func checkTransactionID(tx *primitives.Transaction, txid string) error {
+	if actual := hex.EncodeToString(tx.ID()); actual != txid {
+		return fmt.Errorf("transaction hash mismatch: expected %s, got %s", txid, actual)
+	}
+	return nil
+}
+
+func decodeBlockHex(blockHex string) (*primitives.Block, error) {
+	blockB, err := hex.DecodeString(blockHex)
+	if err != nil {
+		return nil, err
+	}
+	block := new(primitives.Block)
+	if err := block.Decode(bytes.NewReader(blockB)); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+func decodeHeaderHex(headerHex string) (*primitives.Block, error) {
+	headerB, err := hex.DecodeString(headerHex)
+	if err != nil {
+		return nil, err
+	}
+	header := new(primitives.Block)
+	if err := header.DecodeHeader(bytes.NewReader(headerB)); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+func checkBlockHash(block *primitives.Block, hash string) error {
+	if actual := hex.EncodeToString(block.Hash()); actual != hash {
+		return fmt.Errorf("block hash mismatch: expected %s, got %s", hash, actual)
+	}
+	return nil
+}
+
+// checkBlockRoots verifies that the block's transactions match the merkle
+// and witness roots committed to in its header.
+func checkBlockRoots(block *primitives.Block) error {
+	if block.ComputeMerkleRoot() != block.MerkleRoot {
+		return errors.New("block merkle root mismatch")
+	}
+	if block.ComputeWitnessRoot() != block.WitnessRoot {
+		return errors.New("block witness root mismatch")
+	}
+	return nil
+}
+
+func decodeFixedHex(dst []byte, s string) error {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	if len(b) != len(dst) {
+		return fmt.Errorf("invalid length: expected %d, got %d", len(dst), len(b))
+	}
+	copy(dst, b)
+	return nil
+}
+
+func decodeHexItems(items []string) ([][]byte, error) {
+	var out [][]byte
+	for _, item := range items {
+		b, err := hex.DecodeString(item)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+func encodeHexItems(items [][]byte) []string {
+	out := make([]string, len(items))
+	for i, item := range items {
+		out[i] = hex.EncodeToString(item)
+	}
+	return out
+}
@@ -1,64 +1,79 @@
 package client
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"github.com/mslipper/handshake/primitives"
 )
 
-func (c *Client) GetInfo() (*NodeInfo, error) {
+func (c *Client) GetInfo(ctx context.Context) (*NodeInfo, error) {
 	info := new(NodeInfo)
-	if err := c.getJSON("", info); err != nil {
+	if err := c.getJSON(ctx, "", info); err != nil {
 		return nil, err
 	}
 	return info, nil
 }
 
-func (c *Client) GetMempoolSnapshot() ([]string, error) {
+func (c *Client) GetMempoolSnapshot(ctx context.Context) ([]string, error) {
 	var out []string
-	if err := c.getJSON("mempool", out); err != nil {
+	if err := c.getJSON(ctx, "mempool", &out); err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *Client) GetMempoolRejectsFilter() (*MempoolRejectsFilterInfo, error) {
+func (c *Client) GetMempoolRejectsFilter(ctx context.Context) (*MempoolRejectsFilterInfo, error) {
 	info := new(MempoolRejectsFilterInfo)
-	if err := c.getJSON("mempool/invalid", info); err != nil {
+	if err := c.getJSON(ctx, "mempool/invalid", info); err != nil {
 		return nil, err
 	}
 	return info, nil
 }
 
-func (c *Client) TestMempoolRejectsFilter(hash string) (bool, error) {
+func (c *Client) TestMempoolRejectsFilter(ctx context.Context, hash string) (bool, error) {
 	info := new(struct {
 		Invalid bool `json:"invalid"`
 	})
-	if err := c.getJSON(fmt.Sprintf("mempool/invalid/%s", hash), info); err != nil {
+	if err := c.getJSON(ctx, fmt.Sprintf("mempool/invalid/%s", hash), info); err != nil {
 		return false, err
 	}
 	return info.Invalid, nil
 }
 
-func (c *Client) GetBlockByHash(hash string) (*RESTBlock, error) {
+func (c *Client) GetBlockByHash(ctx context.Context, hash string) (*RESTBlock, error) {
 	block := new(RESTBlock)
-	if err := c.getJSON(fmt.Sprintf("block/%s", hash), block); err != nil {
+	if err := c.getJSON(ctx, fmt.Sprintf("block/%s", hash), block); err != nil {
 		return nil, err
 	}
 	return block, nil
 }
 
-func (c *Client) GetBlockByHeight(height int) (*RESTBlock, error) {
+// GetBlockPrimitiveByHash fetches the block and converts it to its native
+// form. See RESTBlock.Primitive for the checks performed.
+func (c *Client) GetBlockPrimitiveByHash(ctx context.Context, hash string) (*primitives.Block, error) {
+	block, err := c.GetBlockByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if block.Hash != hash {
+		return nil, fmt.Errorf("block hash mismatch: expected %s, got %s", hash, block.Hash)
+	}
+	return block.Primitive()
+}
+
+func (c *Client) GetBlockByHeight(ctx context.Context, height int) (*RESTBlock, error) {
 	if height < 0 {
 		return nil, errors.New("cannot set a negative height")
 	}
 	block := new(RESTBlock)
-	if err := c.getJSON(fmt.Sprintf("block/%d", height), block); err != nil {
+	if err := c.getJSON(ctx, fmt.Sprintf("block/%d", height), block); err != nil {
 		return nil, err
 	}
 	return block, nil
 }
 
-func (c *Client) BroadcastTransaction(tx string) error {
+func (c *Client) BroadcastTransaction(ctx context.Context, tx string) error {
 	body := struct {
 		Tx string `json:"tx"`
 	}{
@@ -67,7 +82,7 @@ func (c *Client) BroadcastTransaction(tx string) error {
 	res := new(struct {
 		Success bool `json:"success"`
 	})
-	if err := c.postJSON("broadcast", body, res); err != nil {
+	if err := c.postJSON(ctx, "broadcast", body, res); err != nil {
 		return err
 	}
 	if !res.Success {
@@ -76,7 +91,7 @@ func (c *Client) BroadcastTransaction(tx string) error {
 	return nil
 }
 
-func (c *Client) BroadcastClaim(claim string) error {
+func (c *Client) BroadcastClaim(ctx context.Context, claim string) error {
 	body := struct {
 		Claim string `json:"claim"`
 	}{
@@ -85,7 +100,7 @@ func (c *Client) BroadcastClaim(claim string) error {
 	res := new(struct {
 		Success bool `json:"success"`
 	})
-	if err := c.postJSON("claim", body, res); err != nil {
+	if err := c.postJSON(ctx, "claim", body, res); err != nil {
 		return err
 	}
 	if !res.Success {
@@ -94,20 +109,20 @@ func (c *Client) BroadcastClaim(claim string) error {
 	return nil
 }
 
-func (c *Client) EstimateFee(blocks int) (uint64, error) {
+func (c *Client) EstimateFee(ctx context.Context, blocks int) (uint64, error) {
 	if blocks < 0 {
 		return 0, errors.New("blocks cannot be negative")
 	}
 	res := new(struct {
 		Rate uint64 `json:"rate"`
 	})
-	if err := c.getJSON(fmt.Sprintf("fee?blocks=%d", blocks), res); err != nil {
+	if err := c.getJSON(ctx, fmt.Sprintf("fee?blocks=%d", blocks), res); err != nil {
 		return 0, err
 	}
 	return res.Rate, nil
 }
 
-func (c *Client) ResetBlockchain(height int) error {
+func (c *Client) ResetBlockchain(ctx context.Context, height int) error {
 	if height < 0 {
 		return errors.New("cannot set a zero height")
 	}
@@ -119,7 +134,7 @@ func (c *Client) ResetBlockchain(height int) error {
 	res := new(struct {
 		Success bool `json:"success"`
 	})
-	if err := c.postJSON("reset", body, res); err != nil {
+	if err := c.postJSON(ctx, "reset", body, res); err != nil {
 		return err
 	}
 	if !res.Success {
@@ -128,33 +143,45 @@ func (c *Client) ResetBlockchain(height int) error {
 	return nil
 }
 
-func (c *Client) GetCoinByOutpoint(hash string, index int) (*Coin, error) {
+func (c *Client) GetCoinByOutpoint(ctx context.Context, hash string, index int) (*Coin, error) {
 	res := new(Coin)
-	if err := c.getJSON(fmt.Sprintf("coin/%s/%d", hash, index), res); err != nil {
+	if err := c.getJSON(ctx, fmt.Sprintf("coin/%s/%d", hash, index), res); err != nil {
 		return nil, err
 	}
 	return res, nil
 }
 
-func (c *Client) GetCoinsByAddress(address string) ([]*Coin, error) {
+// GetCoinsByAddress returns the unspent coins paying to address.
+func (c *Client) GetCoinsByAddress(ctx context.Context, address string) ([]*Coin, error) {
 	var res []*Coin
-	if err := c.getJSON(fmt.Sprintf("coins/address/%s", address), res); err != nil {
+	if err := c.getJSON(ctx, fmt.Sprintf("coin/address/%s", address), &res); err != nil {
 		return nil, err
 	}
 	return res, nil
 }
 
-func (c *Client) GetTransactionByHash(hash string) (*Transaction, error) {
+func (c *Client) GetTransactionByHash(ctx context.Context, hash string) (*Transaction, error) {
 	res := new(Transaction)
-	if err := c.getJSON(fmt.Sprintf("tx/%s", hash), res); err != nil {
+	if err := c.getJSON(ctx, fmt.Sprintf("tx/%s", hash), res); err != nil {
 		return nil, err
 	}
 	return res, nil
 }
 
-func (c *Client) GetTransactionsByAddress(address string) ([]*Transaction, error) {
+func (c *Client) GetTransactionPrimitiveByHash(ctx context.Context, hash string) (*primitives.Transaction, error) {
+	tx, err := c.GetTransactionByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if tx.Hash != hash {
+		return nil, fmt.Errorf("transaction hash mismatch: expected %s, got %s", hash, tx.Hash)
+	}
+	return tx.Primitive()
+}
+
+func (c *Client) GetTransactionsByAddress(ctx context.Context, address string) ([]*Transaction, error) {
 	var res []*Transaction
-	if err := c.getJSON(fmt.Sprintf("tx/address/%s", address), res); err != nil {
+	if err := c.getJSON(ctx, fmt.Sprintf("tx/address/%s", address), &res); err != nil {
 		return nil, err
 	}
 	return res, nil
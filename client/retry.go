@@ -0,0 +1,211 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultCooldown = 30 * time.Second
+
+// RetryPolicy controls how idempotent calls are retried. Calls that change
+// state, such as sendrawtransaction or any wallet action, are never retried.
+type RetryPolicy struct {
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+}
+
+var DefaultRetryPolicy = &RetryPolicy{
+	MaxAttempts: 4,
+	MinBackoff:  100 * time.Millisecond,
+	MaxBackoff:  5 * time.Second,
+}
+
+var noRetry = &RetryPolicy{
+	MaxAttempts: 1,
+}
+
+// backoff returns the delay before the given retry attempt: exponential in
+// the attempt number, capped at MaxBackoff, with up to half of it jittered.
+func (r *RetryPolicy) backoff(attempt int) time.Duration {
+	d := r.MinBackoff
+	for i := 1; i < attempt && d < r.MaxBackoff; i++ {
+		d *= 2
+	}
+	if r.MaxBackoff > 0 && d > r.MaxBackoff {
+		d = r.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// WithRetry enables retries of idempotent calls on connection errors and 5xx
+// responses, except JSON-RPC responses carrying an RPC error. A nil policy
+// uses DefaultRetryPolicy.
+func WithRetry(policy *RetryPolicy) Opt {
+	return func(c *Client) {
+		if policy == nil {
+			policy = DefaultRetryPolicy
+		}
+		if policy.MaxAttempts < 1 {
+			policy = noRetry
+		}
+		c.retry = policy
+	}
+}
+
+// WithHosts adds failover hosts. They are tried in order after the host
+// passed to NewClient. Hosts without an explicit port use the client's port.
+// A host that fails is skipped for the health cooldown. Health is not probed
+// in the background; call CheckHealth to refresh it sooner.
+func WithHosts(hosts ...string) Opt {
+	return func(c *Client) {
+		c.hosts = append(c.hosts, hosts...)
+	}
+}
+
+// WithHealthCooldown sets how long a failed host is skipped before requests
+// are sent to it again.
+func WithHealthCooldown(cooldown time.Duration) Opt {
+	return func(c *Client) {
+		c.cooldown = cooldown
+	}
+}
+
+type endpoint struct {
+	host      string
+	mtx       sync.Mutex
+	downUntil time.Time
+}
+
+func (e *endpoint) markHealthy() {
+	e.mtx.Lock()
+	e.downUntil = time.Time{}
+	e.mtx.Unlock()
+}
+
+func (e *endpoint) markUnhealthy(cooldown time.Duration) {
+	e.mtx.Lock()
+	e.downUntil = time.Now().Add(cooldown)
+	e.mtx.Unlock()
+}
+
+func (e *endpoint) healthyAt(now time.Time) (bool, time.Time) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	return !now.Before(e.downUntil), e.downUntil
+}
+
+// pickEndpoint returns the first healthy endpoint. If every endpoint is
+// down, the one that will come back soonest is used.
+func (c *Client) pickEndpoint() *endpoint {
+	now := time.Now()
+	var best *endpoint
+	var bestUntil time.Time
+	for _, ep := range c.endpoints {
+		healthy, until := ep.healthyAt(now)
+		if healthy {
+			return ep
+		}
+		if best == nil || until.Before(bestUntil) {
+			best = ep
+			bestUntil = until
+		}
+	}
+	return best
+}
+
+// HealthyHosts returns the hosts that are not currently being skipped.
+func (c *Client) HealthyHosts() []string {
+	now := time.Now()
+	var hosts []string
+	for _, ep := range c.endpoints {
+		if healthy, _ := ep.healthyAt(now); healthy {
+			hosts = append(hosts, ep.host)
+		}
+	}
+	return hosts
+}
+
+// CheckHealth probes every host and updates its health. It returns an error
+// if no host responded.
+func (c *Client) CheckHealth(ctx context.Context) error {
+	var lastErr error
+	var ok bool
+	for _, ep := range c.endpoints {
+		if _, err := c.sendTo(ctx, ep, "GET", "", nil); err != nil {
+			ep.markUnhealthy(c.cooldown)
+			lastErr = err
+			continue
+		}
+		ep.markHealthy()
+		ok = true
+	}
+	if !ok {
+		return lastErr
+	}
+	return nil
+}
+
+// isDialError reports whether err happened before a connection to the host
+// was established.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500
+	}
+	return true
+}
+
+var idempotentRPCPrefixes = []string{
+	"get",
+	"list",
+	"decode",
+	"validate",
+	"estimate",
+	"verify",
+}
+
+var nonIdempotentRPCs = map[string]bool{
+	"getnewaddress":       true,
+	"getrawchangeaddress": true,
+}
+
+func isIdempotentRPC(method string) bool {
+	if nonIdempotentRPCs[method] {
+		return false
+	}
+	for _, prefix := range idempotentRPCPrefixes {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,188 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+var fastRetry = &RetryPolicy{
+	MaxAttempts: 3,
+	MinBackoff:  time.Millisecond,
+	MaxBackoff:  time.Millisecond,
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := &RetryPolicy{
+		MaxAttempts: 10,
+		MinBackoff:  100 * time.Millisecond,
+		MaxBackoff:  time.Second,
+	}
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{9, time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			d := policy.backoff(tt.attempt)
+			require.True(t, d >= tt.max/2 && d <= tt.max, "attempt %d: %s", tt.attempt, d)
+		}
+	}
+	require.Equal(t, time.Duration(0), noRetry.backoff(1))
+}
+
+func statusServer(t *testing.T, status int, body string) *testServer {
+	return newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	})
+}
+
+func TestClient_Failover(t *testing.T) {
+	down := statusServer(t, http.StatusServiceUnavailable, "Service Unavailable")
+	defer down.Close()
+	up := newTestServer(t, rpcResults(t, map[string]string{
+		"getblockcount":      "7436",
+		"sendrawtransaction": `"1d0f8de2757488cbd59bea7b8f7c7ad5aa9ebd6459631e801a041062338a8630"`,
+	}))
+	defer up.Close()
+
+	c := NewClient(down.URL, WithHosts(up.URL), WithRetry(fastRetry), WithHealthCooldown(time.Minute))
+	count, err := c.RPCGetBlockCount(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 7436, count)
+	require.Len(t, down.Requests(), 1)
+	require.Len(t, up.Requests(), 1)
+	require.Equal(t, []string{up.URL}, c.HealthyHosts())
+
+	// The failed host is skipped until its cooldown expires.
+	_, err = c.RPCGetBlockCount(context.Background())
+	require.NoError(t, err)
+	require.Len(t, down.Requests(), 1)
+	require.Len(t, up.Requests(), 2)
+}
+
+func TestClient_DialFailover(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	refused := "http://" + l.Addr().String()
+	l.Close()
+	down := statusServer(t, http.StatusServiceUnavailable, "Service Unavailable")
+	defer down.Close()
+	up := newTestServer(t, rpcResults(t, map[string]string{
+		"sendrawtransaction": `"1d0f8de2757488cbd59bea7b8f7c7ad5aa9ebd6459631e801a041062338a8630"`,
+	}))
+	defer up.Close()
+
+	// A call that changes state moves on from a host that refused the
+	// connection, since the node never saw it, but not from one that
+	// answered.
+	c := NewClient(refused, WithHosts(up.URL))
+	txid, err := c.RPCSendRawTransaction(context.Background(), "00")
+	require.NoError(t, err)
+	require.Equal(t, "1d0f8de2757488cbd59bea7b8f7c7ad5aa9ebd6459631e801a041062338a8630", txid)
+	require.Len(t, up.Requests(), 1)
+	require.Equal(t, []string{up.URL}, c.HealthyHosts())
+
+	c = NewClient(down.URL, WithHosts(up.URL))
+	_, err = c.RPCSendRawTransaction(context.Background(), "00")
+	var httpErr *HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	require.Len(t, down.Requests(), 1)
+	require.Len(t, up.Requests(), 1)
+}
+
+func TestClient_RetryExhausted(t *testing.T) {
+	down := statusServer(t, http.StatusBadGateway, "")
+	defer down.Close()
+
+	c := NewClient(down.URL, WithRetry(fastRetry))
+	_, err := c.RPCGetBlockCount(context.Background())
+	var httpErr *HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	require.Equal(t, http.StatusBadGateway, httpErr.StatusCode)
+	require.Len(t, down.Requests(), fastRetry.MaxAttempts)
+}
+
+func TestClient_NoRetry(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		call   func(c *Client) error
+	}{
+		{
+			"non-idempotent call",
+			http.StatusServiceUnavailable,
+			func(c *Client) error {
+				_, err := c.RPCSendRawTransaction(context.Background(), "00")
+				return err
+			},
+		},
+		{
+			"client error",
+			http.StatusUnauthorized,
+			func(c *Client) error {
+				_, err := c.RPCGetBlockCount(context.Background())
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := statusServer(t, tt.status, "")
+			defer srv.Close()
+			c := NewClient(srv.URL, WithRetry(fastRetry))
+			var httpErr *HTTPError
+			require.True(t, errors.As(tt.call(c), &httpErr))
+			require.Equal(t, tt.status, httpErr.StatusCode)
+			require.Len(t, srv.Requests(), 1)
+		})
+	}
+}
+
+func TestClient_RPCErrorWithServerError(t *testing.T) {
+	srv := statusServer(t, http.StatusInternalServerError, `{"result":null,"error":{"message":"Block not found.","code":-8},"id":1}`)
+	defer srv.Close()
+	other := statusServer(t, http.StatusOK, `{"result":1,"error":null,"id":1}`)
+	defer other.Close()
+
+	c := NewClient(srv.URL, WithHosts(other.URL), WithRetry(fastRetry))
+	_, err := c.RPCGetBlockHashByHeight(context.Background(), 100000)
+	var httpErr *HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	require.Equal(t, http.StatusInternalServerError, httpErr.StatusCode)
+	var rpcErr *RPCError
+	require.True(t, errors.As(err, &rpcErr))
+	require.Equal(t, RPCInvalidParameter, rpcErr.Code)
+	require.Equal(t, "Block not found.", rpcErr.Message)
+	require.Equal(t, "getblockhash", rpcErr.Method)
+	require.Len(t, srv.Requests(), 1)
+	require.Empty(t, other.Requests())
+	require.Equal(t, []string{srv.URL, other.URL}, c.HealthyHosts())
+}
+
+func TestClient_BadRequestNotRetried(t *testing.T) {
+	c := NewClient("http://bad host", WithRetry(&RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Hour,
+		MaxBackoff:  time.Hour,
+	}))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := c.RPCGetBlockCount(ctx)
+	require.Error(t, err)
+	require.False(t, errors.Is(err, context.DeadlineExceeded))
+	require.Equal(t, []string{"http://bad host"}, c.HealthyHosts())
+}
@@ -1,216 +1,727 @@
 package client
 
 import (
-	"encoding/json"
+	"context"
+	"github.com/mslipper/handshake/dns"
+	"github.com/mslipper/handshake/primitives"
 	"strconv"
 )
 
-func (c *Client) RPCStop() error {
-	var res json.RawMessage
-	if err := c.executeRPC("stop", res); err != nil {
+func (c *Client) RPCStop(ctx context.Context) error {
+	if err := c.executeRPC(ctx, "stop", nil); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (c *Client) RPCGetInfo() (*GetInfoResult, error) {
+func (c *Client) RPCGetInfo(ctx context.Context) (*GetInfoResult, error) {
 	res := new(GetInfoResult)
-	if err := c.executeRPC("getinfo", res); err != nil {
+	if err := c.executeRPC(ctx, "getinfo", res); err != nil {
 		return nil, err
 	}
 	return res, nil
 }
 
-func (c *Client) RPCGetMemoryInfo() (*GetMemoryInfoResult, error) {
+func (c *Client) RPCGetMemoryInfo(ctx context.Context) (*GetMemoryInfoResult, error) {
 	res := new(GetMemoryInfoResult)
-	if err := c.executeRPC("getmemoryinfo", res); err != nil {
+	if err := c.executeRPC(ctx, "getmemoryinfo", res); err != nil {
 		return nil, err
 	}
 	return res, nil
 }
 
-func (c *Client) RPCSetLogLevel(level string) error {
-	if err := c.executeRPC("setloglevel", nil, level); err != nil {
+func (c *Client) RPCSetLogLevel(ctx context.Context, level string) error {
+	if err := c.executeRPC(ctx, "setloglevel", nil, level); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (c *Client) RPCValidateAddress(address string) (*ValidateAddressResult, error) {
+func (c *Client) RPCValidateAddress(ctx context.Context, address string) (*ValidateAddressResult, error) {
 	res := new(ValidateAddressResult)
-	if err := c.executeRPC("validateaddress", res, address); err != nil {
+	if err := c.executeRPC(ctx, "validateaddress", res, address); err != nil {
 		return nil, err
 	}
 	return res, nil
 }
 
-func (c *Client) RPCCreateMultisig(numRequired int, keys []string) (*CreateMultisigResult, error) {
+func (c *Client) RPCCreateMultisig(ctx context.Context, numRequired int, keys []string) (*CreateMultisigResult, error) {
 	res := new(CreateMultisigResult)
-	if err := c.executeRPC("createmultisig", res, numRequired, keys); err != nil {
+	if err := c.executeRPC(ctx, "createmultisig", res, numRequired, keys); err != nil {
 		return nil, err
 	}
 	return res, nil
 }
 
-func (c *Client) RPCSignMessageWithPrivkey(privKey string, message string) (string, error) {
+func (c *Client) RPCSignMessageWithPrivkey(ctx context.Context, privKey string, message string) (string, error) {
 	var res string
-	if err := c.executeRPC("signmessagewithprivkey", &res, privKey, message); err != nil {
+	if err := c.executeRPC(ctx, "signmessagewithprivkey", &res, privKey, message); err != nil {
 		return "", err
 	}
 	return res, nil
 }
 
-func (c *Client) RPCVerifyMessage(address string, signature string, message string) (bool, error) {
+func (c *Client) RPCVerifyMessage(ctx context.Context, address string, signature string, message string) (bool, error) {
 	var res bool
-	if err := c.executeRPC("verifymessage", res, address, signature, message); err != nil {
+	if err := c.executeRPC(ctx, "verifymessage", &res, address, signature, message); err != nil {
 		return false, err
 	}
 	return res, nil
 }
 
-func (c *Client) RPCSetMockTime(timestamp int) error {
-	if err := c.executeRPC("setmocktime", nil, strconv.Itoa(timestamp)); err != nil {
+func (c *Client) RPCSetMockTime(ctx context.Context, timestamp int) error {
+	if err := c.executeRPC(ctx, "setmocktime", nil, strconv.Itoa(timestamp)); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (c *Client) RPCPruneBlockchain() error {
-	if err := c.executeRPC("pruneblockchain", nil); err != nil {
+func (c *Client) RPCPruneBlockchain(ctx context.Context) error {
+	if err := c.executeRPC(ctx, "pruneblockchain", nil); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (c *Client) RPCInvalidateBlock(blockHash string) error {
-	if err := c.executeRPC("invalidateblock", nil, blockHash); err != nil {
+func (c *Client) RPCInvalidateBlock(ctx context.Context, blockHash string) error {
+	if err := c.executeRPC(ctx, "invalidateblock", nil, blockHash); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (c *Client) RPCReconsiderBlock(blockHash string) error {
-	if err := c.executeRPC("reconsiderblock", nil, blockHash); err != nil {
+func (c *Client) RPCReconsiderBlock(ctx context.Context, blockHash string) error {
+	if err := c.executeRPC(ctx, "reconsiderblock", nil, blockHash); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (c *Client) RPCGetBlockchainInfo() (*GetBlockchainInfoResult, error) {
+func (c *Client) RPCGetBlockchainInfo(ctx context.Context) (*GetBlockchainInfoResult, error) {
 	res := new(GetBlockchainInfoResult)
-	if err := c.executeRPC("getblockchaininfo", res); err != nil {
+	if err := c.executeRPC(ctx, "getblockchaininfo", res); err != nil {
 		return nil, err
 	}
 	return res, nil
 }
 
-func (c *Client) RPCGetBestBlockHash() (string, error) {
+func (c *Client) RPCGetBestBlockHash(ctx context.Context) (string, error) {
 	var res string
-	if err := c.executeRPC("getbestblockhash", &res); err != nil {
+	if err := c.executeRPC(ctx, "getbestblockhash", &res); err != nil {
 		return "", err
 	}
 	return res, nil
 }
 
-func (c *Client) RPCGetBlockCount() (int, error) {
+func (c *Client) RPCGetBlockCount(ctx context.Context) (int, error) {
 	var res int
-	if err := c.executeRPC("getblockcount", &res); err != nil {
+	if err := c.executeRPC(ctx, "getblockcount", &res); err != nil {
 		return 0, err
 	}
 	return res, nil
 }
 
-func (c *Client) RPCGetBlockByHashWithoutTxs(blockHash string) (*RPCBlockWithoutTxsResponse, error) {
+func (c *Client) RPCGetBlockByHashWithoutTxs(ctx context.Context, blockHash string) (*RPCBlockWithoutTxsResponse, error) {
 	res := new(RPCBlockWithoutTxsResponse)
-	if err := c.executeRPC("getblock", res, blockHash, true, false); err != nil {
+	if err := c.executeRPC(ctx, "getblock", res, blockHash, true, false); err != nil {
 		return nil, err
 	}
 	return res, nil
 }
 
-func (c *Client) RPCGetBlockByHashWithTxs(blockHash string) (*RPCBlockWithTxsResponse, error) {
+func (c *Client) RPCGetBlockByHashWithTxs(ctx context.Context, blockHash string) (*RPCBlockWithTxsResponse, error) {
 	res := new(RPCBlockWithTxsResponse)
-	if err := c.executeRPC("getblock", res, blockHash, true, true); err != nil {
+	if err := c.executeRPC(ctx, "getblock", res, blockHash, true, true); err != nil {
 		return nil, err
 	}
 	return res, nil
 }
 
-func (c *Client) RPCGetBlockHexByHash(blockHash string) (string, error) {
+func (c *Client) RPCGetBlockHexByHash(ctx context.Context, blockHash string) (string, error) {
 	var res string
-	if err := c.executeRPC("getblock", &res, blockHash, false, false); err != nil {
+	if err := c.executeRPC(ctx, "getblock", &res, blockHash, false, false); err != nil {
 		return "", err
 	}
 	return res, nil
 }
 
-func (c *Client) RPCGetBlockByHeightWithoutTxs(blockHeight int) (*RPCBlockWithoutTxsResponse, error) {
+// RPCGetBlockPrimitiveByHash fetches the raw block and checks it against the
+// requested hash and its header's merkle and witness roots.
+func (c *Client) RPCGetBlockPrimitiveByHash(ctx context.Context, blockHash string) (*primitives.Block, error) {
+	blockHex, err := c.RPCGetBlockHexByHash(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	block, err := decodeBlockHex(blockHex)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBlockHash(block, blockHash); err != nil {
+		return nil, err
+	}
+	if err := checkBlockRoots(block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+func (c *Client) RPCGetBlockByHeightWithoutTxs(ctx context.Context, blockHeight int) (*RPCBlockWithoutTxsResponse, error) {
 	res := new(RPCBlockWithoutTxsResponse)
-	if err := c.executeRPC("getblockbyheight", res, blockHeight, true, false); err != nil {
+	if err := c.executeRPC(ctx, "getblockbyheight", res, blockHeight, true, false); err != nil {
 		return nil, err
 	}
 	return res, nil
 }
 
-func (c *Client) RPCGetBlockByHeightWithTxs(blockHeight int) (*RPCBlockWithTxsResponse, error) {
+func (c *Client) RPCGetBlockByHeightWithTxs(ctx context.Context, blockHeight int) (*RPCBlockWithTxsResponse, error) {
 	res := new(RPCBlockWithTxsResponse)
-	if err := c.executeRPC("getblockbyheight", res, blockHeight, true, true); err != nil {
+	if err := c.executeRPC(ctx, "getblockbyheight", res, blockHeight, true, true); err != nil {
 		return nil, err
 	}
 	return res, nil
 }
 
-func (c *Client) RPCGetBlockHexByHeight(blockHeight int) (string, error) {
+func (c *Client) RPCGetBlockHexByHeight(ctx context.Context, blockHeight int) (string, error) {
 	var res string
-	if err := c.executeRPC("getblockbyheight", &res, blockHeight, false, false); err != nil {
+	if err := c.executeRPC(ctx, "getblockbyheight", &res, blockHeight, false, false); err != nil {
 		return "", err
 	}
 	return res, nil
 }
 
-func (c *Client) RPCGetBlockHashByHeight(blockHeight int) (string, error) {
+// RPCGetBlockPrimitiveByHeight fetches the raw block and checks its
+// transactions against its header's merkle and witness roots.
+func (c *Client) RPCGetBlockPrimitiveByHeight(ctx context.Context, blockHeight int) (*primitives.Block, error) {
+	blockHex, err := c.RPCGetBlockHexByHeight(ctx, blockHeight)
+	if err != nil {
+		return nil, err
+	}
+	block, err := decodeBlockHex(blockHex)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBlockRoots(block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+func (c *Client) RPCGetBlockHashByHeight(ctx context.Context, blockHeight int) (string, error) {
 	var res string
-	if err := c.executeRPC("getblockhash", &res, blockHeight); err != nil {
+	if err := c.executeRPC(ctx, "getblockhash", &res, blockHeight); err != nil {
 		return "", err
 	}
 	return res, nil
 }
 
-func (c *Client) RPCGetBlockHeaderByHash(blockHash string) (*GetBlockHeaderResult, error) {
+func (c *Client) RPCGetBlockHeaderByHash(ctx context.Context, blockHash string) (*GetBlockHeaderResult, error) {
 	res := new(GetBlockHeaderResult)
-	if err := c.executeRPC("getblockheader", res, blockHash, true); err != nil {
+	if err := c.executeRPC(ctx, "getblockheader", res, blockHash, true); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetBlockHeaderHexByHash(ctx context.Context, blockHash string) (string, error) {
+	var res string
+	if err := c.executeRPC(ctx, "getblockheader", &res, blockHash, false); err != nil {
+		return "", err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetBlockHeaderPrimitiveByHash(ctx context.Context, blockHash string) (*primitives.Block, error) {
+	headerHex, err := c.RPCGetBlockHeaderHexByHash(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	header, err := decodeHeaderHex(headerHex)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBlockHash(header, blockHash); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+func (c *Client) RPCGetChainTips(ctx context.Context) ([]*GetChainTipsResult, error) {
+	var res []*GetChainTipsResult
+	if err := c.executeRPC(ctx, "getchaintips", &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetDifficulty(ctx context.Context) (float64, error) {
+	var res float64
+	if err := c.executeRPC(ctx, "getdifficulty", &res); err != nil {
+		return 0, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetNameByHash(ctx context.Context, hash string) (*string, error) {
+	var res *string
+	if err := c.executeRPC(ctx, "getnamebyhash", &res, hash); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetNameProof(ctx context.Context, name string) (*GetNameProofResult, error) {
+	res := new(GetNameProofResult)
+	if err := c.executeRPC(ctx, "getnameproof", res, name); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetNameInfo(ctx context.Context, name string) (*GetNameInfoResult, error) {
+	res := new(GetNameInfoResult)
+	if err := c.executeRPC(ctx, "getnameinfo", res, name); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetNameResource(ctx context.Context, name string) (*dns.Resource, error) {
+	var res *dns.Resource
+	if err := c.executeRPC(ctx, "getnameresource", &res, name); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetNames(ctx context.Context) ([]*NameStateResult, error) {
+	var res []*NameStateResult
+	if err := c.executeRPC(ctx, "getnames", &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetAuctionInfo(ctx context.Context, name string) (*GetAuctionInfoResult, error) {
+	res := new(GetAuctionInfoResult)
+	if err := c.executeRPC(ctx, "getauctioninfo", res, name); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetBids(ctx context.Context, name string, own bool) ([]*BidResult, error) {
+	var res []*BidResult
+	if err := c.executeRPC(ctx, "getbids", &res, name, own); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetReveals(ctx context.Context, name string, own bool) ([]*RevealResult, error) {
+	var res []*RevealResult
+	if err := c.executeRPC(ctx, "getreveals", &res, name, own); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCSendRawClaim(ctx context.Context, claim string) (string, error) {
+	var res string
+	if err := c.executeRPC(ctx, "sendrawclaim", &res, claim); err != nil {
+		return "", err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCSendRawAirdrop(ctx context.Context, airdrop string) (string, error) {
+	var res string
+	if err := c.executeRPC(ctx, "sendrawairdrop", &res, airdrop); err != nil {
+		return "", err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetRawMempool(ctx context.Context) ([]string, error) {
+	var res []string
+	if err := c.executeRPC(ctx, "getrawmempool", &res, false); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetRawMempoolVerbose(ctx context.Context) (map[string]*MempoolEntryResult, error) {
+	var res map[string]*MempoolEntryResult
+	if err := c.executeRPC(ctx, "getrawmempool", &res, true); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetMempoolInfo(ctx context.Context) (*GetMempoolInfoResult, error) {
+	res := new(GetMempoolInfoResult)
+	if err := c.executeRPC(ctx, "getmempoolinfo", res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetMempoolEntry(ctx context.Context, txid string) (*MempoolEntryResult, error) {
+	res := new(MempoolEntryResult)
+	if err := c.executeRPC(ctx, "getmempoolentry", res, txid); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetMempoolAncestors(ctx context.Context, txid string) ([]string, error) {
+	var res []string
+	if err := c.executeRPC(ctx, "getmempoolancestors", &res, txid, false); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetMempoolAncestorsVerbose(ctx context.Context, txid string) ([]*MempoolEntryResult, error) {
+	var res []*MempoolEntryResult
+	if err := c.executeRPC(ctx, "getmempoolancestors", &res, txid, true); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetMempoolDescendants(ctx context.Context, txid string) ([]string, error) {
+	var res []string
+	if err := c.executeRPC(ctx, "getmempooldescendants", &res, txid, false); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetMempoolDescendantsVerbose(ctx context.Context, txid string) ([]*MempoolEntryResult, error) {
+	var res []*MempoolEntryResult
+	if err := c.executeRPC(ctx, "getmempooldescendants", &res, txid, true); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCPrioritiseTransaction(ctx context.Context, txid string, priorityDelta int64, feeDelta int64) (bool, error) {
+	var res bool
+	if err := c.executeRPC(ctx, "prioritisetransaction", &res, txid, priorityDelta, feeDelta); err != nil {
+		return false, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetRawTransaction(ctx context.Context, txid string) (*RPCTx, error) {
+	res := new(RPCTx)
+	if err := c.executeRPC(ctx, "getrawtransaction", res, txid, true); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetRawTransactionHex(ctx context.Context, txid string) (string, error) {
+	var res string
+	if err := c.executeRPC(ctx, "getrawtransaction", &res, txid, false); err != nil {
+		return "", err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetRawTransactionPrimitive(ctx context.Context, txid string) (*primitives.Transaction, error) {
+	txHex, err := c.RPCGetRawTransactionHex(ctx, txid)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := decodeTransactionHex(txHex)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTransactionID(tx, txid); err != nil {
 		return nil, err
 	}
+	return tx, nil
+}
+
+func (c *Client) RPCDecodeRawTransaction(ctx context.Context, txHex string) (*RPCTx, error) {
+	res := new(RPCTx)
+	if err := c.executeRPC(ctx, "decoderawtransaction", res, txHex); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCSendRawTransaction(ctx context.Context, txHex string) (string, error) {
+	var res string
+	if err := c.executeRPC(ctx, "sendrawtransaction", &res, txHex); err != nil {
+		return "", err
+	}
 	return res, nil
 }
 
-func (c *Client) RPCGetBlockHeaderHexByHash(blockHash string) (string, error) {
+func (c *Client) RPCSendRawTransactionPrimitive(ctx context.Context, tx *primitives.Transaction) (string, error) {
+	txHex, err := encodeTransactionHex(tx)
+	if err != nil {
+		return "", err
+	}
+	return c.RPCSendRawTransaction(ctx, txHex)
+}
+
+func (c *Client) RPCCreateRawTransaction(ctx context.Context, inputs []*CreateRawTransactionInput, outputs map[string]float64, locktime int) (string, error) {
 	var res string
-	if err := c.executeRPC("getblockheader", &res, blockHash, false); err != nil {
+	if err := c.executeRPC(ctx, "createrawtransaction", &res, inputs, outputs, locktime); err != nil {
 		return "", err
 	}
 	return res, nil
 }
 
-func (c *Client) RPCGetChainTips() ([]*GetChainTipsResult, error) {
-	res := make([]*GetChainTipsResult, 0)
-	if err := c.executeRPC("getchaintips", res); err != nil {
+func (c *Client) RPCCreateRawTransactionPrimitive(ctx context.Context, inputs []*CreateRawTransactionInput, outputs map[string]float64, locktime int) (*primitives.Transaction, error) {
+	txHex, err := c.RPCCreateRawTransaction(ctx, inputs, outputs, locktime)
+	if err != nil {
+		return nil, err
+	}
+	return decodeTransactionHex(txHex)
+}
+
+func (c *Client) RPCSignRawTransaction(ctx context.Context, txHex string, prevouts []*SignRawTransactionPrevout, privKeys []string, sigHashType string) (*SignRawTransactionResult, error) {
+	if prevouts == nil {
+		prevouts = make([]*SignRawTransactionPrevout, 0)
+	}
+	if privKeys == nil {
+		privKeys = make([]string, 0)
+	}
+	if sigHashType == "" {
+		sigHashType = "ALL"
+	}
+	res := new(SignRawTransactionResult)
+	if err := c.executeRPC(ctx, "signrawtransaction", res, txHex, prevouts, privKeys, sigHashType); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCSignRawTransactionPrimitive(ctx context.Context, tx *primitives.Transaction, prevouts []*SignRawTransactionPrevout, privKeys []string, sigHashType string) (*primitives.Transaction, bool, error) {
+	txHex, err := encodeTransactionHex(tx)
+	if err != nil {
+		return nil, false, err
+	}
+	res, err := c.RPCSignRawTransaction(ctx, txHex, prevouts, privKeys, sigHashType)
+	if err != nil {
+		return nil, false, err
+	}
+	signed, err := decodeTransactionHex(res.Hex)
+	if err != nil {
+		return nil, false, err
+	}
+	return signed, res.Complete, nil
+}
+
+func (c *Client) RPCGetTxOut(ctx context.Context, txid string, index int, includeMempool bool) (*GetTxOutResult, error) {
+	var res *GetTxOutResult
+	if err := c.executeRPC(ctx, "gettxout", &res, txid, index, includeMempool); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetTxOutSetInfo(ctx context.Context) (*GetTxOutSetInfoResult, error) {
+	res := new(GetTxOutSetInfoResult)
+	if err := c.executeRPC(ctx, "gettxoutsetinfo", res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+const (
+	AddNodeAdd    = "add"
+	AddNodeRemove = "remove"
+	AddNodeOneTry = "onetry"
+
+	SetBanAdd    = "add"
+	SetBanRemove = "remove"
+)
+
+func (c *Client) RPCGetPeerInfo(ctx context.Context) ([]*PeerInfoResult, error) {
+	var res []*PeerInfoResult
+	if err := c.executeRPC(ctx, "getpeerinfo", &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetConnectionCount(ctx context.Context) (int, error) {
+	var res int
+	if err := c.executeRPC(ctx, "getconnectioncount", &res); err != nil {
+		return 0, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetNetTotals(ctx context.Context) (*GetNetTotalsResult, error) {
+	res := new(GetNetTotalsResult)
+	if err := c.executeRPC(ctx, "getnettotals", res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetNetworkInfo(ctx context.Context) (*GetNetworkInfoResult, error) {
+	res := new(GetNetworkInfoResult)
+	if err := c.executeRPC(ctx, "getnetworkinfo", res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCAddNode(ctx context.Context, addr string, cmd string) error {
+	if err := c.executeRPC(ctx, "addnode", nil, addr, cmd); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) RPCDisconnectNode(ctx context.Context, addr string) error {
+	if err := c.executeRPC(ctx, "disconnectnode", nil, addr); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) RPCGetAddedNodeInfo(ctx context.Context, addr string) ([]*AddedNodeInfoResult, error) {
+	var params []interface{}
+	if addr != "" {
+		params = append(params, addr)
+	}
+	var res []*AddedNodeInfoResult
+	if err := c.executeRPC(ctx, "getaddednodeinfo", &res, params...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCSetBan(ctx context.Context, addr string, cmd string) error {
+	if err := c.executeRPC(ctx, "setban", nil, addr, cmd); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) RPCListBanned(ctx context.Context) ([]*BannedResult, error) {
+	var res []*BannedResult
+	if err := c.executeRPC(ctx, "listbanned", &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCClearBanned(ctx context.Context) error {
+	if err := c.executeRPC(ctx, "clearbanned", nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) RPCPing(ctx context.Context) error {
+	if err := c.executeRPC(ctx, "ping", nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) RPCGetMiningInfo(ctx context.Context) (*GetMiningInfoResult, error) {
+	res := new(GetMiningInfoResult)
+	if err := c.executeRPC(ctx, "getmininginfo", res); err != nil {
 		return nil, err
 	}
 	return res, nil
 }
 
-func (c *Client) RPCGetDifficulty() (float64, error) {
+func (c *Client) RPCGetNetworkHashPS(ctx context.Context, blocks int, height int) (float64, error) {
 	var res float64
-	if err := c.executeRPC("getdifficulty", &res); err != nil {
+	if err := c.executeRPC(ctx, "getnetworkhashps", &res, blocks, height); err != nil {
 		return 0, err
 	}
 	return res, nil
 }
 
-func (c *Client) RPCGetNameByHash(hash string) (*string, error) {
+func (c *Client) RPCGetBlockTemplate(ctx context.Context, req *BlockTemplateRequest) (*GetBlockTemplateResult, error) {
+	if req == nil {
+		req = new(BlockTemplateRequest)
+	}
+	res := new(GetBlockTemplateResult)
+	if err := c.executeRPC(ctx, "getblocktemplate", res, req); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// RPCProposeBlock submits a block in getblocktemplate proposal mode. It
+// returns an empty string if the block would be accepted, otherwise the
+// rejection reason.
+func (c *Client) RPCProposeBlock(ctx context.Context, blockHex string) (string, error) {
+	var res *string
+	req := &BlockTemplateRequest{
+		Mode: "proposal",
+		Data: blockHex,
+	}
+	if err := c.executeRPC(ctx, "getblocktemplate", &res, req); err != nil {
+		return "", err
+	}
+	if res == nil {
+		return "", nil
+	}
+	return *res, nil
+}
+
+// RPCSubmitBlock returns an empty string if the block was accepted,
+// otherwise the rejection reason.
+func (c *Client) RPCSubmitBlock(ctx context.Context, blockHex string) (string, error) {
 	var res *string
-	if err := c.executeRPC("getnamebyhash", &res, hash); err != nil {
+	if err := c.executeRPC(ctx, "submitblock", &res, blockHex); err != nil {
+		return "", err
+	}
+	if res == nil {
+		return "", nil
+	}
+	return *res, nil
+}
+
+func (c *Client) RPCGetWork(ctx context.Context) (*GetWorkResult, error) {
+	res := new(GetWorkResult)
+	if err := c.executeRPC(ctx, "getwork", res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCSubmitWork(ctx context.Context, data string) (bool, error) {
+	var res bool
+	if err := c.executeRPC(ctx, "submitwork", &res, data); err != nil {
+		return false, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCSetGenerate(ctx context.Context, generate bool, procLimit int) (bool, error) {
+	var res bool
+	if err := c.executeRPC(ctx, "setgenerate", &res, generate, procLimit); err != nil {
+		return false, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGetGenerate(ctx context.Context) (bool, error) {
+	var res bool
+	if err := c.executeRPC(ctx, "getgenerate", &res); err != nil {
+		return false, err
+	}
+	return res, nil
+}
+
+func (c *Client) RPCGenerateToAddress(ctx context.Context, numBlocks int, address string) ([]string, error) {
+	var res []string
+	if err := c.executeRPC(ctx, "generatetoaddress", &res, numBlocks, address); err != nil {
 		return nil, err
 	}
 	return res, nil
@@ -0,0 +1,1117 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"github.com/mslipper/handshake/dns"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/mslipper/handshake/urkel"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"testing"
+)
+
+// The name RPC responses below follow the shape of hsd's getnameinfo,
+// getnameresource, getnames, getnamebyhash and getnameproof output.
+const (
+	nameStateJSON = `{
+		"name": "proofofconcept",
+		"nameHash": "fe61b26f69cab8119ab654b5604f7f679582c0745afa9d4fa009ebf49d877f09",
+		"state": "CLOSED",
+		"height": 8578,
+		"renewal": 8578,
+		"owner": {
+			"hash": "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+			"index": 0
+		},
+		"value": 1000000,
+		"highest": 2000000,
+		"data": "000601026869",
+		"transfer": 0,
+		"revoked": 0,
+		"claimed": 0,
+		"renewals": 0,
+		"registered": true,
+		"expired": false,
+		"weak": false,
+		"stats": {
+			"renewalPeriodStart": 8578,
+			"renewalPeriodEnd": 113698,
+			"blocksUntilExpire": 105118,
+			"daysUntilExpire": 729.99
+		}
+	}`
+	nameInfoJSON = `{
+		"start": {
+			"reserved": false,
+			"week": 1,
+			"start": 2016
+		},
+		"info": ` + nameStateJSON + `
+	}`
+	nameResourceJSON = `{
+		"records": [
+			{"type": "NS", "ns": "ns1.example."},
+			{"type": "GLUE4", "ns": "ns1.example.", "address": "10.0.0.1"},
+			{"type": "TXT", "txt": ["hi"]}
+		]
+	}`
+	nameProofJSON = `{
+		"hash": "0000000000000000000000000000000000000000000000000000000000000000",
+		"height": 8580,
+		"root": "dbb5d90aa4c8e7f95ae60ad2c9458eeb7f1d36e29e874ad92556b7558b721515",
+		"name": "proofofconcept",
+		"key": "fe61b26f69cab8119ab654b5604f7f679582c0745afa9d4fa009ebf49d877f09",
+		"proof": {
+			"type": "TYPE_EXISTS",
+			"depth": 9,
+			"nodes": [
+				["11111110", "9cd45ce9b449db0389e4d9cf37862d929756fb5c32d95204de6a1807220e2195"]
+			],
+			"value": "0e70726f6f666f66636f6e6365707406000006010268698221000082210000000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f00fe40420f00fe80841e0010"
+		}
+	}`
+)
+
+func TestClient_NameRPCs(t *testing.T) {
+	srv := newTestServer(t, rpcResults(t, map[string]string{
+		"getnameinfo":     nameInfoJSON,
+		"getnameresource": nameResourceJSON,
+		"getnames":        "[" + nameStateJSON + "]",
+		"getnamebyhash":   `"proofofconcept"`,
+		"getnameproof":    nameProofJSON,
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL)
+	ctx := context.Background()
+
+	info, err := c.RPCGetNameInfo(ctx, "proofofconcept")
+	require.NoError(t, err)
+	require.Equal(t, 2016, info.Start.Start)
+	require.Equal(t, "CLOSED", info.Info.State)
+	require.Equal(t, 8578, info.Info.Height)
+	require.EqualValues(t, 2000000, info.Info.Highest)
+	require.True(t, info.Info.Registered)
+	resource, err := info.Info.Resource()
+	require.NoError(t, err)
+	require.Equal(t, []string{"hi"}, resource.Records[0].(*dns.TXTRecord).Entries)
+	method, params := rpcParams(t, srv.LastRequest())
+	require.Equal(t, "getnameinfo", method)
+	require.Equal(t, []interface{}{"proofofconcept"}, params)
+
+	resource, err = c.RPCGetNameResource(ctx, "proofofconcept")
+	require.NoError(t, err)
+	require.Len(t, resource.Records, 3)
+	require.Equal(t, "ns1.example.", resource.Records[0].(*dns.NSRecord).NS)
+	require.Equal(t, "10.0.0.1", resource.Records[1].(*dns.Glue4Record).Address.String())
+
+	names, err := c.RPCGetNames(ctx)
+	require.NoError(t, err)
+	require.Len(t, names, 1)
+	require.Equal(t, "fe61b26f69cab8119ab654b5604f7f679582c0745afa9d4fa009ebf49d877f09", names[0].NameHash)
+
+	name, err := c.RPCGetNameByHash(ctx, names[0].NameHash)
+	require.NoError(t, err)
+	require.Equal(t, "proofofconcept", *name)
+
+	proof, err := c.RPCGetNameProof(ctx, "proofofconcept")
+	require.NoError(t, err)
+	require.Equal(t, urkel.ProofTypeExists, proof.Proof.Type)
+	root, err := hex.DecodeString(proof.Root)
+	require.NoError(t, err)
+	value, err := proof.Proof.Verify(root, primitives.HashName(proof.Name))
+	require.NoError(t, err)
+	ns := new(primitives.NameState)
+	require.NoError(t, ns.Decode(bytes.NewReader(value)))
+	require.Equal(t, "proofofconcept", ns.Name)
+}
+
+// The auction responses follow hsd's getbids and getreveals output, where
+// the value of another wallet's bid is unknown until it is revealed.
+const (
+	bidsJSON = `[
+		{
+			"name": "proofofconcept",
+			"nameHash": "fe61b26f69cab8119ab654b5604f7f679582c0745afa9d4fa009ebf49d877f09",
+			"prevout": {
+				"hash": "1d0f8de2757488cbd59bea7b8f7c7ad5aa9ebd6459631e801a041062338a8630",
+				"index": 0
+			},
+			"value": 1000000,
+			"lockup": 5000000,
+			"blind": "9ab4ea12f6cbb2a4fe4bba9ec7a36d8aa3d2fba4ac48e6cc7c3c7b6e7d3f1a40",
+			"own": true
+		},
+		{
+			"name": "proofofconcept",
+			"nameHash": "fe61b26f69cab8119ab654b5604f7f679582c0745afa9d4fa009ebf49d877f09",
+			"prevout": {
+				"hash": "2e1f9ef3868599dce6acfb8c9f8d8be6bbafce7570742f912b15217344b97741",
+				"index": 1
+			},
+			"lockup": 3000000,
+			"blind": "0b8f2d3c1e4a5b6c7d8e9fa0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0",
+			"own": false
+		}
+	]`
+	revealsJSON = `[
+		{
+			"name": "proofofconcept",
+			"nameHash": "fe61b26f69cab8119ab654b5604f7f679582c0745afa9d4fa009ebf49d877f09",
+			"prevout": {
+				"hash": "3f20a0f4979600edf7bd0c9da09e9cf7ccc0df8681853a023c2632845524a8852",
+				"index": 0
+			},
+			"value": 1000000,
+			"height": 8570,
+			"own": true
+		}
+	]`
+)
+
+func TestClient_AuctionRPCs(t *testing.T) {
+	srv := newTestServer(t, rpcResults(t, map[string]string{
+		"getauctioninfo": nameStateJSON[:len(nameStateJSON)-1] + `, "bids": ` + bidsJSON + `, "reveals": ` + revealsJSON + `}`,
+		"getbids":        bidsJSON,
+		"getreveals":     revealsJSON,
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL)
+	ctx := context.Background()
+
+	info, err := c.RPCGetAuctionInfo(ctx, "proofofconcept")
+	require.NoError(t, err)
+	require.Equal(t, "CLOSED", info.State)
+	require.Len(t, info.Bids, 2)
+	require.Len(t, info.Reveals, 1)
+	method, params := rpcParams(t, srv.LastRequest())
+	require.Equal(t, "getauctioninfo", method)
+	require.Equal(t, []interface{}{"proofofconcept"}, params)
+
+	bids, err := c.RPCGetBids(ctx, "proofofconcept", true)
+	require.NoError(t, err)
+	require.Len(t, bids, 2)
+	require.EqualValues(t, 1000000, *bids[0].Value)
+	require.EqualValues(t, 5000000, bids[0].Lockup)
+	require.Equal(t, "1d0f8de2757488cbd59bea7b8f7c7ad5aa9ebd6459631e801a041062338a8630", bids[0].Prevout.Hash)
+	require.True(t, bids[0].Own)
+	require.Nil(t, bids[1].Value)
+	require.Equal(t, 1, bids[1].Prevout.Index)
+	require.False(t, bids[1].Own)
+	method, params = rpcParams(t, srv.LastRequest())
+	require.Equal(t, "getbids", method)
+	require.Equal(t, []interface{}{"proofofconcept", true}, params)
+
+	reveals, err := c.RPCGetReveals(ctx, "proofofconcept", false)
+	require.NoError(t, err)
+	require.Len(t, reveals, 1)
+	require.EqualValues(t, 1000000, reveals[0].Value)
+	require.Equal(t, 8570, reveals[0].Height)
+	method, params = rpcParams(t, srv.LastRequest())
+	require.Equal(t, "getreveals", method)
+	require.Equal(t, []interface{}{"proofofconcept", false}, params)
+}
+
+func TestClient_NameRPCsNotFound(t *testing.T) {
+	srv := newTestServer(t, rpcResults(t, map[string]string{
+		"getnameinfo":     `{"start": {"reserved": false, "week": 1, "start": 2016}, "info": null}`,
+		"getnameresource": "null",
+		"getnamebyhash":   "null",
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL)
+	ctx := context.Background()
+
+	info, err := c.RPCGetNameInfo(ctx, "unregistered")
+	require.NoError(t, err)
+	require.Nil(t, info.Info)
+
+	resource, err := c.RPCGetNameResource(ctx, "unregistered")
+	require.NoError(t, err)
+	require.Nil(t, resource)
+
+	name, err := c.RPCGetNameByHash(ctx, "00")
+	require.NoError(t, err)
+	require.Nil(t, name)
+}
+
+// rpcTest is a single RPC call against a server answering its method with
+// result. The call's request must carry params, and check inspects what it
+// returned.
+type rpcTest struct {
+	name   string
+	method string
+	result string
+	call   func(ctx context.Context, c *Client) (interface{}, error)
+	params []interface{}
+	check  func(t *testing.T, res interface{})
+}
+
+func runRPCTests(t *testing.T, tests []rpcTest) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, rpcResults(t, map[string]string{
+				tt.method: tt.result,
+			}))
+			defer srv.Close()
+			res, err := tt.call(context.Background(), NewClient(srv.URL))
+			require.NoError(t, err)
+			method, params := rpcParams(t, srv.LastRequest())
+			require.Equal(t, tt.method, method)
+			require.Equal(t, tt.params, params)
+			if tt.check != nil {
+				tt.check(t, res)
+			}
+		})
+	}
+}
+
+const (
+	mempoolTxid      = "1d0f8de2757488cbd59bea7b8f7c7ad5aa9ebd6459631e801a041062338a8630"
+	mempoolEntryJSON = `{
+		"size": 215,
+		"fee": 0.0043,
+		"modifiedfee": 0,
+		"time": 1580745078,
+		"height": 10161,
+		"startingpriority": 0,
+		"currentpriority": 0,
+		"descendantcount": 0,
+		"descendantsize": 215,
+		"descendantfees": 4300,
+		"ancestorcount": 0,
+		"ancestorsize": 215,
+		"ancestorfees": 4300,
+		"depends": ["9cd45ce9b449db0389e4d9cf37862d929756fb5c32d95204de6a1807220e2195"]
+	}`
+	rpcTxJSON = `{
+		"txid": "1d0f8de2757488cbd59bea7b8f7c7ad5aa9ebd6459631e801a041062338a8630",
+		"hash": "6d3f9f0a3b3a1c9e0a2b0f4ad4c4f6f4f3e3a1b0c9d8e7f6a5b4c3d2e1f0a9b8",
+		"size": 215,
+		"vsize": 140,
+		"version": 0,
+		"locktime": 0,
+		"vin": [{
+			"coinbase": false,
+			"txid": "9cd45ce9b449db0389e4d9cf37862d929756fb5c32d95204de6a1807220e2195",
+			"vout": 1,
+			"txinwitness": ["3044", "02aa"],
+			"sequence": 4294967295
+		}],
+		"vout": [{
+			"value": 1999.9957,
+			"n": 0,
+			"address": {
+				"version": 0,
+				"hash": "378906696d5d4e4e9f7cfc6c328a8e2df63a32d1"
+			},
+			"covenant": {
+				"type": 0,
+				"action": "NONE",
+				"items": []
+			}
+		}],
+		"blockhash": "0000000000000424ee6c2a5d6e0da5edfc47a4a10328c1792056ee48303c3e40",
+		"confirmations": 6,
+		"time": 1580745078,
+		"blocktime": 1580745078,
+		"hex": "00"
+	}`
+)
+
+func TestClient_TransactionRPCs(t *testing.T) {
+	txData, err := ioutil.ReadFile("../primitives/testdata/tx_1d0f8de2757488cbd59bea7b8f7c7ad5aa9ebd6459631e801a041062338a8630.bin")
+	require.NoError(t, err)
+	txHex := hex.EncodeToString(txData)
+	tx := new(primitives.Transaction)
+	require.NoError(t, tx.Decode(bytes.NewReader(txData)))
+
+	checkTx := func(t *testing.T, res interface{}) {
+		require.Equal(t, mempoolTxid, hex.EncodeToString(res.(*primitives.Transaction).ID()))
+	}
+	checkRPCTx := func(t *testing.T, res interface{}) {
+		rpcTx := res.(*RPCTx)
+		require.Equal(t, mempoolTxid, rpcTx.ID)
+		require.Len(t, rpcTx.Vin, 1)
+		require.EqualValues(t, 1, rpcTx.Vin[0].Vout)
+		require.Equal(t, []string{"3044", "02aa"}, rpcTx.Vin[0].Txinwitness)
+		require.Equal(t, 1999.9957, rpcTx.VOut[0].Value)
+		require.Equal(t, "378906696d5d4e4e9f7cfc6c328a8e2df63a32d1", rpcTx.VOut[0].Address.Hash)
+		require.Equal(t, 6, rpcTx.Confirmations)
+	}
+	checkEntries := func(t *testing.T, res interface{}) {
+		entries := res.([]*MempoolEntryResult)
+		require.Len(t, entries, 1)
+		require.Equal(t, 215, entries[0].Size)
+		require.Equal(t, 0.0043, entries[0].Fee)
+	}
+
+	runRPCTests(t, []rpcTest{
+		{
+			name:   "getrawmempool",
+			method: "getrawmempool",
+			result: `["` + mempoolTxid + `"]`,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetRawMempool(ctx)
+			},
+			params: []interface{}{false},
+			check: func(t *testing.T, res interface{}) {
+				require.Equal(t, []string{mempoolTxid}, res)
+			},
+		},
+		{
+			name:   "getrawmempool verbose",
+			method: "getrawmempool",
+			result: `{"` + mempoolTxid + `": ` + mempoolEntryJSON + `}`,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetRawMempoolVerbose(ctx)
+			},
+			params: []interface{}{true},
+			check: func(t *testing.T, res interface{}) {
+				entry := res.(map[string]*MempoolEntryResult)[mempoolTxid]
+				require.NotNil(t, entry)
+				require.Equal(t, 10161, entry.Height)
+				require.EqualValues(t, 4300, entry.AncestorFees)
+				require.Equal(t, []string{"9cd45ce9b449db0389e4d9cf37862d929756fb5c32d95204de6a1807220e2195"}, entry.Depends)
+			},
+		},
+		{
+			name:   "getmempoolinfo",
+			method: "getmempoolinfo",
+			result: `{"size": 1, "bytes": 215, "usage": 215, "maxmempool": 100000000, "mempoolminfee": 0.00001}`,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetMempoolInfo(ctx)
+			},
+			params: []interface{}{},
+			check: func(t *testing.T, res interface{}) {
+				info := res.(*GetMempoolInfoResult)
+				require.Equal(t, 1, info.Size)
+				require.Equal(t, 100000000, info.MaxMempool)
+				require.Equal(t, 0.00001, info.MempoolMinFee)
+			},
+		},
+		{
+			name:   "getmempoolentry",
+			method: "getmempoolentry",
+			result: mempoolEntryJSON,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetMempoolEntry(ctx, mempoolTxid)
+			},
+			params: []interface{}{mempoolTxid},
+			check: func(t *testing.T, res interface{}) {
+				require.Equal(t, 215, res.(*MempoolEntryResult).DescendantSize)
+			},
+		},
+		{
+			name:   "getmempoolancestors",
+			method: "getmempoolancestors",
+			result: `["9cd45ce9b449db0389e4d9cf37862d929756fb5c32d95204de6a1807220e2195"]`,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetMempoolAncestors(ctx, mempoolTxid)
+			},
+			params: []interface{}{mempoolTxid, false},
+			check: func(t *testing.T, res interface{}) {
+				require.Equal(t, []string{"9cd45ce9b449db0389e4d9cf37862d929756fb5c32d95204de6a1807220e2195"}, res)
+			},
+		},
+		{
+			name:   "getmempoolancestors verbose",
+			method: "getmempoolancestors",
+			result: "[" + mempoolEntryJSON + "]",
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetMempoolAncestorsVerbose(ctx, mempoolTxid)
+			},
+			params: []interface{}{mempoolTxid, true},
+			check:  checkEntries,
+		},
+		{
+			name:   "getmempooldescendants",
+			method: "getmempooldescendants",
+			result: "[]",
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetMempoolDescendants(ctx, mempoolTxid)
+			},
+			params: []interface{}{mempoolTxid, false},
+			check: func(t *testing.T, res interface{}) {
+				require.Empty(t, res)
+			},
+		},
+		{
+			name:   "getmempooldescendants verbose",
+			method: "getmempooldescendants",
+			result: "[" + mempoolEntryJSON + "]",
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetMempoolDescendantsVerbose(ctx, mempoolTxid)
+			},
+			params: []interface{}{mempoolTxid, true},
+			check:  checkEntries,
+		},
+		{
+			name:   "prioritisetransaction",
+			method: "prioritisetransaction",
+			result: "true",
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCPrioritiseTransaction(ctx, mempoolTxid, 1000, -500)
+			},
+			params: []interface{}{mempoolTxid, float64(1000), float64(-500)},
+			check: func(t *testing.T, res interface{}) {
+				require.Equal(t, true, res)
+			},
+		},
+		{
+			name:   "getrawtransaction",
+			method: "getrawtransaction",
+			result: rpcTxJSON,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetRawTransaction(ctx, mempoolTxid)
+			},
+			params: []interface{}{mempoolTxid, true},
+			check:  checkRPCTx,
+		},
+		{
+			name:   "getrawtransaction hex",
+			method: "getrawtransaction",
+			result: `"` + txHex + `"`,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetRawTransactionHex(ctx, mempoolTxid)
+			},
+			params: []interface{}{mempoolTxid, false},
+			check: func(t *testing.T, res interface{}) {
+				require.Equal(t, txHex, res)
+			},
+		},
+		{
+			name:   "getrawtransaction primitive",
+			method: "getrawtransaction",
+			result: `"` + txHex + `"`,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetRawTransactionPrimitive(ctx, mempoolTxid)
+			},
+			params: []interface{}{mempoolTxid, false},
+			check:  checkTx,
+		},
+		{
+			name:   "decoderawtransaction",
+			method: "decoderawtransaction",
+			result: rpcTxJSON,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCDecodeRawTransaction(ctx, txHex)
+			},
+			params: []interface{}{txHex},
+			check:  checkRPCTx,
+		},
+		{
+			name:   "sendrawtransaction",
+			method: "sendrawtransaction",
+			result: `"` + mempoolTxid + `"`,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCSendRawTransaction(ctx, txHex)
+			},
+			params: []interface{}{txHex},
+			check: func(t *testing.T, res interface{}) {
+				require.Equal(t, mempoolTxid, res)
+			},
+		},
+		{
+			name:   "sendrawtransaction primitive",
+			method: "sendrawtransaction",
+			result: `"` + mempoolTxid + `"`,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCSendRawTransactionPrimitive(ctx, tx)
+			},
+			params: []interface{}{txHex},
+			check: func(t *testing.T, res interface{}) {
+				require.Equal(t, mempoolTxid, res)
+			},
+		},
+		{
+			name:   "createrawtransaction",
+			method: "createrawtransaction",
+			result: `"` + txHex + `"`,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				sequence := 0
+				return c.RPCCreateRawTransactionPrimitive(ctx, []*CreateRawTransactionInput{
+					{Txid: mempoolTxid, Vout: 1, Sequence: &sequence},
+				}, map[string]float64{
+					"rs1qx7ysvmt4j6wf6hhmcmzr9z5w9hmr5vk3xvf2dk": 1999.9957,
+				}, 10)
+			},
+			params: []interface{}{
+				[]interface{}{
+					map[string]interface{}{"txid": mempoolTxid, "vout": float64(1), "sequence": float64(0)},
+				},
+				map[string]interface{}{"rs1qx7ysvmt4j6wf6hhmcmzr9z5w9hmr5vk3xvf2dk": 1999.9957},
+				float64(10),
+			},
+			check: checkTx,
+		},
+		{
+			name:   "signrawtransaction defaults",
+			method: "signrawtransaction",
+			result: `{"hex": "` + txHex + `", "complete": true}`,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCSignRawTransaction(ctx, txHex, nil, nil, "")
+			},
+			params: []interface{}{txHex, []interface{}{}, []interface{}{}, "ALL"},
+			check: func(t *testing.T, res interface{}) {
+				require.Equal(t, txHex, res.(*SignRawTransactionResult).Hex)
+				require.True(t, res.(*SignRawTransactionResult).Complete)
+			},
+		},
+		{
+			name:   "signrawtransaction primitive",
+			method: "signrawtransaction",
+			result: `{"hex": "` + txHex + `", "complete": false}`,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				signed, complete, err := c.RPCSignRawTransactionPrimitive(ctx, tx, []*SignRawTransactionPrevout{
+					{Txid: mempoolTxid, Vout: 1, Amount: 2000},
+				}, []string{"EPuP1sPpfGgiMC3jgXdTpYiTBeVyhbSkEuvBKw2ZNbpJCA4Zk9CB"}, "SINGLE")
+				return []interface{}{signed, complete}, err
+			},
+			params: []interface{}{
+				txHex,
+				[]interface{}{
+					map[string]interface{}{"txid": mempoolTxid, "vout": float64(1), "amount": float64(2000)},
+				},
+				[]interface{}{"EPuP1sPpfGgiMC3jgXdTpYiTBeVyhbSkEuvBKw2ZNbpJCA4Zk9CB"},
+				"SINGLE",
+			},
+			check: func(t *testing.T, res interface{}) {
+				checkTx(t, res.([]interface{})[0])
+				require.Equal(t, false, res.([]interface{})[1])
+			},
+		},
+		{
+			name:   "gettxout",
+			method: "gettxout",
+			result: `{
+				"bestblock": "0000000000000424ee6c2a5d6e0da5edfc47a4a10328c1792056ee48303c3e40",
+				"confirmations": 6,
+				"value": 1999.9957,
+				"address": {"version": 0, "hash": "378906696d5d4e4e9f7cfc6c328a8e2df63a32d1"},
+				"covenant": {"type": 0, "action": "NONE", "items": []},
+				"coinbase": false
+			}`,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetTxOut(ctx, mempoolTxid, 0, true)
+			},
+			params: []interface{}{mempoolTxid, float64(0), true},
+			check: func(t *testing.T, res interface{}) {
+				out := res.(*GetTxOutResult)
+				require.Equal(t, 6, out.Confirmations)
+				require.Equal(t, "378906696d5d4e4e9f7cfc6c328a8e2df63a32d1", out.Address.Hash)
+			},
+		},
+		{
+			name:   "gettxout spent",
+			method: "gettxout",
+			result: "null",
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetTxOut(ctx, mempoolTxid, 1, false)
+			},
+			params: []interface{}{mempoolTxid, float64(1), false},
+			check: func(t *testing.T, res interface{}) {
+				require.Nil(t, res.(*GetTxOutResult))
+			},
+		},
+		{
+			name:   "gettxoutsetinfo",
+			method: "gettxoutsetinfo",
+			result: `{
+				"height": 10162,
+				"bestblock": "0000000000000424ee6c2a5d6e0da5edfc47a4a10328c1792056ee48303c3e40",
+				"transactions": 12000,
+				"txouts": 25000,
+				"bytes_serialized": 0,
+				"hash_serialized": 0,
+				"total_amount": 1000000.5,
+				"total_burned": 12.25
+			}`,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetTxOutSetInfo(ctx)
+			},
+			params: []interface{}{},
+			check: func(t *testing.T, res interface{}) {
+				info := res.(*GetTxOutSetInfoResult)
+				require.Equal(t, 10162, info.Height)
+				require.Equal(t, 25000, info.TxOuts)
+				require.Equal(t, 12.25, info.TotalBurned)
+			},
+		},
+	})
+}
+
+func TestClient_RPCGetRawTransactionPrimitiveMismatch(t *testing.T) {
+	txData, err := ioutil.ReadFile("../primitives/testdata/tx_1d0f8de2757488cbd59bea7b8f7c7ad5aa9ebd6459631e801a041062338a8630.bin")
+	require.NoError(t, err)
+	srv := newTestServer(t, rpcResults(t, map[string]string{
+		"getrawtransaction": `"` + hex.EncodeToString(txData) + `"`,
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL)
+
+	// A node answering with a different transaction than the one asked for
+	// is caught by comparing hashes.
+	otherTxid := "9cd45ce9b449db0389e4d9cf37862d929756fb5c32d95204de6a1807220e2195"
+	_, err = c.RPCGetRawTransactionPrimitive(context.Background(), otherTxid)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "transaction hash mismatch")
+}
+
+// peerInfoJSON follows the shape of hsd's getpeerinfo output.
+const peerInfoJSON = `[{
+	"id": 1,
+	"addr": "165.22.151.242:12038",
+	"addrlocal": "10.0.0.2:44806",
+	"name": "aorsxa4ylaacshipyjkfbvzfkh3jhh4yowtoqdt64nzemqtiw2whk@165.22.151.242:12038",
+	"services": "00000001",
+	"relaytxes": true,
+	"lastsend": 1580745100,
+	"lastrecv": 1580745101,
+	"bytessent": 5123,
+	"bytesrecv": 1048576,
+	"conntime": 62,
+	"timeoffset": -1,
+	"pingtime": 0.072,
+	"minping": 0.068,
+	"version": 1,
+	"subver": "/hsd:2.0.1/",
+	"inbound": false,
+	"startingheight": 10161,
+	"besthash": "0000000000000424ee6c2a5d6e0da5edfc47a4a10328c1792056ee48303c3e40",
+	"bestheight": 10162,
+	"banscore": 0,
+	"inflight": [],
+	"whitelisted": false
+}]`
+
+func TestClient_NetworkRPCs(t *testing.T) {
+	runRPCTests(t, []rpcTest{
+		{
+			name:   "getpeerinfo",
+			method: "getpeerinfo",
+			result: peerInfoJSON,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetPeerInfo(ctx)
+			},
+			params: []interface{}{},
+			check: func(t *testing.T, res interface{}) {
+				peers := res.([]*PeerInfoResult)
+				require.Len(t, peers, 1)
+				require.Equal(t, "165.22.151.242:12038", peers[0].Addr)
+				require.Equal(t, "/hsd:2.0.1/", peers[0].SubVer)
+				require.EqualValues(t, 1048576, peers[0].BytesRecv)
+				require.Equal(t, 0.072, peers[0].PingTime)
+				require.Equal(t, 10162, peers[0].BestHeight)
+				require.Empty(t, peers[0].Inflight)
+			},
+		},
+		{
+			name:   "getconnectioncount",
+			method: "getconnectioncount",
+			result: "8",
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetConnectionCount(ctx)
+			},
+			params: []interface{}{},
+			check: func(t *testing.T, res interface{}) {
+				require.Equal(t, 8, res)
+			},
+		},
+		{
+			name:   "getnettotals",
+			method: "getnettotals",
+			result: `{"totalbytesrecv": 1048576, "totalbytessent": 5123, "timemillis": 1580745101000}`,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetNetTotals(ctx)
+			},
+			params: []interface{}{},
+			check: func(t *testing.T, res interface{}) {
+				totals := res.(*GetNetTotalsResult)
+				require.EqualValues(t, 1048576, totals.TotalBytesRecv)
+				require.EqualValues(t, 5123, totals.TotalBytesSent)
+				require.EqualValues(t, 1580745101000, totals.TimeMillis)
+			},
+		},
+		{
+			name:   "getnetworkinfo",
+			method: "getnetworkinfo",
+			result: `{
+				"version": "2.0.1",
+				"subversion": "/hsd:2.0.1/",
+				"protocolversion": 1,
+				"identitykey": "03b1b2b3b4b5b6b7b8b9babbbcbdbebfc0c1c2c3c4c5c6c7c8c9cacbcccdcecfd0",
+				"localservices": "00000001",
+				"localrelay": true,
+				"timeoffset": 0,
+				"networkactive": true,
+				"connections": 8,
+				"networks": [],
+				"relayfee": 0.00001,
+				"incrementalfee": 0,
+				"localaddresses": [{"address": "10.0.0.2", "port": 12038, "score": 3}],
+				"warnings": ""
+			}`,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetNetworkInfo(ctx)
+			},
+			params: []interface{}{},
+			check: func(t *testing.T, res interface{}) {
+				info := res.(*GetNetworkInfoResult)
+				require.Equal(t, "/hsd:2.0.1/", info.SubVersion)
+				require.Equal(t, 8, info.Connections)
+				require.Len(t, info.LocalAddresses, 1)
+				require.Equal(t, 12038, info.LocalAddresses[0].Port)
+			},
+		},
+		{
+			name:   "addnode",
+			method: "addnode",
+			result: "null",
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return nil, c.RPCAddNode(ctx, "165.22.151.242:12038", AddNodeOneTry)
+			},
+			params: []interface{}{"165.22.151.242:12038", "onetry"},
+		},
+		{
+			name:   "addnode remove",
+			method: "addnode",
+			result: "null",
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return nil, c.RPCAddNode(ctx, "165.22.151.242:12038", AddNodeRemove)
+			},
+			params: []interface{}{"165.22.151.242:12038", "remove"},
+		},
+		{
+			name:   "disconnectnode",
+			method: "disconnectnode",
+			result: "null",
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return nil, c.RPCDisconnectNode(ctx, "165.22.151.242:12038")
+			},
+			params: []interface{}{"165.22.151.242:12038"},
+		},
+		{
+			name:   "getaddednodeinfo",
+			method: "getaddednodeinfo",
+			result: `[{
+				"addednode": "165.22.151.242:12038",
+				"connected": true,
+				"addresses": [{"address": "165.22.151.242:12038", "connected": "outbound"}]
+			}]`,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetAddedNodeInfo(ctx, "165.22.151.242:12038")
+			},
+			params: []interface{}{"165.22.151.242:12038"},
+			check: func(t *testing.T, res interface{}) {
+				nodes := res.([]*AddedNodeInfoResult)
+				require.Len(t, nodes, 1)
+				require.True(t, nodes[0].Connected)
+				require.Equal(t, "outbound", nodes[0].Addresses[0].Connected)
+			},
+		},
+		{
+			name:   "getaddednodeinfo all",
+			method: "getaddednodeinfo",
+			result: "[]",
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetAddedNodeInfo(ctx, "")
+			},
+			params: []interface{}{},
+		},
+		{
+			name:   "setban",
+			method: "setban",
+			result: "null",
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return nil, c.RPCSetBan(ctx, "165.22.151.242", SetBanRemove)
+			},
+			params: []interface{}{"165.22.151.242", "remove"},
+		},
+		{
+			name:   "setban add",
+			method: "setban",
+			result: "null",
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return nil, c.RPCSetBan(ctx, "165.22.151.242", SetBanAdd)
+			},
+			params: []interface{}{"165.22.151.242", "add"},
+		},
+		{
+			name:   "listbanned",
+			method: "listbanned",
+			result: `[{
+				"address": "165.22.151.242",
+				"banned_until": 1580831501,
+				"ban_created": 1580745101,
+				"ban_reason": "node misbehaving"
+			}]`,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCListBanned(ctx)
+			},
+			params: []interface{}{},
+			check: func(t *testing.T, res interface{}) {
+				bans := res.([]*BannedResult)
+				require.Len(t, bans, 1)
+				require.Equal(t, "165.22.151.242", bans[0].Address)
+				require.Equal(t, 1580831501, bans[0].BannedUntil)
+				require.Equal(t, "node misbehaving", bans[0].BanReason)
+			},
+		},
+		{
+			name:   "clearbanned",
+			method: "clearbanned",
+			result: "null",
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return nil, c.RPCClearBanned(ctx)
+			},
+			params: []interface{}{},
+		},
+		{
+			name:   "ping",
+			method: "ping",
+			result: "null",
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return nil, c.RPCPing(ctx)
+			},
+			params: []interface{}{},
+		},
+	})
+}
+
+// blockTemplateJSON follows the shape of hsd's getblocktemplate output.
+const blockTemplateJSON = `{
+	"capabilities": ["proposal"],
+	"mutable": ["time", "transactions", "prevblock"],
+	"version": 0,
+	"rules": [],
+	"vbavailable": {},
+	"vbrequired": 0,
+	"height": 10163,
+	"previousblockhash": "0000000000000424ee6c2a5d6e0da5edfc47a4a10328c1792056ee48303c3e40",
+	"treeroot": "dbb5d90aa4c8e7f95ae60ad2c9458eeb7f1d36e29e874ad92556b7558b721515",
+	"reservedroot": "0000000000000000000000000000000000000000000000000000000000000000",
+	"mask": "0000000000000000000000000000000000000000000000000000000000000000",
+	"target": "00000000000005a4a70000000000000000000000000000000000000000000000",
+	"bits": "1a05a4a7",
+	"noncerange": "000000000000000000000000000000000000000000000000ffffffffffffffffffffffffffffffffffffffffffffffff",
+	"curtime": 1580745700,
+	"mintime": 1580745079,
+	"maxtime": 1580752900,
+	"expires": 1580752900,
+	"sigoplimit": 80000,
+	"sizelimit": 1000000,
+	"weightlimit": 4000000,
+	"longpollid": "0000000000000424ee6c2a5d6e0da5edfc47a4a10328c1792056ee48303c3e400000000001",
+	"submitold": false,
+	"coinbaseaux": {"flags": "6d696e656420627920687364"},
+	"coinbasevalue": 2000004300,
+	"claims": [],
+	"airdrops": [],
+	"transactions": [{
+		"data": "00",
+		"txid": "1d0f8de2757488cbd59bea7b8f7c7ad5aa9ebd6459631e801a041062338a8630",
+		"hash": "6d3f9f0a3b3a1c9e0a2b0f4ad4c4f6f4f3e3a1b0c9d8e7f6a5b4c3d2e1f0a9b8",
+		"depends": [],
+		"fee": 4300,
+		"sigops": 0,
+		"weight": 860
+	}]
+}`
+
+func TestClient_MiningRPCs(t *testing.T) {
+	runRPCTests(t, []rpcTest{
+		{
+			name:   "getmininginfo",
+			method: "getmininginfo",
+			result: `{
+				"blocks": 10162,
+				"currentblocksize": 0,
+				"currentblockweight": 0,
+				"currentblocktx": 0,
+				"difficulty": 1128.93,
+				"errors": "",
+				"genproclimit": 0,
+				"networkhashps": 120398523.51,
+				"pooledtx": 1,
+				"testnet": false,
+				"chain": "main",
+				"generate": false
+			}`,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetMiningInfo(ctx)
+			},
+			params: []interface{}{},
+			check: func(t *testing.T, res interface{}) {
+				info := res.(*GetMiningInfoResult)
+				require.Equal(t, 10162, info.Blocks)
+				require.Equal(t, 1128.93, info.Difficulty)
+				require.Equal(t, "main", info.Chain)
+			},
+		},
+		{
+			name:   "getnetworkhashps",
+			method: "getnetworkhashps",
+			result: "120398523.51",
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetNetworkHashPS(ctx, 120, -1)
+			},
+			params: []interface{}{float64(120), float64(-1)},
+			check: func(t *testing.T, res interface{}) {
+				require.Equal(t, 120398523.51, res)
+			},
+		},
+		{
+			name:   "getblocktemplate default request",
+			method: "getblocktemplate",
+			result: blockTemplateJSON,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetBlockTemplate(ctx, nil)
+			},
+			params: []interface{}{map[string]interface{}{}},
+			check: func(t *testing.T, res interface{}) {
+				tmpl := res.(*GetBlockTemplateResult)
+				require.Equal(t, 10163, tmpl.Height)
+				require.Equal(t, "1a05a4a7", tmpl.Bits)
+				require.EqualValues(t, 2000004300, tmpl.CoinbaseValue)
+				require.Equal(t, "6d696e656420627920687364", tmpl.CoinbaseAux["flags"])
+				require.Len(t, tmpl.Transactions, 1)
+				require.EqualValues(t, 4300, tmpl.Transactions[0].Fee)
+			},
+		},
+		{
+			name:   "getblocktemplate longpoll",
+			method: "getblocktemplate",
+			result: blockTemplateJSON,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetBlockTemplate(ctx, &BlockTemplateRequest{
+					Mode:         "template",
+					Capabilities: []string{"coinbasetxn", "longpoll"},
+					LongPollID:   "0000000000000424ee6c2a5d6e0da5edfc47a4a10328c1792056ee48303c3e400000000001",
+				})
+			},
+			params: []interface{}{map[string]interface{}{
+				"mode":         "template",
+				"capabilities": []interface{}{"coinbasetxn", "longpoll"},
+				"longpollid":   "0000000000000424ee6c2a5d6e0da5edfc47a4a10328c1792056ee48303c3e400000000001",
+			}},
+		},
+		{
+			name:   "proposal accepted",
+			method: "getblocktemplate",
+			result: "null",
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCProposeBlock(ctx, "00")
+			},
+			params: []interface{}{map[string]interface{}{"mode": "proposal", "data": "00"}},
+			check: func(t *testing.T, res interface{}) {
+				require.Equal(t, "", res)
+			},
+		},
+		{
+			name:   "proposal rejected",
+			method: "getblocktemplate",
+			result: `"inconclusive-not-best-prevblk"`,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCProposeBlock(ctx, "00")
+			},
+			params: []interface{}{map[string]interface{}{"mode": "proposal", "data": "00"}},
+			check: func(t *testing.T, res interface{}) {
+				require.Equal(t, "inconclusive-not-best-prevblk", res)
+			},
+		},
+		{
+			name:   "submitblock accepted",
+			method: "submitblock",
+			result: "null",
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCSubmitBlock(ctx, "00")
+			},
+			params: []interface{}{"00"},
+			check: func(t *testing.T, res interface{}) {
+				require.Equal(t, "", res)
+			},
+		},
+		{
+			name:   "submitblock rejected",
+			method: "submitblock",
+			result: `"high-hash"`,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCSubmitBlock(ctx, "00")
+			},
+			params: []interface{}{"00"},
+			check: func(t *testing.T, res interface{}) {
+				require.Equal(t, "high-hash", res)
+			},
+		},
+		{
+			name:   "getwork",
+			method: "getwork",
+			result: `{
+				"network": "main",
+				"data": "00000000",
+				"target": "00000000000005a4a70000000000000000000000000000000000000000000000",
+				"height": 10163,
+				"time": 1580745700
+			}`,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetWork(ctx)
+			},
+			params: []interface{}{},
+			check: func(t *testing.T, res interface{}) {
+				work := res.(*GetWorkResult)
+				require.Equal(t, "main", work.Network)
+				require.Equal(t, 10163, work.Height)
+			},
+		},
+		{
+			name:   "submitwork",
+			method: "submitwork",
+			result: "false",
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCSubmitWork(ctx, "00000000")
+			},
+			params: []interface{}{"00000000"},
+			check: func(t *testing.T, res interface{}) {
+				require.Equal(t, false, res)
+			},
+		},
+		{
+			name:   "setgenerate",
+			method: "setgenerate",
+			result: "true",
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCSetGenerate(ctx, true, 2)
+			},
+			params: []interface{}{true, float64(2)},
+			check: func(t *testing.T, res interface{}) {
+				require.Equal(t, true, res)
+			},
+		},
+		{
+			name:   "getgenerate",
+			method: "getgenerate",
+			result: "true",
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGetGenerate(ctx)
+			},
+			params: []interface{}{},
+			check: func(t *testing.T, res interface{}) {
+				require.Equal(t, true, res)
+			},
+		},
+		{
+			name:   "generatetoaddress",
+			method: "generatetoaddress",
+			result: `["0000000000000424ee6c2a5d6e0da5edfc47a4a10328c1792056ee48303c3e40", "000000000000003d56d278ef00c657de6b1fcb549f9e04e299f6a918c2573b94"]`,
+			call: func(ctx context.Context, c *Client) (interface{}, error) {
+				return c.RPCGenerateToAddress(ctx, 2, "rs1qx7ysvmt4j6wf6hhmcmzr9z5w9hmr5vk3xvf2dk")
+			},
+			params: []interface{}{float64(2), "rs1qx7ysvmt4j6wf6hhmcmzr9z5w9hmr5vk3xvf2dk"},
+			check: func(t *testing.T, res interface{}) {
+				require.Len(t, res, 2)
+			},
+		},
+	})
+}
@@ -1,6 +1,12 @@
 package client
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/mslipper/handshake/dns"
+	"github.com/mslipper/handshake/urkel"
+)
 
 type GetInfoResult struct {
 	Version         string  `json:"version"`
@@ -120,6 +126,7 @@ type RPCTx struct {
 	Confirmations int       `json:"confirmations"`
 	Time          int       `json:"time"`
 	BlockTime     int       `json:"blocktime"`
+	Hex           string    `json:"hex"`
 }
 
 type RPCAddress struct {
@@ -168,3 +175,296 @@ type GetChainTipsResult struct {
 	Branchlen int    `json:"branchlen"`
 	Status    string `json:"status"`
 }
+
+type GetNameProofResult struct {
+	Hash   string       `json:"hash"`
+	Height int          `json:"height"`
+	Root   string       `json:"root"`
+	Name   string       `json:"name"`
+	Key    string       `json:"key"`
+	Proof  *urkel.Proof `json:"proof"`
+}
+
+type RPCOutpoint struct {
+	Hash  string `json:"hash"`
+	Index int    `json:"index"`
+}
+
+type NameStateResult struct {
+	Name       string          `json:"name"`
+	NameHash   string          `json:"nameHash"`
+	State      string          `json:"state"`
+	Height     int             `json:"height"`
+	Renewal    int             `json:"renewal"`
+	Owner      RPCOutpoint     `json:"owner"`
+	Value      int64           `json:"value"`
+	Highest    int64           `json:"highest"`
+	Data       string          `json:"data"`
+	Transfer   int             `json:"transfer"`
+	Revoked    int             `json:"revoked"`
+	Claimed    int             `json:"claimed"`
+	Renewals   int             `json:"renewals"`
+	Registered bool            `json:"registered"`
+	Expired    bool            `json:"expired"`
+	Weak       bool            `json:"weak"`
+	Stats      json.RawMessage `json:"stats"`
+}
+
+func (n *NameStateResult) Resource() (*dns.Resource, error) {
+	data, err := hex.DecodeString(n.Data)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	resource := new(dns.Resource)
+	if err := resource.Decode(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
+
+type NameStartInfo struct {
+	Reserved bool `json:"reserved"`
+	Week     int  `json:"week"`
+	Start    int  `json:"start"`
+}
+
+type GetNameInfoResult struct {
+	Start NameStartInfo    `json:"start"`
+	Info  *NameStateResult `json:"info"`
+}
+
+type BidResult struct {
+	Name     string      `json:"name"`
+	NameHash string      `json:"nameHash"`
+	Prevout  RPCOutpoint `json:"prevout"`
+	Value    *int64      `json:"value"`
+	Lockup   int64       `json:"lockup"`
+	Blind    string      `json:"blind"`
+	Own      bool        `json:"own"`
+}
+
+type RevealResult struct {
+	Name     string      `json:"name"`
+	NameHash string      `json:"nameHash"`
+	Prevout  RPCOutpoint `json:"prevout"`
+	Value    int64       `json:"value"`
+	Height   int         `json:"height"`
+	Own      bool        `json:"own"`
+}
+
+type GetAuctionInfoResult struct {
+	NameStateResult
+	Bids    []*BidResult    `json:"bids"`
+	Reveals []*RevealResult `json:"reveals"`
+}
+
+type GetMempoolInfoResult struct {
+	Size          int     `json:"size"`
+	Bytes         int     `json:"bytes"`
+	Usage         int     `json:"usage"`
+	MaxMempool    int     `json:"maxmempool"`
+	MempoolMinFee float64 `json:"mempoolminfee"`
+}
+
+type MempoolEntryResult struct {
+	Size             int      `json:"size"`
+	Fee              float64  `json:"fee"`
+	ModifiedFee      float64  `json:"modifiedfee"`
+	Time             int      `json:"time"`
+	Height           int      `json:"height"`
+	StartingPriority float64  `json:"startingpriority"`
+	CurrentPriority  float64  `json:"currentpriority"`
+	DescendantCount  int      `json:"descendantcount"`
+	DescendantSize   int      `json:"descendantsize"`
+	DescendantFees   float64  `json:"descendantfees"`
+	AncestorCount    int      `json:"ancestorcount"`
+	AncestorSize     int      `json:"ancestorsize"`
+	AncestorFees     float64  `json:"ancestorfees"`
+	Depends          []string `json:"depends"`
+}
+
+type CreateRawTransactionInput struct {
+	Txid     string `json:"txid"`
+	Vout     int    `json:"vout"`
+	Sequence *int   `json:"sequence,omitempty"`
+}
+
+type SignRawTransactionPrevout struct {
+	Txid    string  `json:"txid"`
+	Vout    int     `json:"vout"`
+	Address string  `json:"address,omitempty"`
+	Amount  float64 `json:"amount"`
+}
+
+type SignRawTransactionResult struct {
+	Hex      string `json:"hex"`
+	Complete bool   `json:"complete"`
+}
+
+type GetTxOutResult struct {
+	BestBlock     string     `json:"bestblock"`
+	Confirmations int        `json:"confirmations"`
+	Value         float64    `json:"value"`
+	Address       RPCAddress `json:"address"`
+	Covenant      Covenant   `json:"covenant"`
+	Coinbase      bool       `json:"coinbase"`
+}
+
+type GetTxOutSetInfoResult struct {
+	Height          int     `json:"height"`
+	BestBlock       string  `json:"bestblock"`
+	Transactions    int     `json:"transactions"`
+	TxOuts          int     `json:"txouts"`
+	BytesSerialized int     `json:"bytes_serialized"`
+	HashSerialized  int     `json:"hash_serialized"`
+	TotalAmount     float64 `json:"total_amount"`
+	TotalBurned     float64 `json:"total_burned"`
+}
+
+type PeerInfoResult struct {
+	ID             int      `json:"id"`
+	Addr           string   `json:"addr"`
+	AddrLocal      string   `json:"addrlocal"`
+	Name           string   `json:"name"`
+	Services       string   `json:"services"`
+	RelayTxes      bool     `json:"relaytxes"`
+	LastSend       int      `json:"lastsend"`
+	LastRecv       int      `json:"lastrecv"`
+	BytesSent      int64    `json:"bytessent"`
+	BytesRecv      int64    `json:"bytesrecv"`
+	ConnTime       int      `json:"conntime"`
+	TimeOffset     int      `json:"timeoffset"`
+	PingTime       float64  `json:"pingtime"`
+	MinPing        float64  `json:"minping"`
+	Version        int      `json:"version"`
+	SubVer         string   `json:"subver"`
+	Inbound        bool     `json:"inbound"`
+	StartingHeight int      `json:"startingheight"`
+	BestHash       string   `json:"besthash"`
+	BestHeight     int      `json:"bestheight"`
+	BanScore       int      `json:"banscore"`
+	Inflight       []string `json:"inflight"`
+	Whitelisted    bool     `json:"whitelisted"`
+}
+
+type GetNetTotalsResult struct {
+	TotalBytesRecv int64 `json:"totalbytesrecv"`
+	TotalBytesSent int64 `json:"totalbytessent"`
+	TimeMillis     int64 `json:"timemillis"`
+}
+
+type LocalAddressResult struct {
+	Address string `json:"address"`
+	Port    int    `json:"port"`
+	Score   int    `json:"score"`
+}
+
+type GetNetworkInfoResult struct {
+	Version         string                `json:"version"`
+	SubVersion      string                `json:"subversion"`
+	ProtocolVersion int                   `json:"protocolversion"`
+	IdentityKey     string                `json:"identitykey"`
+	LocalServices   string                `json:"localservices"`
+	LocalRelay      bool                  `json:"localrelay"`
+	TimeOffset      int                   `json:"timeoffset"`
+	NetworkActive   bool                  `json:"networkactive"`
+	Connections     int                   `json:"connections"`
+	Networks        []json.RawMessage     `json:"networks"`
+	RelayFee        float64               `json:"relayfee"`
+	IncrementalFee  float64               `json:"incrementalfee"`
+	LocalAddresses  []*LocalAddressResult `json:"localaddresses"`
+	Warnings        string                `json:"warnings"`
+}
+
+type AddedNodeAddressResult struct {
+	Address   string `json:"address"`
+	Connected string `json:"connected"`
+}
+
+type AddedNodeInfoResult struct {
+	AddedNode string                    `json:"addednode"`
+	Connected bool                      `json:"connected"`
+	Addresses []*AddedNodeAddressResult `json:"addresses"`
+}
+
+type BannedResult struct {
+	Address     string `json:"address"`
+	BannedUntil int    `json:"banned_until"`
+	BanCreated  int    `json:"ban_created"`
+	BanReason   string `json:"ban_reason"`
+}
+
+type GetMiningInfoResult struct {
+	Blocks             int     `json:"blocks"`
+	CurrentBlockSize   int     `json:"currentblocksize"`
+	CurrentBlockWeight int     `json:"currentblockweight"`
+	CurrentBlockTx     int     `json:"currentblocktx"`
+	Difficulty         float64 `json:"difficulty"`
+	Errors             string  `json:"errors"`
+	GenProcLimit       int     `json:"genproclimit"`
+	NetworkHashPS      float64 `json:"networkhashps"`
+	PooledTx           int     `json:"pooledtx"`
+	Testnet            bool    `json:"testnet"`
+	Chain              string  `json:"chain"`
+	Generate           bool    `json:"generate"`
+}
+
+type BlockTemplateRequest struct {
+	Mode         string   `json:"mode,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"`
+	Rules        []string `json:"rules,omitempty"`
+	LongPollID   string   `json:"longpollid,omitempty"`
+	Data         string   `json:"data,omitempty"`
+}
+
+type BlockTemplateTx struct {
+	Data    string `json:"data"`
+	Txid    string `json:"txid"`
+	Hash    string `json:"hash"`
+	Depends []int  `json:"depends"`
+	Fee     int64  `json:"fee"`
+	Sigops  int    `json:"sigops"`
+	Weight  int    `json:"weight"`
+}
+
+type GetBlockTemplateResult struct {
+	Capabilities      []string           `json:"capabilities"`
+	Mutable           []string           `json:"mutable"`
+	Version           int                `json:"version"`
+	Rules             []string           `json:"rules"`
+	VBAvailable       map[string]int     `json:"vbavailable"`
+	VBRequired        int                `json:"vbrequired"`
+	Height            int                `json:"height"`
+	PreviousBlockHash string             `json:"previousblockhash"`
+	TreeRoot          string             `json:"treeroot"`
+	ReservedRoot      string             `json:"reservedroot"`
+	Mask              string             `json:"mask"`
+	Target            string             `json:"target"`
+	Bits              string             `json:"bits"`
+	NonceRange        string             `json:"noncerange"`
+	CurTime           int                `json:"curtime"`
+	MinTime           int                `json:"mintime"`
+	MaxTime           int                `json:"maxtime"`
+	Expires           int                `json:"expires"`
+	SigOpLimit        int                `json:"sigoplimit"`
+	SizeLimit         int                `json:"sizelimit"`
+	WeightLimit       int                `json:"weightlimit"`
+	LongPollID        string             `json:"longpollid"`
+	SubmitOld         bool               `json:"submitold"`
+	CoinbaseAux       map[string]string  `json:"coinbaseaux"`
+	CoinbaseValue     int64              `json:"coinbasevalue"`
+	Claims            []json.RawMessage  `json:"claims"`
+	Airdrops          []json.RawMessage  `json:"airdrops"`
+	Transactions      []*BlockTemplateTx `json:"transactions"`
+}
+
+type GetWorkResult struct {
+	Network string `json:"network"`
+	Data    string `json:"data"`
+	Target  string `json:"target"`
+	Height  int    `json:"height"`
+	Time    int    `json:"time"`
+}
@@ -0,0 +1,429 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/net/websocket"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hsd's websocket API speaks the socket.io v2 protocol (engine.io v3) as
+// implemented by bsock. Only the websocket transport is supported.
+const (
+	eioOpen    = '0'
+	eioClose   = '1'
+	eioPing    = '2'
+	eioPong    = '3'
+	eioMessage = '4'
+	eioNoop    = '6'
+
+	// Binary frames carry the engine.io packet type as a raw byte rather
+	// than an ASCII digit.
+	eioBinaryMessage = 4
+
+	sioConnect     = '0'
+	sioDisconnect  = '1'
+	sioEvent       = '2'
+	sioAck         = '3'
+	sioError       = '4'
+	sioBinaryEvent = '5'
+	sioBinaryAck   = '6'
+)
+
+var ErrSocketClosed = errors.New("socket closed")
+
+type socketAck struct {
+	result json.RawMessage
+	err    error
+}
+
+type socketPacket struct {
+	typ         byte
+	id          int64
+	attachments int
+	data        string
+	binary      [][]byte
+}
+
+type socketConn struct {
+	ws        *websocket.Conn
+	writeMtx  sync.Mutex
+	mtx       sync.Mutex
+	pending   map[int64]chan *socketAck
+	ackID     int64
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+	err       error
+	onEvent   func(name string, args []interface{})
+}
+
+var frameCodec = websocket.Codec{
+	Marshal: func(v interface{}) ([]byte, byte, error) {
+		return []byte(v.(string)), websocket.TextFrame, nil
+	},
+	Unmarshal: func(data []byte, payloadType byte, v interface{}) error {
+		f := v.(*wsFrame)
+		f.binary = payloadType == websocket.BinaryFrame
+		f.data = data
+		return nil
+	},
+}
+
+type wsFrame struct {
+	binary bool
+	data   []byte
+}
+
+func (c *Client) dialSocket(ctx context.Context, ep *endpoint, onEvent func(string, []interface{})) (*socketConn, error) {
+	rawURL := c.makeURL(ep.host, "socket.io/?EIO=3&transport=websocket")
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	origin := *u
+	var useTLS bool
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+		useTLS = true
+	default:
+		return nil, fmt.Errorf("unsupported scheme %s", u.Scheme)
+	}
+	config, err := websocket.NewConfig(u.String(), origin.String())
+	if err != nil {
+		return nil, err
+	}
+	if c.apiKey != "" {
+		config.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte("x:"+c.apiKey)))
+	}
+
+	dialer := new(net.Dialer)
+	rwc, err := dialer.DialContext(ctx, "tcp", u.Host)
+	if err != nil {
+		return nil, err
+	}
+	if useTLS {
+		tlsConn := tls.Client(rwc, &tls.Config{
+			ServerName: u.Hostname(),
+		})
+		if err := tlsConn.Handshake(); err != nil {
+			rwc.Close()
+			return nil, err
+		}
+		rwc = tlsConn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		rwc.SetDeadline(deadline)
+	}
+	ws, err := websocket.NewClient(config, rwc)
+	if err != nil {
+		rwc.Close()
+		return nil, err
+	}
+	rwc.SetDeadline(time.Time{})
+
+	conn := &socketConn{
+		ws:      ws,
+		pending: make(map[int64]chan *socketAck),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		onEvent: onEvent,
+	}
+	go conn.readLoop()
+	return conn, nil
+}
+
+// call emits an event with an ack ID and waits for the server's response.
+func (s *socketConn) call(ctx context.Context, event string, result interface{}, args ...interface{}) error {
+	s.mtx.Lock()
+	s.ackID++
+	id := s.ackID
+	ch := make(chan *socketAck, 1)
+	s.pending[id] = ch
+	s.mtx.Unlock()
+	defer func() {
+		s.mtx.Lock()
+		delete(s.pending, id)
+		s.mtx.Unlock()
+	}()
+
+	data, err := json.Marshal(append([]interface{}{event}, args...))
+	if err != nil {
+		return err
+	}
+	if err := s.write(fmt.Sprintf("%c%c%d%s", eioMessage, sioEvent, id, data)); err != nil {
+		select {
+		case <-s.done:
+			return s.err
+		default:
+			return err
+		}
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.done:
+		return s.err
+	case ack := <-ch:
+		if ack.err != nil {
+			return ack.err
+		}
+		if result == nil || ack.result == nil {
+			return nil
+		}
+		if err := json.Unmarshal(ack.result, result); err != nil {
+			return &DecodeError{
+				Body: ack.result,
+				Err:  err,
+			}
+		}
+		return nil
+	}
+}
+
+func (s *socketConn) write(msg string) error {
+	s.writeMtx.Lock()
+	defer s.writeMtx.Unlock()
+	return frameCodec.Send(s.ws, msg)
+}
+
+func (s *socketConn) close(err error) {
+	s.closeOnce.Do(func() {
+		if err == nil {
+			err = ErrSocketClosed
+		}
+		s.err = err
+		s.ws.Close()
+		close(s.done)
+	})
+}
+
+// readLoop handles incoming packets until the connection closes. Event and
+// ack handlers run on it and must not block.
+func (s *socketConn) readLoop() {
+	defer close(s.stopped)
+	var partial *socketPacket
+	var timeout time.Duration
+	for {
+		if timeout > 0 {
+			s.ws.SetReadDeadline(time.Now().Add(timeout))
+		}
+		frame := new(wsFrame)
+		if err := frameCodec.Receive(s.ws, frame); err != nil {
+			s.close(err)
+			return
+		}
+		if len(frame.data) == 0 {
+			continue
+		}
+
+		if frame.binary {
+			if partial == nil || frame.data[0] != eioBinaryMessage {
+				continue
+			}
+			partial.binary = append(partial.binary, frame.data[1:])
+			if len(partial.binary) == partial.attachments {
+				s.handlePacket(partial)
+				partial = nil
+			}
+			continue
+		}
+
+		msg := string(frame.data)
+		switch msg[0] {
+		case eioOpen:
+			var open struct {
+				PingInterval int `json:"pingInterval"`
+				PingTimeout  int `json:"pingTimeout"`
+			}
+			if err := json.Unmarshal([]byte(msg[1:]), &open); err == nil && open.PingInterval > 0 {
+				interval := time.Duration(open.PingInterval) * time.Millisecond
+				timeout = interval + time.Duration(open.PingTimeout)*time.Millisecond
+				go s.pingLoop(interval)
+			}
+		case eioClose:
+			s.close(ErrSocketClosed)
+			return
+		case eioPing:
+			if err := s.write(string(eioPong) + msg[1:]); err != nil {
+				s.close(err)
+				return
+			}
+		case eioPong, eioNoop:
+		case eioMessage:
+			packet, err := parseSocketPacket(msg[1:])
+			if err != nil {
+				s.close(err)
+				return
+			}
+			if packet.typ == sioDisconnect {
+				s.close(ErrSocketClosed)
+				return
+			}
+			if packet.attachments > 0 {
+				partial = packet
+				continue
+			}
+			s.handlePacket(packet)
+		}
+	}
+}
+
+func (s *socketConn) pingLoop(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-t.C:
+			if err := s.write(string(eioPing)); err != nil {
+				s.close(err)
+				return
+			}
+		}
+	}
+}
+
+func (s *socketConn) handlePacket(packet *socketPacket) {
+	var data interface{}
+	if packet.data != "" {
+		if err := json.Unmarshal([]byte(packet.data), &data); err != nil {
+			return
+		}
+	}
+	data = fillPlaceholders(data, packet.binary)
+
+	switch packet.typ {
+	case sioEvent, sioBinaryEvent:
+		args, ok := data.([]interface{})
+		if !ok || len(args) == 0 {
+			return
+		}
+		name, ok := args[0].(string)
+		if !ok {
+			return
+		}
+		s.onEvent(name, args[1:])
+	case sioAck, sioBinaryAck:
+		// Only the first ack for an ID is delivered; duplicates find no
+		// pending call.
+		s.mtx.Lock()
+		ch := s.pending[packet.id]
+		delete(s.pending, packet.id)
+		s.mtx.Unlock()
+		if ch == nil {
+			return
+		}
+		select {
+		case ch <- parseAck(data):
+		default:
+		}
+	case sioError:
+		s.close(fmt.Errorf("socket error: %v", data))
+	}
+}
+
+// parseAck reads bsock's [err, result] ack payload.
+func parseAck(data interface{}) *socketAck {
+	args, _ := data.([]interface{})
+	if len(args) > 0 && args[0] != nil {
+		rpcErr := new(RPCError)
+		if m, ok := args[0].(map[string]interface{}); ok {
+			rpcErr.Message, _ = m["message"].(string)
+			if code, ok := m["code"].(float64); ok {
+				rpcErr.Code = int(code)
+			}
+		} else {
+			rpcErr.Message = fmt.Sprint(args[0])
+		}
+		return &socketAck{
+			err: rpcErr,
+		}
+	}
+	if len(args) < 2 {
+		return new(socketAck)
+	}
+	result, err := json.Marshal(args[1])
+	return &socketAck{
+		result: result,
+		err:    err,
+	}
+}
+
+func parseSocketPacket(msg string) (*socketPacket, error) {
+	if len(msg) == 0 {
+		return nil, errors.New("empty socket packet")
+	}
+	packet := &socketPacket{
+		typ: msg[0],
+		id:  -1,
+	}
+	msg = msg[1:]
+	if packet.typ == sioBinaryEvent || packet.typ == sioBinaryAck {
+		i := strings.IndexByte(msg, '-')
+		if i == -1 {
+			return nil, errors.New("malformed binary packet")
+		}
+		n, err := strconv.Atoi(msg[:i])
+		if err != nil {
+			return nil, err
+		}
+		packet.attachments = n
+		msg = msg[i+1:]
+	}
+	if strings.HasPrefix(msg, "/") {
+		i := strings.IndexByte(msg, ',')
+		if i == -1 {
+			msg = ""
+		} else {
+			msg = msg[i+1:]
+		}
+	}
+	i := 0
+	for i < len(msg) && msg[i] >= '0' && msg[i] <= '9' {
+		i++
+	}
+	if i > 0 {
+		id, err := strconv.ParseInt(msg[:i], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		packet.id = id
+	}
+	packet.data = msg[i:]
+	return packet, nil
+}
+
+func fillPlaceholders(v interface{}, attachments [][]byte) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if ph, ok := t["_placeholder"].(bool); ok && ph {
+			num, _ := t["num"].(float64)
+			if int(num) < len(attachments) {
+				return attachments[int(num)]
+			}
+			return nil
+		}
+		for k, e := range t {
+			t[k] = fillPlaceholders(e, attachments)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = fillPlaceholders(e, attachments)
+		}
+	}
+	return v
+}
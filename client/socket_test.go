@@ -0,0 +1,259 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// socketHandler answers a call from the client. It may ack with
+// fakeSocket.ack or not at all.
+type socketHandler func(s *fakeSocket, id int64, event string, args []json.RawMessage)
+
+// fakeSocketServer speaks enough of bsock's engine.io v3 and socket.io v2
+// framing to stand in for hsd's websocket API.
+type fakeSocketServer struct {
+	*httptest.Server
+	pingInterval int
+	handler      socketHandler
+	conns        chan *fakeSocket
+	auth         chan string
+}
+
+type fakeSocket struct {
+	ws       *websocket.Conn
+	writeMtx sync.Mutex
+	calls    chan string
+	pings    chan string
+	pongs    chan string
+}
+
+func newFakeSocketServer(pingInterval int, handler socketHandler) *fakeSocketServer {
+	if handler == nil {
+		handler = func(s *fakeSocket, id int64, event string, args []json.RawMessage) {
+			s.ack(id, nil, nil)
+		}
+	}
+	srv := &fakeSocketServer{
+		pingInterval: pingInterval,
+		handler:      handler,
+		conns:        make(chan *fakeSocket, 8),
+		auth:         make(chan string, 8),
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/socket.io/", websocket.Handler(srv.serve))
+	srv.Server = httptest.NewServer(mux)
+	return srv
+}
+
+func (srv *fakeSocketServer) serve(ws *websocket.Conn) {
+	select {
+	case srv.auth <- ws.Request().Header.Get("Authorization"):
+	default:
+	}
+	s := &fakeSocket{
+		ws:    ws,
+		calls: make(chan string, 64),
+		pings: make(chan string, 64),
+		pongs: make(chan string, 64),
+	}
+	s.send(fmt.Sprintf(`0{"sid":"abc","upgrades":[],"pingInterval":%d,"pingTimeout":5000}`, srv.pingInterval))
+	s.send("40")
+	srv.conns <- s
+	for {
+		var msg string
+		if err := websocket.Message.Receive(ws, &msg); err != nil {
+			return
+		}
+		switch {
+		case strings.HasPrefix(msg, "2"):
+			s.pings <- msg[1:]
+			s.send("3" + msg[1:])
+		case strings.HasPrefix(msg, "3"):
+			s.pongs <- msg[1:]
+		case strings.HasPrefix(msg, "42"):
+			msg = msg[2:]
+			i := 0
+			for i < len(msg) && msg[i] >= '0' && msg[i] <= '9' {
+				i++
+			}
+			id, _ := strconv.ParseInt(msg[:i], 10, 64)
+			var args []json.RawMessage
+			if err := json.Unmarshal([]byte(msg[i:]), &args); err != nil || len(args) == 0 {
+				return
+			}
+			var event string
+			if err := json.Unmarshal(args[0], &event); err != nil {
+				return
+			}
+			s.calls <- event
+			srv.handler(s, id, event, args[1:])
+		}
+	}
+}
+
+func (s *fakeSocket) send(msg string) {
+	s.writeMtx.Lock()
+	defer s.writeMtx.Unlock()
+	_ = websocket.Message.Send(s.ws, msg)
+}
+
+func (s *fakeSocket) sendBinary(data []byte) {
+	s.writeMtx.Lock()
+	defer s.writeMtx.Unlock()
+	_ = websocket.Message.Send(s.ws, append([]byte{eioBinaryMessage}, data...))
+}
+
+func (s *fakeSocket) ack(id int64, err interface{}, result interface{}) {
+	data, _ := json.Marshal([]interface{}{err, result})
+	s.send(fmt.Sprintf("43%d%s", id, data))
+}
+
+func (s *fakeSocket) emit(event string, args ...interface{}) {
+	data, _ := json.Marshal(append([]interface{}{event}, args...))
+	s.send(fmt.Sprintf("42%s", data))
+}
+
+// emitBinary emits an event whose arguments are binary attachments.
+func (s *fakeSocket) emitBinary(event string, attachments ...[]byte) {
+	args := []interface{}{event}
+	for i := range attachments {
+		args = append(args, map[string]interface{}{"_placeholder": true, "num": i})
+	}
+	data, _ := json.Marshal(args)
+	s.send(fmt.Sprintf("45%d-%s", len(attachments), data))
+	for _, attachment := range attachments {
+		s.sendBinary(attachment)
+	}
+}
+
+func receive(t *testing.T, ch chan string) string {
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+		return ""
+	}
+}
+
+type eventRecorder struct {
+	events chan []interface{}
+}
+
+func (r *eventRecorder) onEvent(name string, args []interface{}) {
+	r.events <- append([]interface{}{name}, args...)
+}
+
+func dialTestSocket(t *testing.T, srv *fakeSocketServer, opts ...Opt) (*socketConn, *fakeSocket, *eventRecorder) {
+	c := NewClient(srv.URL, opts...)
+	rec := &eventRecorder{events: make(chan []interface{}, 16)}
+	conn, err := c.dialSocket(context.Background(), c.pickEndpoint(), rec.onEvent)
+	require.NoError(t, err)
+	select {
+	case s := <-srv.conns:
+		return conn, s, rec
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+		return nil, nil, nil
+	}
+}
+
+func TestSocket_Handshake(t *testing.T) {
+	srv := newFakeSocketServer(0, nil)
+	defer srv.Close()
+	conn, _, _ := dialTestSocket(t, srv, WithAPIKey("apikey"))
+	defer conn.close(nil)
+	require.Equal(t, "Basic eDphcGlrZXk=", receive(t, srv.auth))
+
+	conn.close(nil)
+	<-conn.stopped
+	require.Equal(t, ErrSocketClosed, conn.call(context.Background(), "watch chain", nil))
+}
+
+func TestSocket_PingPong(t *testing.T) {
+	srv := newFakeSocketServer(10, nil)
+	defer srv.Close()
+	conn, s, _ := dialTestSocket(t, srv)
+	defer conn.close(nil)
+
+	// The client pings at the interval announced in the open packet and
+	// answers the server's pings.
+	require.Equal(t, "", receive(t, s.pings))
+	require.Equal(t, "", receive(t, s.pings))
+	s.send("2probe")
+	require.Equal(t, "probe", receive(t, s.pongs))
+}
+
+func TestSocket_Ack(t *testing.T) {
+	srv := newFakeSocketServer(0, func(s *fakeSocket, id int64, event string, args []json.RawMessage) {
+		switch event {
+		case "echo":
+			var arg string
+			_ = json.Unmarshal(args[0], &arg)
+			// Duplicate acks and acks for unknown calls are ignored.
+			s.ack(id, nil, arg)
+			s.ack(id, nil, "duplicate")
+			s.ack(id+1000, nil, "unknown")
+		case "fail":
+			s.ack(id, map[string]interface{}{"message": "Invalid filter.", "code": -1}, nil)
+		case "silent":
+		}
+	})
+	defer srv.Close()
+	conn, _, _ := dialTestSocket(t, srv)
+	defer conn.close(nil)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		var res string
+		require.NoError(t, conn.call(ctx, "echo", &res, fmt.Sprintf("hello %d", i)))
+		require.Equal(t, fmt.Sprintf("hello %d", i), res)
+	}
+
+	err := conn.call(ctx, "fail", nil)
+	var rpcErr *RPCError
+	require.True(t, errors.As(err, &rpcErr))
+	require.Equal(t, "Invalid filter.", rpcErr.Message)
+	require.Equal(t, RPCMiscError, rpcErr.Code)
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, conn.call(timeoutCtx, "silent", nil))
+
+	conn.mtx.Lock()
+	require.Empty(t, conn.pending)
+	conn.mtx.Unlock()
+}
+
+func TestSocket_BinaryPlaceholders(t *testing.T) {
+	srv := newFakeSocketServer(0, nil)
+	defer srv.Close()
+	conn, s, rec := dialTestSocket(t, srv)
+	defer conn.close(nil)
+
+	s.emitBinary("block connect", []byte{0x01, 0x02}, []byte{0x03})
+	s.emit("chain reset", "0a0b")
+	select {
+	case ev := <-rec.events:
+		require.Equal(t, []interface{}{"block connect", []byte{0x01, 0x02}, []byte{0x03}}, ev)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+	}
+	select {
+	case ev := <-rec.events:
+		require.Equal(t, []interface{}{"chain reset", "0a0b"}, ev)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+	}
+}
@@ -0,0 +1,390 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/mslipper/handshake/primitives"
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventChainConnect    EventType = "chain connect"
+	EventChainDisconnect EventType = "chain disconnect"
+	EventBlockConnect    EventType = "block connect"
+	EventBlockDisconnect EventType = "block disconnect"
+	EventChainReset      EventType = "chain reset"
+	EventTx              EventType = "tx"
+
+	// EventReconnect is delivered after the subscription reconnects. Events
+	// sent by the node while disconnected are lost, so consumers should
+	// resync from their last known block.
+	EventReconnect EventType = "reconnect"
+)
+
+// ErrEventsDropped is reported on Subscription.Errors when events arrive
+// while the event buffer is full. The events are lost, so consumers should
+// resync as they would after EventReconnect.
+var ErrEventsDropped = errors.New("event buffer full, events dropped")
+
+const (
+	maxReconnectBackoff = 30 * time.Second
+	defaultEventBuffer  = 64
+)
+
+// Event is a decoded websocket event. Entry is set for chain and block
+// events. For block connect events Block holds the header along with the
+// transactions that matched the subscription's filter, which is none if no
+// filter was set. Tx is set for tx events.
+type Event struct {
+	Type  EventType
+	Entry *primitives.ChainEntry
+	Block *primitives.Block
+	Tx    *primitives.Transaction
+}
+
+type SubscribeOptions struct {
+	Chain   bool
+	Mempool bool
+	// Filter is a serialized bloom filter used by the node to select which
+	// transactions are sent.
+	Filter []byte
+	// Buffer is the size of the event buffer. Events that arrive while it
+	// is full are dropped and reported as ErrEventsDropped.
+	Buffer int
+}
+
+// Subscription delivers events from a node's websocket API. It reconnects
+// automatically until Close is called or the context passed to Subscribe is
+// canceled, at which point Events is closed.
+type Subscription struct {
+	c      *Client
+	opts   SubscribeOptions
+	ctx    context.Context
+	cancel context.CancelFunc
+	events chan *Event
+	errs   chan error
+
+	mtx     sync.Mutex
+	conn    *socketConn
+	filter  []byte
+	added   [][]byte
+	stopped chan struct{}
+}
+
+// Subscribe connects to the node's websocket API, authenticates with the
+// client's API key and watches the channels selected in opts. Only the
+// initial connection is synchronous; later failures are reported on Errors.
+func (c *Client) Subscribe(ctx context.Context, opts *SubscribeOptions) (*Subscription, error) {
+	if opts == nil {
+		opts = new(SubscribeOptions)
+	}
+	buffer := opts.Buffer
+	if buffer <= 0 {
+		buffer = defaultEventBuffer
+	}
+	subCtx, cancel := context.WithCancel(ctx)
+	s := &Subscription{
+		c:       c,
+		opts:    *opts,
+		ctx:     subCtx,
+		cancel:  cancel,
+		events:  make(chan *Event, buffer),
+		errs:    make(chan error, 1),
+		filter:  opts.Filter,
+		stopped: make(chan struct{}),
+	}
+	conn, err := s.connect(ctx, false)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	go s.run(conn)
+	return s, nil
+}
+
+func (s *Subscription) Events() <-chan *Event {
+	return s.events
+}
+
+// Errors reports connection failures. Errors are dropped if they are not
+// read.
+func (s *Subscription) Errors() <-chan error {
+	return s.errs
+}
+
+// SetFilter replaces the bloom filter used to select transactions. It is
+// restored automatically after reconnecting.
+func (s *Subscription) SetFilter(ctx context.Context, filter []byte) error {
+	s.mtx.Lock()
+	s.filter = filter
+	s.added = nil
+	conn := s.conn
+	s.mtx.Unlock()
+	if conn == nil {
+		return ErrSocketClosed
+	}
+	return conn.call(ctx, "set filter", nil, hex.EncodeToString(filter))
+}
+
+// AddFilter adds data elements to the node's copy of the filter.
+func (s *Subscription) AddFilter(ctx context.Context, chunks [][]byte) error {
+	s.mtx.Lock()
+	s.added = append(s.added, chunks...)
+	conn := s.conn
+	s.mtx.Unlock()
+	if conn == nil {
+		return ErrSocketClosed
+	}
+	return conn.call(ctx, "add filter", nil, hexChunks(chunks))
+}
+
+func (s *Subscription) ResetFilter(ctx context.Context) error {
+	s.mtx.Lock()
+	s.filter = nil
+	s.added = nil
+	conn := s.conn
+	s.mtx.Unlock()
+	if conn == nil {
+		return ErrSocketClosed
+	}
+	return conn.call(ctx, "reset filter", nil)
+}
+
+func (s *Subscription) Close() error {
+	s.cancel()
+	<-s.stopped
+	return nil
+}
+
+func (s *Subscription) run(conn *socketConn) {
+	defer close(s.stopped)
+	defer close(s.events)
+	attempt := 0
+	for {
+		if conn != nil {
+			attempt = 0
+			select {
+			case <-s.ctx.Done():
+				conn.close(ErrSocketClosed)
+				<-conn.stopped
+				return
+			case <-conn.done:
+				<-conn.stopped
+				s.reportErr(conn.err)
+			}
+		}
+		s.mtx.Lock()
+		s.conn = nil
+		s.mtx.Unlock()
+
+		attempt++
+		if err := sleepCtx(s.ctx, reconnectBackoff(attempt)); err != nil {
+			return
+		}
+		var err error
+		conn, err = s.connect(s.ctx, true)
+		if err != nil {
+			s.reportErr(err)
+		}
+	}
+}
+
+func (s *Subscription) connect(ctx context.Context, reconnect bool) (*socketConn, error) {
+	ep := s.c.pickEndpoint()
+	conn, err := s.c.dialSocket(ctx, ep, s.handleEvent)
+	if err != nil {
+		ep.markUnhealthy(s.c.cooldown)
+		return nil, err
+	}
+	// The reconnect event must precede any event sent on the new
+	// connection, which may start arriving as soon as we watch a channel.
+	if reconnect {
+		s.deliver(&Event{
+			Type: EventReconnect,
+		})
+	}
+	if err := s.setup(ctx, conn); err != nil {
+		conn.close(err)
+		<-conn.stopped
+		return nil, err
+	}
+	s.mtx.Lock()
+	s.conn = conn
+	s.mtx.Unlock()
+	return conn, nil
+}
+
+func (s *Subscription) setup(ctx context.Context, conn *socketConn) error {
+	if s.c.apiKey != "" {
+		if err := conn.call(ctx, "auth", nil, s.c.apiKey); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+	if s.opts.Chain {
+		if err := conn.call(ctx, "watch chain", nil); err != nil {
+			return fmt.Errorf("watch chain: %w", err)
+		}
+	}
+	if s.opts.Mempool {
+		if err := conn.call(ctx, "watch mempool", nil); err != nil {
+			return fmt.Errorf("watch mempool: %w", err)
+		}
+	}
+	s.mtx.Lock()
+	filter := s.filter
+	added := s.added
+	s.mtx.Unlock()
+	if filter != nil {
+		if err := conn.call(ctx, "set filter", nil, hex.EncodeToString(filter)); err != nil {
+			return fmt.Errorf("set filter: %w", err)
+		}
+	}
+	if len(added) > 0 {
+		if err := conn.call(ctx, "add filter", nil, hexChunks(added)); err != nil {
+			return fmt.Errorf("add filter: %w", err)
+		}
+	}
+	return nil
+}
+
+// handleEvent runs on the connection's read loop. It never blocks, so that
+// acks keep being processed while the consumer is behind.
+func (s *Subscription) handleEvent(name string, args []interface{}) {
+	ev, err := decodeEvent(EventType(name), args)
+	if err != nil {
+		s.reportErr(err)
+		return
+	}
+	if ev == nil {
+		return
+	}
+	select {
+	case s.events <- ev:
+	default:
+		s.reportErr(ErrEventsDropped)
+	}
+}
+
+func (s *Subscription) deliver(ev *Event) {
+	select {
+	case s.events <- ev:
+	case <-s.ctx.Done():
+	}
+}
+
+func (s *Subscription) reportErr(err error) {
+	select {
+	case s.errs <- err:
+	default:
+	}
+}
+
+func decodeEvent(typ EventType, args []interface{}) (*Event, error) {
+	ev := &Event{
+		Type: typ,
+	}
+	switch typ {
+	case EventChainConnect, EventChainDisconnect, EventBlockDisconnect, EventChainReset:
+		entry, err := decodeEntryArg(args, 0)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", typ, err)
+		}
+		ev.Entry = entry
+		ev.Block = entry.Header
+	case EventBlockConnect:
+		entry, err := decodeEntryArg(args, 0)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", typ, err)
+		}
+		block := *entry.Header
+		if len(args) > 1 {
+			rawTxs, _ := args[1].([]interface{})
+			for _, rawTx := range rawTxs {
+				tx, err := decodeTxArg(rawTx)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", typ, err)
+				}
+				block.Transactions = append(block.Transactions, tx)
+			}
+		}
+		ev.Entry = entry
+		ev.Block = &block
+	case EventTx:
+		if len(args) == 0 {
+			return nil, fmt.Errorf("%s: missing argument", typ)
+		}
+		tx, err := decodeTxArg(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", typ, err)
+		}
+		ev.Tx = tx
+	default:
+		return nil, nil
+	}
+	return ev, nil
+}
+
+func decodeEntryArg(args []interface{}, i int) (*primitives.ChainEntry, error) {
+	if len(args) <= i {
+		return nil, errors.New("missing argument")
+	}
+	raw, err := argBytes(args[i])
+	if err != nil {
+		return nil, err
+	}
+	entry := new(primitives.ChainEntry)
+	if err := entry.Decode(bytes.NewReader(raw)); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func decodeTxArg(arg interface{}) (*primitives.Transaction, error) {
+	raw, err := argBytes(arg)
+	if err != nil {
+		return nil, err
+	}
+	tx := new(primitives.Transaction)
+	if err := tx.Decode(bytes.NewReader(raw)); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// argBytes accepts binary attachments as well as hex strings, which older
+// nodes send in place of buffers.
+func argBytes(arg interface{}) ([]byte, error) {
+	switch t := arg.(type) {
+	case []byte:
+		return t, nil
+	case string:
+		return hex.DecodeString(t)
+	default:
+		return nil, errors.New("unexpected argument type")
+	}
+}
+
+func hexChunks(chunks [][]byte) []string {
+	out := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		out[i] = hex.EncodeToString(chunk)
+	}
+	return out
+}
+
+func reconnectBackoff(attempt int) time.Duration {
+	d := 500 * time.Millisecond
+	for i := 1; i < attempt && d < maxReconnectBackoff; i++ {
+		d *= 2
+	}
+	if d > maxReconnectBackoff {
+		d = maxReconnectBackoff
+	}
+	return d
+}
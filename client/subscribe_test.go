@@ -0,0 +1,134 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func encodedEntry(t *testing.T, height uint32) []byte {
+	data, err := ioutil.ReadFile("../primitives/testdata/block_000000000000003d56d278ef00c657de6b1fcb549f9e04e299f6a918c2573b94.bin")
+	require.NoError(t, err)
+	header := new(primitives.Block)
+	require.NoError(t, header.DecodeHeader(bytes.NewReader(data)))
+	entry := &primitives.ChainEntry{
+		Height:    height,
+		Header:    header,
+		Chainwork: big.NewInt(1),
+	}
+	copy(entry.Hash[:], header.Hash())
+	buf := new(bytes.Buffer)
+	require.NoError(t, entry.Encode(buf))
+	return buf.Bytes()
+}
+
+func nextEvent(t *testing.T, sub *Subscription) *Event {
+	select {
+	case ev := <-sub.Events():
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+		return nil
+	}
+}
+
+func TestSubscribe_BlockConnect(t *testing.T) {
+	srv := newFakeSocketServer(0, nil)
+	defer srv.Close()
+	c := NewClient(srv.URL, WithAPIKey("apikey"))
+	filter := []byte{0x01, 0x02}
+	sub, err := c.Subscribe(context.Background(), &SubscribeOptions{
+		Chain:  true,
+		Filter: filter,
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+	s := <-srv.conns
+	require.Equal(t, "auth", receive(t, s.calls))
+	require.Equal(t, "watch chain", receive(t, s.calls))
+	require.Equal(t, "set filter", receive(t, s.calls))
+
+	entry := encodedEntry(t, 100)
+	s.emitBinary("block connect", entry)
+	s.emit("chain connect", hex.EncodeToString(entry))
+	ev := nextEvent(t, sub)
+	require.Equal(t, EventBlockConnect, ev.Type)
+	require.EqualValues(t, 100, ev.Entry.Height)
+	require.Empty(t, ev.Block.Transactions)
+	ev = nextEvent(t, sub)
+	require.Equal(t, EventChainConnect, ev.Type)
+	require.EqualValues(t, 100, ev.Entry.Height)
+}
+
+func TestSubscribe_SlowConsumer(t *testing.T) {
+	srv := newFakeSocketServer(0, nil)
+	defer srv.Close()
+	c := NewClient(srv.URL)
+	sub, err := c.Subscribe(context.Background(), &SubscribeOptions{
+		Chain:  true,
+		Buffer: 1,
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+	s := <-srv.conns
+
+	entry := encodedEntry(t, 1)
+	for i := 0; i < 3; i++ {
+		s.emitBinary("chain connect", entry)
+	}
+	select {
+	case err := <-sub.Errors():
+		require.Equal(t, ErrEventsDropped, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+	}
+
+	// Acks are still processed while nobody reads events.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, sub.AddFilter(ctx, [][]byte{{0x03}}))
+	require.Equal(t, EventChainConnect, nextEvent(t, sub).Type)
+}
+
+func TestSubscribe_Reconnect(t *testing.T) {
+	srv := newFakeSocketServer(0, nil)
+	defer srv.Close()
+	c := NewClient(srv.URL, WithAPIKey("apikey"))
+	sub, err := c.Subscribe(context.Background(), &SubscribeOptions{
+		Chain:   true,
+		Mempool: true,
+	})
+	require.NoError(t, err)
+	s := <-srv.conns
+	for _, call := range []string{"auth", "watch chain", "watch mempool"} {
+		require.Equal(t, call, receive(t, s.calls))
+	}
+	require.NoError(t, sub.AddFilter(context.Background(), [][]byte{{0x03}}))
+	require.Equal(t, "add filter", receive(t, s.calls))
+
+	s.ws.Close()
+	require.Equal(t, EventReconnect, nextEvent(t, sub).Type)
+	select {
+	case s = <-srv.conns:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+	}
+	for _, call := range []string{"auth", "watch chain", "watch mempool", "add filter"} {
+		require.Equal(t, call, receive(t, s.calls))
+	}
+
+	s.emit("chain reset", hex.EncodeToString(encodedEntry(t, 7)))
+	ev := nextEvent(t, sub)
+	require.Equal(t, EventChainReset, ev.Type)
+	require.EqualValues(t, 7, ev.Entry.Height)
+
+	require.NoError(t, sub.Close())
+	_, ok := <-sub.Events()
+	require.False(t, ok)
+}
@@ -0,0 +1,290 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/mslipper/handshake/dns"
+	"net/url"
+)
+
+// WalletClient talks to hsd's wallet server, which listens on a separate
+// port from the node and authenticates individual wallets with tokens.
+type WalletClient struct {
+	c *Client
+}
+
+func NewWalletClient(host string, opts ...Opt) *WalletClient {
+	c := newClient(host, opts...)
+	if c.port == 0 {
+		c.port = c.network.WalletPort()
+	}
+	return &WalletClient{
+		c: c,
+	}
+}
+
+func (wc *WalletClient) ListWallets(ctx context.Context) ([]string, error) {
+	var res []string
+	if err := wc.c.getJSON(ctx, "wallet", &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (wc *WalletClient) CreateWallet(ctx context.Context, id string, opts *CreateWalletOptions) (*WalletInfo, error) {
+	if opts == nil {
+		opts = new(CreateWalletOptions)
+	}
+	res := new(WalletInfo)
+	if err := wc.c.putJSON(ctx, fmt.Sprintf("wallet/%s", url.PathEscape(id)), opts, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Wallet returns a handle scoped to a single wallet. The token may be empty
+// if the wallet server does not have wallet auth enabled.
+func (wc *WalletClient) Wallet(id string, token string) *Wallet {
+	return &Wallet{
+		c:     wc.c,
+		id:    id,
+		token: token,
+	}
+}
+
+type Wallet struct {
+	c     *Client
+	id    string
+	token string
+}
+
+func (w *Wallet) ID() string {
+	return w.id
+}
+
+func (w *Wallet) path(suffix string, query url.Values) string {
+	path := fmt.Sprintf("wallet/%s", url.PathEscape(w.id))
+	if suffix != "" {
+		path = fmt.Sprintf("%s/%s", path, suffix)
+	}
+	if query == nil {
+		query = make(url.Values)
+	}
+	if w.token != "" {
+		query.Set("token", w.token)
+	}
+	if len(query) > 0 {
+		path = fmt.Sprintf("%s?%s", path, query.Encode())
+	}
+	return path
+}
+
+func accountQuery(account string) url.Values {
+	query := make(url.Values)
+	if account != "" {
+		query.Set("account", account)
+	}
+	return query
+}
+
+func (w *Wallet) GetInfo(ctx context.Context) (*WalletInfo, error) {
+	res := new(WalletInfo)
+	if err := w.c.getJSON(ctx, w.path("", nil), res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (w *Wallet) GetAccounts(ctx context.Context) ([]string, error) {
+	var res []string
+	if err := w.c.getJSON(ctx, w.path("account", nil), &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (w *Wallet) GetAccount(ctx context.Context, account string) (*WalletAccount, error) {
+	res := new(WalletAccount)
+	if err := w.c.getJSON(ctx, w.path(fmt.Sprintf("account/%s", url.PathEscape(account)), nil), res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (w *Wallet) CreateAccount(ctx context.Context, account string, opts *CreateAccountOptions) (*WalletAccount, error) {
+	if opts == nil {
+		opts = new(CreateAccountOptions)
+	}
+	res := new(WalletAccount)
+	if err := w.c.putJSON(ctx, w.path(fmt.Sprintf("account/%s", url.PathEscape(account)), nil), opts, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (w *Wallet) CreateAddress(ctx context.Context, account string) (*WalletAddress, error) {
+	body := struct {
+		Account string `json:"account,omitempty"`
+	}{
+		account,
+	}
+	res := new(WalletAddress)
+	if err := w.c.postJSON(ctx, w.path("address", nil), body, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (w *Wallet) CreateChange(ctx context.Context, account string) (*WalletAddress, error) {
+	body := struct {
+		Account string `json:"account,omitempty"`
+	}{
+		account,
+	}
+	res := new(WalletAddress)
+	if err := w.c.postJSON(ctx, w.path("change", nil), body, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (w *Wallet) GetBalance(ctx context.Context, account string) (*WalletBalance, error) {
+	res := new(WalletBalance)
+	if err := w.c.getJSON(ctx, w.path("balance", accountQuery(account)), res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (w *Wallet) GetCoins(ctx context.Context, account string) ([]*Coin, error) {
+	var res []*Coin
+	if err := w.c.getJSON(ctx, w.path("coin", accountQuery(account)), &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (w *Wallet) GetHistory(ctx context.Context, account string) ([]*WalletTx, error) {
+	var res []*WalletTx
+	if err := w.c.getJSON(ctx, w.path("tx/history", accountQuery(account)), &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (w *Wallet) GetPending(ctx context.Context, account string) ([]*WalletTx, error) {
+	var res []*WalletTx
+	if err := w.c.getJSON(ctx, w.path("tx/unconfirmed", accountQuery(account)), &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (w *Wallet) GetTransaction(ctx context.Context, hash string) (*WalletTx, error) {
+	res := new(WalletTx)
+	if err := w.c.getJSON(ctx, w.path(fmt.Sprintf("tx/%s", hash), nil), res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (w *Wallet) Send(ctx context.Context, opts *SendOptions) (*WalletTx, error) {
+	res := new(WalletTx)
+	if err := w.c.postJSON(ctx, w.path("send", nil), opts, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (w *Wallet) Sign(ctx context.Context, txHex string, passphrase string) (*Transaction, error) {
+	body := struct {
+		Tx         string `json:"tx"`
+		Passphrase string `json:"passphrase,omitempty"`
+	}{
+		txHex,
+		passphrase,
+	}
+	res := new(Transaction)
+	if err := w.c.postJSON(ctx, w.path("sign", nil), body, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (w *Wallet) Open(ctx context.Context, name string, opts *AuctionOptions) (*Transaction, error) {
+	return w.auction(ctx, "open", &auctionRequest{
+		AuctionOptions: opts,
+		Name:           name,
+	})
+}
+
+func (w *Wallet) Bid(ctx context.Context, name string, bid int64, lockup int64, opts *AuctionOptions) (*Transaction, error) {
+	return w.auction(ctx, "bid", &auctionRequest{
+		AuctionOptions: opts,
+		Name:           name,
+		Bid:            bid,
+		Lockup:         lockup,
+	})
+}
+
+func (w *Wallet) Reveal(ctx context.Context, name string, opts *AuctionOptions) (*Transaction, error) {
+	return w.auction(ctx, "reveal", &auctionRequest{
+		AuctionOptions: opts,
+		Name:           name,
+	})
+}
+
+func (w *Wallet) Redeem(ctx context.Context, name string, opts *AuctionOptions) (*Transaction, error) {
+	return w.auction(ctx, "redeem", &auctionRequest{
+		AuctionOptions: opts,
+		Name:           name,
+	})
+}
+
+func (w *Wallet) Update(ctx context.Context, name string, resource *dns.Resource, opts *AuctionOptions) (*Transaction, error) {
+	if resource == nil {
+		resource = new(dns.Resource)
+	}
+	return w.auction(ctx, "update", &auctionRequest{
+		AuctionOptions: opts,
+		Name:           name,
+		Data:           resource,
+	})
+}
+
+func (w *Wallet) Renew(ctx context.Context, name string, opts *AuctionOptions) (*Transaction, error) {
+	return w.auction(ctx, "renewal", &auctionRequest{
+		AuctionOptions: opts,
+		Name:           name,
+	})
+}
+
+func (w *Wallet) Transfer(ctx context.Context, name string, address string, opts *AuctionOptions) (*Transaction, error) {
+	return w.auction(ctx, "transfer", &auctionRequest{
+		AuctionOptions: opts,
+		Name:           name,
+		Address:        address,
+	})
+}
+
+func (w *Wallet) Finalize(ctx context.Context, name string, opts *AuctionOptions) (*Transaction, error) {
+	return w.auction(ctx, "finalize", &auctionRequest{
+		AuctionOptions: opts,
+		Name:           name,
+	})
+}
+
+func (w *Wallet) Revoke(ctx context.Context, name string, opts *AuctionOptions) (*Transaction, error) {
+	return w.auction(ctx, "revoke", &auctionRequest{
+		AuctionOptions: opts,
+		Name:           name,
+	})
+}
+
+func (w *Wallet) auction(ctx context.Context, action string, req *auctionRequest) (*Transaction, error) {
+	res := new(Transaction)
+	if err := w.c.postJSON(ctx, w.path(action, nil), req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,316 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/mslipper/handshake/dns"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestWalletClient_Port(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []Opt
+		nodePort   int
+		walletPort int
+	}{
+		{
+			"defaults",
+			nil,
+			12037,
+			12039,
+		},
+		{
+			"network",
+			[]Opt{WithNetwork(primitives.NetworkRegtest)},
+			14037,
+			14039,
+		},
+		{
+			"port after network",
+			[]Opt{WithNetwork(primitives.NetworkRegtest), WithPort(1234)},
+			1234,
+			1234,
+		},
+		{
+			"network after port",
+			[]Opt{WithPort(1234), WithNetwork(primitives.NetworkRegtest)},
+			14037,
+			14039,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.nodePort, NewClient("http://localhost", tt.opts...).port)
+			require.Equal(t, tt.walletPort, NewWalletClient("http://localhost", tt.opts...).c.port)
+		})
+	}
+}
+
+const walletInfoJSON = `{
+	"network": "regtest",
+	"wid": 1,
+	"id": "primary",
+	"watchOnly": false,
+	"accountDepth": 1,
+	"token": "e2a0bb7c5ba1d5ad1fa7d2b0d0e3c4f0c7e6e4fd9a3c3b2c3b1f3e8c9a0d1e2f",
+	"tokenDepth": 0,
+	"master": {
+		"encrypted": false
+	},
+	"balance": {
+		"tx": 2,
+		"coin": 2,
+		"unconfirmed": 4000000000,
+		"confirmed": 4000000000,
+		"lockedUnconfirmed": 0,
+		"lockedConfirmed": 0
+	}
+}`
+
+func TestWallet_REST(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/wallet":
+			fmt.Fprint(w, `["primary", "my wallet"]`)
+		case "/wallet/primary", "/wallet/my wallet":
+			fmt.Fprint(w, walletInfoJSON)
+		case "/wallet/primary/account", "/wallet/primary/coin", "/wallet/primary/tx/history", "/wallet/primary/tx/unconfirmed":
+			fmt.Fprint(w, `[]`)
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	})
+	defer srv.Close()
+	wc := NewWalletClient(srv.URL, WithAPIKey("apikey"))
+	ctx := context.Background()
+
+	ids, err := wc.ListWallets(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"primary", "my wallet"}, ids)
+	req := srv.LastRequest()
+	require.Equal(t, "GET", req.Method)
+	require.Equal(t, "/wallet", req.Path)
+	require.Equal(t, "apikey", req.Pass)
+
+	info, err := wc.CreateWallet(ctx, "my wallet", &CreateWalletOptions{Passphrase: "secret"})
+	require.NoError(t, err)
+	require.Equal(t, "primary", info.ID)
+	require.EqualValues(t, 4000000000, info.Balance.Confirmed)
+	req = srv.LastRequest()
+	require.Equal(t, "PUT", req.Method)
+	require.Equal(t, "/wallet/my wallet", req.Path)
+	require.JSONEq(t, `{"passphrase": "secret"}`, string(req.Body))
+
+	token := "e2a0bb7c5ba1d5ad1fa7d2b0d0e3c4f0c7e6e4fd9a3c3b2c3b1f3e8c9a0d1e2f"
+	wallet := wc.Wallet("primary", token)
+	yes := true
+	resource := &dns.Resource{
+		Records: []dns.Record{&dns.TXTRecord{Entries: []string{"hi"}}},
+	}
+	tests := []struct {
+		name   string
+		call   func() error
+		method string
+		path   string
+		query  url.Values
+		body   string
+	}{
+		{
+			"info",
+			func() error {
+				_, err := wallet.GetInfo(ctx)
+				return err
+			},
+			"GET",
+			"/wallet/primary",
+			nil,
+			"",
+		},
+		{
+			"accounts",
+			func() error {
+				_, err := wallet.GetAccounts(ctx)
+				return err
+			},
+			"GET",
+			"/wallet/primary/account",
+			nil,
+			"",
+		},
+		{
+			"create account",
+			func() error {
+				_, err := wallet.CreateAccount(ctx, "savings", nil)
+				return err
+			},
+			"PUT",
+			"/wallet/primary/account/savings",
+			nil,
+			`{}`,
+		},
+		{
+			"create address",
+			func() error {
+				_, err := wallet.CreateAddress(ctx, "savings")
+				return err
+			},
+			"POST",
+			"/wallet/primary/address",
+			nil,
+			`{"account": "savings"}`,
+		},
+		{
+			"create change",
+			func() error {
+				_, err := wallet.CreateChange(ctx, "")
+				return err
+			},
+			"POST",
+			"/wallet/primary/change",
+			nil,
+			`{}`,
+		},
+		{
+			"balance",
+			func() error {
+				_, err := wallet.GetBalance(ctx, "savings")
+				return err
+			},
+			"GET",
+			"/wallet/primary/balance",
+			url.Values{"account": {"savings"}},
+			"",
+		},
+		{
+			"coins",
+			func() error {
+				_, err := wallet.GetCoins(ctx, "")
+				return err
+			},
+			"GET",
+			"/wallet/primary/coin",
+			nil,
+			"",
+		},
+		{
+			"history",
+			func() error {
+				_, err := wallet.GetHistory(ctx, "default")
+				return err
+			},
+			"GET",
+			"/wallet/primary/tx/history",
+			url.Values{"account": {"default"}},
+			"",
+		},
+		{
+			"pending",
+			func() error {
+				_, err := wallet.GetPending(ctx, "")
+				return err
+			},
+			"GET",
+			"/wallet/primary/tx/unconfirmed",
+			nil,
+			"",
+		},
+		{
+			"send",
+			func() error {
+				_, err := wallet.Send(ctx, &SendOptions{
+					Outputs: []*SendOutput{{Value: 1000000, Address: "rs1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqrmmj5a"}},
+					Rate:    1000,
+				})
+				return err
+			},
+			"POST",
+			"/wallet/primary/send",
+			nil,
+			`{"outputs": [{"value": 1000000, "address": "rs1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqrmmj5a"}], "rate": 1000}`,
+		},
+		{
+			"sign",
+			func() error {
+				_, err := wallet.Sign(ctx, "00", "secret")
+				return err
+			},
+			"POST",
+			"/wallet/primary/sign",
+			nil,
+			`{"tx": "00", "passphrase": "secret"}`,
+		},
+		{
+			"bid",
+			func() error {
+				_, err := wallet.Bid(ctx, "proofofconcept", 1000000, 2000000, &AuctionOptions{Account: "savings", Broadcast: &yes})
+				return err
+			},
+			"POST",
+			"/wallet/primary/bid",
+			nil,
+			`{"account": "savings", "broadcast": true, "name": "proofofconcept", "bid": 1000000, "lockup": 2000000}`,
+		},
+		{
+			"update",
+			func() error {
+				_, err := wallet.Update(ctx, "proofofconcept", resource, nil)
+				return err
+			},
+			"POST",
+			"/wallet/primary/update",
+			nil,
+			`{"name": "proofofconcept", "data": {"records": [{"type": "TXT", "txt": ["hi"]}]}}`,
+		},
+		{
+			"renew",
+			func() error {
+				_, err := wallet.Renew(ctx, "proofofconcept", nil)
+				return err
+			},
+			"POST",
+			"/wallet/primary/renewal",
+			nil,
+			`{"name": "proofofconcept"}`,
+		},
+		{
+			"transfer",
+			func() error {
+				_, err := wallet.Transfer(ctx, "proofofconcept", "rs1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqrmmj5a", nil)
+				return err
+			},
+			"POST",
+			"/wallet/primary/transfer",
+			nil,
+			`{"name": "proofofconcept", "address": "rs1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqrmmj5a"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.call())
+			req := srv.LastRequest()
+			require.Equal(t, tt.method, req.Method)
+			require.Equal(t, tt.path, req.Path)
+			query := url.Values{"token": {token}}
+			for k, v := range tt.query {
+				query[k] = v
+			}
+			require.Equal(t, query, req.Query)
+			if tt.body == "" {
+				require.Empty(t, req.Body)
+			} else {
+				require.JSONEq(t, tt.body, string(req.Body))
+			}
+		})
+	}
+
+	// Wallets without a token send no token parameter.
+	_, err = wc.Wallet("my wallet", "").GetInfo(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "/wallet/my wallet", srv.LastRequest().Path)
+	require.Empty(t, srv.LastRequest().Query)
+}
@@ -0,0 +1,203 @@
+package client
+
+import (
+	"context"
+	"github.com/mslipper/handshake/dns"
+)
+
+func (wc *WalletClient) RPCSelectWallet(ctx context.Context, id string) error {
+	if err := wc.c.executeRPC(ctx, "selectwallet", nil, id); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (wc *WalletClient) RPCGetWalletInfo(ctx context.Context) (*GetWalletInfoResult, error) {
+	res := new(GetWalletInfoResult)
+	if err := wc.c.executeRPC(ctx, "getwalletinfo", res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (wc *WalletClient) RPCGetBalance(ctx context.Context, account string, minConf int) (float64, error) {
+	var res float64
+	if err := wc.c.executeRPC(ctx, "getbalance", &res, accountParam(account), minConf); err != nil {
+		return 0, err
+	}
+	return res, nil
+}
+
+func (wc *WalletClient) RPCGetNewAddress(ctx context.Context, account string) (string, error) {
+	var res string
+	if err := wc.c.executeRPC(ctx, "getnewaddress", &res, accountParam(account)); err != nil {
+		return "", err
+	}
+	return res, nil
+}
+
+func (wc *WalletClient) RPCListUnspent(ctx context.Context, minConf int, maxConf int, addresses []string) ([]*ListUnspentResult, error) {
+	if addresses == nil {
+		addresses = make([]string, 0)
+	}
+	var res []*ListUnspentResult
+	if err := wc.c.executeRPC(ctx, "listunspent", &res, minConf, maxConf, addresses); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (wc *WalletClient) RPCSendToAddress(ctx context.Context, address string, amount float64, subtractFee bool) (string, error) {
+	var res string
+	if err := wc.c.executeRPC(ctx, "sendtoaddress", &res, address, amount, "", "", subtractFee); err != nil {
+		return "", err
+	}
+	return res, nil
+}
+
+func (wc *WalletClient) RPCWalletPassphrase(ctx context.Context, passphrase string, timeout int) error {
+	if err := wc.c.executeRPC(ctx, "walletpassphrase", nil, passphrase, timeout); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (wc *WalletClient) RPCWalletLock(ctx context.Context) error {
+	if err := wc.c.executeRPC(ctx, "walletlock", nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (wc *WalletClient) RPCImportName(ctx context.Context, name string, rescanHeight int) error {
+	if err := wc.c.executeRPC(ctx, "importname", nil, name, rescanHeight); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (wc *WalletClient) RPCGetNames(ctx context.Context) ([]*NameStateResult, error) {
+	var res []*NameStateResult
+	if err := wc.c.executeRPC(ctx, "getnames", &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (wc *WalletClient) RPCGetAuctionInfo(ctx context.Context, name string) (*GetAuctionInfoResult, error) {
+	res := new(GetAuctionInfoResult)
+	if err := wc.c.executeRPC(ctx, "getauctioninfo", res, name); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (wc *WalletClient) RPCGetBids(ctx context.Context, name string, own bool) ([]*BidResult, error) {
+	var res []*BidResult
+	if err := wc.c.executeRPC(ctx, "getbids", &res, name, own); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (wc *WalletClient) RPCGetReveals(ctx context.Context, name string, own bool) ([]*RevealResult, error) {
+	var res []*RevealResult
+	if err := wc.c.executeRPC(ctx, "getreveals", &res, name, own); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (wc *WalletClient) RPCSendOpen(ctx context.Context, name string, force bool, account string) (*Transaction, error) {
+	return wc.nameAction(ctx, "sendopen", name, force, accountParam(account))
+}
+
+func (wc *WalletClient) RPCCreateOpen(ctx context.Context, name string, force bool, account string) (*Transaction, error) {
+	return wc.nameAction(ctx, "createopen", name, force, accountParam(account))
+}
+
+func (wc *WalletClient) RPCSendBid(ctx context.Context, name string, bid float64, lockup float64, account string) (*Transaction, error) {
+	return wc.nameAction(ctx, "sendbid", name, bid, lockup, accountParam(account))
+}
+
+func (wc *WalletClient) RPCCreateBid(ctx context.Context, name string, bid float64, lockup float64, account string) (*Transaction, error) {
+	return wc.nameAction(ctx, "createbid", name, bid, lockup, accountParam(account))
+}
+
+func (wc *WalletClient) RPCSendReveal(ctx context.Context, name string, account string) (*Transaction, error) {
+	return wc.nameAction(ctx, "sendreveal", name, accountParam(account))
+}
+
+func (wc *WalletClient) RPCCreateReveal(ctx context.Context, name string, account string) (*Transaction, error) {
+	return wc.nameAction(ctx, "createreveal", name, accountParam(account))
+}
+
+func (wc *WalletClient) RPCSendRedeem(ctx context.Context, name string, account string) (*Transaction, error) {
+	return wc.nameAction(ctx, "sendredeem", name, accountParam(account))
+}
+
+func (wc *WalletClient) RPCCreateRedeem(ctx context.Context, name string, account string) (*Transaction, error) {
+	return wc.nameAction(ctx, "createredeem", name, accountParam(account))
+}
+
+func (wc *WalletClient) RPCSendUpdate(ctx context.Context, name string, resource *dns.Resource, account string) (*Transaction, error) {
+	if resource == nil {
+		resource = new(dns.Resource)
+	}
+	return wc.nameAction(ctx, "sendupdate", name, resource, accountParam(account))
+}
+
+func (wc *WalletClient) RPCCreateUpdate(ctx context.Context, name string, resource *dns.Resource, account string) (*Transaction, error) {
+	if resource == nil {
+		resource = new(dns.Resource)
+	}
+	return wc.nameAction(ctx, "createupdate", name, resource, accountParam(account))
+}
+
+func (wc *WalletClient) RPCSendRenewal(ctx context.Context, name string, account string) (*Transaction, error) {
+	return wc.nameAction(ctx, "sendrenewal", name, accountParam(account))
+}
+
+func (wc *WalletClient) RPCCreateRenewal(ctx context.Context, name string, account string) (*Transaction, error) {
+	return wc.nameAction(ctx, "createrenewal", name, accountParam(account))
+}
+
+func (wc *WalletClient) RPCSendTransfer(ctx context.Context, name string, address string, account string) (*Transaction, error) {
+	return wc.nameAction(ctx, "sendtransfer", name, address, accountParam(account))
+}
+
+func (wc *WalletClient) RPCCreateTransfer(ctx context.Context, name string, address string, account string) (*Transaction, error) {
+	return wc.nameAction(ctx, "createtransfer", name, address, accountParam(account))
+}
+
+func (wc *WalletClient) RPCSendFinalize(ctx context.Context, name string, account string) (*Transaction, error) {
+	return wc.nameAction(ctx, "sendfinalize", name, accountParam(account))
+}
+
+func (wc *WalletClient) RPCCreateFinalize(ctx context.Context, name string, account string) (*Transaction, error) {
+	return wc.nameAction(ctx, "createfinalize", name, accountParam(account))
+}
+
+func (wc *WalletClient) RPCSendRevoke(ctx context.Context, name string, account string) (*Transaction, error) {
+	return wc.nameAction(ctx, "sendrevoke", name, accountParam(account))
+}
+
+func (wc *WalletClient) RPCCreateRevoke(ctx context.Context, name string, account string) (*Transaction, error) {
+	return wc.nameAction(ctx, "createrevoke", name, accountParam(account))
+}
+
+func (wc *WalletClient) nameAction(ctx context.Context, method string, params ...interface{}) (*Transaction, error) {
+	res := new(Transaction)
+	if err := wc.c.executeRPC(ctx, method, res, params...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// accountParam maps an empty account name to hsd's default account.
+func accountParam(account string) string {
+	if account == "" {
+		return "default"
+	}
+	return account
+}
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/mslipper/handshake/dns"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const (
+	walletInfoRPCJSON = `{
+		"walletid": "primary",
+		"walletversion": 6,
+		"balance": 40,
+		"unconfirmed_balance": 40,
+		"txcount": 2,
+		"keypoololdest": 0,
+		"keypoolsize": 0,
+		"unlocked_until": 0,
+		"paytxfee": 0.001
+	}`
+	listUnspentJSON = `[{
+		"txid": "1d0f8de2757488cbd59bea7b8f7c7ad5aa9ebd6459631e801a041062338a8630",
+		"vout": 0,
+		"address": "rs1qx7ysvmt4j6wf6hhmcmzr9z5w9hmr5vk3xvf2dk",
+		"account": "default",
+		"redeemScript": "",
+		"amount": 2000,
+		"confirmations": 100,
+		"spendable": true,
+		"solvable": true
+	}]`
+	walletTxJSON = `{
+		"hash": "1d0f8de2757488cbd59bea7b8f7c7ad5aa9ebd6459631e801a041062338a8630",
+		"witnessHash": "6d3f9f0a3b3a1c9e0a2b0f4ad4c4f6f4f3e3a1b0c9d8e7f6a5b4c3d2e1f0a9b8",
+		"fee": 2800,
+		"rate": 20000,
+		"mtime": 1580745078,
+		"version": 0,
+		"inputs": [],
+		"outputs": [],
+		"locktime": 0,
+		"hex": "00"
+	}`
+)
+
+func TestWalletClient_RPC(t *testing.T) {
+	srv := newTestServer(t, rpcResults(t, map[string]string{
+		"selectwallet":     "null",
+		"getwalletinfo":    walletInfoRPCJSON,
+		"getbalance":       "40",
+		"getnewaddress":    `"rs1qx7ysvmt4j6wf6hhmcmzr9z5w9hmr5vk3xvf2dk"`,
+		"listunspent":      listUnspentJSON,
+		"walletpassphrase": "null",
+		"sendbid":          walletTxJSON,
+		"createupdate":     walletTxJSON,
+		"sendtransfer":     walletTxJSON,
+	}))
+	defer srv.Close()
+	wc := NewWalletClient(srv.URL, WithAPIKey("apikey"))
+	ctx := context.Background()
+
+	require.NoError(t, wc.RPCSelectWallet(ctx, "primary"))
+
+	info, err := wc.RPCGetWalletInfo(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "primary", info.WalletID)
+	require.Equal(t, 0.001, info.PayTxFee)
+
+	balance, err := wc.RPCGetBalance(ctx, "", 1)
+	require.NoError(t, err)
+	require.Equal(t, 40.0, balance)
+
+	addr, err := wc.RPCGetNewAddress(ctx, "savings")
+	require.NoError(t, err)
+	require.Equal(t, "rs1qx7ysvmt4j6wf6hhmcmzr9z5w9hmr5vk3xvf2dk", addr)
+
+	unspent, err := wc.RPCListUnspent(ctx, 1, 9999999, nil)
+	require.NoError(t, err)
+	require.Len(t, unspent, 1)
+	require.Equal(t, 2000.0, unspent[0].Amount)
+
+	require.NoError(t, wc.RPCWalletPassphrase(ctx, "secret", 60))
+
+	tx, err := wc.RPCSendBid(ctx, "proofofconcept", 1, 2, "")
+	require.NoError(t, err)
+	require.Equal(t, "1d0f8de2757488cbd59bea7b8f7c7ad5aa9ebd6459631e801a041062338a8630", tx.Hash)
+
+	_, err = wc.RPCCreateUpdate(ctx, "proofofconcept", &dns.Resource{
+		Records: []dns.Record{&dns.TXTRecord{Entries: []string{"hi"}}},
+	}, "savings")
+	require.NoError(t, err)
+
+	_, err = wc.RPCSendTransfer(ctx, "proofofconcept", "rs1qx7ysvmt4j6wf6hhmcmzr9z5w9hmr5vk3xvf2dk", "")
+	require.NoError(t, err)
+
+	expected := []struct {
+		method string
+		params []interface{}
+	}{
+		{"selectwallet", []interface{}{"primary"}},
+		{"getwalletinfo", []interface{}{}},
+		{"getbalance", []interface{}{"default", 1.0}},
+		{"getnewaddress", []interface{}{"savings"}},
+		{"listunspent", []interface{}{1.0, 9999999.0, []interface{}{}}},
+		{"walletpassphrase", []interface{}{"secret", 60.0}},
+		{"sendbid", []interface{}{"proofofconcept", 1.0, 2.0, "default"}},
+		{"createupdate", []interface{}{
+			"proofofconcept",
+			map[string]interface{}{
+				"records": []interface{}{
+					map[string]interface{}{"type": "TXT", "txt": []interface{}{"hi"}},
+				},
+			},
+			"savings",
+		}},
+		{"sendtransfer", []interface{}{"proofofconcept", "rs1qx7ysvmt4j6wf6hhmcmzr9z5w9hmr5vk3xvf2dk", "default"}},
+	}
+	requests := srv.Requests()
+	require.Len(t, requests, len(expected))
+	for i, req := range requests {
+		require.Equal(t, "POST", req.Method)
+		require.Equal(t, "/", req.Path)
+		require.Equal(t, "apikey", req.Pass)
+		method, params := rpcParams(t, req)
+		require.Equal(t, expected[i].method, method)
+		require.Equal(t, expected[i].params, params)
+	}
+}
+
+func TestWalletClient_RPCError(t *testing.T) {
+	srv := newTestServer(t, rpcResults(t, nil))
+	defer srv.Close()
+	wc := NewWalletClient(srv.URL)
+
+	_, err := wc.RPCGetWalletInfo(context.Background())
+	var rpcErr *RPCError
+	require.True(t, errors.As(err, &rpcErr))
+	require.Equal(t, RPCMethodNotFound, rpcErr.Code)
+	require.Equal(t, "getwalletinfo", rpcErr.Method)
+}
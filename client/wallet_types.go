@@ -0,0 +1,177 @@
+package client
+
+import "github.com/mslipper/handshake/dns"
+
+type WalletBalance struct {
+	Account           int   `json:"account"`
+	Tx                int   `json:"tx"`
+	Coin              int   `json:"coin"`
+	Unconfirmed       int64 `json:"unconfirmed"`
+	Confirmed         int64 `json:"confirmed"`
+	LockedUnconfirmed int64 `json:"lockedUnconfirmed"`
+	LockedConfirmed   int64 `json:"lockedConfirmed"`
+}
+
+type WalletMaster struct {
+	Encrypted bool   `json:"encrypted"`
+	Until     int    `json:"until"`
+	Iv        string `json:"iv"`
+	Algorithm string `json:"algorithm"`
+}
+
+type WalletInfo struct {
+	Network      string         `json:"network"`
+	WID          int            `json:"wid"`
+	ID           string         `json:"id"`
+	WatchOnly    bool           `json:"watchOnly"`
+	AccountDepth int            `json:"accountDepth"`
+	Token        string         `json:"token"`
+	TokenDepth   int            `json:"tokenDepth"`
+	Master       WalletMaster   `json:"master"`
+	Balance      *WalletBalance `json:"balance"`
+}
+
+type CreateWalletOptions struct {
+	Type       string `json:"type,omitempty"`
+	Master     string `json:"master,omitempty"`
+	Mnemonic   string `json:"mnemonic,omitempty"`
+	AccountKey string `json:"accountKey,omitempty"`
+	WatchOnly  bool   `json:"watchOnly,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+	M          int    `json:"m,omitempty"`
+	N          int    `json:"n,omitempty"`
+	Lookahead  int    `json:"lookahead,omitempty"`
+}
+
+type WalletAccount struct {
+	Name           string         `json:"name"`
+	Initialized    bool           `json:"initialized"`
+	WatchOnly      bool           `json:"watchOnly"`
+	Type           string         `json:"type"`
+	M              int            `json:"m"`
+	N              int            `json:"n"`
+	AccountIndex   int            `json:"accountIndex"`
+	ReceiveDepth   int            `json:"receiveDepth"`
+	ChangeDepth    int            `json:"changeDepth"`
+	Lookahead      int            `json:"lookahead"`
+	ReceiveAddress string         `json:"receiveAddress"`
+	ChangeAddress  string         `json:"changeAddress"`
+	AccountKey     string         `json:"accountKey"`
+	Keys           []string       `json:"keys"`
+	Balance        *WalletBalance `json:"balance"`
+}
+
+type CreateAccountOptions struct {
+	Type       string `json:"type,omitempty"`
+	AccountKey string `json:"accountKey,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+	M          int    `json:"m,omitempty"`
+	N          int    `json:"n,omitempty"`
+	Lookahead  int    `json:"lookahead,omitempty"`
+}
+
+type WalletAddress struct {
+	Name      string `json:"name"`
+	Account   int    `json:"account"`
+	Branch    int    `json:"branch"`
+	Index     int    `json:"index"`
+	PublicKey string `json:"publicKey"`
+	Script    string `json:"script"`
+	Address   string `json:"address"`
+}
+
+type WalletPath struct {
+	Name       string `json:"name"`
+	Account    int    `json:"account"`
+	Change     bool   `json:"change"`
+	Derivation string `json:"derivation"`
+}
+
+type WalletTxInput struct {
+	Value   int64       `json:"value"`
+	Address string      `json:"address"`
+	Path    *WalletPath `json:"path"`
+}
+
+type WalletTxOutput struct {
+	Value    int64       `json:"value"`
+	Address  string      `json:"address"`
+	Covenant Covenant    `json:"covenant"`
+	Path     *WalletPath `json:"path"`
+}
+
+type WalletTx struct {
+	Hash          string            `json:"hash"`
+	Height        int               `json:"height"`
+	Block         *string           `json:"block"`
+	Time          int               `json:"time"`
+	Mtime         int               `json:"mtime"`
+	Date          string            `json:"date"`
+	Mdate         string            `json:"mdate"`
+	Size          int               `json:"size"`
+	VirtualSize   int               `json:"virtualSize"`
+	Fee           int64             `json:"fee"`
+	Rate          int64             `json:"rate"`
+	Confirmations int               `json:"confirmations"`
+	Inputs        []*WalletTxInput  `json:"inputs"`
+	Outputs       []*WalletTxOutput `json:"outputs"`
+	Tx            string            `json:"tx"`
+}
+
+type SendOutput struct {
+	Value   int64  `json:"value"`
+	Address string `json:"address"`
+}
+
+type SendOptions struct {
+	Account     string        `json:"account,omitempty"`
+	Outputs     []*SendOutput `json:"outputs"`
+	Rate        int64         `json:"rate,omitempty"`
+	MaxFee      int64         `json:"maxFee,omitempty"`
+	Selection   string        `json:"selection,omitempty"`
+	Smart       bool          `json:"smart,omitempty"`
+	SubtractFee bool          `json:"subtractFee,omitempty"`
+	Passphrase  string        `json:"passphrase,omitempty"`
+}
+
+// AuctionOptions are accepted by every auction action. Sign and Broadcast
+// default to true in hsd when left unset.
+type AuctionOptions struct {
+	Account    string `json:"account,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+	Sign       *bool  `json:"sign,omitempty"`
+	Broadcast  *bool  `json:"broadcast,omitempty"`
+}
+
+type auctionRequest struct {
+	*AuctionOptions
+	Name    string        `json:"name"`
+	Bid     int64         `json:"bid,omitempty"`
+	Lockup  int64         `json:"lockup,omitempty"`
+	Address string        `json:"address,omitempty"`
+	Data    *dns.Resource `json:"data,omitempty"`
+}
+
+type ListUnspentResult struct {
+	Txid          string  `json:"txid"`
+	Vout          int     `json:"vout"`
+	Address       string  `json:"address"`
+	Account       string  `json:"account"`
+	RedeemScript  string  `json:"redeemScript"`
+	Amount        float64 `json:"amount"`
+	Confirmations int     `json:"confirmations"`
+	Spendable     bool    `json:"spendable"`
+	Solvable      bool    `json:"solvable"`
+}
+
+type GetWalletInfoResult struct {
+	WalletID           string  `json:"walletid"`
+	WalletVersion      int     `json:"walletversion"`
+	Balance            float64 `json:"balance"`
+	UnconfirmedBalance float64 `json:"unconfirmed_balance"`
+	TxCount            int     `json:"txcount"`
+	KeypoolOldest      int     `json:"keypoololdest"`
+	KeypoolSize        int     `json:"keypoolsize"`
+	UnlockedUntil      int     `json:"unlocked_until"`
+	PayTxFee           float64 `json:"paytxfee"`
+}
@@ -0,0 +1,196 @@
+package dns
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+)
+
+type jsonResource struct {
+	Records []*jsonRecord `json:"records"`
+}
+
+// jsonRecord is the union of every record type's fields, used for decoding.
+type jsonRecord struct {
+	Type       string   `json:"type"`
+	KeyTag     uint16   `json:"keyTag"`
+	Algorithm  uint8    `json:"algorithm"`
+	DigestType uint8    `json:"digestType"`
+	Digest     string   `json:"digest"`
+	NS         string   `json:"ns"`
+	Address    string   `json:"address"`
+	TXT        []string `json:"txt"`
+}
+
+// The records below hold only the fields hsd emits for each record type, so
+// that zero values are encoded rather than omitted.
+type jsonDSRecord struct {
+	Type       string `json:"type"`
+	KeyTag     uint16 `json:"keyTag"`
+	Algorithm  uint8  `json:"algorithm"`
+	DigestType uint8  `json:"digestType"`
+	Digest     string `json:"digest"`
+}
+
+type jsonNSRecord struct {
+	Type string `json:"type"`
+	NS   string `json:"ns"`
+}
+
+type jsonGlueRecord struct {
+	Type    string `json:"type"`
+	NS      string `json:"ns"`
+	Address string `json:"address"`
+}
+
+type jsonSynthRecord struct {
+	Type    string `json:"type"`
+	Address string `json:"address"`
+}
+
+type jsonTXTRecord struct {
+	Type string   `json:"type"`
+	TXT  []string `json:"txt"`
+}
+
+// MarshalJSON encodes the resource in the format used by hsd's RPC and HTTP
+// APIs.
+func (rs *Resource) MarshalJSON() ([]byte, error) {
+	records := make([]interface{}, 0, len(rs.Records))
+	for _, record := range rs.Records {
+		typ := record.Type().String()
+		var rec interface{}
+		switch rt := record.(type) {
+		case *DSRecord:
+			rec = &jsonDSRecord{
+				Type:       typ,
+				KeyTag:     rt.KeyTag,
+				Algorithm:  rt.Algorithm,
+				DigestType: rt.DigestType,
+				Digest:     hex.EncodeToString(rt.Digest),
+			}
+		case *NSRecord:
+			rec = &jsonNSRecord{
+				Type: typ,
+				NS:   rt.NS,
+			}
+		case *Glue4Record:
+			rec = &jsonGlueRecord{
+				Type:    typ,
+				NS:      rt.NS,
+				Address: rt.Address.String(),
+			}
+		case *Glue6Record:
+			rec = &jsonGlueRecord{
+				Type:    typ,
+				NS:      rt.NS,
+				Address: rt.Address.String(),
+			}
+		case *Synth4Record:
+			rec = &jsonSynthRecord{
+				Type:    typ,
+				Address: rt.Address.String(),
+			}
+		case *Synth6Record:
+			rec = &jsonSynthRecord{
+				Type:    typ,
+				Address: rt.Address.String(),
+			}
+		case *TXTRecord:
+			txt := rt.Entries
+			if txt == nil {
+				txt = []string{}
+			}
+			rec = &jsonTXTRecord{
+				Type: typ,
+				TXT:  txt,
+			}
+		default:
+			return nil, errors.New("cannot encode record")
+		}
+		records = append(records, rec)
+	}
+	return json.Marshal(struct {
+		Records []interface{} `json:"records"`
+	}{records})
+}
+
+func (rs *Resource) UnmarshalJSON(data []byte) error {
+	jr := new(jsonResource)
+	if err := json.Unmarshal(data, jr); err != nil {
+		return err
+	}
+	var records []Record
+	for _, rec := range jr.Records {
+		var record Record
+		switch rec.Type {
+		case RecordTypeDS.String():
+			digest, err := hex.DecodeString(rec.Digest)
+			if err != nil {
+				return err
+			}
+			record = &DSRecord{
+				KeyTag:     rec.KeyTag,
+				Algorithm:  rec.Algorithm,
+				DigestType: rec.DigestType,
+				Digest:     digest,
+			}
+		case RecordTypeNS.String():
+			record = &NSRecord{
+				NS: rec.NS,
+			}
+		case RecordTypeGlue4.String():
+			addr, err := parseJSONIP(rec.Address)
+			if err != nil {
+				return err
+			}
+			record = &Glue4Record{
+				NS:      rec.NS,
+				Address: addr,
+			}
+		case RecordTypeGlue6.String():
+			addr, err := parseJSONIP(rec.Address)
+			if err != nil {
+				return err
+			}
+			record = &Glue6Record{
+				NS:      rec.NS,
+				Address: addr,
+			}
+		case RecordTypeSynth4.String():
+			addr, err := parseJSONIP(rec.Address)
+			if err != nil {
+				return err
+			}
+			record = &Synth4Record{
+				Address: addr,
+			}
+		case RecordTypeSynth6.String():
+			addr, err := parseJSONIP(rec.Address)
+			if err != nil {
+				return err
+			}
+			record = &Synth6Record{
+				Address: addr,
+			}
+		case RecordTypeTXT.String():
+			record = &TXTRecord{
+				Entries: rec.TXT,
+			}
+		default:
+			return errors.New("unknown record type")
+		}
+		records = append(records, record)
+	}
+	rs.Records = records
+	return nil
+}
+
+func parseJSONIP(addr string) (net.IP, error) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return nil, errors.New("invalid IP")
+	}
+	return ip, nil
+}
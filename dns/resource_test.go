@@ -2,6 +2,7 @@ package dns
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"github.com/stretchr/testify/require"
 	"io/ioutil"
@@ -38,3 +39,50 @@ func TestResource_Golden(t *testing.T) {
 		})
 	}
 }
+
+func TestResource_JSON(t *testing.T) {
+	files, err := ioutil.ReadDir("testdata")
+	require.NoError(t, err)
+	for _, f := range files {
+		t.Run(f.Name(), func(t *testing.T) {
+			expData, err := ioutil.ReadFile(fmt.Sprintf("testdata/%s", f.Name()))
+			require.NoError(t, err)
+			resource := new(Resource)
+			require.NoError(t, resource.Decode(bytes.NewReader(expData)))
+			jsonData, err := json.Marshal(resource)
+			require.NoError(t, err)
+			fromJSON := new(Resource)
+			require.NoError(t, json.Unmarshal(jsonData, fromJSON))
+			actData := new(bytes.Buffer)
+			require.NoError(t, fromJSON.Encode(actData))
+			require.EqualValues(t, expData, actData.Bytes())
+		})
+	}
+}
+
+func TestResource_JSONZeroValues(t *testing.T) {
+	resource := &Resource{
+		Records: []Record{
+			&DSRecord{
+				Digest: []byte{0x01, 0x02},
+			},
+			&TXTRecord{},
+		},
+	}
+	jsonData, err := json.Marshal(resource)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"records": [
+			{"type": "DS", "keyTag": 0, "algorithm": 0, "digestType": 0, "digest": "0102"},
+			{"type": "TXT", "txt": []}
+		]
+	}`, string(jsonData))
+
+	fromJSON := new(Resource)
+	require.NoError(t, json.Unmarshal(jsonData, fromJSON))
+	expData := new(bytes.Buffer)
+	require.NoError(t, resource.Encode(expData))
+	actData := new(bytes.Buffer)
+	require.NoError(t, fromJSON.Encode(actData))
+	require.Equal(t, expData.Bytes(), actData.Bytes())
+}
@@ -52,7 +52,7 @@ func ReadUint8(r io.Reader) (uint8, error) {
 func ReadVarint(r io.Reader) (uint64, error) {
 	sigil, err := ReadByte(r)
 	if err != nil {
-		return 0, nil
+		return 0, err
 	}
 	if sigil < 0xfd {
 		return uint64(sigil), nil
@@ -56,7 +56,7 @@ func WriteVarint(w io.Writer, val uint64) error {
 		buf[0] = 0xfe
 		binary.LittleEndian.PutUint32(buf[1:], uint32(val))
 	} else {
-		buf := make([]byte, 9)
+		buf = make([]byte, 9)
 		buf[0] = 0xff
 		binary.LittleEndian.PutUint64(buf[1:], val)
 	}
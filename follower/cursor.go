@@ -0,0 +1,134 @@
+package follower
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Cursor is the last block a follower has processed.
+type Cursor struct {
+	Height int
+	Hash   [32]byte
+}
+
+type cursorJSON struct {
+	Height int    `json:"height"`
+	Hash   string `json:"hash"`
+}
+
+func (c *Cursor) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&cursorJSON{
+		Height: c.Height,
+		Hash:   hex.EncodeToString(c.Hash[:]),
+	})
+}
+
+func (c *Cursor) UnmarshalJSON(data []byte) error {
+	raw := new(cursorJSON)
+	if err := json.Unmarshal(data, raw); err != nil {
+		return err
+	}
+	hashB, err := hex.DecodeString(raw.Hash)
+	if err != nil {
+		return err
+	}
+	if len(hashB) != 32 {
+		return errors.New("invalid cursor hash")
+	}
+	c.Height = raw.Height
+	copy(c.Hash[:], hashB)
+	return nil
+}
+
+// CursorStore persists a follower's cursor. Load returns nil if no cursor
+// has been saved.
+type CursorStore interface {
+	Load(ctx context.Context) (*Cursor, error)
+	Save(ctx context.Context, cursor *Cursor) error
+}
+
+type MemoryCursorStore struct {
+	mtx    sync.Mutex
+	cursor *Cursor
+}
+
+func NewMemoryCursorStore() *MemoryCursorStore {
+	return new(MemoryCursorStore)
+}
+
+func (m *MemoryCursorStore) Load(ctx context.Context) (*Cursor, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.cursor == nil {
+		return nil, nil
+	}
+	cursor := *m.cursor
+	return &cursor, nil
+}
+
+func (m *MemoryCursorStore) Save(ctx context.Context, cursor *Cursor) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	saved := *cursor
+	m.cursor = &saved
+	return nil
+}
+
+// FileCursorStore saves the cursor as JSON. Writes go through a temporary
+// file so a crash never leaves a partially written cursor behind.
+type FileCursorStore struct {
+	path string
+}
+
+func NewFileCursorStore(path string) *FileCursorStore {
+	return &FileCursorStore{
+		path: path,
+	}
+}
+
+func (s *FileCursorStore) Load(ctx context.Context) (*Cursor, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cursor := new(Cursor)
+	if err := json.Unmarshal(data, cursor); err != nil {
+		return nil, err
+	}
+	return cursor, nil
+}
+
+func (s *FileCursorStore) Save(ctx context.Context, cursor *Cursor) error {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,260 @@
+package follower
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mslipper/handshake/primitives"
+	"sync"
+	"time"
+)
+
+const defaultPollInterval = 10 * time.Second
+
+var (
+	ErrReorgTooDeep = errors.New("reorganization past start height")
+	ErrHashMismatch = errors.New("block hash does not match requested hash")
+)
+
+// BlockSource serves blocks from a node's main chain.
+type BlockSource interface {
+	BlockCount(ctx context.Context) (int, error)
+	BlockHashByHeight(ctx context.Context, height int) ([32]byte, error)
+	BlockByHash(ctx context.Context, hash [32]byte) (*primitives.Block, error)
+}
+
+// Handler receives blocks in chain order. Disconnect is called with blocks
+// in reverse order, tip first, before the blocks of the new chain are
+// connected. If a handler returns an error, the cursor is not advanced and
+// the same block is delivered again on the next sync.
+type Handler interface {
+	Connect(ctx context.Context, height int, block *primitives.Block) error
+	Disconnect(ctx context.Context, height int, block *primitives.Block) error
+}
+
+type Opt func(f *ChainFollower)
+
+func WithPollInterval(interval time.Duration) Opt {
+	return func(f *ChainFollower) {
+		f.pollInterval = interval
+	}
+}
+
+// WithErrorHandler sets a function that Run calls with every error returned
+// by Sync before retrying. Without one, Run retries silently.
+func WithErrorHandler(handler func(error)) Opt {
+	return func(f *ChainFollower) {
+		f.onError = handler
+	}
+}
+
+// ChainFollower walks a node's chain from a start height to its tip,
+// delivering connected and disconnected blocks to a Handler. Its position
+// is saved to a CursorStore after every block, so a restarted follower
+// resumes where it left off. Delivery is at least once: a block whose
+// handler succeeded may be delivered again if saving the cursor failed.
+type ChainFollower struct {
+	src          BlockSource
+	store        CursorStore
+	handler      Handler
+	start        int
+	pollInterval time.Duration
+	onError      func(error)
+	notify       chan struct{}
+	mtx          sync.Mutex
+	cursor       *Cursor
+	loaded       bool
+}
+
+func NewChainFollower(src BlockSource, store CursorStore, start int, handler Handler, opts ...Opt) *ChainFollower {
+	f := &ChainFollower{
+		src:          src,
+		store:        store,
+		handler:      handler,
+		start:        start,
+		pollInterval: defaultPollInterval,
+		notify:       make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Cursor returns the last connected block, or nil if no block has been
+// connected yet.
+func (f *ChainFollower) Cursor() *Cursor {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if f.cursor == nil || f.cursor.Height < f.start {
+		return nil
+	}
+	cursor := *f.cursor
+	return &cursor
+}
+
+// Notify triggers a sync in Run without waiting for the poll interval, for
+// example when a websocket subscription reports a new block.
+func (f *ChainFollower) Notify() {
+	select {
+	case f.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Run syncs until the context is canceled. Sync errors are passed to the
+// error handler and retried on the next poll, except ErrReorgTooDeep, which
+// is returned.
+func (f *ChainFollower) Run(ctx context.Context) error {
+	t := time.NewTicker(f.pollInterval)
+	defer t.Stop()
+	for {
+		if err := f.Sync(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, ErrReorgTooDeep) {
+				return err
+			}
+			if f.onError != nil {
+				f.onError(err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		case <-f.notify:
+		}
+	}
+}
+
+// Sync connects blocks up to the source's current tip, first disconnecting
+// any blocks that are no longer on the source's main chain.
+func (f *ChainFollower) Sync(ctx context.Context) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if !f.loaded {
+		cursor, err := f.store.Load(ctx)
+		if err != nil {
+			return err
+		}
+		f.cursor = cursor
+		f.loaded = true
+	}
+
+	if err := f.rewind(ctx); err != nil {
+		return err
+	}
+
+	count, err := f.src.BlockCount(ctx)
+	if err != nil {
+		return err
+	}
+	for height := f.nextHeight(); height <= count; height++ {
+		hash, err := f.src.BlockHashByHeight(ctx, height)
+		if err != nil {
+			return err
+		}
+		block, err := f.fetch(ctx, hash)
+		if err != nil {
+			return err
+		}
+		if f.cursor != nil && block.PrevHash != f.cursor.Hash {
+			// The chain changed underneath us since rewinding.
+			prev := f.cursor
+			if err := f.rewind(ctx); err != nil {
+				return err
+			}
+			if f.cursor == prev {
+				return fmt.Errorf("block %d does not link to cursor", height)
+			}
+			height = f.nextHeight() - 1
+			continue
+		}
+		if err := f.handler.Connect(ctx, height, block); err != nil {
+			return fmt.Errorf("connect block %d: %w", height, err)
+		}
+		if err := f.advance(ctx, &Cursor{
+			Height: height,
+			Hash:   hash,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewind disconnects blocks from the cursor back to the most recent block
+// that is still on the source's main chain.
+func (f *ChainFollower) rewind(ctx context.Context) error {
+	if f.cursor == nil {
+		return nil
+	}
+	count, err := f.src.BlockCount(ctx)
+	if err != nil {
+		return err
+	}
+	for f.cursor != nil && f.cursor.Height >= f.start {
+		if f.cursor.Height <= count {
+			hash, err := f.src.BlockHashByHeight(ctx, f.cursor.Height)
+			if err != nil {
+				return err
+			}
+			if hash == f.cursor.Hash {
+				return nil
+			}
+		}
+		block, err := f.fetch(ctx, f.cursor.Hash)
+		if err != nil {
+			return err
+		}
+		if err := f.handler.Disconnect(ctx, f.cursor.Height, block); err != nil {
+			return fmt.Errorf("disconnect block %d: %w", f.cursor.Height, err)
+		}
+		if err := f.advance(ctx, &Cursor{
+			Height: f.cursor.Height - 1,
+			Hash:   block.PrevHash,
+		}); err != nil {
+			return err
+		}
+	}
+	if f.cursor != nil && f.cursor.Height < f.start && f.cursor.Height >= 0 {
+		hash, err := f.src.BlockHashByHeight(ctx, f.cursor.Height)
+		if err != nil {
+			return err
+		}
+		if hash != f.cursor.Hash {
+			return ErrReorgTooDeep
+		}
+	}
+	return nil
+}
+
+func (f *ChainFollower) fetch(ctx context.Context, hash [32]byte) (*primitives.Block, error) {
+	block, err := f.src.BlockByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(block.Hash(), hash[:]) {
+		return nil, ErrHashMismatch
+	}
+	return block, nil
+}
+
+func (f *ChainFollower) advance(ctx context.Context, cursor *Cursor) error {
+	if err := f.store.Save(ctx, cursor); err != nil {
+		return err
+	}
+	f.cursor = cursor
+	return nil
+}
+
+func (f *ChainFollower) nextHeight() int {
+	if f.cursor == nil {
+		return f.start
+	}
+	return f.cursor.Height + 1
+}
@@ -0,0 +1,190 @@
+package follower
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type fakeChain struct {
+	main   []*primitives.Block
+	blocks map[[32]byte]*primitives.Block
+}
+
+func newFakeChain(n int) *fakeChain {
+	c := &fakeChain{
+		blocks: make(map[[32]byte]*primitives.Block),
+	}
+	c.extend(0, n, 0)
+	return c
+}
+
+// extend replaces the chain above height with n new blocks. salt makes the
+// new blocks differ from any previous ones at the same height.
+func (c *fakeChain) extend(height int, n int, salt uint32) {
+	c.main = c.main[:height]
+	for i := 0; i < n; i++ {
+		block := &primitives.Block{
+			Nonce: salt,
+			Time:  uint64(len(c.main)),
+		}
+		if len(c.main) > 0 {
+			copy(block.PrevHash[:], c.main[len(c.main)-1].Hash())
+		}
+		c.main = append(c.main, block)
+		c.blocks[hashOf(block)] = block
+	}
+}
+
+func (c *fakeChain) BlockCount(ctx context.Context) (int, error) {
+	return len(c.main) - 1, nil
+}
+
+func (c *fakeChain) BlockHashByHeight(ctx context.Context, height int) ([32]byte, error) {
+	if height < 0 || height >= len(c.main) {
+		return [32]byte{}, errors.New("out of range")
+	}
+	return hashOf(c.main[height]), nil
+}
+
+func (c *fakeChain) BlockByHash(ctx context.Context, hash [32]byte) (*primitives.Block, error) {
+	block, ok := c.blocks[hash]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return block, nil
+}
+
+func hashOf(block *primitives.Block) [32]byte {
+	var hash [32]byte
+	copy(hash[:], block.Hash())
+	return hash
+}
+
+type recorder struct {
+	events []string
+	fail   int
+}
+
+func (r *recorder) Connect(ctx context.Context, height int, block *primitives.Block) error {
+	if r.fail == height {
+		return errors.New("handler failed")
+	}
+	r.events = append(r.events, fmt.Sprintf("+%d/%d", height, block.Nonce))
+	return nil
+}
+
+func (r *recorder) Disconnect(ctx context.Context, height int, block *primitives.Block) error {
+	r.events = append(r.events, fmt.Sprintf("-%d/%d", height, block.Nonce))
+	return nil
+}
+
+func TestChainFollower_Sync(t *testing.T) {
+	ctx := context.Background()
+	chain := newFakeChain(6)
+	store := NewMemoryCursorStore()
+	rec := &recorder{fail: -1}
+	f := NewChainFollower(chain, store, 2, rec)
+
+	require.NoError(t, f.Sync(ctx))
+	require.Equal(t, []string{"+2/0", "+3/0", "+4/0", "+5/0"}, rec.events)
+	require.Equal(t, 5, f.Cursor().Height)
+
+	rec.events = nil
+	chain.extend(4, 3, 1)
+	require.NoError(t, f.Sync(ctx))
+	require.Equal(t, []string{"-5/0", "-4/0", "+4/1", "+5/1", "+6/1"}, rec.events)
+
+	// a shorter chain with more work disconnects past the new tip
+	rec.events = nil
+	chain.extend(3, 1, 2)
+	require.NoError(t, f.Sync(ctx))
+	require.Equal(t, []string{"-6/1", "-5/1", "-4/1", "-3/0", "+3/2"}, rec.events)
+
+	// a restarted follower resumes from the store
+	rec.events = nil
+	chain.extend(4, 2, 3)
+	f = NewChainFollower(chain, store, 2, rec)
+	require.NoError(t, f.Sync(ctx))
+	require.Equal(t, []string{"+4/3", "+5/3"}, rec.events)
+
+	rec.events = nil
+	chain.extend(1, 5, 4)
+	require.True(t, errors.Is(f.Sync(ctx), ErrReorgTooDeep))
+	require.Equal(t, []string{"-5/3", "-4/3", "-3/2", "-2/0"}, rec.events)
+}
+
+func TestChainFollower_HandlerError(t *testing.T) {
+	ctx := context.Background()
+	chain := newFakeChain(5)
+	rec := &recorder{fail: 3}
+	f := NewChainFollower(chain, NewMemoryCursorStore(), 0, rec)
+	require.Error(t, f.Sync(ctx))
+	require.Equal(t, 2, f.Cursor().Height)
+
+	rec.fail = -1
+	require.NoError(t, f.Sync(ctx))
+	require.Equal(t, []string{"+0/0", "+1/0", "+2/0", "+3/0", "+4/0"}, rec.events)
+}
+
+func TestChainFollower_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	chain := newFakeChain(5)
+	rec := &recorder{fail: 3}
+	var errs []error
+	f := NewChainFollower(chain, NewMemoryCursorStore(), 0, rec,
+		WithPollInterval(time.Millisecond),
+		WithErrorHandler(func(err error) {
+			errs = append(errs, err)
+			rec.fail = -1
+		}),
+	)
+	done := make(chan error, 1)
+	go func() {
+		done <- f.Run(ctx)
+	}()
+	require.Eventually(t, func() bool {
+		cursor := f.Cursor()
+		return cursor != nil && cursor.Height == 4
+	}, 5*time.Second, time.Millisecond)
+	cancel()
+	require.Equal(t, context.Canceled, <-done)
+	require.Len(t, errs, 1)
+	require.EqualError(t, errs[0], "connect block 3: handler failed")
+	require.Equal(t, []string{"+0/0", "+1/0", "+2/0", "+3/0", "+4/0"}, rec.events)
+
+	chain.extend(0, 5, 1)
+	f = NewChainFollower(chain, NewMemoryCursorStore(), 2, rec)
+	require.NoError(t, f.Sync(context.Background()))
+	chain.extend(1, 5, 2)
+	require.True(t, errors.Is(f.Run(context.Background()), ErrReorgTooDeep))
+}
+
+func TestFileCursorStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "follower")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	store := NewFileCursorStore(filepath.Join(dir, "cursor.json"))
+	cursor, err := store.Load(ctx)
+	require.NoError(t, err)
+	require.Nil(t, cursor)
+
+	exp := &Cursor{
+		Height: 1234,
+		Hash:   [32]byte{0x01, 0x02},
+	}
+	require.NoError(t, store.Save(ctx, exp))
+	cursor, err = store.Load(ctx)
+	require.NoError(t, err)
+	require.Equal(t, exp, cursor)
+}
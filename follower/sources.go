@@ -0,0 +1,46 @@
+package follower
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"github.com/mslipper/handshake/client"
+	"github.com/mslipper/handshake/primitives"
+)
+
+// ClientSource adapts a node client to the BlockSource interface. Blocks are
+// fetched in raw form and verified locally.
+type ClientSource struct {
+	c *client.Client
+}
+
+func NewClientSource(c *client.Client) *ClientSource {
+	return &ClientSource{
+		c: c,
+	}
+}
+
+func (s *ClientSource) BlockCount(ctx context.Context) (int, error) {
+	return s.c.RPCGetBlockCount(ctx)
+}
+
+func (s *ClientSource) BlockHashByHeight(ctx context.Context, height int) ([32]byte, error) {
+	var hash [32]byte
+	hashHex, err := s.c.RPCGetBlockHashByHeight(ctx, height)
+	if err != nil {
+		return hash, err
+	}
+	hashB, err := hex.DecodeString(hashHex)
+	if err != nil {
+		return hash, err
+	}
+	if len(hashB) != len(hash) {
+		return hash, errors.New("invalid block hash")
+	}
+	copy(hash[:], hashB)
+	return hash, nil
+}
+
+func (s *ClientSource) BlockByHash(ctx context.Context, hash [32]byte) (*primitives.Block, error) {
+	return s.c.RPCGetBlockPrimitiveByHash(ctx, hex.EncodeToString(hash[:]))
+}
@@ -3,8 +3,10 @@ module github.com/mslipper/handshake
 go 1.13
 
 require (
+	github.com/btcsuite/btcd v0.20.1-beta
 	github.com/btcsuite/btcutil v1.0.2
 	github.com/miekg/dns v1.1.29
 	github.com/stretchr/testify v1.5.1
 	golang.org/x/crypto v0.0.0-20200422194213-44a606286825
+	golang.org/x/net v0.0.0-20190923162816-aa69164e4478
 )
@@ -0,0 +1,655 @@
+package p2p
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mslipper/handshake/brontide"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/mslipper/handshake/wire"
+	"io/ioutil"
+	"math"
+	mrand "math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBanDuration = 24 * time.Hour
+
+	newBucketCount   = 256
+	triedBucketCount = 64
+	bucketSize       = 64
+	addrBookVersion  = 1
+	retryInterval    = 10 * time.Minute
+	staleAge         = 30 * 24 * time.Hour
+	maxAttempts      = 3
+)
+
+var ErrNoSeeds = errors.New("no seeds could be resolved")
+
+type knownAddr struct {
+	addr        *wire.NetAddress
+	src         net.IP
+	attempts    int
+	lastAttempt time.Time
+	lastSuccess time.Time
+	tried       bool
+	bucket      int
+}
+
+// AddrBook tracks candidate peers. Addresses we have only heard about live
+// in new buckets chosen by the address and whoever told us about it, so a
+// single source cannot flood the book. Addresses we have connected to move
+// to tried buckets. Both kinds are offered by Select.
+type AddrBook struct {
+	network    primitives.Network
+	path       string
+	seeds      []string
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+	now        func() time.Time
+
+	mtx          sync.Mutex
+	key          [32]byte
+	rand         *mrand.Rand
+	addrs        map[string]*knownAddr
+	newBuckets   [newBucketCount]map[string]*knownAddr
+	triedBuckets [triedBucketCount]map[string]*knownAddr
+	banned       map[string]time.Time
+}
+
+type AddrBookOpt func(a *AddrBook)
+
+// WithAddrBookFile persists the book to path on Save and reads it back on
+// Load.
+func WithAddrBookFile(path string) AddrBookOpt {
+	return func(a *AddrBook) {
+		a.path = path
+	}
+}
+
+// WithSeeds replaces the network's default seeds.
+func WithSeeds(seeds []string) AddrBookOpt {
+	return func(a *AddrBook) {
+		a.seeds = seeds
+	}
+}
+
+func WithLookupHost(lookup func(ctx context.Context, host string) ([]string, error)) AddrBookOpt {
+	return func(a *AddrBook) {
+		a.lookupHost = lookup
+	}
+}
+
+func WithAddrBookClock(now func() time.Time) AddrBookOpt {
+	return func(a *AddrBook) {
+		a.now = now
+	}
+}
+
+func NewAddrBook(network primitives.Network, opts ...AddrBookOpt) *AddrBook {
+	a := &AddrBook{
+		network:    network,
+		seeds:      network.Seeds(),
+		lookupHost: net.DefaultResolver.LookupHost,
+		now:        time.Now,
+	}
+	if _, err := rand.Read(a.key[:]); err != nil {
+		panic(err)
+	}
+	a.reset()
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+func (a *AddrBook) reset() {
+	var seed [8]byte
+	if _, err := rand.Read(seed[:]); err != nil {
+		panic(err)
+	}
+	a.rand = mrand.New(mrand.NewSource(int64(binary.LittleEndian.Uint64(seed[:]))))
+	a.addrs = make(map[string]*knownAddr)
+	for i := range a.newBuckets {
+		a.newBuckets[i] = make(map[string]*knownAddr)
+	}
+	for i := range a.triedBuckets {
+		a.triedBuckets[i] = make(map[string]*knownAddr)
+	}
+	a.banned = make(map[string]time.Time)
+}
+
+// Len returns the number of known addresses.
+func (a *AddrBook) Len() int {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return len(a.addrs)
+}
+
+func (a *AddrBook) NumTried() int {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	var n int
+	for _, bucket := range a.triedBuckets {
+		n += len(bucket)
+	}
+	return n
+}
+
+// Bootstrap resolves the seeds and adds what they return. Seeds in
+// "<key>@host" form keep their identity key.
+func (a *AddrBook) Bootstrap(ctx context.Context) (int, error) {
+	var added int
+	var lastErr error
+	resolved := false
+	for _, seed := range a.seeds {
+		var key [wire.KeySize]byte
+		host := seed
+		port := a.network.P2PPort()
+		if strings.Contains(seed, "@") {
+			pub, hostPort, err := brontide.ParseIdentityAddr(seed, a.network.BrontidePort())
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			copy(key[:], pub.SerializeCompressed())
+			h, p, _ := net.SplitHostPort(hostPort)
+			host = h
+			port, _ = strconv.Atoi(p)
+		}
+		ips, err := a.resolve(ctx, host)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resolved = true
+		for _, ip := range ips {
+			if a.Add(&wire.NetAddress{
+				Time:     uint64(a.now().Unix()),
+				Services: wire.ServiceNetwork,
+				IP:       ip,
+				Port:     uint16(port),
+				Key:      key,
+			}, nil) {
+				added++
+			}
+		}
+	}
+	if !resolved && len(a.seeds) > 0 {
+		return 0, fmt.Errorf("%w: %v", ErrNoSeeds, lastErr)
+	}
+	return added, nil
+}
+
+func (a *AddrBook) resolve(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	hosts, err := a.lookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var ips []net.IP
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
+
+// AddAddrs ingests the addresses from an addr message sent by src and
+// returns how many were new.
+func (a *AddrBook) AddAddrs(addrs []*wire.NetAddress, src net.IP) int {
+	var added int
+	for _, addr := range addrs {
+		if a.Add(addr, src) {
+			added++
+		}
+	}
+	return added
+}
+
+// Add adds addr to a new bucket, returning false if it was invalid or
+// already known. Known addresses have their time, services and key
+// refreshed instead.
+func (a *AddrBook) Add(addr *wire.NetAddress, src net.IP) bool {
+	if addr.IP == nil || addr.IP.IsUnspecified() || addr.Port == 0 {
+		return false
+	}
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	now := a.now()
+	id := addr.HostPort()
+	if ka := a.addrs[id]; ka != nil {
+		if addr.Time > ka.addr.Time && int64(addr.Time) <= now.Unix() {
+			ka.addr.Time = addr.Time
+		}
+		ka.addr.Services |= addr.Services
+		if !ka.addr.HasKey() {
+			ka.addr.Key = addr.Key
+		}
+		return false
+	}
+
+	cpy := *addr
+	if cpy.Time == 0 || int64(cpy.Time) > now.Add(retryInterval).Unix() {
+		cpy.Time = uint64(now.Add(-5 * 24 * time.Hour).Unix())
+	}
+	ka := &knownAddr{
+		addr: &cpy,
+		src:  src,
+	}
+	a.insertNew(id, ka)
+	return true
+}
+
+func (a *AddrBook) insertNew(id string, ka *knownAddr) {
+	ka.tried = false
+	ka.bucket = a.newBucket(ka.addr.IP, ka.src)
+	bucket := a.newBuckets[ka.bucket]
+	if len(bucket) >= bucketSize {
+		a.evictNew(bucket)
+	}
+	bucket[id] = ka
+	a.addrs[id] = ka
+}
+
+// evictNew drops a stale address from bucket, or else the one we heard
+// about longest ago.
+func (a *AddrBook) evictNew(bucket map[string]*knownAddr) {
+	var oldestID string
+	var oldest *knownAddr
+	for id, ka := range bucket {
+		if a.isStale(ka) {
+			oldestID = id
+			break
+		}
+		if oldest == nil || ka.addr.Time < oldest.addr.Time {
+			oldestID = id
+			oldest = ka
+		}
+	}
+	delete(bucket, oldestID)
+	delete(a.addrs, oldestID)
+}
+
+func (a *AddrBook) isStale(ka *knownAddr) bool {
+	now := a.now()
+	if now.Sub(time.Unix(int64(ka.addr.Time), 0)) > staleAge {
+		return true
+	}
+	return ka.lastSuccess.IsZero() && ka.attempts >= maxAttempts
+}
+
+// MarkAttempt records a connection attempt to addr.
+func (a *AddrBook) MarkAttempt(addr *wire.NetAddress) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	ka := a.addrs[addr.HostPort()]
+	if ka == nil {
+		return
+	}
+	ka.attempts++
+	ka.lastAttempt = a.now()
+}
+
+// MarkSuccess records a completed handshake with addr and moves it to a
+// tried bucket. If that bucket is full, its least recently successful
+// address is moved back to the new table.
+func (a *AddrBook) MarkSuccess(addr *wire.NetAddress) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	id := addr.HostPort()
+	ka := a.addrs[id]
+	if ka == nil {
+		return
+	}
+	now := a.now()
+	ka.attempts = 0
+	ka.lastSuccess = now
+	ka.lastAttempt = now
+	ka.addr.Time = uint64(now.Unix())
+	if ka.tried {
+		return
+	}
+	delete(a.newBuckets[ka.bucket], id)
+	a.insertTried(id, ka)
+}
+
+func (a *AddrBook) insertTried(id string, ka *knownAddr) {
+	ka.tried = true
+	ka.bucket = a.triedBucket(ka.addr.IP)
+	bucket := a.triedBuckets[ka.bucket]
+	if len(bucket) >= bucketSize {
+		var oldestID string
+		var oldest *knownAddr
+		for otherID, other := range bucket {
+			if oldest == nil || other.lastSuccess.Before(oldest.lastSuccess) {
+				oldestID = otherID
+				oldest = other
+			}
+		}
+		delete(bucket, oldestID)
+		a.insertNew(oldestID, oldest)
+	}
+	bucket[id] = ka
+	a.addrs[id] = ka
+}
+
+// Ban stops Select from returning any address on ip until d has passed.
+func (a *AddrBook) Ban(ip net.IP, d time.Duration) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.banned[ip.String()] = a.now().Add(d)
+}
+
+func (a *AddrBook) IsBanned(ip net.IP) bool {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return a.isBanned(ip)
+}
+
+func (a *AddrBook) isBanned(ip net.IP) bool {
+	until, ok := a.banned[ip.String()]
+	if !ok {
+		return false
+	}
+	if a.now().After(until) {
+		delete(a.banned, ip.String())
+		return false
+	}
+	return true
+}
+
+// Select picks an address to connect to, or nil if none is eligible. It
+// chooses evenly between the new and tried tables and favours addresses
+// that have not failed recently. Addresses for which exclude returns true,
+// such as ones already connected, are skipped.
+func (a *AddrBook) Select(exclude func(addr *wire.NetAddress) bool) *wire.NetAddress {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	useTried := a.rand.Intn(2) == 0
+	if ka := a.pick(useTried, exclude); ka != nil {
+		return ka
+	}
+	return a.pick(!useTried, exclude)
+}
+
+func (a *AddrBook) pick(tried bool, exclude func(addr *wire.NetAddress) bool) *wire.NetAddress {
+	var candidates []*knownAddr
+	var weights []float64
+	var total float64
+	for _, ka := range a.addrs {
+		if ka.tried != tried || a.isBanned(ka.addr.IP) {
+			continue
+		}
+		if exclude != nil && exclude(ka.addr) {
+			continue
+		}
+		weight := a.chance(ka)
+		candidates = append(candidates, ka)
+		weights = append(weights, weight)
+		total += weight
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	r := a.rand.Float64() * total
+	for i, weight := range weights {
+		r -= weight
+		if r < 0 {
+			cpy := *candidates[i].addr
+			return &cpy
+		}
+	}
+	cpy := *candidates[len(candidates)-1].addr
+	return &cpy
+}
+
+func (a *AddrBook) chance(ka *knownAddr) float64 {
+	c := 1.0
+	if a.now().Sub(ka.lastAttempt) < retryInterval {
+		c *= 0.01
+	}
+	attempts := ka.attempts
+	if attempts > 8 {
+		attempts = 8
+	}
+	return c * math.Pow(0.66, float64(attempts))
+}
+
+// Addresses returns up to max known addresses for answering getaddr.
+func (a *AddrBook) Addresses(max int) []*wire.NetAddress {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	var out []*wire.NetAddress
+	for _, ka := range a.addrs {
+		if len(out) >= max {
+			break
+		}
+		if a.isBanned(ka.addr.IP) || a.isStale(ka) {
+			continue
+		}
+		cpy := *ka.addr
+		out = append(out, &cpy)
+	}
+	return out
+}
+
+func (a *AddrBook) newBucket(ip net.IP, src net.IP) int {
+	h := sha256.New()
+	h.Write(a.key[:])
+	h.Write(group(ip))
+	h.Write(group(src))
+	return int(binary.LittleEndian.Uint32(h.Sum(nil)) % newBucketCount)
+}
+
+func (a *AddrBook) triedBucket(ip net.IP) int {
+	h := sha256.New()
+	h.Write(a.key[:])
+	h.Write(ip.To16())
+	return int(binary.LittleEndian.Uint32(h.Sum(nil)) % triedBucketCount)
+}
+
+// group returns the network an address belongs to for bucketing: its /16
+// for IPv4 and /32 for IPv6.
+func group(ip net.IP) []byte {
+	if ip == nil {
+		return nil
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return append([]byte{4}, ip4[:2]...)
+	}
+	return append([]byte{6}, ip.To16()[:4]...)
+}
+
+type addrBookFile struct {
+	Version int              `json:"version"`
+	Network string           `json:"network"`
+	Key     string           `json:"key"`
+	Addrs   []*addrBookEntry `json:"addrs"`
+	Banned  map[string]int64 `json:"banned"`
+}
+
+type addrBookEntry struct {
+	Host        string `json:"host"`
+	Port        uint16 `json:"port"`
+	Services    uint32 `json:"services"`
+	Time        uint64 `json:"time"`
+	Key         string `json:"key,omitempty"`
+	Src         string `json:"src,omitempty"`
+	Attempts    int    `json:"attempts"`
+	LastAttempt int64  `json:"lastAttempt"`
+	LastSuccess int64  `json:"lastSuccess"`
+	Tried       bool   `json:"tried"`
+}
+
+// Load replaces the book's contents with those saved at its file. A
+// missing file leaves the book empty.
+func (a *AddrBook) Load() error {
+	if a.path == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(a.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	file := new(addrBookFile)
+	if err := json.Unmarshal(data, file); err != nil {
+		return err
+	}
+	if file.Version != addrBookVersion {
+		return fmt.Errorf("unsupported address book version %d", file.Version)
+	}
+	if file.Network != a.network.String() {
+		return fmt.Errorf("address book is for network %s", file.Network)
+	}
+	key, err := hex.DecodeString(file.Key)
+	if err != nil || len(key) != len(a.key) {
+		return errors.New("invalid address book key")
+	}
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.reset()
+	copy(a.key[:], key)
+	for host, until := range file.Banned {
+		a.banned[host] = time.Unix(until, 0)
+	}
+	for _, entry := range file.Addrs {
+		ka, err := entry.knownAddr()
+		if err != nil {
+			return err
+		}
+		id := ka.addr.HostPort()
+		if a.addrs[id] != nil {
+			continue
+		}
+		if ka.tried {
+			a.insertTried(id, ka)
+		} else {
+			a.insertNew(id, ka)
+		}
+	}
+	return nil
+}
+
+// Save writes the book to its file through a temporary file.
+func (a *AddrBook) Save() error {
+	if a.path == "" {
+		return nil
+	}
+	a.mtx.Lock()
+	file := &addrBookFile{
+		Version: addrBookVersion,
+		Network: a.network.String(),
+		Key:     hex.EncodeToString(a.key[:]),
+		Banned:  make(map[string]int64),
+	}
+	now := a.now()
+	for host, until := range a.banned {
+		if now.Before(until) {
+			file.Banned[host] = until.Unix()
+		}
+	}
+	for _, ka := range a.addrs {
+		file.Addrs = append(file.Addrs, newAddrBookEntry(ka))
+	}
+	a.mtx.Unlock()
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(a.path), filepath.Base(a.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), a.path)
+}
+
+func newAddrBookEntry(ka *knownAddr) *addrBookEntry {
+	entry := &addrBookEntry{
+		Host:     ka.addr.IP.String(),
+		Port:     ka.addr.Port,
+		Services: ka.addr.Services,
+		Time:     ka.addr.Time,
+		Attempts: ka.attempts,
+		Tried:    ka.tried,
+	}
+	if ka.addr.HasKey() {
+		entry.Key = hex.EncodeToString(ka.addr.Key[:])
+	}
+	if ka.src != nil {
+		entry.Src = ka.src.String()
+	}
+	if !ka.lastAttempt.IsZero() {
+		entry.LastAttempt = ka.lastAttempt.Unix()
+	}
+	if !ka.lastSuccess.IsZero() {
+		entry.LastSuccess = ka.lastSuccess.Unix()
+	}
+	return entry
+}
+
+func (e *addrBookEntry) knownAddr() (*knownAddr, error) {
+	ip := net.ParseIP(e.Host)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid address book host %s", e.Host)
+	}
+	ka := &knownAddr{
+		addr: &wire.NetAddress{
+			Time:     e.Time,
+			Services: e.Services,
+			IP:       ip,
+			Port:     e.Port,
+		},
+		src:      net.ParseIP(e.Src),
+		attempts: e.Attempts,
+		tried:    e.Tried,
+	}
+	if e.Key != "" {
+		key, err := hex.DecodeString(e.Key)
+		if err != nil || len(key) != wire.KeySize {
+			return nil, errors.New("invalid address book identity key")
+		}
+		copy(ka.addr.Key[:], key)
+	}
+	if e.LastAttempt != 0 {
+		ka.lastAttempt = time.Unix(e.LastAttempt, 0)
+	}
+	if e.LastSuccess != 0 {
+		ka.lastSuccess = time.Unix(e.LastSuccess, 0)
+	}
+	return ka, nil
+}
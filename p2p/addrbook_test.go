@@ -0,0 +1,173 @@
+package p2p
+
+import (
+	"context"
+	"errors"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/mslipper/handshake/brontide"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/mslipper/handshake/wire"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testAddr(ip string, port uint16) *wire.NetAddress {
+	return &wire.NetAddress{
+		Time:     uint64(time.Now().Add(-time.Hour).Unix()),
+		Services: wire.ServiceNetwork,
+		IP:       net.ParseIP(ip),
+		Port:     port,
+	}
+}
+
+func TestAddrBook_AddSelect(t *testing.T) {
+	book := NewAddrBook(primitives.NetworkRegtest)
+	require.Nil(t, book.Select(nil))
+
+	src := net.ParseIP("10.0.0.1")
+	added := book.AddAddrs([]*wire.NetAddress{
+		testAddr("1.2.3.4", 14038),
+		testAddr("1.2.3.4", 14038),
+		testAddr("5.6.7.8", 14038),
+		testAddr("0.0.0.0", 14038),
+		testAddr("9.9.9.9", 0),
+	}, src)
+	require.Equal(t, 2, added)
+	require.Equal(t, 2, book.Len())
+
+	// Refreshing a known address merges services.
+	update := testAddr("1.2.3.4", 14038)
+	update.Services = wire.ServiceBloom
+	require.False(t, book.Add(update, src))
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		addr := book.Select(nil)
+		require.NotNil(t, addr)
+		seen[addr.HostPort()] = true
+		if addr.HostPort() == "1.2.3.4:14038" {
+			require.Equal(t, wire.ServiceNetwork|wire.ServiceBloom, addr.Services)
+		}
+	}
+	require.Len(t, seen, 2)
+
+	addr := book.Select(func(addr *wire.NetAddress) bool {
+		return addr.HostPort() == "1.2.3.4:14038"
+	})
+	require.Equal(t, "5.6.7.8:14038", addr.HostPort())
+
+	book.Ban(net.ParseIP("5.6.7.8"), time.Hour)
+	require.True(t, book.IsBanned(net.ParseIP("5.6.7.8")))
+	for i := 0; i < 20; i++ {
+		require.Equal(t, "1.2.3.4:14038", book.Select(nil).HostPort())
+	}
+	require.Nil(t, book.Select(func(addr *wire.NetAddress) bool {
+		return true
+	}))
+	require.Len(t, book.Addresses(10), 1)
+}
+
+func TestAddrBook_Tried(t *testing.T) {
+	book := NewAddrBook(primitives.NetworkRegtest)
+	addr := testAddr("1.2.3.4", 14038)
+	book.Add(addr, nil)
+	book.Add(testAddr("5.6.7.8", 14038), nil)
+	book.MarkAttempt(addr)
+	book.MarkSuccess(addr)
+	require.Equal(t, 1, book.NumTried())
+	require.Equal(t, 2, book.Len())
+
+	// Unknown addresses are ignored.
+	book.MarkSuccess(testAddr("9.9.9.9", 14038))
+	require.Equal(t, 1, book.NumTried())
+}
+
+func TestAddrBook_Eviction(t *testing.T) {
+	now := time.Now()
+	book := NewAddrBook(primitives.NetworkRegtest, WithAddrBookClock(func() time.Time {
+		return now
+	}))
+	src := net.ParseIP("10.0.0.1")
+	// Everything from one source in one /16 lands in the same bucket.
+	for i := 0; i < bucketSize+10; i++ {
+		addr := testAddr("1.2.3.4", uint16(1000+i))
+		addr.Time = uint64(now.Add(time.Duration(i-1000) * time.Minute).Unix())
+		require.True(t, book.Add(addr, src))
+	}
+	require.Equal(t, bucketSize, book.Len())
+	for _, addr := range book.Addresses(bucketSize) {
+		require.True(t, addr.Port >= 1010)
+	}
+}
+
+func TestAddrBook_Bootstrap(t *testing.T) {
+	priv, err := btcec.NewPrivateKey(btcec.S256())
+	require.NoError(t, err)
+	identity := brontide.FormatIdentityAddr(priv.PubKey(), "9.9.9.9")
+
+	lookup := func(ctx context.Context, host string) ([]string, error) {
+		if host == "seed.example" {
+			return []string{"1.2.3.4", "5.6.7.8"}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	book := NewAddrBook(primitives.NetworkMainnet, WithSeeds([]string{"seed.example", "down.example", identity}), WithLookupHost(lookup))
+	added, err := book.Bootstrap(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, added)
+
+	var keyed *wire.NetAddress
+	for _, addr := range book.Addresses(10) {
+		if addr.HasKey() {
+			keyed = addr
+			continue
+		}
+		require.EqualValues(t, primitives.NetworkMainnet.P2PPort(), addr.Port)
+	}
+	require.NotNil(t, keyed)
+	require.Equal(t, priv.PubKey().SerializeCompressed(), keyed.Key[:])
+	require.EqualValues(t, primitives.NetworkMainnet.BrontidePort(), keyed.Port)
+
+	book = NewAddrBook(primitives.NetworkMainnet, WithSeeds([]string{"down.example"}), WithLookupHost(lookup))
+	_, err = book.Bootstrap(context.Background())
+	require.True(t, errors.Is(err, ErrNoSeeds))
+
+	added, err = NewAddrBook(primitives.NetworkRegtest).Bootstrap(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, added)
+}
+
+func TestAddrBook_Persistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "addrbook")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "peers.json")
+
+	book := NewAddrBook(primitives.NetworkRegtest, WithAddrBookFile(path))
+	require.NoError(t, book.Load())
+	tried := testAddr("1.2.3.4", 14038)
+	tried.Key[0] = 0x02
+	book.Add(tried, net.ParseIP("10.0.0.1"))
+	book.Add(testAddr("5.6.7.8", 14038), nil)
+	book.MarkSuccess(tried)
+	book.Ban(net.ParseIP("5.6.7.8"), time.Hour)
+	require.NoError(t, book.Save())
+
+	loaded := NewAddrBook(primitives.NetworkRegtest, WithAddrBookFile(path))
+	require.NoError(t, loaded.Load())
+	require.Equal(t, 2, loaded.Len())
+	require.Equal(t, 1, loaded.NumTried())
+	require.Equal(t, book.key, loaded.key)
+	require.True(t, loaded.IsBanned(net.ParseIP("5.6.7.8")))
+	addr := loaded.Select(nil)
+	require.Equal(t, "1.2.3.4:14038", addr.HostPort())
+	require.Equal(t, tried.Key, addr.Key)
+
+	other := NewAddrBook(primitives.NetworkMainnet, WithAddrBookFile(path))
+	require.Error(t, other.Load())
+}
@@ -0,0 +1,244 @@
+package p2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/mslipper/handshake/wire"
+)
+
+const banScoreInvalidCompact = 100
+
+var (
+	ErrInvalidCompact    = errors.New("invalid compact block")
+	ErrShortIDCollision  = errors.New("short id collision")
+	ErrIncompleteBlock   = errors.New("compact block is incomplete")
+	ErrInvalidBlockTxn   = errors.New("blocktxn does not match request")
+	ErrBadReconstruction = errors.New("reconstructed block does not match header")
+)
+
+// Mempool supplies candidate transactions for compact block
+// reconstruction.
+type Mempool interface {
+	Transactions() []*primitives.Transaction
+}
+
+// CompactBlock rebuilds a block from a cmpctblock message. Prefilled
+// transactions are placed up front; the rest are matched by short ID
+// against the mempool and, failing that, fetched with getblocktxn.
+type CompactBlock struct {
+	hash   [32]byte
+	header *primitives.Block
+	key    [16]byte
+	txs    []*primitives.Transaction
+	ids    map[uint64]int
+	filled int
+}
+
+func NewCompactBlock(msg *wire.CmpctBlockMessage) (*CompactBlock, error) {
+	if msg.Header == nil {
+		return nil, ErrInvalidCompact
+	}
+	total := len(msg.IDs) + len(msg.Prefilled)
+	if total == 0 || total > wire.MaxCmpctIndex+1 {
+		return nil, ErrInvalidCompact
+	}
+	c := &CompactBlock{
+		header: msg.Header,
+		key:    msg.SipKey(),
+		txs:    make([]*primitives.Transaction, total),
+		ids:    make(map[uint64]int, len(msg.IDs)),
+	}
+	copy(c.hash[:], msg.Header.Hash())
+
+	last := -1
+	for _, ptx := range msg.Prefilled {
+		if ptx.Tx == nil || ptx.Index <= last || ptx.Index >= total {
+			return nil, ErrInvalidCompact
+		}
+		c.txs[ptx.Index] = ptx.Tx
+		c.filled++
+		last = ptx.Index
+	}
+
+	index := 0
+	for _, id := range msg.IDs {
+		for c.txs[index] != nil {
+			index++
+		}
+		if _, ok := c.ids[id]; ok {
+			return nil, ErrShortIDCollision
+		}
+		c.ids[id] = index
+		index++
+	}
+	return c, nil
+}
+
+func (c *CompactBlock) Hash() [32]byte {
+	return c.hash
+}
+
+func (c *CompactBlock) Complete() bool {
+	return c.filled == len(c.txs)
+}
+
+// FillMempool fills in transactions whose short IDs match txs and reports
+// whether the block is complete. Two candidates with the same short ID
+// leave the slot empty so it is requested from the peer instead.
+func (c *CompactBlock) FillMempool(txs []*primitives.Transaction) bool {
+	seen := make(map[int]bool)
+	for _, tx := range txs {
+		if c.Complete() {
+			break
+		}
+		index, ok := c.ids[wire.ShortID(c.key, tx.WitnessHash())]
+		if !ok {
+			continue
+		}
+		if seen[index] {
+			if c.txs[index] != nil {
+				c.txs[index] = nil
+				c.filled--
+			}
+			continue
+		}
+		seen[index] = true
+		if c.txs[index] == nil {
+			c.txs[index] = tx
+			c.filled++
+		}
+	}
+	return c.Complete()
+}
+
+// Missing returns the indexes of the transactions still needed.
+func (c *CompactBlock) Missing() []int {
+	var missing []int
+	for i, tx := range c.txs {
+		if tx == nil {
+			missing = append(missing, i)
+		}
+	}
+	return missing
+}
+
+// FillMissing fills in the transactions from a blocktxn response to the
+// request returned by Missing.
+func (c *CompactBlock) FillMissing(msg *wire.BlockTxnMessage) error {
+	if msg.Hash != c.hash {
+		return ErrInvalidBlockTxn
+	}
+	missing := c.Missing()
+	if len(msg.Txs) != len(missing) {
+		return ErrInvalidBlockTxn
+	}
+	for i, index := range missing {
+		if msg.Txs[i] == nil {
+			return ErrInvalidBlockTxn
+		}
+		c.txs[index] = msg.Txs[i]
+		c.filled++
+	}
+	return nil
+}
+
+// Block assembles the full block, checking the transactions against the
+// header's merkle and witness roots.
+func (c *CompactBlock) Block() (*primitives.Block, error) {
+	if !c.Complete() {
+		return nil, ErrIncompleteBlock
+	}
+	block := *c.header
+	block.Transactions = make([]*primitives.Transaction, len(c.txs))
+	copy(block.Transactions, c.txs)
+	if err := checkBlockRoots(&block); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// ReconstructBlock turns a compact block sent by the peer into a full
+// block. Transactions are taken from mempool where possible and the rest
+// are requested with getblocktxn. If the block still cannot be rebuilt,
+// for example because of a short ID collision, the full block is fetched
+// instead. mempool may be nil.
+func (p *Peer) ReconstructBlock(ctx context.Context, msg *wire.CmpctBlockMessage, mempool Mempool) (*primitives.Block, error) {
+	c, err := NewCompactBlock(msg)
+	if errors.Is(err, ErrShortIDCollision) {
+		return p.getFullBlock(ctx, msg.Header)
+	}
+	if err != nil {
+		p.Misbehave(banScoreInvalidCompact, err.Error())
+		return nil, err
+	}
+	if mempool != nil {
+		c.FillMempool(mempool.Transactions())
+	}
+	if !c.Complete() {
+		if err := p.getBlockTxn(ctx, c); err != nil {
+			return nil, err
+		}
+	}
+	block, err := c.Block()
+	if errors.Is(err, ErrBadReconstruction) {
+		return p.getFullBlock(ctx, msg.Header)
+	}
+	return block, err
+}
+
+func (p *Peer) getBlockTxn(ctx context.Context, c *CompactBlock) error {
+	ctx, cancel := context.WithTimeout(ctx, p.requestTimeout)
+	defer cancel()
+	hash := c.Hash()
+	res, err := p.Request(ctx, &wire.GetBlockTxnMessage{
+		Hash:    hash,
+		Indexes: c.Missing(),
+	}, func(msg wire.Message) bool {
+		blockTxn, ok := msg.(*wire.BlockTxnMessage)
+		return ok && blockTxn.Hash == hash
+	})
+	if err != nil {
+		return err
+	}
+	if err := c.FillMissing(res.(*wire.BlockTxnMessage)); err != nil {
+		p.Misbehave(banScoreInvalidCompact, err.Error())
+		return err
+	}
+	return nil
+}
+
+func (p *Peer) getFullBlock(ctx context.Context, header *primitives.Block) (*primitives.Block, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.requestTimeout)
+	defer cancel()
+	item := &wire.InvItem{Type: wire.InvTypeBlock}
+	copy(item.Hash[:], header.Hash())
+	res, err := p.Request(ctx, &wire.GetDataMessage{
+		Items: []*wire.InvItem{item},
+	}, func(msg wire.Message) bool {
+		return matchesItem(msg, item)
+	})
+	if err != nil {
+		return nil, err
+	}
+	blockMsg, ok := res.(*wire.BlockMessage)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if err := checkBlockRoots(blockMsg.Block); err != nil {
+		p.Misbehave(banScoreInvalidCompact, err.Error())
+		return nil, err
+	}
+	return blockMsg.Block, nil
+}
+
+func checkBlockRoots(block *primitives.Block) error {
+	if block.ComputeMerkleRoot() != block.MerkleRoot {
+		return fmt.Errorf("%w: bad merkle root", ErrBadReconstruction)
+	}
+	if block.ComputeWitnessRoot() != block.WitnessRoot {
+		return fmt.Errorf("%w: bad witness root", ErrBadReconstruction)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package p2p
+
+import (
+	"context"
+	"errors"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/mslipper/handshake/primitives/primitivestest"
+	"github.com/mslipper/handshake/wire"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type testMempool []*primitives.Transaction
+
+func (m testMempool) Transactions() []*primitives.Transaction {
+	return m
+}
+
+// serveCompact answers getblocktxn and getdata for block, passing each
+// request on to the given channels.
+func serveCompact(remote *Peer, block *primitives.Block, blockTxn chan *wire.GetBlockTxnMessage, getData chan *wire.GetDataMessage) {
+	for msg := range remote.Messages() {
+		switch m := msg.(type) {
+		case *wire.GetBlockTxnMessage:
+			blockTxn <- m
+			res, err := wire.NewBlockTxnMessage(block, m)
+			if err != nil {
+				continue
+			}
+			_ = remote.Send(res)
+		case *wire.GetDataMessage:
+			getData <- m
+			_ = remote.Send(&wire.BlockMessage{Block: block})
+		}
+	}
+}
+
+func TestCompactBlock_Fill(t *testing.T) {
+	block := primitivestest.LoadBlock(t, primitivestest.GoldenBlock)
+	msg := wire.NewCmpctBlockMessage(block, [8]byte{0x01})
+
+	c, err := NewCompactBlock(msg)
+	require.NoError(t, err)
+	require.False(t, c.Complete())
+	require.Len(t, c.Missing(), len(block.Transactions)-1)
+	_, err = c.Block()
+	require.Equal(t, ErrIncompleteBlock, err)
+
+	// Everything but the last two transactions is in the mempool, along
+	// with an unrelated one.
+	mempool := append([]*primitives.Transaction{}, block.Transactions[1:len(block.Transactions)-2]...)
+	unrelated := *block.Transactions[1]
+	unrelated.Locktime++
+	mempool = append(mempool, &unrelated)
+	require.False(t, c.FillMempool(mempool))
+	missing := c.Missing()
+	require.Equal(t, []int{len(block.Transactions) - 2, len(block.Transactions) - 1}, missing)
+
+	var hash [32]byte
+	copy(hash[:], block.Hash())
+	require.Equal(t, ErrInvalidBlockTxn, c.FillMissing(&wire.BlockTxnMessage{Hash: hash}))
+	require.NoError(t, c.FillMissing(&wire.BlockTxnMessage{
+		Hash: hash,
+		Txs:  block.Transactions[len(block.Transactions)-2:],
+	}))
+	res, err := c.Block()
+	require.NoError(t, err)
+	require.Equal(t, block.Hash(), res.Hash())
+	require.Equal(t, block.Transactions, res.Transactions)
+}
+
+func TestNewCompactBlock_Errors(t *testing.T) {
+	block := primitivestest.LoadBlock(t, primitivestest.GoldenBlock)
+	tests := []struct {
+		name   string
+		mutate func(msg *wire.CmpctBlockMessage)
+		err    error
+	}{
+		{
+			"empty",
+			func(msg *wire.CmpctBlockMessage) {
+				msg.IDs = nil
+				msg.Prefilled = nil
+			},
+			ErrInvalidCompact,
+		},
+		{
+			"prefilled out of range",
+			func(msg *wire.CmpctBlockMessage) {
+				msg.Prefilled[0].Index = len(block.Transactions)
+			},
+			ErrInvalidCompact,
+		},
+		{
+			"duplicate short id",
+			func(msg *wire.CmpctBlockMessage) {
+				msg.IDs[1] = msg.IDs[0]
+			},
+			ErrShortIDCollision,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := wire.NewCmpctBlockMessage(block, [8]byte{})
+			tt.mutate(msg)
+			_, err := NewCompactBlock(msg)
+			require.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func TestPeer_ReconstructBlock(t *testing.T) {
+	block := primitivestest.LoadBlock(t, primitivestest.GoldenBlock)
+	local, remote := peerPair(t, []PeerOpt{WithRequestTimeout(time.Second)}, nil)
+	defer local.Close()
+	defer remote.Close()
+	blockTxn := make(chan *wire.GetBlockTxnMessage, 4)
+	getData := make(chan *wire.GetDataMessage, 4)
+	go serveCompact(remote, block, blockTxn, getData)
+
+	// A full mempool needs no round trip.
+	msg := wire.NewCmpctBlockMessage(block, [8]byte{0x01})
+	res, err := local.ReconstructBlock(context.Background(), msg, testMempool(block.Transactions[1:]))
+	require.NoError(t, err)
+	require.Equal(t, block.Transactions, res.Transactions)
+
+	// Missing transactions are fetched with getblocktxn.
+	res, err = local.ReconstructBlock(context.Background(), msg, testMempool(block.Transactions[2:]))
+	require.NoError(t, err)
+	require.Equal(t, block.Transactions, res.Transactions)
+	require.Equal(t, []int{1}, (<-blockTxn).Indexes)
+
+	// A mempool transaction that collides with a short ID yields a bad
+	// reconstruction, so the full block is fetched.
+	forged := wire.NewCmpctBlockMessage(block, [8]byte{0x02})
+	wrong := *block.Transactions[2]
+	wrong.Locktime++
+	forged.IDs[1] = wire.ShortID(forged.SipKey(), wrong.WitnessHash())
+	res, err = local.ReconstructBlock(context.Background(), forged, testMempool(append([]*primitives.Transaction{&wrong}, block.Transactions[1:]...)))
+	require.NoError(t, err)
+	require.Equal(t, block.Transactions, res.Transactions)
+	require.Equal(t, wire.InvTypeBlock, (<-getData).Items[0].Type)
+
+	// So are blocks whose short IDs collide with each other.
+	forged = wire.NewCmpctBlockMessage(block, [8]byte{0x03})
+	forged.IDs[1] = forged.IDs[0]
+	res, err = local.ReconstructBlock(context.Background(), forged, nil)
+	require.NoError(t, err)
+	require.Equal(t, block.Hash(), res.Hash())
+	<-getData
+	require.False(t, local.Banned())
+}
+
+func TestPeer_ReconstructBlockInvalidBlockTxn(t *testing.T) {
+	block := primitivestest.LoadBlock(t, primitivestest.GoldenBlock)
+	local, remote := peerPair(t, []PeerOpt{WithRequestTimeout(time.Second)}, nil)
+	defer local.Close()
+	defer remote.Close()
+	go func() {
+		for msg := range remote.Messages() {
+			if m, ok := msg.(*wire.GetBlockTxnMessage); ok {
+				_ = remote.Send(&wire.BlockTxnMessage{Hash: m.Hash})
+			}
+		}
+	}()
+
+	msg := wire.NewCmpctBlockMessage(block, [8]byte{})
+	_, err := local.ReconstructBlock(context.Background(), msg, nil)
+	require.True(t, errors.Is(err, ErrInvalidBlockTxn))
+	require.True(t, local.Banned())
+}
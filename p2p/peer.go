@@ -0,0 +1,818 @@
+package p2p
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/mslipper/handshake/wire"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultUserAgent        = "/handshake-go:0.1.0/"
+	DefaultHandshakeTimeout = 10 * time.Second
+	DefaultPingInterval     = 30 * time.Second
+	DefaultPingTimeout      = 2 * time.Minute
+	DefaultWriteTimeout     = 30 * time.Second
+	DefaultRequestTimeout   = 10 * time.Second
+	DefaultBanThreshold     = 100
+	DefaultMessageBuffer    = 32
+	sendQueueSize           = 64
+)
+
+const (
+	banScoreMalformed  = 10
+	banScoreUnexpected = 1
+)
+
+var (
+	ErrPeerClosed       = errors.New("peer closed")
+	ErrHandshakeTimeout = errors.New("handshake timed out")
+	ErrPingTimeout      = errors.New("peer did not respond to ping")
+	ErrSelfConnection   = errors.New("connected to self")
+	ErrObsoleteVersion  = errors.New("peer protocol version is obsolete")
+	ErrMissingServices  = errors.New("peer does not offer required services")
+	ErrBanned           = errors.New("peer banned")
+)
+
+type PeerOpt func(p *Peer)
+
+func WithServices(services uint32) PeerOpt {
+	return func(p *Peer) {
+		p.services = services
+	}
+}
+
+// WithRequiredServices disconnects outbound peers that do not advertise
+// all of the given services.
+func WithRequiredServices(services uint32) PeerOpt {
+	return func(p *Peer) {
+		p.requiredServices = services
+	}
+}
+
+func WithUserAgent(agent string) PeerOpt {
+	return func(p *Peer) {
+		p.agent = agent
+	}
+}
+
+// WithHeight sets the function used to report our best height in the
+// version message.
+func WithHeight(height func() uint32) PeerOpt {
+	return func(p *Peer) {
+		p.height = height
+	}
+}
+
+// WithNoRelay asks the remote not to announce transactions until a filter
+// is loaded.
+func WithNoRelay() PeerOpt {
+	return func(p *Peer) {
+		p.noRelay = true
+	}
+}
+
+// WithPreferHeaders sends sendheaders once the handshake completes, so
+// that new blocks are announced with headers rather than inv.
+func WithPreferHeaders() PeerOpt {
+	return func(p *Peer) {
+		p.preferHeaders = true
+	}
+}
+
+// WithCompactBlocks sends sendcmpct with the given mode once the handshake
+// completes. Mode 1 requests high-bandwidth relay.
+func WithCompactBlocks(mode uint8) PeerOpt {
+	return func(p *Peer) {
+		p.compact = true
+		p.compactMode = mode
+	}
+}
+
+func WithHandshakeTimeout(timeout time.Duration) PeerOpt {
+	return func(p *Peer) {
+		p.handshakeTimeout = timeout
+	}
+}
+
+func WithPingInterval(interval time.Duration, timeout time.Duration) PeerOpt {
+	return func(p *Peer) {
+		p.pingInterval = interval
+		p.pingTimeout = timeout
+	}
+}
+
+func WithWriteTimeout(timeout time.Duration) PeerOpt {
+	return func(p *Peer) {
+		p.writeTimeout = timeout
+	}
+}
+
+// WithRequestTimeout bounds how long high-level requests such as
+// GetNameProof wait for a response.
+func WithRequestTimeout(timeout time.Duration) PeerOpt {
+	return func(p *Peer) {
+		p.requestTimeout = timeout
+	}
+}
+
+func WithBanThreshold(threshold int) PeerOpt {
+	return func(p *Peer) {
+		p.banThreshold = threshold
+	}
+}
+
+func WithMessageBuffer(size int) PeerOpt {
+	return func(p *Peer) {
+		p.bufferSize = size
+	}
+}
+
+// WithNonceCheck installs a function reporting whether a version nonce
+// belongs to one of our own connections, to detect connecting to self
+// across a pool of peers.
+func WithNonceCheck(isLocal func(nonce [8]byte) bool) PeerOpt {
+	return func(p *Peer) {
+		p.isLocalNonce = isLocal
+	}
+}
+
+// Peer speaks the Handshake P2P protocol over a single connection. It
+// handles the version handshake, keepalive and feature negotiation itself
+// and delivers every other message on typed channels.
+//
+// Every channel must be drained: a full channel stalls the peer's read
+// loop. Channels are closed once the peer disconnects.
+type Peer struct {
+	conn     net.Conn
+	network  primitives.Network
+	magic    uint32
+	outbound bool
+	nonce    [8]byte
+
+	services         uint32
+	requiredServices uint32
+	agent            string
+	height           func() uint32
+	noRelay          bool
+	preferHeaders    bool
+	compact          bool
+	compactMode      uint8
+	handshakeTimeout time.Duration
+	pingInterval     time.Duration
+	pingTimeout      time.Duration
+	writeTimeout     time.Duration
+	requestTimeout   time.Duration
+	banThreshold     int
+	bufferSize       int
+	isLocalNonce     func(nonce [8]byte) bool
+
+	sendQueue chan outMessage
+
+	mtx               sync.Mutex
+	remoteVersion     *wire.VersionMessage
+	verackRecv        bool
+	handshakeDone     chan struct{}
+	remotePrefHeaders bool
+	remoteCompact     *wire.SendCmpctMessage
+	feeRate           int64
+	pingNonce         [8]byte
+	pingSent          time.Time
+	latency           time.Duration
+	minLatency        time.Duration
+	banScore          int
+	lastRecv          time.Time
+	startOnce         sync.Once
+	disconnectOnce    sync.Once
+	err               error
+	done              chan struct{}
+	inv               chan *wire.InvMessage
+	headers           chan *wire.HeadersMessage
+	blocks            chan *wire.BlockMessage
+	txs               chan *wire.TxMessage
+	addrs             chan *wire.AddrMessage
+	notFound          chan *wire.NotFoundMessage
+	rejects           chan *wire.RejectMessage
+	messages          chan wire.Message
+	waiters           []*waiter
+}
+
+type waiter struct {
+	match func(msg wire.Message) bool
+	ch    chan wire.Message
+}
+
+func NewPeer(conn net.Conn, network primitives.Network, outbound bool, opts ...PeerOpt) *Peer {
+	p := &Peer{
+		conn:             conn,
+		network:          network,
+		magic:            network.Magic(),
+		outbound:         outbound,
+		agent:            DefaultUserAgent,
+		height:           func() uint32 { return 0 },
+		handshakeTimeout: DefaultHandshakeTimeout,
+		pingInterval:     DefaultPingInterval,
+		pingTimeout:      DefaultPingTimeout,
+		writeTimeout:     DefaultWriteTimeout,
+		requestTimeout:   DefaultRequestTimeout,
+		banThreshold:     DefaultBanThreshold,
+		bufferSize:       DefaultMessageBuffer,
+		handshakeDone:    make(chan struct{}),
+		done:             make(chan struct{}),
+		sendQueue:        make(chan outMessage, sendQueueSize),
+	}
+	if _, err := rand.Read(p.nonce[:]); err != nil {
+		panic(err)
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.inv = make(chan *wire.InvMessage, p.bufferSize)
+	p.headers = make(chan *wire.HeadersMessage, p.bufferSize)
+	p.blocks = make(chan *wire.BlockMessage, p.bufferSize)
+	p.txs = make(chan *wire.TxMessage, p.bufferSize)
+	p.addrs = make(chan *wire.AddrMessage, p.bufferSize)
+	p.notFound = make(chan *wire.NotFoundMessage, p.bufferSize)
+	p.rejects = make(chan *wire.RejectMessage, p.bufferSize)
+	p.messages = make(chan wire.Message, p.bufferSize)
+	return p
+}
+
+// Start sends our version and blocks until the handshake completes, the
+// handshake timeout elapses or ctx is cancelled. The peer is disconnected
+// if the handshake fails.
+func (p *Peer) Start(ctx context.Context) error {
+	started := false
+	p.startOnce.Do(func() {
+		started = true
+	})
+	if !started {
+		return errors.New("peer already started")
+	}
+
+	// Our version is queued before the read loop starts so that it always
+	// precedes our verack.
+	go p.writeLoop()
+	p.queue(p.localVersion())
+	go p.readLoop()
+
+	timer := time.NewTimer(p.handshakeTimeout)
+	defer timer.Stop()
+	select {
+	case <-p.handshakeDone:
+	case <-p.done:
+		return p.Err()
+	case <-timer.C:
+		p.Disconnect(ErrHandshakeTimeout)
+		return ErrHandshakeTimeout
+	case <-ctx.Done():
+		p.Disconnect(ctx.Err())
+		return ctx.Err()
+	}
+
+	if p.preferHeaders {
+		if err := p.Send(new(wire.SendHeadersMessage)); err != nil {
+			p.Disconnect(err)
+			return err
+		}
+	}
+	if p.compact {
+		err := p.Send(&wire.SendCmpctMessage{
+			Mode:    p.compactMode,
+			Version: 1,
+		})
+		if err != nil {
+			p.Disconnect(err)
+			return err
+		}
+	}
+	go p.pingLoop()
+	return nil
+}
+
+func (p *Peer) localVersion() *wire.VersionMessage {
+	remote := new(wire.NetAddress)
+	if addr, ok := p.conn.RemoteAddr().(*net.TCPAddr); ok {
+		remote.IP = addr.IP
+		remote.Port = uint16(addr.Port)
+	}
+	return &wire.VersionMessage{
+		Version:  wire.ProtocolVersion,
+		Services: p.services,
+		Time:     uint64(time.Now().Unix()),
+		Remote:   remote,
+		Nonce:    p.nonce,
+		Agent:    p.agent,
+		Height:   p.height(),
+		NoRelay:  p.noRelay,
+	}
+}
+
+type outMessage struct {
+	msg   wire.Message
+	errCh chan error
+}
+
+// Send writes a single message to the peer, waiting until it has been
+// written.
+func (p *Peer) Send(msg wire.Message) error {
+	errCh := make(chan error, 1)
+	select {
+	case p.sendQueue <- outMessage{msg, errCh}:
+	case <-p.done:
+		return p.Err()
+	}
+	select {
+	case err := <-errCh:
+		return err
+	case <-p.done:
+		return p.Err()
+	}
+}
+
+// queue sends a message without waiting for it to be written. The read
+// loop replies this way so that it never blocks on a slow writer.
+func (p *Peer) queue(msg wire.Message) {
+	select {
+	case p.sendQueue <- outMessage{msg: msg}:
+	case <-p.done:
+	}
+}
+
+func (p *Peer) writeLoop() {
+	for {
+		var out outMessage
+		select {
+		case out = <-p.sendQueue:
+		case <-p.done:
+			return
+		}
+		var err error
+		if p.writeTimeout > 0 {
+			err = p.conn.SetWriteDeadline(time.Now().Add(p.writeTimeout))
+		}
+		if err == nil {
+			err = wire.WriteMessage(p.conn, p.magic, out.msg)
+		}
+		if out.errCh != nil {
+			out.errCh <- err
+		}
+		if err != nil {
+			p.Disconnect(err)
+			return
+		}
+	}
+}
+
+// Disconnect closes the connection, recording err as the reason. Only the
+// first reason is kept.
+func (p *Peer) Disconnect(err error) {
+	p.disconnectOnce.Do(func() {
+		p.mtx.Lock()
+		p.err = err
+		p.mtx.Unlock()
+		close(p.done)
+		p.conn.Close()
+	})
+}
+
+func (p *Peer) Close() error {
+	p.Disconnect(ErrPeerClosed)
+	return nil
+}
+
+func (p *Peer) Done() <-chan struct{} {
+	return p.done
+}
+
+// Err returns the reason the peer disconnected, or nil if it is still
+// connected.
+func (p *Peer) Err() error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.err
+}
+
+// Misbehave adds to the peer's ban score, disconnecting it with ErrBanned
+// once the threshold is reached. It reports whether the peer was banned.
+func (p *Peer) Misbehave(score int, reason string) bool {
+	p.mtx.Lock()
+	p.banScore += score
+	banned := p.banScore >= p.banThreshold
+	p.mtx.Unlock()
+	if banned {
+		p.Disconnect(fmt.Errorf("%w: %s", ErrBanned, reason))
+	}
+	return banned
+}
+
+func (p *Peer) BanScore() int {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.banScore
+}
+
+func (p *Peer) Banned() bool {
+	return errors.Is(p.Err(), ErrBanned)
+}
+
+func (p *Peer) Network() primitives.Network {
+	return p.network
+}
+
+func (p *Peer) Outbound() bool {
+	return p.outbound
+}
+
+func (p *Peer) Nonce() [8]byte {
+	return p.nonce
+}
+
+func (p *Peer) Addr() string {
+	return p.conn.RemoteAddr().String()
+}
+
+// Version returns the remote's version message, or nil before it has been
+// received.
+func (p *Peer) Version() *wire.VersionMessage {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.remoteVersion
+}
+
+func (p *Peer) Services() uint32 {
+	version := p.Version()
+	if version == nil {
+		return 0
+	}
+	return version.Services
+}
+
+func (p *Peer) HasServices(services uint32) bool {
+	return p.Services()&services == services
+}
+
+// PrefersHeaders reports whether the remote asked to be sent headers
+// instead of block invs.
+func (p *Peer) PrefersHeaders() bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.remotePrefHeaders
+}
+
+// CompactMode returns the remote's sendcmpct settings, or nil if it has
+// not asked for compact blocks.
+func (p *Peer) CompactMode() *wire.SendCmpctMessage {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.remoteCompact
+}
+
+func (p *Peer) FeeRate() int64 {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.feeRate
+}
+
+// Latency returns the round trip time of the last answered ping, and the
+// lowest seen so far.
+func (p *Peer) Latency() (time.Duration, time.Duration) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.latency, p.minLatency
+}
+
+func (p *Peer) LastRecv() time.Time {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.lastRecv
+}
+
+func (p *Peer) Inv() <-chan *wire.InvMessage {
+	return p.inv
+}
+
+func (p *Peer) Headers() <-chan *wire.HeadersMessage {
+	return p.headers
+}
+
+func (p *Peer) Blocks() <-chan *wire.BlockMessage {
+	return p.blocks
+}
+
+func (p *Peer) Txs() <-chan *wire.TxMessage {
+	return p.txs
+}
+
+func (p *Peer) Addrs() <-chan *wire.AddrMessage {
+	return p.addrs
+}
+
+func (p *Peer) NotFound() <-chan *wire.NotFoundMessage {
+	return p.notFound
+}
+
+func (p *Peer) Rejects() <-chan *wire.RejectMessage {
+	return p.rejects
+}
+
+// Messages delivers every message without a dedicated channel that the
+// peer does not handle itself.
+func (p *Peer) Messages() <-chan wire.Message {
+	return p.messages
+}
+
+// Request sends msg and waits for the first incoming message accepted by
+// match. The matched message is returned here instead of being delivered
+// on a channel.
+func (p *Peer) Request(ctx context.Context, msg wire.Message, match func(msg wire.Message) bool) (wire.Message, error) {
+	w := &waiter{
+		match: match,
+		ch:    make(chan wire.Message, 1),
+	}
+	p.mtx.Lock()
+	p.waiters = append(p.waiters, w)
+	p.mtx.Unlock()
+	defer p.removeWaiter(w)
+
+	if err := p.Send(msg); err != nil {
+		return nil, err
+	}
+	select {
+	case res := <-w.ch:
+		return res, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.done:
+		return nil, p.Err()
+	}
+}
+
+func (p *Peer) removeWaiter(w *waiter) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	for i, other := range p.waiters {
+		if other == w {
+			p.waiters = append(p.waiters[:i], p.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// takeWaiter hands msg to the oldest waiter that matches it, reporting
+// whether one did.
+func (p *Peer) takeWaiter(msg wire.Message) bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	for i, w := range p.waiters {
+		if !w.match(msg) {
+			continue
+		}
+		p.waiters = append(p.waiters[:i], p.waiters[i+1:]...)
+		w.ch <- msg
+		return true
+	}
+	return false
+}
+
+// Ping sends a ping immediately. Latency is updated when the matching pong
+// arrives.
+func (p *Peer) Ping() error {
+	var nonce [8]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return err
+	}
+	p.mtx.Lock()
+	p.pingNonce = nonce
+	p.pingSent = time.Now()
+	p.mtx.Unlock()
+	return p.Send(&wire.PingMessage{Nonce: nonce})
+}
+
+func (p *Peer) pingLoop() {
+	ticker := time.NewTicker(p.pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-p.done:
+			return
+		}
+		p.mtx.Lock()
+		outstanding := !p.pingSent.IsZero()
+		stalled := outstanding && time.Since(p.pingSent) > p.pingTimeout
+		p.mtx.Unlock()
+		if stalled {
+			p.Disconnect(ErrPingTimeout)
+			return
+		}
+		if outstanding {
+			continue
+		}
+		if err := p.Ping(); err != nil {
+			return
+		}
+	}
+}
+
+func (p *Peer) readLoop() {
+	defer func() {
+		close(p.inv)
+		close(p.headers)
+		close(p.blocks)
+		close(p.txs)
+		close(p.addrs)
+		close(p.notFound)
+		close(p.rejects)
+		close(p.messages)
+	}()
+	for {
+		msg, err := wire.ReadMessage(p.conn, p.magic)
+		if err != nil {
+			var decodeErr *wire.DecodeError
+			switch {
+			case errors.As(err, &decodeErr):
+				if p.Misbehave(banScoreMalformed, decodeErr.Error()) {
+					return
+				}
+				continue
+			case errors.Is(err, wire.ErrMessageTooLarge):
+				p.Misbehave(p.banThreshold, err.Error())
+			default:
+				p.Disconnect(err)
+			}
+			return
+		}
+		p.mtx.Lock()
+		p.lastRecv = time.Now()
+		p.mtx.Unlock()
+		if err := p.handleMessage(msg); err != nil {
+			p.Disconnect(err)
+			return
+		}
+	}
+}
+
+func (p *Peer) handleMessage(msg wire.Message) error {
+	switch m := msg.(type) {
+	case *wire.VersionMessage:
+		return p.handleVersion(m)
+	case *wire.VerackMessage:
+		return p.handleVerack()
+	}
+
+	select {
+	case <-p.handshakeDone:
+	default:
+		p.Misbehave(banScoreUnexpected, fmt.Sprintf("%s before handshake", msg.Type()))
+		return nil
+	}
+
+	switch m := msg.(type) {
+	case *wire.PingMessage:
+		p.queue(&wire.PongMessage{Nonce: m.Nonce})
+		return nil
+	case *wire.PongMessage:
+		p.handlePong(m)
+		return nil
+	case *wire.SendHeadersMessage:
+		p.mtx.Lock()
+		p.remotePrefHeaders = true
+		p.mtx.Unlock()
+		return nil
+	case *wire.SendCmpctMessage:
+		p.mtx.Lock()
+		p.remoteCompact = m
+		p.mtx.Unlock()
+		return nil
+	case *wire.FeeFilterMessage:
+		p.mtx.Lock()
+		p.feeRate = m.Rate
+		p.mtx.Unlock()
+		return nil
+	}
+
+	if p.takeWaiter(msg) {
+		return nil
+	}
+
+	switch m := msg.(type) {
+	case *wire.InvMessage:
+		select {
+		case p.inv <- m:
+		case <-p.done:
+		}
+	case *wire.HeadersMessage:
+		select {
+		case p.headers <- m:
+		case <-p.done:
+		}
+	case *wire.BlockMessage:
+		select {
+		case p.blocks <- m:
+		case <-p.done:
+		}
+	case *wire.TxMessage:
+		select {
+		case p.txs <- m:
+		case <-p.done:
+		}
+	case *wire.AddrMessage:
+		select {
+		case p.addrs <- m:
+		case <-p.done:
+		}
+	case *wire.NotFoundMessage:
+		select {
+		case p.notFound <- m:
+		case <-p.done:
+		}
+	case *wire.RejectMessage:
+		select {
+		case p.rejects <- m:
+		case <-p.done:
+		}
+	default:
+		select {
+		case p.messages <- msg:
+		case <-p.done:
+		}
+	}
+	return nil
+}
+
+func (p *Peer) handleVersion(m *wire.VersionMessage) error {
+	p.mtx.Lock()
+	duplicate := p.remoteVersion != nil
+	p.mtx.Unlock()
+	if duplicate {
+		p.Misbehave(banScoreUnexpected, "duplicate version")
+		return nil
+	}
+	if m.Nonce == p.nonce || (p.isLocalNonce != nil && p.isLocalNonce(m.Nonce)) {
+		return ErrSelfConnection
+	}
+	if m.Version < wire.MinProtocolVersion {
+		return ErrObsoleteVersion
+	}
+	if p.outbound && m.Services&p.requiredServices != p.requiredServices {
+		return ErrMissingServices
+	}
+	p.mtx.Lock()
+	p.remoteVersion = m
+	p.mtx.Unlock()
+	p.queue(new(wire.VerackMessage))
+	p.maybeFinishHandshake()
+	return nil
+}
+
+func (p *Peer) handleVerack() error {
+	p.mtx.Lock()
+	duplicate := p.verackRecv
+	p.verackRecv = true
+	p.mtx.Unlock()
+	if duplicate {
+		p.Misbehave(banScoreUnexpected, "duplicate verack")
+		return nil
+	}
+	p.maybeFinishHandshake()
+	return nil
+}
+
+func (p *Peer) maybeFinishHandshake() {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.remoteVersion == nil || !p.verackRecv {
+		return
+	}
+	select {
+	case <-p.handshakeDone:
+	default:
+		close(p.handshakeDone)
+	}
+}
+
+func (p *Peer) handlePong(m *wire.PongMessage) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.pingSent.IsZero() || m.Nonce != p.pingNonce {
+		return
+	}
+	p.latency = time.Since(p.pingSent)
+	if p.minLatency == 0 || p.latency < p.minLatency {
+		p.minLatency = p.latency
+	}
+	p.pingSent = time.Time{}
+}
+
+func (p *Peer) String() string {
+	if p.outbound {
+		return "outbound peer " + p.Addr()
+	}
+	return "inbound peer " + p.Addr()
+}
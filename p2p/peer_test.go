@@ -0,0 +1,296 @@
+package p2p
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/mslipper/handshake/wire"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+	"time"
+)
+
+var testMagic = primitives.NetworkRegtest.Magic()
+
+func peerPair(t *testing.T, localOpts []PeerOpt, remoteOpts []PeerOpt) (*Peer, *Peer) {
+	localConn, remoteConn := net.Pipe()
+	local := NewPeer(localConn, primitives.NetworkRegtest, true, localOpts...)
+	remote := NewPeer(remoteConn, primitives.NetworkRegtest, false, remoteOpts...)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- remote.Start(context.Background())
+	}()
+	require.NoError(t, local.Start(context.Background()))
+	require.NoError(t, <-errCh)
+	return local, remote
+}
+
+// rawPeer starts a peer against a connection driven by hand, returning the
+// raw end after the handshake has been completed on it.
+func rawPeer(t *testing.T, opts ...PeerOpt) (*Peer, net.Conn) {
+	localConn, rawConn := net.Pipe()
+	local := NewPeer(localConn, primitives.NetworkRegtest, true, opts...)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- local.Start(context.Background())
+	}()
+	msg, err := wire.ReadMessage(rawConn, testMagic)
+	require.NoError(t, err)
+	require.IsType(t, new(wire.VersionMessage), msg)
+	require.NoError(t, wire.WriteMessage(rawConn, testMagic, &wire.VersionMessage{
+		Version:  wire.ProtocolVersion,
+		Services: wire.ServiceNetwork,
+		Agent:    "/raw/",
+	}))
+	msg, err = wire.ReadMessage(rawConn, testMagic)
+	require.NoError(t, err)
+	require.IsType(t, new(wire.VerackMessage), msg)
+	require.NoError(t, wire.WriteMessage(rawConn, testMagic, new(wire.VerackMessage)))
+	require.NoError(t, <-errCh)
+	return local, rawConn
+}
+
+func TestPeer_Handshake(t *testing.T) {
+	local, remote := peerPair(t, []PeerOpt{
+		WithServices(wire.ServiceBloom),
+		WithUserAgent("/local/"),
+		WithHeight(func() uint32 { return 42 }),
+		WithPreferHeaders(),
+		WithCompactBlocks(1),
+		WithNoRelay(),
+	}, []PeerOpt{
+		WithServices(wire.ServiceNetwork),
+	})
+	defer local.Close()
+	defer remote.Close()
+
+	require.True(t, local.HasServices(wire.ServiceNetwork))
+	require.True(t, remote.HasServices(wire.ServiceBloom))
+	require.Equal(t, "/local/", remote.Version().Agent)
+	require.EqualValues(t, 42, remote.Version().Height)
+	require.True(t, remote.Version().NoRelay)
+	require.Equal(t, DefaultUserAgent, local.Version().Agent)
+
+	require.Eventually(t, func() bool {
+		return remote.PrefersHeaders() && remote.CompactMode() != nil
+	}, time.Second, time.Millisecond)
+	require.EqualValues(t, 1, remote.CompactMode().Mode)
+	require.False(t, local.PrefersHeaders())
+	require.Nil(t, local.CompactMode())
+}
+
+func TestPeer_PingPong(t *testing.T) {
+	local, remote := peerPair(t, nil, nil)
+	defer local.Close()
+	defer remote.Close()
+
+	require.NoError(t, local.Ping())
+	require.Eventually(t, func() bool {
+		latency, _ := local.Latency()
+		return latency > 0
+	}, time.Second, time.Millisecond)
+	latency, minLatency := local.Latency()
+	require.Equal(t, latency, minLatency)
+}
+
+func TestPeer_PingTimeout(t *testing.T) {
+	local, raw := rawPeer(t, WithPingInterval(5*time.Millisecond, 20*time.Millisecond))
+	defer raw.Close()
+
+	// Swallow pings without answering them.
+	go func() {
+		for {
+			if _, err := wire.ReadMessage(raw, testMagic); err != nil {
+				return
+			}
+		}
+	}()
+	select {
+	case <-local.Done():
+	case <-time.After(time.Second):
+		t.Fatal("peer was not disconnected")
+	}
+	require.Equal(t, ErrPingTimeout, local.Err())
+}
+
+func TestPeer_Channels(t *testing.T) {
+	local, remote := peerPair(t, nil, nil)
+	defer remote.Close()
+
+	inv := &wire.InvMessage{
+		Items: []*wire.InvItem{
+			{Type: wire.InvTypeBlock},
+		},
+	}
+	require.NoError(t, remote.Send(inv))
+	require.NoError(t, remote.Send(&wire.HeadersMessage{}))
+	require.NoError(t, remote.Send(&wire.RejectMessage{Message: wire.MessageTypeTx, Reason: "bad"}))
+	require.NoError(t, remote.Send(&wire.GetProofMessage{}))
+	require.NoError(t, remote.Send(&wire.FeeFilterMessage{Rate: 1000}))
+
+	require.Equal(t, inv.Items[0].Type, (<-local.Inv()).Items[0].Type)
+	require.Empty(t, (<-local.Headers()).Headers)
+	require.Equal(t, "bad", (<-local.Rejects()).Reason)
+	require.IsType(t, new(wire.GetProofMessage), <-local.Messages())
+	require.Eventually(t, func() bool {
+		return local.FeeRate() == 1000
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, local.Close())
+	_, ok := <-local.Inv()
+	require.False(t, ok)
+	require.Equal(t, ErrPeerClosed, local.Err())
+	require.Error(t, local.Send(new(wire.PingMessage)))
+}
+
+func TestPeer_HandshakeFailures(t *testing.T) {
+	t.Run("timeout", func(t *testing.T) {
+		localConn, rawConn := net.Pipe()
+		defer rawConn.Close()
+		go func() {
+			_, _ = wire.ReadMessage(rawConn, testMagic)
+		}()
+		local := NewPeer(localConn, primitives.NetworkRegtest, true, WithHandshakeTimeout(20*time.Millisecond))
+		require.Equal(t, ErrHandshakeTimeout, local.Start(context.Background()))
+	})
+
+	tests := []struct {
+		name    string
+		opts    []PeerOpt
+		version func(local *Peer) *wire.VersionMessage
+		err     error
+	}{
+		{
+			"self connection",
+			nil,
+			func(local *Peer) *wire.VersionMessage {
+				return &wire.VersionMessage{Version: wire.ProtocolVersion, Nonce: local.Nonce()}
+			},
+			ErrSelfConnection,
+		},
+		{
+			"obsolete",
+			nil,
+			func(local *Peer) *wire.VersionMessage {
+				return &wire.VersionMessage{Version: 0}
+			},
+			ErrObsoleteVersion,
+		},
+		{
+			"missing services",
+			[]PeerOpt{WithRequiredServices(wire.ServiceNetwork | wire.ServiceBloom)},
+			func(local *Peer) *wire.VersionMessage {
+				return &wire.VersionMessage{Version: wire.ProtocolVersion, Services: wire.ServiceNetwork}
+			},
+			ErrMissingServices,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			localConn, rawConn := net.Pipe()
+			defer rawConn.Close()
+			local := NewPeer(localConn, primitives.NetworkRegtest, true, tt.opts...)
+			go func() {
+				_, _ = wire.ReadMessage(rawConn, testMagic)
+				_ = wire.WriteMessage(rawConn, testMagic, tt.version(local))
+			}()
+			require.Equal(t, tt.err, local.Start(context.Background()))
+			require.Equal(t, tt.err, local.Err())
+		})
+	}
+}
+
+func TestPeer_BanScore(t *testing.T) {
+	local, raw := rawPeer(t, WithBanThreshold(25))
+	defer raw.Close()
+	go func() {
+		for {
+			if _, err := wire.ReadMessage(raw, testMagic); err != nil {
+				return
+			}
+		}
+	}()
+
+	// An inv with trailing data is malformed but the stream stays in sync.
+	malformed := new(bytes.Buffer)
+	require.NoError(t, wire.WriteMessage(malformed, testMagic, new(wire.InvMessage)))
+	frame := malformed.Bytes()
+	frame[5]++
+	frame = append(frame, 0x00)
+	for i := 0; i < 2; i++ {
+		_, err := raw.Write(frame)
+		require.NoError(t, err)
+	}
+	require.NoError(t, wire.WriteMessage(raw, testMagic, new(wire.VersionMessage)))
+	require.NoError(t, wire.WriteMessage(raw, testMagic, new(wire.MempoolMessage)))
+	require.Eventually(t, func() bool {
+		return local.BanScore() == 21
+	}, time.Second, time.Millisecond)
+	require.False(t, local.Banned())
+	require.IsType(t, new(wire.MempoolMessage), <-local.Messages())
+
+	_, err := raw.Write(frame)
+	require.NoError(t, err)
+	<-local.Done()
+	require.True(t, local.Banned())
+	require.True(t, errors.Is(local.Err(), ErrBanned))
+}
+
+func TestPeer_Oversize(t *testing.T) {
+	local, raw := rawPeer(t)
+	defer raw.Close()
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, wire.WriteMessage(buf, testMagic, &wire.PingMessage{}))
+	frame := buf.Bytes()
+	frame[5] = 100
+	go func() {
+		_, _ = raw.Write(frame)
+	}()
+	<-local.Done()
+	require.True(t, local.Banned())
+	require.Equal(t, DefaultBanThreshold, local.BanScore())
+}
+
+func TestPeer_MessageBeforeHandshake(t *testing.T) {
+	localConn, rawConn := net.Pipe()
+	defer rawConn.Close()
+	local := NewPeer(localConn, primitives.NetworkRegtest, true, WithBanThreshold(1), WithHandshakeTimeout(time.Second))
+	go func() {
+		_, _ = wire.ReadMessage(rawConn, testMagic)
+		_ = wire.WriteMessage(rawConn, testMagic, new(wire.MempoolMessage))
+	}()
+	err := local.Start(context.Background())
+	require.True(t, errors.Is(err, ErrBanned))
+}
+
+func TestPeer_Request(t *testing.T) {
+	local, remote := peerPair(t, nil, nil)
+	defer local.Close()
+	defer remote.Close()
+
+	go func() {
+		for msg := range remote.Messages() {
+			if req, ok := msg.(*wire.GetProofMessage); ok {
+				_ = remote.Send(&wire.NotFoundMessage{})
+				_ = remote.Send(&wire.GetProofMessage{Root: req.Key, Key: req.Root})
+			}
+		}
+	}()
+	res, err := local.Request(context.Background(), &wire.GetProofMessage{Root: [32]byte{1}, Key: [32]byte{2}}, func(msg wire.Message) bool {
+		_, ok := msg.(*wire.GetProofMessage)
+		return ok
+	})
+	require.NoError(t, err)
+	require.Equal(t, [32]byte{2}, res.(*wire.GetProofMessage).Root)
+	require.NotNil(t, <-local.NotFound())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = local.Request(ctx, new(wire.MempoolMessage), func(msg wire.Message) bool {
+		return false
+	})
+	require.Equal(t, context.DeadlineExceeded, err)
+}
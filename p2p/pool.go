@@ -0,0 +1,772 @@
+package p2p
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/mslipper/handshake/brontide"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/mslipper/handshake/wire"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMaxOutbound    = 8
+	DefaultMaxInbound     = 8
+	DefaultStallTimeout   = 30 * time.Second
+	DefaultRejectWait     = 2 * time.Second
+	DefaultRefillInterval = 10 * time.Second
+	txRelayTime           = 10 * time.Minute
+	maxAnnounced          = 10000
+)
+
+var (
+	ErrPoolClosed = errors.New("pool closed")
+	ErrPoolFull   = errors.New("pool has no free inbound slots")
+	ErrStalled    = errors.New("peer stalled")
+	ErrNotFound   = errors.New("no peer could serve the requested data")
+)
+
+// TxRejectError is returned by BroadcastTx when a peer rejects the
+// transaction.
+type TxRejectError struct {
+	Hash   [32]byte
+	Code   uint8
+	Reason string
+}
+
+func (t *TxRejectError) Error() string {
+	return fmt.Sprintf("transaction rejected: %s (code %d)", t.Reason, t.Code)
+}
+
+// PeerMessage is a message from one of the pool's peers that the pool did
+// not handle itself.
+type PeerMessage struct {
+	Peer    *Peer
+	Message wire.Message
+}
+
+type broadcast struct {
+	tx       *primitives.Transaction
+	expires  time.Time
+	acked    chan struct{}
+	ackOnce  sync.Once
+	rejected chan struct{}
+	rejOnce  sync.Once
+	rejects  []*wire.RejectMessage
+}
+
+// Pool keeps a set of outbound connections filled from an address book,
+// accepts inbound connections and relays between its peers and callers.
+//
+// The Messages channel must be drained: a full channel stalls the peers.
+type Pool struct {
+	network        primitives.Network
+	book           *AddrBook
+	maxOutbound    int
+	maxInbound     int
+	listener       net.Listener
+	dialer         func(ctx context.Context, network, addr string) (net.Conn, error)
+	identityKey    *btcec.PrivateKey
+	peerOpts       []PeerOpt
+	stallTimeout   time.Duration
+	rejectWait     time.Duration
+	refillInterval time.Duration
+
+	ctx        context.Context
+	cancel     context.CancelFunc
+	mtx        sync.Mutex
+	peers      map[*Peer]bool
+	pending    map[string]bool
+	handshakes int
+	nonces     map[[8]byte]bool
+	inflight   map[*Peer]int
+	announced  map[wire.InvItem][]*Peer
+	broadcasts map[[32]byte]*broadcast
+	messages   chan *PeerMessage
+	refill     chan struct{}
+	startOnce  sync.Once
+	closeOnce  sync.Once
+	done       chan struct{}
+	wg         sync.WaitGroup
+}
+
+type PoolOpt func(p *Pool)
+
+func WithMaxOutbound(n int) PoolOpt {
+	return func(p *Pool) {
+		p.maxOutbound = n
+	}
+}
+
+func WithMaxInbound(n int) PoolOpt {
+	return func(p *Pool) {
+		p.maxInbound = n
+	}
+}
+
+// WithListener accepts inbound peers from l. Wrap it with
+// brontide.NewListener to accept encrypted connections.
+func WithListener(l net.Listener) PoolOpt {
+	return func(p *Pool) {
+		p.listener = l
+	}
+}
+
+func WithDialer(dialer func(ctx context.Context, network, addr string) (net.Conn, error)) PoolOpt {
+	return func(p *Pool) {
+		p.dialer = dialer
+	}
+}
+
+// WithIdentityKey makes the pool use brontide for outbound connections to
+// addresses with a known identity key.
+func WithIdentityKey(key *btcec.PrivateKey) PoolOpt {
+	return func(p *Pool) {
+		p.identityKey = key
+	}
+}
+
+// WithPeerOpts sets options applied to every peer the pool creates.
+func WithPeerOpts(opts ...PeerOpt) PoolOpt {
+	return func(p *Pool) {
+		p.peerOpts = opts
+	}
+}
+
+// WithStallTimeout sets how long a peer has to answer a getdata before it
+// is disconnected and the request moves to another peer.
+func WithStallTimeout(timeout time.Duration) PoolOpt {
+	return func(p *Pool) {
+		p.stallTimeout = timeout
+	}
+}
+
+// WithRejectWait sets how long BroadcastTx waits for a reject after a peer
+// has fetched the transaction.
+func WithRejectWait(wait time.Duration) PoolOpt {
+	return func(p *Pool) {
+		p.rejectWait = wait
+	}
+}
+
+func WithRefillInterval(interval time.Duration) PoolOpt {
+	return func(p *Pool) {
+		p.refillInterval = interval
+	}
+}
+
+func NewPool(network primitives.Network, book *AddrBook, opts ...PoolOpt) *Pool {
+	var d net.Dialer
+	p := &Pool{
+		network:        network,
+		book:           book,
+		maxOutbound:    DefaultMaxOutbound,
+		maxInbound:     DefaultMaxInbound,
+		dialer:         d.DialContext,
+		stallTimeout:   DefaultStallTimeout,
+		rejectWait:     DefaultRejectWait,
+		refillInterval: DefaultRefillInterval,
+		peers:          make(map[*Peer]bool),
+		pending:        make(map[string]bool),
+		nonces:         make(map[[8]byte]bool),
+		inflight:       make(map[*Peer]int),
+		announced:      make(map[wire.InvItem][]*Peer),
+		broadcasts:     make(map[[32]byte]*broadcast),
+		messages:       make(chan *PeerMessage, DefaultMessageBuffer),
+		refill:         make(chan struct{}, 1),
+		done:           make(chan struct{}),
+	}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Start begins accepting inbound peers and filling outbound slots. It
+// returns immediately; the pool runs until Close.
+func (p *Pool) Start() {
+	p.startOnce.Do(func() {
+		if p.listener != nil {
+			p.wg.Add(1)
+			go p.acceptLoop()
+		}
+		p.wg.Add(1)
+		go p.refillLoop()
+	})
+}
+
+// Close disconnects every peer and stops the pool.
+func (p *Pool) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.done)
+		p.cancel()
+		if p.listener != nil {
+			p.listener.Close()
+		}
+		for _, peer := range p.Peers() {
+			peer.Close()
+		}
+		p.wg.Wait()
+	})
+	return nil
+}
+
+func (p *Pool) Messages() <-chan *PeerMessage {
+	return p.messages
+}
+
+func (p *Pool) Peers() []*Peer {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	peers := make([]*Peer, 0, len(p.peers))
+	for peer := range p.peers {
+		peers = append(peers, peer)
+	}
+	return peers
+}
+
+func (p *Pool) counts() (int, int) {
+	var outbound, inbound int
+	for peer := range p.peers {
+		if peer.Outbound() {
+			outbound++
+		} else {
+			inbound++
+		}
+	}
+	return outbound, inbound
+}
+
+// Connect dials addr and adds it to the pool once the handshake completes.
+func (p *Pool) Connect(ctx context.Context, addr *wire.NetAddress) (*Peer, error) {
+	hostPort := addr.HostPort()
+	p.mtx.Lock()
+	if p.pending[hostPort] {
+		p.mtx.Unlock()
+		return nil, fmt.Errorf("already connecting to %s", hostPort)
+	}
+	p.pending[hostPort] = true
+	p.mtx.Unlock()
+	return p.dial(ctx, addr)
+}
+
+// dial connects to an address already marked as pending and clears the
+// mark once the peer has been added or the attempt has failed.
+func (p *Pool) dial(ctx context.Context, addr *wire.NetAddress) (*Peer, error) {
+	hostPort := addr.HostPort()
+	defer func() {
+		p.mtx.Lock()
+		delete(p.pending, hostPort)
+		p.mtx.Unlock()
+	}()
+
+	p.book.MarkAttempt(addr)
+	conn, err := p.dialer(ctx, "tcp", hostPort)
+	if err != nil {
+		return nil, err
+	}
+	if addr.HasKey() && p.identityKey != nil {
+		pub, err := btcec.ParsePubKey(addr.Key[:], btcec.S256())
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		bConn, err := brontide.NewClientConn(conn, p.identityKey, pub)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = bConn
+	}
+	peer, err := p.startPeer(ctx, conn, true)
+	if err != nil {
+		return nil, err
+	}
+	p.book.MarkSuccess(addr)
+	return peer, nil
+}
+
+func (p *Pool) startPeer(ctx context.Context, conn net.Conn, outbound bool) (*Peer, error) {
+	opts := append([]PeerOpt{WithNonceCheck(p.isLocalNonce)}, p.peerOpts...)
+	peer := NewPeer(conn, p.network, outbound, opts...)
+	p.mtx.Lock()
+	p.nonces[peer.Nonce()] = true
+	p.mtx.Unlock()
+	err := peer.Start(ctx)
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if !outbound {
+		p.handshakes--
+	}
+	if err != nil {
+		delete(p.nonces, peer.Nonce())
+		return nil, err
+	}
+	select {
+	case <-p.done:
+		delete(p.nonces, peer.Nonce())
+		peer.Close()
+		return nil, ErrPoolClosed
+	default:
+	}
+	if _, inbound := p.counts(); !outbound && inbound >= p.maxInbound {
+		delete(p.nonces, peer.Nonce())
+		peer.Disconnect(ErrPoolFull)
+		return nil, ErrPoolFull
+	}
+	p.peers[peer] = true
+	p.wg.Add(1)
+	go p.handlePeer(peer)
+	for hash, b := range p.broadcasts {
+		if time.Now().Before(b.expires) {
+			go peer.Send(txInv(hash))
+		}
+	}
+	return peer, nil
+}
+
+func (p *Pool) isLocalNonce(nonce [8]byte) bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.nonces[nonce]
+}
+
+func (p *Pool) acceptLoop() {
+	defer p.wg.Done()
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			select {
+			case <-p.done:
+				return
+			default:
+			}
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				continue
+			}
+			return
+		}
+		if p.book.IsBanned(remoteIP(conn.RemoteAddr().String())) {
+			conn.Close()
+			continue
+		}
+		// Connections still in their handshake hold a slot, so that a burst
+		// of them cannot exceed the limit.
+		p.mtx.Lock()
+		_, inbound := p.counts()
+		full := inbound+p.handshakes >= p.maxInbound
+		if !full {
+			p.handshakes++
+		}
+		p.mtx.Unlock()
+		if full {
+			conn.Close()
+			continue
+		}
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			_, _ = p.startPeer(p.ctx, conn, false)
+		}()
+	}
+}
+
+func (p *Pool) refillLoop() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(p.refillInterval)
+	defer ticker.Stop()
+	for {
+		p.fillOutbound()
+		select {
+		case <-ticker.C:
+		case <-p.refill:
+		case <-p.done:
+			return
+		}
+	}
+}
+
+func (p *Pool) triggerRefill() {
+	select {
+	case p.refill <- struct{}{}:
+	default:
+	}
+}
+
+// fillOutbound starts connections to addresses from the book until the
+// outbound target is met, counting connections still in progress.
+func (p *Pool) fillOutbound() {
+	if p.book.Len() == 0 {
+		ctx, cancel := context.WithTimeout(p.ctx, p.stallTimeout)
+		_, _ = p.book.Bootstrap(ctx)
+		cancel()
+	}
+	for {
+		p.mtx.Lock()
+		outbound, _ := p.counts()
+		if outbound+len(p.pending) >= p.maxOutbound {
+			p.mtx.Unlock()
+			return
+		}
+		addr := p.book.Select(p.isConnected)
+		if addr == nil {
+			p.mtx.Unlock()
+			return
+		}
+		p.pending[addr.HostPort()] = true
+		p.mtx.Unlock()
+
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			ctx, cancel := context.WithTimeout(p.ctx, p.stallTimeout)
+			defer cancel()
+			_, _ = p.dial(ctx, addr)
+		}()
+	}
+}
+
+// isConnected is called with the pool's lock held.
+func (p *Pool) isConnected(addr *wire.NetAddress) bool {
+	hostPort := addr.HostPort()
+	if p.pending[hostPort] {
+		return true
+	}
+	for peer := range p.peers {
+		if peer.Addr() == hostPort {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Pool) removePeer(peer *Peer) {
+	p.mtx.Lock()
+	delete(p.peers, peer)
+	delete(p.nonces, peer.Nonce())
+	delete(p.inflight, peer)
+	p.mtx.Unlock()
+	if peer.Banned() {
+		p.book.Ban(remoteIP(peer.Addr()), DefaultBanDuration)
+	}
+	p.triggerRefill()
+}
+
+func (p *Pool) handlePeer(peer *Peer) {
+	defer p.wg.Done()
+	defer p.removePeer(peer)
+	for {
+		// Channels are closed on disconnect, which shows up here as a
+		// nil message.
+		var msg wire.Message
+		select {
+		case m := <-peer.Inv():
+			if m == nil {
+				return
+			}
+			p.recordInv(peer, m)
+			msg = m
+		case m := <-peer.Headers():
+			if m == nil {
+				return
+			}
+			msg = m
+		case m := <-peer.Blocks():
+			if m == nil {
+				return
+			}
+			msg = m
+		case m := <-peer.Txs():
+			if m == nil {
+				return
+			}
+			msg = m
+		case m := <-peer.Addrs():
+			if m == nil {
+				return
+			}
+			p.book.AddAddrs(m.Addrs, remoteIP(peer.Addr()))
+			continue
+		case <-peer.NotFound():
+			continue
+		case m := <-peer.Rejects():
+			if m == nil {
+				return
+			}
+			p.handleReject(m)
+			msg = m
+		case m := <-peer.Messages():
+			if m == nil || p.handleRequest(peer, m) {
+				continue
+			}
+			msg = m
+		case <-peer.Done():
+			return
+		case <-p.done:
+			return
+		}
+		select {
+		case p.messages <- &PeerMessage{Peer: peer, Message: msg}:
+		case <-peer.Done():
+			return
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// handleRequest serves getdata for transactions being broadcast and
+// getaddr from the address book, reporting whether msg was handled.
+func (p *Pool) handleRequest(peer *Peer, msg wire.Message) bool {
+	switch m := msg.(type) {
+	case *wire.GetDataMessage:
+		var notFound []*wire.InvItem
+		for _, item := range m.Items {
+			p.mtx.Lock()
+			b := p.broadcasts[item.Hash]
+			p.mtx.Unlock()
+			if item.Type != wire.InvTypeTx || b == nil {
+				notFound = append(notFound, item)
+				continue
+			}
+			if err := peer.Send(&wire.TxMessage{Tx: b.tx}); err != nil {
+				return true
+			}
+			b.ackOnce.Do(func() {
+				close(b.acked)
+			})
+		}
+		if len(notFound) > 0 {
+			_ = peer.Send(&wire.NotFoundMessage{Items: notFound})
+		}
+		return true
+	case *wire.GetAddrMessage:
+		_ = peer.Send(&wire.AddrMessage{Addrs: p.book.Addresses(wire.MaxAddrs)})
+		return true
+	}
+	return false
+}
+
+func (p *Pool) handleReject(m *wire.RejectMessage) {
+	if m.Message != wire.MessageTypeTx {
+		return
+	}
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	b := p.broadcasts[m.Hash]
+	if b == nil {
+		return
+	}
+	b.rejects = append(b.rejects, m)
+	b.rejOnce.Do(func() {
+		close(b.rejected)
+	})
+}
+
+func (p *Pool) recordInv(peer *Peer, m *wire.InvMessage) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if len(p.announced) > maxAnnounced {
+		p.announced = make(map[wire.InvItem][]*Peer)
+	}
+	for _, item := range m.Items {
+		p.announced[*item] = append(p.announced[*item], peer)
+	}
+}
+
+// GetData fetches a block or transaction. Peers that announced the item
+// are asked first, then the least busy peers. A peer that does not answer
+// within the stall timeout is disconnected and the next one is asked.
+func (p *Pool) GetData(ctx context.Context, item *wire.InvItem) (wire.Message, error) {
+	for _, peer := range p.candidates(item) {
+		res, err := p.requestFrom(ctx, peer, item)
+		if err == nil {
+			p.mtx.Lock()
+			delete(p.announced, *item)
+			p.mtx.Unlock()
+			return res, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (p *Pool) candidates(item *wire.InvItem) []*Peer {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	announced := make(map[*Peer]bool)
+	for _, peer := range p.announced[*item] {
+		announced[peer] = true
+	}
+	peers := make([]*Peer, 0, len(p.peers))
+	for peer := range p.peers {
+		peers = append(peers, peer)
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		if announced[peers[i]] != announced[peers[j]] {
+			return announced[peers[i]]
+		}
+		return p.inflight[peers[i]] < p.inflight[peers[j]]
+	})
+	return peers
+}
+
+func (p *Pool) requestFrom(ctx context.Context, peer *Peer, item *wire.InvItem) (wire.Message, error) {
+	p.mtx.Lock()
+	p.inflight[peer]++
+	p.mtx.Unlock()
+	defer func() {
+		p.mtx.Lock()
+		if p.inflight[peer] > 0 {
+			p.inflight[peer]--
+		}
+		p.mtx.Unlock()
+	}()
+
+	reqCtx, cancel := context.WithTimeout(ctx, p.stallTimeout)
+	defer cancel()
+	res, err := peer.Request(reqCtx, &wire.GetDataMessage{
+		Items: []*wire.InvItem{item},
+	}, func(msg wire.Message) bool {
+		return matchesItem(msg, item)
+	})
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			peer.Disconnect(ErrStalled)
+		}
+		return nil, err
+	}
+	if _, ok := res.(*wire.NotFoundMessage); ok {
+		return nil, ErrNotFound
+	}
+	return res, nil
+}
+
+func matchesItem(msg wire.Message, item *wire.InvItem) bool {
+	switch m := msg.(type) {
+	case *wire.BlockMessage:
+		return item.Type == wire.InvTypeBlock && hashEqual(item.Hash, m.Block.Hash())
+	case *wire.CmpctBlockMessage:
+		return item.Type == wire.InvTypeCmpctBlock && hashEqual(item.Hash, m.Header.Hash())
+	case *wire.TxMessage:
+		return item.Type == wire.InvTypeTx && hashEqual(item.Hash, m.Tx.ID())
+	case *wire.NotFoundMessage:
+		for _, other := range m.Items {
+			if *other == *item {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// BroadcastTx announces tx to every peer and serves it to those that ask
+// for it. It returns once a peer has fetched the transaction and no peer
+// rejected it within the reject wait, or with a TxRejectError. The
+// transaction keeps being offered to new peers for a while afterwards.
+func (p *Pool) BroadcastTx(ctx context.Context, tx *primitives.Transaction) error {
+	var hash [32]byte
+	copy(hash[:], tx.ID())
+
+	now := time.Now()
+	p.mtx.Lock()
+	for other, b := range p.broadcasts {
+		if now.After(b.expires) {
+			delete(p.broadcasts, other)
+		}
+	}
+	b := p.broadcasts[hash]
+	if b == nil {
+		b = &broadcast{
+			tx:       tx,
+			acked:    make(chan struct{}),
+			rejected: make(chan struct{}),
+		}
+		p.broadcasts[hash] = b
+	}
+	b.expires = now.Add(txRelayTime)
+	p.mtx.Unlock()
+
+	peers := p.Peers()
+	if len(peers) == 0 {
+		return ErrNoPeers
+	}
+	inv := txInv(hash)
+	for _, peer := range peers {
+		_ = peer.Send(inv)
+	}
+
+	select {
+	case <-b.acked:
+	case <-b.rejected:
+		return b.rejectError(p, hash)
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.done:
+		return ErrPoolClosed
+	}
+	timer := time.NewTimer(p.rejectWait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-b.rejected:
+		return b.rejectError(p, hash)
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.done:
+		return ErrPoolClosed
+	}
+}
+
+// TxRejects returns the rejects received for a transaction broadcast
+// through the pool.
+func (p *Pool) TxRejects(hash [32]byte) []*wire.RejectMessage {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	b := p.broadcasts[hash]
+	if b == nil {
+		return nil
+	}
+	return append([]*wire.RejectMessage(nil), b.rejects...)
+}
+
+func (b *broadcast) rejectError(p *Pool, hash [32]byte) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	reject := b.rejects[0]
+	return &TxRejectError{
+		Hash:   hash,
+		Code:   reject.Code,
+		Reason: reject.Reason,
+	}
+}
+
+func txInv(hash [32]byte) *wire.InvMessage {
+	return &wire.InvMessage{
+		Items: []*wire.InvItem{
+			{Type: wire.InvTypeTx, Hash: hash},
+		},
+	}
+}
+
+func hashEqual(a [32]byte, b []byte) bool {
+	return bytes.Equal(a[:], b)
+}
+
+func remoteIP(addr string) net.IP {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return net.ParseIP(addr)
+	}
+	return net.ParseIP(host)
+}
@@ -0,0 +1,342 @@
+package p2p
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/mslipper/handshake/brontide"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/mslipper/handshake/primitives/primitivestest"
+	"github.com/mslipper/handshake/wire"
+	"github.com/stretchr/testify/require"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// remoteNode listens on localhost and hands out a started peer for every
+// connection it accepts.
+type remoteNode struct {
+	l     net.Listener
+	peers chan *Peer
+	mtx   sync.Mutex
+	all   []*Peer
+}
+
+func newRemoteNode(t *testing.T) *remoteNode {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	n := &remoteNode{
+		l:     l,
+		peers: make(chan *Peer, 8),
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				peer := NewPeer(conn, primitives.NetworkRegtest, false)
+				// Track the peer before the handshake so Close reaches it
+				// even if the dialing side finishes first.
+				n.mtx.Lock()
+				n.all = append(n.all, peer)
+				n.mtx.Unlock()
+				if err := peer.Start(context.Background()); err != nil {
+					return
+				}
+				select {
+				case n.peers <- peer:
+				default:
+				}
+			}()
+		}
+	}()
+	return n
+}
+
+func (n *remoteNode) addr() *wire.NetAddress {
+	tcpAddr := n.l.Addr().(*net.TCPAddr)
+	return &wire.NetAddress{
+		Time:     uint64(time.Now().Unix()),
+		Services: wire.ServiceNetwork,
+		IP:       tcpAddr.IP,
+		Port:     uint16(tcpAddr.Port),
+	}
+}
+
+func (n *remoteNode) Close() {
+	n.l.Close()
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	for _, peer := range n.all {
+		peer.Close()
+	}
+}
+
+func drainPool(pool *Pool) {
+	go func() {
+		for range pool.Messages() {
+		}
+	}()
+}
+
+func TestPool_Outbound(t *testing.T) {
+	book := NewAddrBook(primitives.NetworkRegtest)
+	var nodes []*remoteNode
+	for i := 0; i < 3; i++ {
+		node := newRemoteNode(t)
+		defer node.Close()
+		nodes = append(nodes, node)
+		require.True(t, book.Add(node.addr(), nil))
+	}
+
+	pool := NewPool(primitives.NetworkRegtest, book, WithMaxOutbound(2), WithRefillInterval(10*time.Millisecond))
+	defer pool.Close()
+	drainPool(pool)
+	pool.Start()
+	require.Eventually(t, func() bool {
+		return len(pool.Peers()) == 2
+	}, time.Second, time.Millisecond)
+	require.Equal(t, 2, book.NumTried())
+
+	// A peer that goes away is replaced by the remaining address.
+	lost := pool.Peers()[0].Addr()
+	for _, node := range nodes {
+		if node.addr().HostPort() == lost {
+			node.Close()
+		}
+	}
+	require.Eventually(t, func() bool {
+		return book.NumTried() == 3 && len(pool.Peers()) == 2
+	}, time.Second, time.Millisecond)
+	for _, peer := range pool.Peers() {
+		require.True(t, peer.Outbound())
+	}
+}
+
+func TestPool_Inbound(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	book := NewAddrBook(primitives.NetworkRegtest)
+	pool := NewPool(primitives.NetworkRegtest, book, WithListener(l), WithMaxOutbound(0), WithMaxInbound(1))
+	defer pool.Close()
+	drainPool(pool)
+	pool.Start()
+
+	dial := func() (*Peer, error) {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		require.NoError(t, err)
+		peer := NewPeer(conn, primitives.NetworkRegtest, true, WithHandshakeTimeout(time.Second))
+		return peer, peer.Start(context.Background())
+	}
+	first, err := dial()
+	require.NoError(t, err)
+	defer first.Close()
+	require.Eventually(t, func() bool {
+		return len(pool.Peers()) == 1
+	}, time.Second, time.Millisecond)
+	_, err = dial()
+	require.Error(t, err)
+
+	// Misbehaving peers are banned from the book.
+	pool.Peers()[0].Misbehave(DefaultBanThreshold, "test")
+	require.Eventually(t, func() bool {
+		return len(pool.Peers()) == 0
+	}, time.Second, time.Millisecond)
+	require.True(t, book.IsBanned(net.ParseIP("127.0.0.1")))
+	_, err = dial()
+	require.Error(t, err)
+}
+
+func TestPool_InboundBurst(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	pool := NewPool(primitives.NetworkRegtest, NewAddrBook(primitives.NetworkRegtest), WithListener(l), WithMaxOutbound(0), WithMaxInbound(2))
+	defer pool.Close()
+	drainPool(pool)
+	pool.Start()
+
+	// Connections that arrive while others are still handshaking count
+	// against the limit.
+	var wg sync.WaitGroup
+	var mtx sync.Mutex
+	var connected int
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := net.Dial("tcp", l.Addr().String())
+			if err != nil {
+				return
+			}
+			peer := NewPeer(conn, primitives.NetworkRegtest, true, WithHandshakeTimeout(time.Second))
+			if err := peer.Start(context.Background()); err != nil {
+				return
+			}
+			defer peer.Close()
+			mtx.Lock()
+			connected++
+			mtx.Unlock()
+			<-pool.done
+		}()
+	}
+	require.Eventually(t, func() bool {
+		return len(pool.Peers()) == 2
+	}, time.Second, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	require.Len(t, pool.Peers(), 2)
+	pool.Close()
+	wg.Wait()
+	require.LessOrEqual(t, connected, 2)
+}
+
+func TestPool_GetData(t *testing.T) {
+	block := primitivestest.LoadBlock(t, primitivestest.GoldenBlock)
+	item := &wire.InvItem{Type: wire.InvTypeBlock}
+	copy(item.Hash[:], block.Hash())
+
+	node := newRemoteNode(t)
+	defer node.Close()
+	pool := NewPool(primitives.NetworkRegtest, NewAddrBook(primitives.NetworkRegtest), WithStallTimeout(50*time.Millisecond))
+	defer pool.Close()
+	drainPool(pool)
+
+	// The first peer announces the block but never serves it; the second
+	// serves it.
+	stalling, err := pool.Connect(context.Background(), node.addr())
+	require.NoError(t, err)
+	stallingRemote := <-node.peers
+	go func() {
+		for range stallingRemote.Messages() {
+		}
+	}()
+	require.NoError(t, stallingRemote.Send(&wire.InvMessage{Items: []*wire.InvItem{item}}))
+
+	_, err = pool.Connect(context.Background(), node.addr())
+	require.NoError(t, err)
+	servingRemote := <-node.peers
+	go func() {
+		for msg := range servingRemote.Messages() {
+			if _, ok := msg.(*wire.GetDataMessage); ok {
+				_ = servingRemote.Send(&wire.NotFoundMessage{Items: []*wire.InvItem{{Type: wire.InvTypeTx}}})
+				_ = servingRemote.Send(&wire.BlockMessage{Block: block})
+			}
+		}
+	}()
+
+	require.Eventually(t, func() bool {
+		pool.mtx.Lock()
+		defer pool.mtx.Unlock()
+		return len(pool.announced[*item]) == 1
+	}, time.Second, time.Millisecond)
+	res, err := pool.GetData(context.Background(), item)
+	require.NoError(t, err)
+	require.Equal(t, block.Hash(), res.(*wire.BlockMessage).Block.Hash())
+	<-stalling.Done()
+	require.Equal(t, ErrStalled, stalling.Err())
+
+	_, err = pool.GetData(context.Background(), &wire.InvItem{Type: wire.InvTypeTx})
+	require.Equal(t, ErrNotFound, err)
+}
+
+func TestPool_BroadcastTx(t *testing.T) {
+	tx := primitivestest.LoadBlock(t, primitivestest.GoldenBlock).Transactions[0]
+	var hash [32]byte
+	copy(hash[:], tx.ID())
+
+	node := newRemoteNode(t)
+	defer node.Close()
+	book := NewAddrBook(primitives.NetworkRegtest)
+	pool := NewPool(primitives.NetworkRegtest, book, WithRejectWait(200*time.Millisecond))
+	defer pool.Close()
+	drainPool(pool)
+
+	require.Equal(t, ErrNoPeers, pool.BroadcastTx(context.Background(), tx))
+
+	_, err := pool.Connect(context.Background(), node.addr())
+	require.NoError(t, err)
+	remote := <-node.peers
+	reject := make(chan bool, 1)
+	reject <- false
+	go func() {
+		for {
+			select {
+			case inv, ok := <-remote.Inv():
+				if !ok {
+					return
+				}
+				_ = remote.Send(&wire.GetDataMessage{Items: inv.Items})
+			case msg, ok := <-remote.Txs():
+				if !ok {
+					return
+				}
+				if <-reject {
+					var txHash [32]byte
+					copy(txHash[:], msg.Tx.ID())
+					_ = remote.Send(&wire.RejectMessage{
+						Message: wire.MessageTypeTx,
+						Code:    wire.RejectInvalid,
+						Reason:  "bad-txns",
+						Hash:    txHash,
+					})
+				}
+			}
+		}
+	}()
+	require.NoError(t, pool.BroadcastTx(context.Background(), tx))
+	require.Empty(t, pool.TxRejects(hash))
+
+	reject <- true
+	err = pool.BroadcastTx(context.Background(), tx)
+	var rejectErr *TxRejectError
+	require.True(t, errors.As(err, &rejectErr))
+	require.Equal(t, "bad-txns", rejectErr.Reason)
+	require.Equal(t, hash, rejectErr.Hash)
+	require.Len(t, pool.TxRejects(hash), 1)
+}
+
+func TestPool_BrontideInbound(t *testing.T) {
+	raw, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat([]byte{0x21}, 32))
+	pool := NewPool(primitives.NetworkRegtest, NewAddrBook(primitives.NetworkRegtest), WithListener(brontide.NewListener(raw, key)), WithMaxOutbound(0))
+	defer pool.Close()
+	drainPool(pool)
+	pool.Start()
+
+	// A client stuck in the brontide handshake does not keep others out.
+	stalled, err := net.Dial("tcp", raw.Addr().String())
+	require.NoError(t, err)
+	defer stalled.Close()
+
+	clientKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat([]byte{0x11}, 32))
+	conn, err := brontide.Dial(clientKey, key.PubKey(), raw.Addr().String(), nil)
+	require.NoError(t, err)
+	peer := NewPeer(conn, primitives.NetworkRegtest, true, WithHandshakeTimeout(time.Second))
+	defer peer.Close()
+	require.NoError(t, peer.Start(context.Background()))
+	require.Eventually(t, func() bool {
+		return len(pool.Peers()) == 1
+	}, time.Second, time.Millisecond)
+}
+
+func TestPool_SelfConnection(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	pool := NewPool(primitives.NetworkRegtest, NewAddrBook(primitives.NetworkRegtest), WithListener(l), WithMaxOutbound(0))
+	defer pool.Close()
+	drainPool(pool)
+	pool.Start()
+
+	tcpAddr := l.Addr().(*net.TCPAddr)
+	_, err = pool.Connect(context.Background(), &wire.NetAddress{
+		IP:   tcpAddr.IP,
+		Port: uint16(tcpAddr.Port),
+	})
+	require.Equal(t, ErrSelfConnection, err)
+	require.Empty(t, pool.Peers())
+}
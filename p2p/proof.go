@@ -0,0 +1,110 @@
+package p2p
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/mslipper/handshake/urkel"
+	"github.com/mslipper/handshake/wire"
+)
+
+const banScoreInvalidProof = 100
+
+var (
+	ErrInvalidProof = errors.New("invalid name proof")
+	ErrNoPeers      = errors.New("no connected peers")
+)
+
+// GetNameProof asks the peer for the Urkel proof of nameHash under root and
+// verifies it. A nil name state with a nil error is a valid proof that the
+// name does not exist. Peers that return an invalid proof are banned.
+func (p *Peer) GetNameProof(ctx context.Context, root [32]byte, nameHash [32]byte) (*primitives.NameState, error) {
+	_, value, err := p.requestProof(ctx, root, nameHash)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	ns, err := decodeNameState(value, nameHash)
+	if err != nil {
+		p.Misbehave(banScoreInvalidProof, err.Error())
+		return nil, err
+	}
+	return ns, nil
+}
+
+// GetProof asks the peer for the Urkel proof of key under root and returns
+// it once it verifies. Peers that return an invalid proof are banned.
+func (p *Peer) GetProof(ctx context.Context, root [32]byte, key [32]byte) (*urkel.Proof, error) {
+	proof, _, err := p.requestProof(ctx, root, key)
+	return proof, err
+}
+
+func (p *Peer) requestProof(ctx context.Context, root [32]byte, key [32]byte) (*urkel.Proof, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.requestTimeout)
+	defer cancel()
+	res, err := p.Request(ctx, &wire.GetProofMessage{
+		Root: root,
+		Key:  key,
+	}, func(msg wire.Message) bool {
+		proof, ok := msg.(*wire.ProofMessage)
+		return ok && proof.Root == root && proof.Key == key
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	msg := res.(*wire.ProofMessage)
+	value, err := verifyProof(msg)
+	if err != nil {
+		p.Misbehave(banScoreInvalidProof, err.Error())
+		return nil, nil, err
+	}
+	return msg.Proof, value, nil
+}
+
+// GetNameProofFromPeers tries each peer in turn until one returns a valid
+// proof, skipping peers that have disconnected.
+func GetNameProofFromPeers(ctx context.Context, peers []*Peer, root [32]byte, nameHash [32]byte) (*primitives.NameState, error) {
+	lastErr := ErrNoPeers
+	for _, peer := range peers {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		select {
+		case <-peer.Done():
+			continue
+		default:
+		}
+		ns, err := peer.GetNameProof(ctx, root, nameHash)
+		if err == nil {
+			return ns, nil
+		}
+		lastErr = fmt.Errorf("peer %s: %w", peer, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, lastErr
+}
+
+func verifyProof(msg *wire.ProofMessage) ([]byte, error) {
+	if msg.Proof == nil {
+		return nil, ErrInvalidProof
+	}
+	value, err := msg.Proof.Verify(msg.Root[:], msg.Key[:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProof, err)
+	}
+	return value, nil
+}
+
+func decodeNameState(value []byte, nameHash [32]byte) (*primitives.NameState, error) {
+	ns := new(primitives.NameState)
+	if err := ns.Decode(bytes.NewReader(value)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProof, err)
+	}
+	if !bytes.Equal(ns.NameHash(), nameHash[:]) {
+		return nil, fmt.Errorf("%w: name state does not match key", ErrInvalidProof)
+	}
+	return ns, nil
+}
@@ -0,0 +1,130 @@
+package p2p
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/mslipper/handshake/urkel"
+	"github.com/mslipper/handshake/wire"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/blake2b"
+	"testing"
+	"time"
+)
+
+// singleLeafTree returns the root of a tree holding only ns and a proof
+// server for it.
+func singleLeafTree(t *testing.T, ns *primitives.NameState) ([32]byte, func(req *wire.GetProofMessage) *urkel.Proof) {
+	value := new(bytes.Buffer)
+	require.NoError(t, ns.Encode(value))
+	valueHash := blake2b.Sum256(value.Bytes())
+	h, _ := blake2b.New256(nil)
+	h.Write([]byte{0x00})
+	h.Write(ns.NameHash())
+	h.Write(valueHash[:])
+	var root [32]byte
+	copy(root[:], h.Sum(nil))
+	return root, func(req *wire.GetProofMessage) *urkel.Proof {
+		if bytes.Equal(req.Key[:], ns.NameHash()) {
+			return &urkel.Proof{
+				Type:  urkel.ProofTypeExists,
+				Value: value.Bytes(),
+			}
+		}
+		return &urkel.Proof{
+			Type: urkel.ProofTypeCollision,
+			Key:  ns.NameHash(),
+			Hash: valueHash[:],
+		}
+	}
+}
+
+func serveProofs(remote *Peer, prove func(req *wire.GetProofMessage) *urkel.Proof) {
+	for msg := range remote.Messages() {
+		req, ok := msg.(*wire.GetProofMessage)
+		if !ok {
+			continue
+		}
+		proof := prove(req)
+		if proof == nil {
+			continue
+		}
+		_ = remote.Send(&wire.ProofMessage{
+			Root:  req.Root,
+			Key:   req.Key,
+			Proof: proof,
+		})
+	}
+}
+
+func TestPeer_GetNameProof(t *testing.T) {
+	ns := &primitives.NameState{
+		Name:   "proofofconcept",
+		Height: 8578,
+		Owner:  new(primitives.Outpoint),
+	}
+	root, prove := singleLeafTree(t, ns)
+	var nameHash, otherHash [32]byte
+	copy(nameHash[:], ns.NameHash())
+	copy(otherHash[:], primitives.HashName("other"))
+
+	local, remote := peerPair(t, nil, nil)
+	defer local.Close()
+	defer remote.Close()
+	go serveProofs(remote, prove)
+
+	res, err := local.GetNameProof(context.Background(), root, nameHash)
+	require.NoError(t, err)
+	require.Equal(t, ns.Name, res.Name)
+	require.EqualValues(t, 8578, res.Height)
+
+	res, err = local.GetNameProof(context.Background(), root, otherHash)
+	require.NoError(t, err)
+	require.Nil(t, res)
+}
+
+func TestGetNameProofFromPeers(t *testing.T) {
+	ns := &primitives.NameState{
+		Name:  "proofofconcept",
+		Owner: new(primitives.Outpoint),
+	}
+	root, prove := singleLeafTree(t, ns)
+	var nameHash [32]byte
+	copy(nameHash[:], ns.NameHash())
+
+	opts := []PeerOpt{WithRequestTimeout(20 * time.Millisecond)}
+	silent, silentRemote := peerPair(t, opts, nil)
+	defer silent.Close()
+	defer silentRemote.Close()
+	go serveProofs(silentRemote, func(req *wire.GetProofMessage) *urkel.Proof {
+		return nil
+	})
+	forger, forgerRemote := peerPair(t, opts, nil)
+	defer forgerRemote.Close()
+	go serveProofs(forgerRemote, func(req *wire.GetProofMessage) *urkel.Proof {
+		return &urkel.Proof{
+			Type:  urkel.ProofTypeExists,
+			Value: []byte("forged"),
+		}
+	})
+	closed, closedRemote := peerPair(t, opts, nil)
+	defer closedRemote.Close()
+	require.NoError(t, closed.Close())
+
+	_, err := GetNameProofFromPeers(context.Background(), []*Peer{closed, silent, forger}, root, nameHash)
+	require.True(t, errors.Is(err, ErrInvalidProof))
+	require.True(t, forger.Banned())
+	require.False(t, silent.Banned())
+
+	good, goodRemote := peerPair(t, opts, nil)
+	defer good.Close()
+	defer goodRemote.Close()
+	go serveProofs(goodRemote, prove)
+	res, err := GetNameProofFromPeers(context.Background(), []*Peer{silent, forger, good}, root, nameHash)
+	require.NoError(t, err)
+	require.Equal(t, ns.Name, res.Name)
+
+	_, err = GetNameProofFromPeers(context.Background(), nil, root, nameHash)
+	require.Equal(t, ErrNoPeers, err)
+}
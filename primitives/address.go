@@ -17,7 +17,34 @@ func (a *Address) ToBech32(hrp string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return bech32.Encode(NetworkMainnet.AddressHRP(), append([]byte{a.Version}, data...))
+	return bech32.Encode(hrp, append([]byte{a.Version}, data...))
+}
+
+// NewAddressFromBech32 decodes a bech32 address, returning it along with its
+// human-readable part.
+func NewAddressFromBech32(addr string) (*Address, string, error) {
+	hrp, data, err := bech32.Decode(addr)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) < 1 {
+		return nil, "", errors.New("invalid address length")
+	}
+	version := data[0]
+	if version > 31 {
+		return nil, "", errors.New("invalid address version")
+	}
+	hash, err := bech32.ConvertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(hash) < 2 || len(hash) > 40 {
+		return nil, "", errors.New("invalid address length")
+	}
+	return &Address{
+		Version: version,
+		Hash:    hash,
+	}, hrp, nil
 }
 
 func (a *Address) Encode(w io.Writer) error {
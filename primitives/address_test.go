@@ -17,3 +17,23 @@ func TestAddress_ToBech32(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "hs1qd42hrldu5yqee58se4uj6xctm7nk28r70e84vx", bech)
 }
+
+func TestAddress_Bech32RoundTrip(t *testing.T) {
+	hash, err := hex.DecodeString("6d5571fdbca1019cd0f0cd792d1b0bdfa7651c7e")
+	require.NoError(t, err)
+	addr := &Address{
+		Version: 0,
+		Hash:    hash,
+	}
+	bech, err := addr.ToBech32(NetworkRegtest.AddressHRP())
+	require.NoError(t, err)
+	require.Equal(t, "rs1", bech[:3])
+
+	decoded, hrp, err := NewAddressFromBech32(bech)
+	require.NoError(t, err)
+	require.Equal(t, "rs", hrp)
+	require.Equal(t, addr, decoded)
+
+	_, _, err = NewAddressFromBech32("hs1qd42hrldu5yqee58se4uj6xctm7nk28r70e84vy")
+	require.Error(t, err)
+}
@@ -9,8 +9,9 @@ import (
 )
 
 const (
-	NonceSize = 24
-	MaskSize  = 32
+	NonceSize  = 24
+	MaskSize   = 32
+	HeaderSize = 236
 )
 
 type Block struct {
@@ -90,6 +91,21 @@ func (b *Block) padding(size int) []byte {
 }
 
 func (b *Block) Encode(w io.Writer) error {
+	if err := b.EncodeHeader(w); err != nil {
+		return err
+	}
+	if err := encoding.WriteVarint(w, uint64(len(b.Transactions))); err != nil {
+		return err
+	}
+	for _, tx := range b.Transactions {
+		if err := tx.Encode(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Block) EncodeHeader(w io.Writer) error {
 	if err := encoding.WriteUint32(w, b.Nonce); err != nil {
 		return err
 	}
@@ -123,18 +139,32 @@ func (b *Block) Encode(w io.Writer) error {
 	if _, err := w.Write(b.Mask[:]); err != nil {
 		return err
 	}
-	if err := encoding.WriteVarint(w, uint64(len(b.Transactions))); err != nil {
+	return nil
+}
+
+func (b *Block) Decode(r io.Reader) error {
+	header := new(Block)
+	if err := header.DecodeHeader(r); err != nil {
 		return err
 	}
-	for _, tx := range b.Transactions {
-		if err := tx.Encode(w); err != nil {
+	txCount, err := encoding.ReadVarint(r)
+	if err != nil {
+		return err
+	}
+	var txs []*Transaction
+	for i := 0; i < int(txCount); i++ {
+		tx := new(Transaction)
+		if err := tx.Decode(r); err != nil {
 			return err
 		}
+		txs = append(txs, tx)
 	}
+	*b = *header
+	b.Transactions = txs
 	return nil
 }
 
-func (b *Block) Decode(r io.Reader) error {
+func (b *Block) DecodeHeader(r io.Reader) error {
 	nonce, err := encoding.ReadUint32(r)
 	if err != nil {
 		return err
@@ -144,27 +174,27 @@ func (b *Block) Decode(r io.Reader) error {
 		return err
 	}
 	var hash [32]byte
-	if _, err := r.Read(hash[:]); err != nil {
+	if _, err := io.ReadFull(r, hash[:]); err != nil {
 		return err
 	}
 	var treeRoot [32]byte
-	if _, err := r.Read(treeRoot[:]); err != nil {
+	if _, err := io.ReadFull(r, treeRoot[:]); err != nil {
 		return err
 	}
 	var extraNonce [NonceSize]byte
-	if _, err := r.Read(extraNonce[:]); err != nil {
+	if _, err := io.ReadFull(r, extraNonce[:]); err != nil {
 		return err
 	}
 	var reservedRoot [32]byte
-	if _, err := r.Read(reservedRoot[:]); err != nil {
+	if _, err := io.ReadFull(r, reservedRoot[:]); err != nil {
 		return err
 	}
 	var witnessRoot [32]byte
-	if _, err := r.Read(witnessRoot[:]); err != nil {
+	if _, err := io.ReadFull(r, witnessRoot[:]); err != nil {
 		return err
 	}
 	var merkleRoot [32]byte
-	if _, err := r.Read(merkleRoot[:]); err != nil {
+	if _, err := io.ReadFull(r, merkleRoot[:]); err != nil {
 		return err
 	}
 	version, err := encoding.ReadUint32(r)
@@ -176,21 +206,9 @@ func (b *Block) Decode(r io.Reader) error {
 		return err
 	}
 	var mask [MaskSize]byte
-	if _, err := r.Read(mask[:]); err != nil {
-		return err
-	}
-	txCount, err := encoding.ReadVarint(r)
-	if err != nil {
+	if _, err := io.ReadFull(r, mask[:]); err != nil {
 		return err
 	}
-	var txs []*Transaction
-	for i := 0; i < int(txCount); i++ {
-		tx := new(Transaction)
-		if err := tx.Decode(r); err != nil {
-			return err
-		}
-		txs = append(txs, tx)
-	}
 	b.Nonce = nonce
 	b.Time = ts
 	b.PrevHash = hash
@@ -202,6 +220,5 @@ func (b *Block) Decode(r io.Reader) error {
 	b.Version = version
 	b.Bits = bits
 	b.Mask = mask
-	b.Transactions = txs
 	return nil
 }
@@ -31,6 +31,16 @@ func TestBlock_Golden(t *testing.T) {
 			require.EqualValues(t, expData, actData.Bytes())
 			genHash := block.Hash()
 			require.Equal(t, tt.hash, hex.EncodeToString(genHash))
+			require.True(t, block.VerifyPOW())
+			require.Equal(t, block.MerkleRoot, block.ComputeMerkleRoot())
+			require.Equal(t, block.WitnessRoot, block.ComputeWitnessRoot())
+
+			headerData := new(bytes.Buffer)
+			require.NoError(t, block.EncodeHeader(headerData))
+			require.Equal(t, HeaderSize, headerData.Len())
+			header := new(Block)
+			require.NoError(t, header.DecodeHeader(headerData))
+			require.Equal(t, tt.hash, hex.EncodeToString(header.Hash()))
 		})
 	}
 }
\ No newline at end of file
@@ -0,0 +1,76 @@
+package primitives
+
+import (
+	"bytes"
+	"errors"
+	"github.com/mslipper/handshake/encoding"
+	"io"
+	"math/big"
+)
+
+const ChainEntrySize = 32 + 4 + HeaderSize + 32
+
+// ChainEntry is a block header along with its position in the chain, as
+// serialized by hsd's chain database and websocket API.
+type ChainEntry struct {
+	Hash      [32]byte
+	Height    uint32
+	Header    *Block
+	Chainwork *big.Int
+}
+
+func (c *ChainEntry) Encode(w io.Writer) error {
+	if _, err := w.Write(c.Hash[:]); err != nil {
+		return err
+	}
+	if err := encoding.WriteUint32(w, c.Height); err != nil {
+		return err
+	}
+	if err := c.Header.EncodeHeader(w); err != nil {
+		return err
+	}
+	var work [32]byte
+	if c.Chainwork != nil {
+		workB := c.Chainwork.Bytes()
+		if len(workB) > len(work) {
+			return errors.New("chainwork too large")
+		}
+		for i, b := range workB {
+			work[len(workB)-1-i] = b
+		}
+	}
+	if _, err := w.Write(work[:]); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *ChainEntry) Decode(r io.Reader) error {
+	var hash [32]byte
+	if _, err := io.ReadFull(r, hash[:]); err != nil {
+		return err
+	}
+	height, err := encoding.ReadUint32(r)
+	if err != nil {
+		return err
+	}
+	header := new(Block)
+	if err := header.DecodeHeader(r); err != nil {
+		return err
+	}
+	var work [32]byte
+	if _, err := io.ReadFull(r, work[:]); err != nil {
+		return err
+	}
+	for i, j := 0, len(work)-1; i < j; i, j = i+1, j-1 {
+		work[i], work[j] = work[j], work[i]
+	}
+	if !bytes.Equal(header.Hash(), hash[:]) {
+		return errors.New("chain entry hash does not match header")
+	}
+	c.Hash = hash
+	c.Height = height
+	c.Header = header
+	c.Chainwork = new(big.Int).SetBytes(work[:])
+	return nil
+}
@@ -0,0 +1,37 @@
+package primitives
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"math/big"
+	"testing"
+)
+
+func TestChainEntry_Encoding(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/block_000000000000003d56d278ef00c657de6b1fcb549f9e04e299f6a918c2573b94.bin")
+	require.NoError(t, err)
+	header := new(Block)
+	require.NoError(t, header.DecodeHeader(bytes.NewReader(data)))
+
+	entry := &ChainEntry{
+		Height:    12345,
+		Header:    header,
+		Chainwork: new(big.Int).SetUint64(0x0102030405060708),
+	}
+	copy(entry.Hash[:], header.Hash())
+	buf := new(bytes.Buffer)
+	require.NoError(t, entry.Encode(buf))
+	require.Equal(t, ChainEntrySize, buf.Len())
+	require.EqualValues(t, 0x08, buf.Bytes()[ChainEntrySize-32])
+
+	decoded := new(ChainEntry)
+	require.NoError(t, decoded.Decode(bytes.NewReader(buf.Bytes())))
+	require.Equal(t, entry.Hash, decoded.Hash)
+	require.Equal(t, entry.Height, decoded.Height)
+	require.Equal(t, 0, entry.Chainwork.Cmp(decoded.Chainwork))
+
+	raw := buf.Bytes()
+	raw[0] ^= 0xff
+	require.Error(t, new(ChainEntry).Decode(bytes.NewReader(raw)))
+}
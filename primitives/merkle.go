@@ -0,0 +1,73 @@
+package primitives
+
+import (
+	"golang.org/x/crypto/blake2b"
+)
+
+var (
+	merkleLeafPrefix     = []byte{0x00}
+	merkleInternalPrefix = []byte{0x01}
+	merkleSentinel       = blake2b.Sum256(nil)
+)
+
+// MerkleRoot computes the root of hsd's merkle tree over the given leaves.
+// Leaves and internal nodes are hashed with distinct prefixes, and a node
+// without a sibling is paired with the hash of the empty string.
+func MerkleRoot(leaves [][]byte) [32]byte {
+	if len(leaves) == 0 {
+		return merkleSentinel
+	}
+	nodes := make([][32]byte, len(leaves))
+	for i, leaf := range leaves {
+		nodes[i] = MerkleHashLeaf(leaf)
+	}
+	for len(nodes) > 1 {
+		var next [][32]byte
+		for i := 0; i < len(nodes); i += 2 {
+			right := merkleSentinel
+			if i+1 < len(nodes) {
+				right = nodes[i+1]
+			}
+			next = append(next, MerkleHashInternal(nodes[i], right))
+		}
+		nodes = next
+	}
+	return nodes[0]
+}
+
+func MerkleHashLeaf(leaf []byte) [32]byte {
+	h, _ := blake2b.New256(nil)
+	h.Write(merkleLeafPrefix)
+	h.Write(leaf)
+	var out [32]byte
+	copy(out[:], h.Sum(nil))
+	return out
+}
+
+func MerkleHashInternal(left [32]byte, right [32]byte) [32]byte {
+	h, _ := blake2b.New256(nil)
+	h.Write(merkleInternalPrefix)
+	h.Write(left[:])
+	h.Write(right[:])
+	var out [32]byte
+	copy(out[:], h.Sum(nil))
+	return out
+}
+
+// ComputeMerkleRoot returns the merkle root of the block's transaction IDs.
+func (b *Block) ComputeMerkleRoot() [32]byte {
+	leaves := make([][]byte, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		leaves[i] = tx.ID()
+	}
+	return MerkleRoot(leaves)
+}
+
+// ComputeWitnessRoot returns the merkle root of the block's witness hashes.
+func (b *Block) ComputeWitnessRoot() [32]byte {
+	leaves := make([][]byte, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		leaves[i] = tx.WitnessHash()
+	}
+	return MerkleRoot(leaves)
+}
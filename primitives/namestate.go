@@ -0,0 +1,245 @@
+package primitives
+
+import (
+	"bytes"
+	"errors"
+	"github.com/mslipper/handshake/dns"
+	"github.com/mslipper/handshake/encoding"
+	"io"
+	"math"
+)
+
+const (
+	nameStateFieldTransfer uint8 = 1 << iota
+	nameStateFieldRevoked
+	nameStateFieldClaimed
+	nameStateFieldRenewals
+	nameStateFieldRegistered
+	nameStateFieldExpired
+	nameStateFieldWeak
+)
+
+type NameState struct {
+	Name       string
+	Height     uint32
+	Renewal    uint32
+	Owner      *Outpoint
+	Value      uint64
+	Highest    uint64
+	Data       []byte
+	Transfer   uint32
+	Revoked    uint32
+	Claimed    uint32
+	Renewals   uint32
+	Registered bool
+	Expired    bool
+	Weak       bool
+}
+
+func (ns *NameState) NameHash() []byte {
+	return HashName(ns.Name)
+}
+
+func (ns *NameState) Resource() (*dns.Resource, error) {
+	if len(ns.Data) == 0 {
+		return nil, nil
+	}
+	if len(ns.Data) > dns.MaxResourceSize {
+		return nil, errors.New("resource too large")
+	}
+	resource := new(dns.Resource)
+	if err := resource.Decode(bytes.NewReader(ns.Data)); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
+
+func (ns *NameState) Encode(w io.Writer) error {
+	if len(ns.Name) > math.MaxUint8 {
+		return errors.New("name too long")
+	}
+	if len(ns.Data) > math.MaxUint16 {
+		return errors.New("data too long")
+	}
+	var field uint8
+	if ns.Transfer != 0 {
+		field |= nameStateFieldTransfer
+	}
+	if ns.Revoked != 0 {
+		field |= nameStateFieldRevoked
+	}
+	if ns.Claimed != 0 {
+		field |= nameStateFieldClaimed
+	}
+	if ns.Renewals != 0 {
+		field |= nameStateFieldRenewals
+	}
+	if ns.Registered {
+		field |= nameStateFieldRegistered
+	}
+	if ns.Expired {
+		field |= nameStateFieldExpired
+	}
+	if ns.Weak {
+		field |= nameStateFieldWeak
+	}
+
+	owner := ns.Owner
+	if owner == nil {
+		owner = new(Outpoint)
+	}
+
+	if err := encoding.WriteUint8(w, uint8(len(ns.Name))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, ns.Name); err != nil {
+		return err
+	}
+	if err := encoding.WriteUint16(w, uint16(len(ns.Data))); err != nil {
+		return err
+	}
+	if _, err := w.Write(ns.Data); err != nil {
+		return err
+	}
+	if err := encoding.WriteUint32(w, ns.Height); err != nil {
+		return err
+	}
+	if err := encoding.WriteUint32(w, ns.Renewal); err != nil {
+		return err
+	}
+	if _, err := w.Write(owner.Hash[:]); err != nil {
+		return err
+	}
+	if err := encoding.WriteVarint(w, uint64(owner.Index)); err != nil {
+		return err
+	}
+	if err := encoding.WriteVarint(w, ns.Value); err != nil {
+		return err
+	}
+	if err := encoding.WriteVarint(w, ns.Highest); err != nil {
+		return err
+	}
+	if err := encoding.WriteUint8(w, field); err != nil {
+		return err
+	}
+	if ns.Transfer != 0 {
+		if err := encoding.WriteUint32(w, ns.Transfer); err != nil {
+			return err
+		}
+	}
+	if ns.Revoked != 0 {
+		if err := encoding.WriteUint32(w, ns.Revoked); err != nil {
+			return err
+		}
+	}
+	if ns.Claimed != 0 {
+		if err := encoding.WriteUint32(w, ns.Claimed); err != nil {
+			return err
+		}
+	}
+	if ns.Renewals != 0 {
+		if err := encoding.WriteVarint(w, uint64(ns.Renewals)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ns *NameState) Decode(r io.Reader) error {
+	nameLen, err := encoding.ReadUint8(r)
+	if err != nil {
+		return err
+	}
+	name, err := encoding.ReadString(r, int(nameLen))
+	if err != nil {
+		return err
+	}
+	dataLen, err := encoding.ReadUint16(r)
+	if err != nil {
+		return err
+	}
+	data, err := encoding.ReadBytes(r, int(dataLen))
+	if err != nil {
+		return err
+	}
+	height, err := encoding.ReadUint32(r)
+	if err != nil {
+		return err
+	}
+	renewal, err := encoding.ReadUint32(r)
+	if err != nil {
+		return err
+	}
+	owner := new(Outpoint)
+	if _, err := io.ReadFull(r, owner.Hash[:]); err != nil {
+		return err
+	}
+	ownerIndex, err := encoding.ReadVarint(r)
+	if err != nil {
+		return err
+	}
+	if ownerIndex > math.MaxUint32 {
+		return errors.New("invalid owner index")
+	}
+	owner.Index = uint32(ownerIndex)
+	value, err := encoding.ReadVarint(r)
+	if err != nil {
+		return err
+	}
+	highest, err := encoding.ReadVarint(r)
+	if err != nil {
+		return err
+	}
+	field, err := encoding.ReadUint8(r)
+	if err != nil {
+		return err
+	}
+
+	var transfer uint32
+	if field&nameStateFieldTransfer != 0 {
+		transfer, err = encoding.ReadUint32(r)
+		if err != nil {
+			return err
+		}
+	}
+	var revoked uint32
+	if field&nameStateFieldRevoked != 0 {
+		revoked, err = encoding.ReadUint32(r)
+		if err != nil {
+			return err
+		}
+	}
+	var claimed uint32
+	if field&nameStateFieldClaimed != 0 {
+		claimed, err = encoding.ReadUint32(r)
+		if err != nil {
+			return err
+		}
+	}
+	var renewals uint64
+	if field&nameStateFieldRenewals != 0 {
+		renewals, err = encoding.ReadVarint(r)
+		if err != nil {
+			return err
+		}
+		if renewals > math.MaxUint32 {
+			return errors.New("invalid renewal count")
+		}
+	}
+
+	ns.Name = name
+	ns.Height = height
+	ns.Renewal = renewal
+	ns.Owner = owner
+	ns.Value = value
+	ns.Highest = highest
+	ns.Data = data
+	ns.Transfer = transfer
+	ns.Revoked = revoked
+	ns.Claimed = claimed
+	ns.Renewals = uint32(renewals)
+	ns.Registered = field&nameStateFieldRegistered != 0
+	ns.Expired = field&nameStateFieldExpired != 0
+	ns.Weak = field&nameStateFieldWeak != 0
+	return nil
+}
@@ -0,0 +1,97 @@
+package primitives
+
+import (
+	"bytes"
+	"encoding/hex"
+	"github.com/mslipper/handshake/dns"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// The vectors are assembled field by field from hsd's NameState
+// serialization: value and highest are always present, followed by a
+// one-byte flag field that gates the remaining fields.
+func TestNameState_Encoding(t *testing.T) {
+	var ownerHash [32]byte
+	for i := range ownerHash {
+		ownerHash[i] = 0x11
+	}
+	tests := []struct {
+		name  string
+		state *NameState
+		hex   string
+	}{
+		{
+			"registered name with large values",
+			&NameState{
+				Name:    "proofofconcept",
+				Height:  8578,
+				Renewal: 8578,
+				Owner: &Outpoint{
+					Hash:  ownerHash,
+					Index: 1,
+				},
+				Value:      5000000000,
+				Highest:    6000000000,
+				Data:       []byte{0x00, 0x06, 0x01, 0x02, 'h', 'i'},
+				Registered: true,
+			},
+			"0e" + "70726f6f666f66636f6e63657074" + // name
+				"0600" + "000601026869" + // data
+				"82210000" + "82210000" + // height, renewal
+				"1111111111111111111111111111111111111111111111111111111111111111" + "01" + // owner
+				"ff00f2052a01000000" + "ff00bca06501000000" + // value, highest
+				"10", // flags: registered
+		},
+		{
+			"revoked, expired and weak name with renewals",
+			&NameState{
+				Name:     "flags",
+				Owner:    new(Outpoint),
+				Data:     []byte{},
+				Transfer: 10,
+				Revoked:  11,
+				Claimed:  12,
+				Renewals: 300,
+				Expired:  true,
+				Weak:     true,
+			},
+			"05" + "666c616773" + // name
+				"0000" + // data
+				"00000000" + "00000000" + // height, renewal
+				"0000000000000000000000000000000000000000000000000000000000000000" + "00" + // owner
+				"00" + "00" + // value, highest
+				"6f" + // flags: transfer, revoked, claimed, renewals, expired, weak
+				"0a000000" + "0b000000" + "0c000000" + // transfer, revoked, claimed
+				"fd2c01", // renewals
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			require.NoError(t, tt.state.Encode(buf))
+			require.Equal(t, tt.hex, hex.EncodeToString(buf.Bytes()))
+
+			decoded := new(NameState)
+			require.NoError(t, decoded.Decode(bytes.NewReader(buf.Bytes())))
+			require.EqualValues(t, tt.state, decoded)
+		})
+	}
+}
+
+func TestNameState_Resource(t *testing.T) {
+	state := &NameState{
+		Name: "proofofconcept",
+		Data: []byte{0x00, 0x06, 0x01, 0x02, 'h', 'i'},
+	}
+	resource, err := state.Resource()
+	require.NoError(t, err)
+	require.Len(t, resource.Records, 1)
+	require.Equal(t, []string{"hi"}, resource.Records[0].(*dns.TXTRecord).Entries)
+	require.Equal(t, HashName("proofofconcept"), state.NameHash())
+
+	empty := &NameState{Name: "empty"}
+	resource, err = empty.Resource()
+	require.NoError(t, err)
+	require.Nil(t, resource)
+}
@@ -30,6 +30,86 @@ func (n Network) RPCPort() int {
 	}
 }
 
+func (n Network) WalletPort() int {
+	switch n {
+	case NetworkMainnet:
+		return 12039
+	case NetworkTestnet:
+		return 13039
+	case NetworkRegtest:
+		return 14039
+	case NetworkSimnet:
+		return 15039
+	default:
+		panic("invalid network")
+	}
+}
+
+func (n Network) P2PPort() int {
+	switch n {
+	case NetworkMainnet:
+		return 12038
+	case NetworkTestnet:
+		return 13038
+	case NetworkRegtest:
+		return 14038
+	case NetworkSimnet:
+		return 15038
+	default:
+		panic("invalid network")
+	}
+}
+
+func (n Network) BrontidePort() int {
+	switch n {
+	case NetworkMainnet:
+		return 44806
+	case NetworkTestnet:
+		return 45806
+	case NetworkRegtest:
+		return 46806
+	case NetworkSimnet:
+		return 47806
+	default:
+		panic("invalid network")
+	}
+}
+
+// Magic returns the value that prefixes every P2P packet on the network.
+func (n Network) Magic() uint32 {
+	switch n {
+	case NetworkMainnet:
+		return 0x5b6ef2d3
+	case NetworkTestnet:
+		return 0x154f6d42
+	case NetworkRegtest:
+		return 0xbcf173aa
+	case NetworkSimnet:
+		return 0x473bd012
+	default:
+		panic("invalid network")
+	}
+}
+
+// Seeds returns the DNS seeds used to discover peers on the network. Entries
+// may also be "<key>@host" identity addresses.
+func (n Network) Seeds() []string {
+	switch n {
+	case NetworkMainnet:
+		return []string{
+			"hs-mainnet.bcoin.ninja",
+		}
+	case NetworkTestnet:
+		return []string{
+			"hs-testnet.bcoin.ninja",
+		}
+	case NetworkRegtest, NetworkSimnet:
+		return nil
+	default:
+		panic("invalid network")
+	}
+}
+
 func (n Network) AddressHRP() string {
 	switch n {
 	case NetworkMainnet:
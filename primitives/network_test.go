@@ -14,3 +14,46 @@ func TestNetwork_RPCPort(t *testing.T) {
 		Network("foobar").RPCPort()
 	})
 }
+
+func TestNetwork_WalletPort(t *testing.T) {
+	require.Equal(t, 12039, NetworkMainnet.WalletPort())
+	require.Equal(t, 13039, NetworkTestnet.WalletPort())
+	require.Equal(t, 14039, NetworkRegtest.WalletPort())
+	require.Equal(t, 15039, NetworkSimnet.WalletPort())
+	require.Panics(t, func() {
+		Network("foobar").WalletPort()
+	})
+}
+
+func TestNetwork_P2PPort(t *testing.T) {
+	require.Equal(t, 12038, NetworkMainnet.P2PPort())
+	require.Equal(t, 13038, NetworkTestnet.P2PPort())
+	require.Equal(t, 14038, NetworkRegtest.P2PPort())
+	require.Equal(t, 15038, NetworkSimnet.P2PPort())
+	require.Equal(t, 44806, NetworkMainnet.BrontidePort())
+	require.Equal(t, 47806, NetworkSimnet.BrontidePort())
+	require.Panics(t, func() {
+		Network("foobar").P2PPort()
+	})
+}
+
+func TestNetwork_Seeds(t *testing.T) {
+	require.NotEmpty(t, NetworkMainnet.Seeds())
+	require.NotEmpty(t, NetworkTestnet.Seeds())
+	require.Empty(t, NetworkRegtest.Seeds())
+	require.Panics(t, func() {
+		Network("foobar").Seeds()
+	})
+}
+
+func TestNetwork_Magic(t *testing.T) {
+	magics := make(map[uint32]bool)
+	for _, n := range []Network{NetworkMainnet, NetworkTestnet, NetworkRegtest, NetworkSimnet} {
+		magics[n.Magic()] = true
+	}
+	require.Len(t, magics, 4)
+	require.Equal(t, uint32(0x5b6ef2d3), NetworkMainnet.Magic())
+	require.Panics(t, func() {
+		Network("foobar").Magic()
+	})
+}
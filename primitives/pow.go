@@ -0,0 +1,62 @@
+package primitives
+
+import (
+	"math/big"
+)
+
+var maxTarget = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+func CompactToBig(compact uint32) *big.Int {
+	exponent := uint(compact >> 24)
+	negative := compact&0x00800000 != 0
+	mantissa := int64(compact & 0x007fffff)
+
+	var target *big.Int
+	if exponent <= 3 {
+		target = big.NewInt(mantissa >> (8 * (3 - exponent)))
+	} else {
+		target = big.NewInt(mantissa)
+		target.Lsh(target, 8*(exponent-3))
+	}
+	if negative {
+		target.Neg(target)
+	}
+	return target
+}
+
+func BigToCompact(n *big.Int) uint32 {
+	if n.Sign() == 0 {
+		return 0
+	}
+	abs := new(big.Int).Abs(n)
+	exponent := uint(len(abs.Bytes()))
+	var mantissa uint32
+	if exponent <= 3 {
+		mantissa = uint32(abs.Uint64() << (8 * (3 - exponent)))
+	} else {
+		mantissa = uint32(new(big.Int).Rsh(abs, 8*(exponent-3)).Uint64())
+	}
+	if mantissa&0x00800000 != 0 {
+		mantissa >>= 8
+		exponent++
+	}
+	compact := uint32(exponent<<24) | mantissa
+	if n.Sign() < 0 {
+		compact |= 0x00800000
+	}
+	return compact
+}
+
+// VerifyPOW returns true if the big-endian hash is at or below the target
+// encoded by bits.
+func VerifyPOW(hash []byte, bits uint32) bool {
+	target := CompactToBig(bits)
+	if target.Sign() <= 0 || target.Cmp(maxTarget) > 0 {
+		return false
+	}
+	return new(big.Int).SetBytes(hash).Cmp(target) <= 0
+}
+
+func (b *Block) VerifyPOW() bool {
+	return VerifyPOW(b.Hash(), b.Bits)
+}
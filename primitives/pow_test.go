@@ -0,0 +1,35 @@
+package primitives
+
+import (
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"testing"
+)
+
+func TestCompact(t *testing.T) {
+	tests := []struct {
+		compact uint32
+		hex     string
+	}{
+		{0x1a0250f8, "250f80000000000000000000000000000000000000000000000"},
+		{0x1d00ffff, "ffff0000000000000000000000000000000000000000000000000000"},
+		{0x207fffff, "7fffff0000000000000000000000000000000000000000000000000000000000"},
+		{0x03123456, "123456"},
+		{0x02123400, "1234"},
+	}
+	for _, tt := range tests {
+		target := CompactToBig(tt.compact)
+		require.Equal(t, tt.hex, target.Text(16))
+		require.Equal(t, tt.compact, BigToCompact(target))
+	}
+	require.Equal(t, uint32(0x02008000), BigToCompact(big.NewInt(0x80)))
+}
+
+func TestVerifyPOW(t *testing.T) {
+	hash := make([]byte, 32)
+	hash[6] = 0x02
+	require.True(t, VerifyPOW(hash, 0x1a0250f8))
+	hash[6] = 0x03
+	require.False(t, VerifyPOW(hash, 0x1a0250f8))
+	require.False(t, VerifyPOW(hash, 0))
+}
@@ -0,0 +1,32 @@
+// Package primitivestest loads the golden blocks in primitives/testdata for
+// tests in other packages.
+package primitivestest
+
+import (
+	"bytes"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// GoldenBlock is the hash of mainnet block 7436, which has 23 transactions.
+const GoldenBlock = "000000000000003d56d278ef00c657de6b1fcb549f9e04e299f6a918c2573b94"
+
+// LoadBlock decodes the golden block with the given hash.
+func LoadBlock(t testing.TB, hash string) *primitives.Block {
+	data, err := ioutil.ReadFile(testdataPath("block_" + hash + ".bin"))
+	require.NoError(t, err)
+	block := new(primitives.Block)
+	require.NoError(t, block.Decode(bytes.NewReader(data)))
+	return block
+}
+
+// testdataPath resolves name against primitives/testdata, so callers do
+// not depend on the working directory of the test binary.
+func testdataPath(name string) string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "testdata", name)
+}
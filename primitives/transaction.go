@@ -22,6 +22,20 @@ func (t *Transaction) ID() []byte {
 	return h.Sum(nil)
 }
 
+// WitnessHash commits to both the transaction and its witnesses.
+func (t *Transaction) WitnessHash() []byte {
+	wh, _ := blake2b.New256(nil)
+	for _, witness := range t.Witnesses {
+		if err := witness.Encode(wh); err != nil {
+			panic(err)
+		}
+	}
+	h, _ := blake2b.New256(nil)
+	h.Write(t.ID())
+	h.Write(wh.Sum(nil))
+	return h.Sum(nil)
+}
+
 func (t *Transaction) Encode(w io.Writer) error {
 	if err := t.EncodeNoWitnesses(w); err != nil {
 		return err
@@ -0,0 +1,187 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/mslipper/handshake/spv"
+	"sync"
+	"time"
+)
+
+// HeaderSource returns block headers from an untrusted node.
+type HeaderSource interface {
+	BlockCount(ctx context.Context) (int, error)
+	HeaderByHeight(ctx context.Context, height int) (*primitives.Block, error)
+}
+
+type Checkpoint struct {
+	Height int
+	Hash   [32]byte
+}
+
+// HeaderChain validates headers from a trusted checkpoint to the source's
+// tip and serves the tip's tree root. Each header must link to its parent
+// and pass the network's proof of work, difficulty and timestamp rules.
+// The headers below the checkpoint that difficulty and median time past
+// are computed from are fetched once and authenticated by linkage to the
+// checkpoint.
+type HeaderChain struct {
+	src        HeaderSource
+	params     *spv.Params
+	checkpoint Checkpoint
+	now        func() time.Time
+	mtx        sync.Mutex
+	base       int
+	headers    []*primitives.Block
+}
+
+type HeaderChainOpt func(hc *HeaderChain)
+
+// WithClock replaces the clock used to reject headers from the future.
+func WithClock(now func() time.Time) HeaderChainOpt {
+	return func(hc *HeaderChain) {
+		hc.now = now
+	}
+}
+
+func NewHeaderChain(src HeaderSource, params *spv.Params, checkpoint Checkpoint, opts ...HeaderChainOpt) *HeaderChain {
+	hc := &HeaderChain{
+		src:        src,
+		params:     params,
+		checkpoint: checkpoint,
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(hc)
+	}
+	return hc
+}
+
+func (hc *HeaderChain) TreeRoot(ctx context.Context) ([32]byte, error) {
+	if err := hc.Sync(ctx); err != nil {
+		return [32]byte{}, err
+	}
+	hc.mtx.Lock()
+	defer hc.mtx.Unlock()
+	return hc.headers[len(hc.headers)-1].TreeRoot, nil
+}
+
+func (hc *HeaderChain) Tip() (int, *primitives.Block) {
+	hc.mtx.Lock()
+	defer hc.mtx.Unlock()
+	if len(hc.headers) == 0 {
+		return 0, nil
+	}
+	return hc.height(), hc.headers[len(hc.headers)-1]
+}
+
+// Sync fetches and validates headers up to the source's current tip. If the
+// source has reorganized, including to a chain no longer than ours,
+// validated headers are rolled back until the source's chain agrees with
+// them again, but never past the checkpoint.
+func (hc *HeaderChain) Sync(ctx context.Context) error {
+	hc.mtx.Lock()
+	defer hc.mtx.Unlock()
+
+	if len(hc.headers) == 0 {
+		if err := hc.loadCheckpoint(ctx); err != nil {
+			return err
+		}
+	}
+
+	count, err := hc.src.BlockCount(ctx)
+	if err != nil {
+		return err
+	}
+	if err := hc.rewind(ctx, count); err != nil {
+		return err
+	}
+	for height := hc.height() + 1; height <= count; height++ {
+		header, err := hc.src.HeaderByHeight(ctx, height)
+		if err != nil {
+			return err
+		}
+		tip := hc.headers[len(hc.headers)-1]
+		if !bytes.Equal(header.PrevHash[:], tip.Hash()) {
+			if hc.height() == hc.checkpoint.Height {
+				return errors.New("source chain does not include checkpoint")
+			}
+			hc.headers = hc.headers[:len(hc.headers)-1]
+			height -= 2
+			continue
+		}
+		if err := hc.params.CheckHeader(header, height, hc.header, hc.now()); err != nil {
+			return fmt.Errorf("header at height %d: %w", height, err)
+		}
+		hc.headers = append(hc.headers, header)
+	}
+	return nil
+}
+
+// rewind rolls back validated headers that are above the source's tip or
+// differ from the source's header at the same height.
+func (hc *HeaderChain) rewind(ctx context.Context, count int) error {
+	for hc.height() > hc.checkpoint.Height {
+		height := hc.height()
+		if height <= count {
+			header, err := hc.src.HeaderByHeight(ctx, height)
+			if err != nil {
+				return err
+			}
+			if bytes.Equal(header.Hash(), hc.headers[len(hc.headers)-1].Hash()) {
+				return nil
+			}
+		}
+		hc.headers = hc.headers[:len(hc.headers)-1]
+	}
+	if count < hc.checkpoint.Height {
+		return errors.New("source chain does not include checkpoint")
+	}
+	return nil
+}
+
+// loadCheckpoint fetches the checkpoint header and the headers below it
+// needed to validate its successors.
+func (hc *HeaderChain) loadCheckpoint(ctx context.Context) error {
+	header, err := hc.src.HeaderByHeight(ctx, hc.checkpoint.Height)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(header.Hash(), hc.checkpoint.Hash[:]) {
+		return errors.New("checkpoint hash mismatch")
+	}
+	base := hc.checkpoint.Height - hc.params.TargetWindow - spv.MedianTimespan
+	if base < 0 {
+		base = 0
+	}
+	headers := make([]*primitives.Block, hc.checkpoint.Height-base+1)
+	headers[len(headers)-1] = header
+	for i := len(headers) - 2; i >= 0; i-- {
+		header, err := hc.src.HeaderByHeight(ctx, base+i)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(header.Hash(), headers[i+1].PrevHash[:]) {
+			return errors.New("source chain does not include checkpoint")
+		}
+		headers[i] = header
+	}
+	hc.base = base
+	hc.headers = headers
+	return nil
+}
+
+func (hc *HeaderChain) header(height int) *primitives.Block {
+	i := height - hc.base
+	if i < 0 || i >= len(hc.headers) {
+		return nil
+	}
+	return hc.headers[i]
+}
+
+func (hc *HeaderChain) height() int {
+	return hc.base + len(hc.headers) - 1
+}
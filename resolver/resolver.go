@@ -0,0 +1,119 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mslipper/handshake/dns"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/mslipper/handshake/urkel"
+)
+
+var (
+	ErrNoSources    = errors.New("no proof sources configured")
+	ErrRootMismatch = errors.New("proof source is not synced to the requested tree root")
+)
+
+// RootSource returns a tree root that the caller has validated locally, for
+// example from a PoW-checked header chain.
+type RootSource interface {
+	TreeRoot(ctx context.Context) ([32]byte, error)
+}
+
+// ProofSource fetches an Urkel proof for name under root. Sources are not
+// trusted; every proof they return is verified before use.
+type ProofSource interface {
+	NameProof(ctx context.Context, root [32]byte, name string) (*urkel.Proof, error)
+}
+
+type Resolution struct {
+	Name      string
+	Root      [32]byte
+	Proof     *urkel.Proof
+	NameState *primitives.NameState
+	Resource  *dns.Resource
+}
+
+// Exists returns false if the resolution is a proof of non-existence.
+func (r *Resolution) Exists() bool {
+	return r.NameState != nil
+}
+
+type Resolver struct {
+	roots   RootSource
+	sources []ProofSource
+}
+
+func NewResolver(roots RootSource, sources ...ProofSource) *Resolver {
+	return &Resolver{
+		roots:   roots,
+		sources: sources,
+	}
+}
+
+// ResolveName fetches a proof for name from the configured sources in order
+// and returns the first one that verifies against the local tree root.
+func (r *Resolver) ResolveName(ctx context.Context, name string) (*Resolution, error) {
+	if err := primitives.ValidateName(name); err != nil {
+		return nil, err
+	}
+	if len(r.sources) == 0 {
+		return nil, ErrNoSources
+	}
+	root, err := r.roots.TreeRoot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, source := range r.sources {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		proof, err := source.NameProof(ctx, root, name)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		res, err := VerifyNameProof(root, name, proof)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return res, nil
+	}
+	return nil, fmt.Errorf("failed to resolve %s: %w", name, lastErr)
+}
+
+// VerifyNameProof checks proof against root and decodes the name state it
+// commits to.
+func VerifyNameProof(root [32]byte, name string, proof *urkel.Proof) (*Resolution, error) {
+	nameHash := primitives.HashName(name)
+	value, err := proof.Verify(root[:], nameHash)
+	if err != nil {
+		return nil, err
+	}
+	res := &Resolution{
+		Name:  name,
+		Root:  root,
+		Proof: proof,
+	}
+	if value == nil {
+		return res, nil
+	}
+	ns := new(primitives.NameState)
+	if err := ns.Decode(bytes.NewReader(value)); err != nil {
+		return nil, err
+	}
+	if ns.Name != name {
+		return nil, errors.New("proof name state does not match requested name")
+	}
+	resource, err := ns.Resource()
+	if err != nil {
+		return nil, err
+	}
+	res.NameState = ns
+	res.Resource = resource
+	return res, nil
+}
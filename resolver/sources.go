@@ -0,0 +1,122 @@
+package resolver
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/mslipper/handshake/client"
+	"github.com/mslipper/handshake/p2p"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/mslipper/handshake/urkel"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// ClientSource adapts a node client to the HeaderSource and ProofSource
+// interfaces.
+type ClientSource struct {
+	c *client.Client
+}
+
+func NewClientSource(c *client.Client) *ClientSource {
+	return &ClientSource{
+		c: c,
+	}
+}
+
+func (s *ClientSource) BlockCount(ctx context.Context) (int, error) {
+	return s.c.RPCGetBlockCount(ctx)
+}
+
+func (s *ClientSource) HeaderByHeight(ctx context.Context, height int) (*primitives.Block, error) {
+	hash, err := s.c.RPCGetBlockHashByHeight(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	return s.c.RPCGetBlockHeaderPrimitiveByHash(ctx, hash)
+}
+
+func (s *ClientSource) NameProof(ctx context.Context, root [32]byte, name string) (*urkel.Proof, error) {
+	res, err := s.c.RPCGetNameProof(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return checkProof(res.Root, res.Proof, root)
+}
+
+// FileSource reads proofs saved from the getnameproof RPC, stored as
+// <name>.json in a directory.
+type FileSource struct {
+	dir string
+}
+
+func NewFileSource(dir string) *FileSource {
+	return &FileSource{
+		dir: dir,
+	}
+}
+
+func (s *FileSource) NameProof(ctx context.Context, root [32]byte, name string) (*urkel.Proof, error) {
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, name+".json"))
+	if err != nil {
+		return nil, err
+	}
+	res := new(nameProofFile)
+	if err := json.Unmarshal(data, res); err != nil {
+		return nil, err
+	}
+	return checkProof(res.Root, res.Proof, root)
+}
+
+// PeerSource requests proofs from P2P peers, trying each in turn. peers is
+// called on every lookup, so a pool's Peers method can be passed directly.
+// Peers that serve invalid proofs are banned.
+type PeerSource struct {
+	peers func() []*p2p.Peer
+}
+
+func NewPeerSource(peers func() []*p2p.Peer) *PeerSource {
+	return &PeerSource{
+		peers: peers,
+	}
+}
+
+func (s *PeerSource) NameProof(ctx context.Context, root [32]byte, name string) (*urkel.Proof, error) {
+	var nameHash [32]byte
+	copy(nameHash[:], primitives.HashName(name))
+	lastErr := p2p.ErrNoPeers
+	for _, peer := range s.peers() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		select {
+		case <-peer.Done():
+			continue
+		default:
+		}
+		proof, err := peer.GetProof(ctx, root, nameHash)
+		if err == nil {
+			return proof, nil
+		}
+		lastErr = fmt.Errorf("peer %s: %w", peer, err)
+	}
+	return nil, lastErr
+}
+
+// nameProofFile holds the fields of a saved getnameproof response that
+// the resolver needs.
+type nameProofFile struct {
+	Root  string       `json:"root"`
+	Proof *urkel.Proof `json:"proof"`
+}
+
+func checkProof(proofRoot string, proof *urkel.Proof, root [32]byte) (*urkel.Proof, error) {
+	if proofRoot != hex.EncodeToString(root[:]) {
+		return nil, ErrRootMismatch
+	}
+	if proof == nil {
+		return nil, urkel.ErrMalformed
+	}
+	return proof, nil
+}
@@ -0,0 +1,280 @@
+package spv
+
+import (
+	"context"
+	"errors"
+	"github.com/mslipper/handshake/primitives"
+	"math/big"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoHeaders            = errors.New("spv: chain has no headers")
+	ErrDuplicate            = errors.New("spv: duplicate header")
+	ErrOrphan               = errors.New("spv: header does not connect to the chain")
+	ErrBadGenesis           = errors.New("spv: invalid genesis header")
+	ErrBadPOW               = errors.New("spv: header has insufficient proof of work")
+	ErrBadDifficulty        = errors.New("spv: header has incorrect difficulty bits")
+	ErrTimeTooOld           = errors.New("spv: header time is not after median time past")
+	ErrTimeTooNew           = errors.New("spv: header time is too far in the future")
+	ErrCheckpointMismatch   = errors.New("spv: header does not match checkpoint")
+	ErrForkBeforeCheckpoint = errors.New("spv: header forks before last checkpoint")
+)
+
+var twoTo256 = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// Chain is an in-memory header chain. Every header is fully validated
+// against its parent before it is stored, side chains included, and the
+// main chain always follows the branch with the most cumulative work.
+type Chain struct {
+	params  *Params
+	now     func() time.Time
+	mtx     sync.RWMutex
+	entries map[[32]byte]*primitives.ChainEntry
+	main    []*primitives.ChainEntry
+}
+
+type ChainOpt func(c *Chain)
+
+// WithClock replaces the clock used to reject headers from the future.
+func WithClock(now func() time.Time) ChainOpt {
+	return func(c *Chain) {
+		c.now = now
+	}
+}
+
+// NewChain returns an empty chain. The first header added to it must be the
+// genesis header pinned by params.
+func NewChain(params *Params, opts ...ChainOpt) *Chain {
+	c := &Chain{
+		params:  params,
+		now:     time.Now,
+		entries: make(map[[32]byte]*primitives.ChainEntry),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Chain) Params() *Params {
+	return c.params
+}
+
+// Height returns the height of the main chain's tip, or -1 if the chain is
+// empty.
+func (c *Chain) Height() int {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return len(c.main) - 1
+}
+
+func (c *Chain) Tip() *primitives.ChainEntry {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	if len(c.main) == 0 {
+		return nil
+	}
+	return c.main[len(c.main)-1]
+}
+
+func (c *Chain) Genesis() *primitives.ChainEntry {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	if len(c.main) == 0 {
+		return nil
+	}
+	return c.main[0]
+}
+
+// EntryByHeight returns the main chain entry at height.
+func (c *Chain) EntryByHeight(height int) *primitives.ChainEntry {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	if height < 0 || height >= len(c.main) {
+		return nil
+	}
+	return c.main[height]
+}
+
+// EntryByHash returns the entry for hash, which may be on a side chain.
+func (c *Chain) EntryByHash(hash [32]byte) *primitives.ChainEntry {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.entries[hash]
+}
+
+func (c *Chain) HasHeader(hash [32]byte) bool {
+	return c.EntryByHash(hash) != nil
+}
+
+func (c *Chain) IsMain(hash [32]byte) bool {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	entry := c.entries[hash]
+	return entry != nil && c.isMain(entry)
+}
+
+// TreeRoot returns the name tree root committed to by the main chain's tip.
+func (c *Chain) TreeRoot(ctx context.Context) ([32]byte, error) {
+	tip := c.Tip()
+	if tip == nil {
+		return [32]byte{}, ErrNoHeaders
+	}
+	return tip.Header.TreeRoot, nil
+}
+
+// Locator returns main chain hashes from the tip backwards, dense at first
+// and then exponentially sparser, always ending with the genesis block.
+func (c *Chain) Locator() [][32]byte {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	var locator [][32]byte
+	if len(c.main) == 0 {
+		return locator
+	}
+	step := 1
+	for height := len(c.main) - 1; height > 0; height -= step {
+		locator = append(locator, c.main[height].Hash)
+		if len(locator) >= 10 {
+			step *= 2
+		}
+	}
+	return append(locator, c.main[0].Hash)
+}
+
+// AddHeader validates header and connects it to the chain, reorganizing the
+// main chain if the header's branch now has the most work. It returns true
+// if the main chain changed.
+func (c *Chain) AddHeader(header *primitives.Block) (bool, error) {
+	var hash [32]byte
+	copy(hash[:], header.Hash())
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.entries[hash] != nil {
+		return false, ErrDuplicate
+	}
+	if len(c.main) == 0 {
+		return c.addGenesis(hash, header)
+	}
+	prev := c.entries[header.PrevHash]
+	if prev == nil {
+		return false, ErrOrphan
+	}
+	height := int(prev.Height) + 1
+
+	if cpHash, ok := c.params.checkpoint(height); ok && cpHash != hash {
+		return false, ErrCheckpointMismatch
+	}
+	if fork := c.forkPoint(prev); int(fork.Height) < c.params.lastCheckpoint(len(c.main)-1) {
+		return false, ErrForkBeforeCheckpoint
+	}
+	if err := c.params.CheckHeader(header, height, c.branch(prev), c.now()); err != nil {
+		return false, err
+	}
+
+	entry := &primitives.ChainEntry{
+		Hash:      hash,
+		Height:    uint32(height),
+		Header:    header,
+		Chainwork: new(big.Int).Add(prev.Chainwork, proof(header.Bits)),
+	}
+	c.entries[hash] = entry
+	if entry.Chainwork.Cmp(c.main[len(c.main)-1].Chainwork) <= 0 {
+		return false, nil
+	}
+	c.setBest(entry)
+	return true, nil
+}
+
+func (c *Chain) addGenesis(hash [32]byte, header *primitives.Block) (bool, error) {
+	if header.PrevHash != [32]byte{} || header.Bits != c.params.PowBits {
+		return false, ErrBadGenesis
+	}
+	if c.params.GenesisHash != hash {
+		return false, ErrBadGenesis
+	}
+	if cpHash, ok := c.params.checkpoint(0); ok && cpHash != hash {
+		return false, ErrCheckpointMismatch
+	}
+	entry := &primitives.ChainEntry{
+		Hash:      hash,
+		Height:    0,
+		Header:    header,
+		Chainwork: proof(header.Bits),
+	}
+	c.entries[hash] = entry
+	c.main = append(c.main, entry)
+	return true, nil
+}
+
+// setBest makes entry the main chain's tip, replacing main chain entries
+// back to the point where entry's branch forks off.
+func (c *Chain) setBest(entry *primitives.ChainEntry) {
+	var branch []*primitives.ChainEntry
+	for !c.isMain(entry) {
+		branch = append(branch, entry)
+		entry = c.entries[entry.Header.PrevHash]
+	}
+	c.main = c.main[:entry.Height+1]
+	for i := len(branch) - 1; i >= 0; i-- {
+		c.main = append(c.main, branch[i])
+	}
+}
+
+func (c *Chain) isMain(entry *primitives.ChainEntry) bool {
+	return int(entry.Height) < len(c.main) && c.main[entry.Height] == entry
+}
+
+func (c *Chain) forkPoint(entry *primitives.ChainEntry) *primitives.ChainEntry {
+	for !c.isMain(entry) {
+		entry = c.entries[entry.Header.PrevHash]
+	}
+	return entry
+}
+
+// ancestor returns entry's ancestor at height, which may be on a side
+// chain.
+func (c *Chain) ancestor(entry *primitives.ChainEntry, height int) *primitives.ChainEntry {
+	if height < 0 || height > int(entry.Height) {
+		return nil
+	}
+	for !c.isMain(entry) {
+		if int(entry.Height) == height {
+			return entry
+		}
+		entry = c.entries[entry.Header.PrevHash]
+	}
+	return c.main[height]
+}
+
+// branch returns the headers of the branch ending at tip.
+func (c *Chain) branch(tip *primitives.ChainEntry) HeaderFunc {
+	return func(height int) *primitives.Block {
+		entry := c.ancestor(tip, height)
+		if entry == nil {
+			return nil
+		}
+		return entry.Header
+	}
+}
+
+func (c *Chain) medianTime(entry *primitives.ChainEntry) uint64 {
+	return MedianTime(int(entry.Height), c.branch(entry))
+}
+
+func (c *Chain) nextBits(prev *primitives.ChainEntry, blockTime uint64) uint32 {
+	return c.params.NextBits(int(prev.Height), blockTime, c.branch(prev))
+}
+
+// proof returns the expected number of hashes needed to meet bits.
+func proof(bits uint32) *big.Int {
+	target := primitives.CompactToBig(bits)
+	if target.Sign() <= 0 {
+		return new(big.Int)
+	}
+	return new(big.Int).Div(twoTo256, target.Add(target, big.NewInt(1)))
+}
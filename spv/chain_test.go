@@ -0,0 +1,328 @@
+package spv
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"testing"
+	"time"
+)
+
+const genesisTime = 1580745080
+
+func testParams() *Params {
+	p := newParams(primitives.NetworkRegtest, 0x207fffff, hex.EncodeToString(RegtestParams.GenesisHash[:]))
+	p.TargetWindow = 4
+	p.TargetTimespan = 4 * p.TargetSpacing
+	p.MinActual = p.TargetTimespan * (100 - 16) / 100
+	p.MaxActual = p.TargetTimespan * (100 + 32) / 100
+	return p
+}
+
+func mine(t *testing.T, header *primitives.Block) *primitives.Block {
+	for !header.VerifyPOW() {
+		header.Nonce++
+		require.NotZero(t, header.Nonce)
+	}
+	return header
+}
+
+// testGenesis returns hsd's genesis header for the params' network. The
+// networks share a genesis coinbase and differ only in time and bits.
+func testGenesis(t *testing.T, params *Params) *primitives.Block {
+	times := map[primitives.Network]uint64{
+		primitives.NetworkMainnet: 1580745078,
+		primitives.NetworkTestnet: 1580745079,
+		primitives.NetworkRegtest: 1580745080,
+		primitives.NetworkSimnet:  1580745081,
+	}
+	header := &primitives.Block{
+		Time: times[params.Network],
+		Bits: params.PowBits,
+	}
+	copy(header.MerkleRoot[:], mustHex(t, "8e4c9756fef2ad10375f360e0560fcc7587eb5223ddf8cd7c7e06e60a1140b15"))
+	copy(header.WitnessRoot[:], mustHex(t, "1a2c60b9439206938f8d7823782abdb8b211a57431e9c9b6a6365d8d42893351"))
+	return header
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+// extend mines n headers on top of from, spaced by spacing seconds, and
+// adds them to c. tag is committed to in each header's tree root so that
+// forks built from the same parent differ.
+func extend(t *testing.T, c *Chain, from *primitives.ChainEntry, n int, spacing uint64, tag byte) []*primitives.Block {
+	var headers []*primitives.Block
+	prev := from
+	for i := 0; i < n; i++ {
+		header := &primitives.Block{
+			PrevHash: prev.Hash,
+			Time:     prev.Header.Time + spacing,
+		}
+		header.TreeRoot[0] = tag
+		header.TreeRoot[1] = byte(prev.Height + 1)
+		c.mtx.RLock()
+		header.Bits = c.nextBits(prev, header.Time)
+		c.mtx.RUnlock()
+		mine(t, header)
+		_, err := c.AddHeader(header)
+		require.NoError(t, err)
+		headers = append(headers, header)
+		prev = c.EntryByHash(hashOf(header))
+	}
+	return headers
+}
+
+func hashOf(header *primitives.Block) [32]byte {
+	var hash [32]byte
+	copy(hash[:], header.Hash())
+	return hash
+}
+
+func newTestChain(t *testing.T, params *Params) *Chain {
+	c := NewChain(params)
+	_, err := c.AddHeader(testGenesis(t, params))
+	require.NoError(t, err)
+	return c
+}
+
+func TestChain_Connect(t *testing.T) {
+	c := NewChain(RegtestParams)
+	require.Equal(t, -1, c.Height())
+	require.Nil(t, c.Tip())
+	require.Empty(t, c.Locator())
+	_, err := c.TreeRoot(context.Background())
+	require.Equal(t, ErrNoHeaders, err)
+
+	genesis := testGenesis(t, RegtestParams)
+	changed, err := c.AddHeader(genesis)
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	headers := extend(t, c, c.Tip(), 30, 600, 0)
+	require.Equal(t, 30, c.Height())
+	require.Equal(t, hashOf(headers[29]), c.Tip().Hash)
+	require.Equal(t, hashOf(headers[9]), c.EntryByHeight(10).Hash)
+	require.Equal(t, hashOf(genesis), c.Genesis().Hash)
+	require.True(t, c.IsMain(hashOf(headers[0])))
+
+	root, err := c.TreeRoot(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, headers[29].TreeRoot, root)
+
+	work := new(big.Int).Mul(proof(RegtestParams.PowBits), big.NewInt(31))
+	require.Equal(t, 0, work.Cmp(c.Tip().Chainwork))
+
+	locator := c.Locator()
+	require.Equal(t, c.Tip().Hash, locator[0])
+	require.Equal(t, c.EntryByHeight(21).Hash, locator[9])
+	require.Equal(t, c.EntryByHeight(19).Hash, locator[10])
+	require.Equal(t, hashOf(genesis), locator[len(locator)-1])
+}
+
+func TestChain_Genesis(t *testing.T) {
+	for _, params := range []*Params{MainnetParams, TestnetParams, RegtestParams, SimnetParams} {
+		genesis := testGenesis(t, params)
+		require.Equal(t, params.GenesisHash, hashOf(genesis), params.Network.String())
+
+		// Any other genesis is rejected, even one that is otherwise valid.
+		other := *genesis
+		other.Nonce++
+		c := NewChain(params)
+		_, err := c.AddHeader(&other)
+		require.Equal(t, ErrBadGenesis, err)
+		_, err = c.AddHeader(genesis)
+		require.NoError(t, err)
+	}
+}
+
+func TestChain_Retarget(t *testing.T) {
+	params := testParams()
+	c := newTestChain(t, params)
+
+	// Until a full window exists the limit is used.
+	extend(t, c, c.Tip(), params.TargetWindow, 60, 0)
+	require.Equal(t, params.PowBits, c.Tip().Header.Bits)
+
+	// Fast blocks are clamped to the minimum timespan.
+	extend(t, c, c.Tip(), 1, 60, 0)
+	expected := new(big.Int).Mul(params.PowLimit, big.NewInt(params.MinActual))
+	expected.Div(expected, big.NewInt(params.TargetTimespan))
+	require.Equal(t, primitives.BigToCompact(expected), c.Tip().Header.Bits)
+
+	// Keeping up the pace keeps raising the difficulty.
+	extend(t, c, c.Tip(), 10, 60, 0)
+	require.Equal(t, 1, expected.Cmp(primitives.CompactToBig(c.Tip().Header.Bits)))
+
+	// Slow blocks drift back to the limit, which is never exceeded.
+	extend(t, c, c.Tip(), 40, 6000, 0)
+	require.Equal(t, params.PowBits, c.Tip().Header.Bits)
+
+	extend(t, c, c.Tip(), MedianTimespan+params.TargetWindow, 60, 0)
+	_, err := c.AddHeader(mine(t, &primitives.Block{
+		PrevHash: c.Tip().Hash,
+		Time:     c.Tip().Header.Time + 60,
+		Bits:     params.PowBits,
+	}))
+	require.True(t, errors.Is(err, ErrBadDifficulty))
+}
+
+func TestChain_TargetReset(t *testing.T) {
+	params := testParams()
+	params.TargetReset = true
+	c := newTestChain(t, params)
+	extend(t, c, c.Tip(), 6, 60, 0)
+	require.NotEqual(t, params.PowBits, c.Tip().Header.Bits)
+
+	_, err := c.AddHeader(mine(t, &primitives.Block{
+		PrevHash: c.Tip().Hash,
+		Time:     c.Tip().Header.Time + uint64(params.TargetSpacing*2) + 1,
+		Bits:     params.PowBits,
+	}))
+	require.NoError(t, err)
+}
+
+func TestChain_Reorg(t *testing.T) {
+	c := newTestChain(t, RegtestParams)
+	main := extend(t, c, c.Tip(), 5, 600, 0)
+	fork := c.EntryByHeight(2)
+
+	// An equal-work branch does not replace the main chain.
+	side := extend(t, c, fork, 3, 600, 1)
+	require.Equal(t, hashOf(main[4]), c.Tip().Hash)
+	require.False(t, c.IsMain(hashOf(side[0])))
+	require.NotNil(t, c.EntryByHash(hashOf(side[2])))
+
+	// One more block makes it the best chain.
+	more := extend(t, c, c.EntryByHash(hashOf(side[2])), 1, 600, 1)
+	require.Equal(t, 6, c.Height())
+	require.Equal(t, hashOf(more[0]), c.Tip().Hash)
+	require.Equal(t, hashOf(side[0]), c.EntryByHeight(3).Hash)
+	require.False(t, c.IsMain(hashOf(main[2])))
+	require.True(t, c.IsMain(fork.Hash))
+
+	// And the old branch can take it back.
+	extend(t, c, c.EntryByHash(hashOf(main[4])), 2, 600, 0)
+	require.Equal(t, 7, c.Height())
+	require.True(t, c.IsMain(hashOf(main[2])))
+	require.False(t, c.IsMain(hashOf(side[0])))
+}
+
+func TestChain_Checkpoints(t *testing.T) {
+	params := *RegtestParams
+	builder := newTestChain(t, &params)
+	headers := extend(t, builder, builder.Tip(), 5, 600, 0)
+	params.Checkpoints = []Checkpoint{{Height: 3, Hash: hashOf(headers[2])}}
+
+	c := NewChain(&params)
+	_, err := c.AddHeader(builder.Genesis().Header)
+	require.NoError(t, err)
+	for _, header := range headers[:2] {
+		_, err := c.AddHeader(header)
+		require.NoError(t, err)
+	}
+	bad := mine(t, &primitives.Block{
+		PrevHash: hashOf(headers[1]),
+		Time:     headers[1].Time + 1,
+		Bits:     params.PowBits,
+	})
+	_, err = c.AddHeader(bad)
+	require.Equal(t, ErrCheckpointMismatch, err)
+
+	for _, header := range headers[2:] {
+		_, err := c.AddHeader(header)
+		require.NoError(t, err)
+	}
+	fork := mine(t, &primitives.Block{
+		PrevHash: hashOf(headers[0]),
+		Time:     headers[0].Time + 1,
+		Bits:     params.PowBits,
+	})
+	_, err = c.AddHeader(fork)
+	require.Equal(t, ErrForkBeforeCheckpoint, err)
+
+	fork.PrevHash = hashOf(headers[2])
+	fork.Time = headers[2].Time + 1
+	_, err = c.AddHeader(mine(t, fork))
+	require.NoError(t, err)
+}
+
+func TestChain_Errors(t *testing.T) {
+	now := time.Unix(genesisTime+100*600, 0)
+	c := NewChain(RegtestParams, WithClock(func() time.Time {
+		return now
+	}))
+	genesis := testGenesis(t, RegtestParams)
+
+	orphan := *genesis
+	orphan.PrevHash = [32]byte{0x01}
+	_, err := c.AddHeader(&orphan)
+	require.Equal(t, ErrBadGenesis, err)
+	_, err = c.AddHeader(genesis)
+	require.NoError(t, err)
+	extend(t, c, c.Tip(), 12, 600, 0)
+	tip := c.Tip()
+
+	tests := []struct {
+		name   string
+		header func() *primitives.Block
+		err    error
+	}{
+		{
+			"duplicate",
+			func() *primitives.Block {
+				return tip.Header
+			},
+			ErrDuplicate,
+		},
+		{
+			"orphan",
+			func() *primitives.Block {
+				return mine(t, &primitives.Block{PrevHash: [32]byte{0x01}, Time: tip.Header.Time + 1, Bits: RegtestParams.PowBits})
+			},
+			ErrOrphan,
+		},
+		{
+			"bad pow",
+			func() *primitives.Block {
+				header := &primitives.Block{PrevHash: tip.Hash, Time: tip.Header.Time + 1, Bits: RegtestParams.PowBits}
+				for header.VerifyPOW() {
+					header.Nonce++
+				}
+				return header
+			},
+			ErrBadPOW,
+		},
+		{
+			"time too old",
+			func() *primitives.Block {
+				c.mtx.RLock()
+				mtp := c.medianTime(tip)
+				c.mtx.RUnlock()
+				return mine(t, &primitives.Block{PrevHash: tip.Hash, Time: mtp, Bits: RegtestParams.PowBits})
+			},
+			ErrTimeTooOld,
+		},
+		{
+			"time too new",
+			func() *primitives.Block {
+				future := now.Add(MaxFutureDrift).Unix() + 1
+				return mine(t, &primitives.Block{PrevHash: tip.Hash, Time: uint64(future), Bits: RegtestParams.PowBits})
+			},
+			ErrTimeTooNew,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := c.AddHeader(tt.header())
+			require.Equal(t, tt.err, err)
+			require.Equal(t, tip, c.Tip())
+		})
+	}
+}
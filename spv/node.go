@@ -0,0 +1,328 @@
+package spv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mslipper/handshake/p2p"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/mslipper/handshake/wire"
+	"sync"
+	"time"
+)
+
+const DefaultRequestTimeout = 30 * time.Second
+
+var (
+	ErrNotSynced   = errors.New("spv: headers are not synced")
+	ErrNoGenesis   = errors.New("spv: peer did not serve a genesis header")
+	ErrStalledSync = errors.New("spv: peer sent no new headers")
+)
+
+// Node keeps a Chain in sync with its peers using headers-first sync. Any
+// peer that serves a header breaking consensus rules is banned.
+//
+// A Node can drive a peer on its own with WatchPeer, or follow the peers of
+// a p2p.Pool through HandleMessage.
+type Node struct {
+	chain          *Chain
+	requestTimeout time.Duration
+	mtx            sync.RWMutex
+	synced         bool
+	syncing        map[*p2p.Peer]bool
+}
+
+type NodeOpt func(n *Node)
+
+func WithRequestTimeout(timeout time.Duration) NodeOpt {
+	return func(n *Node) {
+		n.requestTimeout = timeout
+	}
+}
+
+func NewNode(chain *Chain, opts ...NodeOpt) *Node {
+	n := &Node{
+		chain:          chain,
+		requestTimeout: DefaultRequestTimeout,
+		syncing:        make(map[*p2p.Peer]bool),
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n
+}
+
+func (n *Node) Chain() *Chain {
+	return n.chain
+}
+
+func (n *Node) Synced() bool {
+	n.mtx.RLock()
+	defer n.mtx.RUnlock()
+	return n.synced
+}
+
+// TreeRoot returns the tree root of the best validated header. It fails
+// until at least one peer sync has completed, so that roots from a chain
+// that is still catching up are never served.
+func (n *Node) TreeRoot(ctx context.Context) ([32]byte, error) {
+	if !n.Synced() {
+		return [32]byte{}, ErrNotSynced
+	}
+	return n.chain.TreeRoot(ctx)
+}
+
+// SyncPeer downloads headers from peer until it has nothing newer. Peers
+// may not answer a getheaders they have nothing for, so a timed out
+// request counts as done once the chain has reached the height the peer
+// advertised in its version.
+//
+// SyncPeer does not read the peer's message channels. Something else must
+// drain them while it runs, as a Pool or WatchPeer does, or a peer that
+// sends other messages stalls the sync. A header too far in the future
+// returns ErrTimeTooNew without penalty; the sync can be retried later.
+func (n *Node) SyncPeer(ctx context.Context, peer *p2p.Peer) error {
+	if n.chain.Tip() == nil {
+		if err := n.syncGenesis(ctx, peer); err != nil {
+			return err
+		}
+	}
+
+	for {
+		tip := n.chain.Tip()
+		headers, err := n.getHeaders(ctx, peer, n.chain.Locator(), [32]byte{})
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil &&
+				n.chain.Height() >= int(peer.Version().Height) {
+				break
+			}
+			return err
+		}
+		if len(headers) == 0 {
+			break
+		}
+		if err := n.addHeaders(peer, headers); err != nil {
+			return err
+		}
+		if len(headers) < wire.MaxHeaders {
+			break
+		}
+		if n.chain.Tip() == tip {
+			peer.Misbehave(p2p.DefaultBanThreshold, ErrStalledSync.Error())
+			return ErrStalledSync
+		}
+	}
+
+	n.mtx.Lock()
+	n.synced = true
+	n.mtx.Unlock()
+	return nil
+}
+
+// WatchPeer syncs from peer and then follows its block announcements until
+// the peer disconnects or ctx is done. Messages the node has no use for are
+// discarded so that they cannot stall the peer. The peer must not be
+// managed by a Pool; use HandleMessage for those.
+func (n *Node) WatchPeer(ctx context.Context, peer *p2p.Peer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go discardMessages(ctx, peer)
+
+	syncReqs := make(chan struct{}, 1)
+	syncErr := make(chan error, 1)
+	syncReqs <- struct{}{}
+	go func() {
+		for {
+			select {
+			case <-syncReqs:
+			case <-ctx.Done():
+				return
+			}
+			if err := n.SyncPeer(ctx, peer); err != nil && !errors.Is(err, ErrTimeTooNew) {
+				syncErr <- err
+				return
+			}
+		}
+	}()
+	requestSync := func() {
+		select {
+		case syncReqs <- struct{}{}:
+		default:
+		}
+	}
+
+	for {
+		select {
+		case msg, ok := <-peer.Headers():
+			if !ok {
+				return peer.Err()
+			}
+			err := n.addHeaders(peer, msg.Headers)
+			switch {
+			case errors.Is(err, ErrOrphan):
+				requestSync()
+			case err != nil && !errors.Is(err, ErrTimeTooNew):
+				return err
+			}
+		case msg, ok := <-peer.Inv():
+			if !ok {
+				return peer.Err()
+			}
+			if n.hasUnknownBlock(msg) {
+				requestSync()
+			}
+		case err := <-syncErr:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// HandleMessage follows the block announcements of a Pool's peers. Pass it
+// every message read from Pool.Messages. Syncs it starts run in the
+// background until ctx is done, so it never blocks the caller's read loop
+// and with it the pool. Peers serving invalid headers are banned; other
+// sync failures are retried on the peer's next announcement.
+func (n *Node) HandleMessage(ctx context.Context, msg *p2p.PeerMessage) {
+	switch m := msg.Message.(type) {
+	case *wire.HeadersMessage:
+		if err := n.addHeaders(msg.Peer, m.Headers); errors.Is(err, ErrOrphan) {
+			n.startSync(ctx, msg.Peer)
+		}
+	case *wire.InvMessage:
+		if n.hasUnknownBlock(m) {
+			n.startSync(ctx, msg.Peer)
+		}
+	}
+}
+
+// startSync syncs from peer in the background. If a sync from the peer is
+// already running, it is run once more when done so that announcements
+// arriving mid-sync are not missed.
+func (n *Node) startSync(ctx context.Context, peer *p2p.Peer) {
+	n.mtx.Lock()
+	_, running := n.syncing[peer]
+	n.syncing[peer] = running
+	n.mtx.Unlock()
+	if running {
+		return
+	}
+	go func() {
+		for {
+			_ = n.SyncPeer(ctx, peer)
+			n.mtx.Lock()
+			again := n.syncing[peer] && ctx.Err() == nil
+			if !again {
+				delete(n.syncing, peer)
+			} else {
+				n.syncing[peer] = false
+			}
+			n.mtx.Unlock()
+			if !again {
+				return
+			}
+		}
+	}()
+}
+
+// discardMessages drains the peer's channels that WatchPeer does not read
+// until ctx is done or the peer disconnects.
+func discardMessages(ctx context.Context, peer *p2p.Peer) {
+	for {
+		var ok bool
+		select {
+		case _, ok = <-peer.Blocks():
+		case _, ok = <-peer.Txs():
+		case _, ok = <-peer.Addrs():
+		case _, ok = <-peer.NotFound():
+		case _, ok = <-peer.Rejects():
+		case _, ok = <-peer.Messages():
+		case <-ctx.Done():
+			return
+		}
+		if !ok {
+			return
+		}
+	}
+}
+
+func (n *Node) hasUnknownBlock(msg *wire.InvMessage) bool {
+	for _, item := range msg.Items {
+		if item.Type == wire.InvTypeBlock && !n.chain.HasHeader(item.Hash) {
+			return true
+		}
+	}
+	return false
+}
+
+// addHeaders connects headers to the chain in order. Headers that do not
+// connect are returned as ErrOrphan without penalty since the peer may be
+// announcing blocks past what we have, and headers from the future may
+// become valid as our clock catches up. Only consensus failures ban the
+// peer.
+func (n *Node) addHeaders(peer *p2p.Peer, headers []*primitives.Block) error {
+	for _, header := range headers {
+		_, err := n.chain.AddHeader(header)
+		if err == nil || errors.Is(err, ErrDuplicate) {
+			continue
+		}
+		if isConsensusError(err) {
+			peer.Misbehave(p2p.DefaultBanThreshold, err.Error())
+		}
+		return err
+	}
+	return nil
+}
+
+// isConsensusError reports whether err means a header can never be valid,
+// as opposed to not yet being connectable or acceptable.
+func isConsensusError(err error) bool {
+	for _, target := range []error{
+		ErrBadGenesis,
+		ErrBadPOW,
+		ErrBadDifficulty,
+		ErrTimeTooOld,
+		ErrCheckpointMismatch,
+		ErrForkBeforeCheckpoint,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// syncGenesis fetches the genesis header pinned by the params.
+func (n *Node) syncGenesis(ctx context.Context, peer *p2p.Peer) error {
+	headers, err := n.getHeaders(ctx, peer, nil, n.chain.Params().GenesisHash)
+	if err != nil {
+		return err
+	}
+	if len(headers) != 1 {
+		return ErrNoGenesis
+	}
+	if _, err := n.chain.AddHeader(headers[0]); err != nil && !errors.Is(err, ErrDuplicate) {
+		if isConsensusError(err) {
+			peer.Misbehave(p2p.DefaultBanThreshold, err.Error())
+		}
+		return fmt.Errorf("spv: invalid genesis from peer: %w", err)
+	}
+	return nil
+}
+
+func (n *Node) getHeaders(ctx context.Context, peer *p2p.Peer, locator [][32]byte, stop [32]byte) ([]*primitives.Block, error) {
+	ctx, cancel := context.WithTimeout(ctx, n.requestTimeout)
+	defer cancel()
+	res, err := peer.Request(ctx, &wire.GetHeadersMessage{
+		Locator: locator,
+		Stop:    stop,
+	}, func(msg wire.Message) bool {
+		_, ok := msg.(*wire.HeadersMessage)
+		return ok
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*wire.HeadersMessage).Headers, nil
+}
@@ -0,0 +1,354 @@
+package spv
+
+import (
+	"context"
+	"errors"
+	"github.com/mslipper/handshake/p2p"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/mslipper/handshake/wire"
+	"github.com/stretchr/testify/require"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// headerServer answers getheaders from a fixed main chain the way hsd
+// does. If quiet is set, requests with nothing new go unanswered.
+type headerServer struct {
+	mtx     sync.Mutex
+	headers []*primitives.Block
+	quiet   bool
+	peer    *p2p.Peer
+}
+
+func (s *headerServer) setHeaders(headers []*primitives.Block) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.headers = headers
+}
+
+func (s *headerServer) height() uint32 {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return uint32(len(s.headers) - 1)
+}
+
+func (s *headerServer) announce(hash [32]byte) error {
+	return s.peer.Send(&wire.InvMessage{
+		Items: []*wire.InvItem{
+			{Type: wire.InvTypeBlock, Hash: hash},
+		},
+	})
+}
+
+func (s *headerServer) find(hash [32]byte) int {
+	for i, header := range s.headers {
+		if hashOf(header) == hash {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *headerServer) serve(peer *p2p.Peer) {
+	for msg := range peer.Messages() {
+		req, ok := msg.(*wire.GetHeadersMessage)
+		if !ok {
+			continue
+		}
+		res := s.respond(req)
+		if res == nil {
+			continue
+		}
+		_ = peer.Send(res)
+	}
+}
+
+func (s *headerServer) respond(req *wire.GetHeadersMessage) *wire.HeadersMessage {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	res := new(wire.HeadersMessage)
+	if len(req.Locator) == 0 {
+		if i := s.find(req.Stop); i >= 0 {
+			res.Headers = append(res.Headers, s.headers[i])
+		}
+		return res
+	}
+	start := 0
+	for _, hash := range req.Locator {
+		if i := s.find(hash); i >= 0 {
+			start = i
+			break
+		}
+	}
+	for i := start + 1; i < len(s.headers) && len(res.Headers) < wire.MaxHeaders; i++ {
+		res.Headers = append(res.Headers, s.headers[i])
+		if hashOf(s.headers[i]) == req.Stop {
+			break
+		}
+	}
+	if len(res.Headers) == 0 && s.quiet {
+		return nil
+	}
+	return res
+}
+
+func connectServer(t *testing.T, srv *headerServer) *p2p.Peer {
+	localConn, remoteConn := net.Pipe()
+	local := p2p.NewPeer(localConn, primitives.NetworkRegtest, true)
+	errCh := startServer(srv, remoteConn)
+	require.NoError(t, local.Start(context.Background()))
+	require.NoError(t, <-errCh)
+	return local
+}
+
+// connectPool adds srv to a new pool as its only peer.
+func connectPool(t *testing.T, srv *headerServer) (*p2p.Pool, *p2p.Peer) {
+	localConn, remoteConn := net.Pipe()
+	pool := p2p.NewPool(primitives.NetworkRegtest, p2p.NewAddrBook(primitives.NetworkRegtest), p2p.WithDialer(
+		func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return localConn, nil
+		},
+	))
+	errCh := startServer(srv, remoteConn)
+	peer, err := pool.Connect(context.Background(), &wire.NetAddress{
+		IP:   net.ParseIP("127.0.0.1"),
+		Port: 14038,
+	})
+	require.NoError(t, err)
+	require.NoError(t, <-errCh)
+	return pool, peer
+}
+
+func startServer(srv *headerServer, conn net.Conn) <-chan error {
+	remote := p2p.NewPeer(conn, primitives.NetworkRegtest, false, p2p.WithHeight(srv.height))
+	srv.peer = remote
+	errCh := make(chan error, 1)
+	go func() {
+		err := remote.Start(context.Background())
+		if err == nil {
+			go srv.serve(remote)
+		}
+		errCh <- err
+	}()
+	return errCh
+}
+
+// floodInvs announces more transactions than a peer buffers. The returned
+// channel is closed once the other side has read them all.
+func floodInvs(srv *headerServer) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 4*p2p.DefaultMessageBuffer; i++ {
+			err := srv.peer.Send(&wire.InvMessage{
+				Items: []*wire.InvItem{
+					{Type: wire.InvTypeTx, Hash: [32]byte{byte(i)}},
+				},
+			})
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return done
+}
+
+func mainHeaders(c *Chain) []*primitives.Block {
+	var headers []*primitives.Block
+	for i := 0; i <= c.Height(); i++ {
+		headers = append(headers, c.EntryByHeight(i).Header)
+	}
+	return headers
+}
+
+func TestNode_Sync(t *testing.T) {
+	builder := newTestChain(t, RegtestParams)
+	extend(t, builder, builder.Tip(), wire.MaxHeaders+500, 600, 0)
+	peer := connectServer(t, &headerServer{headers: mainHeaders(builder)})
+	defer peer.Close()
+
+	node := NewNode(NewChain(RegtestParams))
+	_, err := node.TreeRoot(context.Background())
+	require.Equal(t, ErrNotSynced, err)
+
+	require.NoError(t, node.SyncPeer(context.Background(), peer))
+	require.Equal(t, builder.Height(), node.Chain().Height())
+	require.Equal(t, builder.Tip().Hash, node.Chain().Tip().Hash)
+	require.Equal(t, builder.Genesis().Hash, node.Chain().Genesis().Hash)
+	root, err := node.TreeRoot(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, builder.Tip().Header.TreeRoot, root)
+}
+
+func TestNode_SyncQuietPeer(t *testing.T) {
+	builder := newTestChain(t, RegtestParams)
+	extend(t, builder, builder.Tip(), 10, 600, 0)
+	peer := connectServer(t, &headerServer{headers: mainHeaders(builder), quiet: true})
+	defer peer.Close()
+
+	node := NewNode(NewChain(RegtestParams), WithRequestTimeout(50*time.Millisecond))
+	require.NoError(t, node.SyncPeer(context.Background(), peer))
+	require.Equal(t, 10, node.Chain().Height())
+	require.True(t, node.Synced())
+}
+
+func TestNode_Reorg(t *testing.T) {
+	builder := newTestChain(t, RegtestParams)
+	extend(t, builder, builder.Tip(), 10, 600, 0)
+	mainChain := mainHeaders(builder)
+	node := NewNode(NewChain(RegtestParams))
+	first := connectServer(t, &headerServer{headers: mainChain})
+	defer first.Close()
+	require.NoError(t, node.SyncPeer(context.Background(), first))
+
+	extend(t, builder, builder.EntryByHeight(5), 8, 600, 1)
+	srv := &headerServer{headers: mainHeaders(builder)}
+	peer := connectServer(t, srv)
+	defer peer.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- node.WatchPeer(ctx, peer)
+	}()
+	require.Eventually(t, func() bool {
+		return node.Chain().Tip().Hash == builder.Tip().Hash
+	}, time.Second, time.Millisecond)
+	require.Equal(t, 13, node.Chain().Height())
+	require.False(t, node.Chain().IsMain(hashOf(mainChain[6])))
+
+	// New blocks are picked up from inv announcements.
+	extend(t, builder, builder.Tip(), 3, 600, 1)
+	srv.setHeaders(mainHeaders(builder))
+	require.NoError(t, srv.announce(builder.Tip().Hash))
+	require.Eventually(t, func() bool {
+		return node.Chain().Height() == 16
+	}, time.Second, time.Millisecond)
+
+	// As are headers pushed directly.
+	next := extend(t, builder, builder.Tip(), 1, 600, 1)
+	require.NoError(t, srv.peer.Send(&wire.HeadersMessage{Headers: next}))
+	require.Eventually(t, func() bool {
+		return node.Chain().Height() == 17
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	require.Equal(t, context.Canceled, <-errCh)
+}
+
+func TestNode_InvalidHeaders(t *testing.T) {
+	builder := newTestChain(t, RegtestParams)
+	headers := extend(t, builder, builder.Tip(), 5, 600, 0)
+	bad := *headers[4]
+	bad.Time = headers[0].Time
+	mine(t, &bad)
+	srv := &headerServer{headers: append(mainHeaders(builder)[:5], &bad)}
+	peer := connectServer(t, srv)
+
+	node := NewNode(NewChain(RegtestParams))
+	err := node.SyncPeer(context.Background(), peer)
+	require.True(t, errors.Is(err, ErrTimeTooOld))
+	require.Equal(t, 4, node.Chain().Height())
+	<-peer.Done()
+	require.True(t, peer.Banned())
+	require.False(t, node.Synced())
+}
+
+func TestNode_WatchPeerFloodedWithInvs(t *testing.T) {
+	builder := newTestChain(t, RegtestParams)
+	extend(t, builder, builder.Tip(), 20, 600, 0)
+	srv := &headerServer{headers: mainHeaders(builder)}
+	peer := connectServer(t, srv)
+	defer peer.Close()
+
+	// With the inv channel full, the peer reads nothing more until it is
+	// drained, including the headers the sync is waiting for.
+	flooded := floodInvs(srv)
+	require.Eventually(t, func() bool {
+		return len(peer.Inv()) == cap(peer.Inv())
+	}, time.Second, time.Millisecond)
+	node := NewNode(NewChain(RegtestParams), WithRequestTimeout(time.Second))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- node.WatchPeer(ctx, peer)
+	}()
+	require.Eventually(t, func() bool {
+		return node.Chain().Tip() != nil && node.Chain().Tip().Hash == builder.Tip().Hash
+	}, 2*time.Second, time.Millisecond)
+	require.True(t, node.Synced())
+	<-flooded
+
+	cancel()
+	require.Equal(t, context.Canceled, <-errCh)
+	require.False(t, peer.Banned())
+}
+
+func TestNode_HandleMessage(t *testing.T) {
+	builder := newTestChain(t, RegtestParams)
+	extend(t, builder, builder.Tip(), 10, 600, 0)
+	srv := &headerServer{headers: mainHeaders(builder)}
+	pool, peer := connectPool(t, srv)
+	defer pool.Close()
+
+	node := NewNode(NewChain(RegtestParams))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for {
+			select {
+			case msg := <-pool.Messages():
+				node.HandleMessage(ctx, msg)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	require.NoError(t, node.SyncPeer(ctx, peer))
+	require.Equal(t, 10, node.Chain().Height())
+
+	// Announcements are picked up from the pool, even behind a flood of
+	// messages the node does not care about.
+	<-floodInvs(srv)
+	extend(t, builder, builder.Tip(), 3, 600, 0)
+	srv.setHeaders(mainHeaders(builder))
+	require.NoError(t, srv.announce(builder.Tip().Hash))
+	require.Eventually(t, func() bool {
+		return node.Chain().Height() == 13
+	}, 2*time.Second, time.Millisecond)
+
+	next := extend(t, builder, builder.Tip(), 1, 600, 0)
+	require.NoError(t, srv.peer.Send(&wire.HeadersMessage{Headers: next}))
+	require.Eventually(t, func() bool {
+		return node.Chain().Height() == 14
+	}, time.Second, time.Millisecond)
+}
+
+func TestNode_TimeTooNew(t *testing.T) {
+	builder := newTestChain(t, RegtestParams)
+	extend(t, builder, builder.Tip(), 20, 600, 0)
+	srv := &headerServer{headers: mainHeaders(builder)}
+	peer := connectServer(t, srv)
+	defer peer.Close()
+
+	// The clock starts at genesis, so headers more than two hours past it
+	// are from the future until it is moved forward.
+	var now int64 = int64(builder.Genesis().Header.Time)
+	clock := func() time.Time {
+		return time.Unix(atomic.LoadInt64(&now), 0)
+	}
+	node := NewNode(NewChain(RegtestParams, WithClock(clock)))
+	err := node.SyncPeer(context.Background(), peer)
+	require.True(t, errors.Is(err, ErrTimeTooNew))
+	require.Equal(t, 12, node.Chain().Height())
+	require.False(t, peer.Banned())
+	require.Zero(t, peer.BanScore())
+
+	atomic.StoreInt64(&now, int64(builder.Tip().Header.Time))
+	require.NoError(t, node.SyncPeer(context.Background(), peer))
+	require.Equal(t, builder.Tip().Hash, node.Chain().Tip().Hash)
+}
@@ -0,0 +1,140 @@
+package spv
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/mslipper/handshake/primitives"
+	"math/big"
+	"time"
+)
+
+const (
+	MedianTimespan = 11
+	MaxFutureDrift = 2 * time.Hour
+)
+
+type Checkpoint struct {
+	Height int
+	Hash   [32]byte
+}
+
+// Params are the consensus rules needed to validate a header chain.
+// Difficulty is retargeted every block from the average target of the last
+// TargetWindow blocks, scaled by how long they took to mine according to
+// median time past.
+type Params struct {
+	Network primitives.Network
+
+	// GenesisHash pins the genesis block. A chain only accepts a genesis
+	// header with this hash.
+	GenesisHash [32]byte
+
+	PowLimit       *big.Int
+	PowBits        uint32
+	TargetWindow   int
+	TargetSpacing  int64
+	TargetTimespan int64
+	MinActual      int64
+	MaxActual      int64
+
+	// TargetReset allows a block to use the minimum difficulty when it is
+	// more than two spacings after its parent.
+	TargetReset   bool
+	NoRetargeting bool
+
+	Checkpoints []Checkpoint
+}
+
+func newParams(network primitives.Network, bits uint32, genesis string) *Params {
+	const (
+		window   = 144
+		spacing  = 10 * 60
+		timespan = window * spacing
+	)
+	return &Params{
+		Network:        network,
+		GenesisHash:    mustHash(genesis),
+		PowLimit:       primitives.CompactToBig(bits),
+		PowBits:        bits,
+		TargetWindow:   window,
+		TargetSpacing:  spacing,
+		TargetTimespan: timespan,
+		MinActual:      timespan * (100 - 16) / 100,
+		MaxActual:      timespan * (100 + 32) / 100,
+	}
+}
+
+var (
+	MainnetParams = func() *Params {
+		p := newParams(primitives.NetworkMainnet, 0x1c00ffff, "5b6ef2d3c1f3cdcadfd9a030ba1811efdd17740f14e166489760741d075992e0")
+		p.Checkpoints = []Checkpoint{
+			mustCheckpoint(2016, "0000000000000424ee6c2a5d6e0da5edfc47a4a10328c1792056ee48303c3e40"),
+			mustCheckpoint(7436, "000000000000003d56d278ef00c657de6b1fcb549f9e04e299f6a918c2573b94"),
+		}
+		return p
+	}()
+	TestnetParams = func() *Params {
+		p := newParams(primitives.NetworkTestnet, 0x1d00ffff, "b1520dd24372f82ec94ebf8cf9d9b037d419c4aa3575d05dec70aedd1b427901")
+		p.TargetReset = true
+		return p
+	}()
+	RegtestParams = func() *Params {
+		p := newParams(primitives.NetworkRegtest, 0x207fffff, "ae3895cf597eff05b19e02a70ceeeecb9dc72dbfe6504a50e9343a72f06a87c5")
+		p.NoRetargeting = true
+		return p
+	}()
+	SimnetParams = newParams(primitives.NetworkSimnet, 0x207fffff, "0e648edc9cddb179014658061ea3f666a45cf44881877ae506e6babefbef6992")
+)
+
+func ParamsForNetwork(network primitives.Network) (*Params, error) {
+	switch network {
+	case primitives.NetworkMainnet:
+		return MainnetParams, nil
+	case primitives.NetworkTestnet:
+		return TestnetParams, nil
+	case primitives.NetworkRegtest:
+		return RegtestParams, nil
+	case primitives.NetworkSimnet:
+		return SimnetParams, nil
+	default:
+		return nil, fmt.Errorf("invalid network %s", network)
+	}
+}
+
+func (p *Params) checkpoint(height int) ([32]byte, bool) {
+	for _, cp := range p.Checkpoints {
+		if cp.Height == height {
+			return cp.Hash, true
+		}
+	}
+	return [32]byte{}, false
+}
+
+// lastCheckpoint returns the height of the highest checkpoint at or below
+// height, or -1 if there is none.
+func (p *Params) lastCheckpoint(height int) int {
+	last := -1
+	for _, cp := range p.Checkpoints {
+		if cp.Height <= height && cp.Height > last {
+			last = cp.Height
+		}
+	}
+	return last
+}
+
+func mustCheckpoint(height int, hashHex string) Checkpoint {
+	return Checkpoint{
+		Height: height,
+		Hash:   mustHash(hashHex),
+	}
+}
+
+func mustHash(hashHex string) [32]byte {
+	var hash [32]byte
+	b, err := hex.DecodeString(hashHex)
+	if err != nil || len(b) != 32 {
+		panic("invalid hash")
+	}
+	copy(hash[:], b)
+	return hash
+}
@@ -0,0 +1,94 @@
+package spv
+
+import (
+	"fmt"
+	"github.com/mslipper/handshake/primitives"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// HeaderFunc returns the header at height on the branch being validated,
+// or nil if there is none.
+type HeaderFunc func(height int) *primitives.Block
+
+// CheckHeader validates the proof of work, difficulty bits and timestamp of
+// header as the block at height, given the branch of headers below it.
+// The header is assumed to link to its parent.
+func (p *Params) CheckHeader(header *primitives.Block, height int, branch HeaderFunc, now time.Time) error {
+	if !header.VerifyPOW() {
+		return ErrBadPOW
+	}
+	if bits := p.NextBits(height-1, header.Time, branch); header.Bits != bits {
+		return fmt.Errorf("%w: got %08x, expected %08x", ErrBadDifficulty, header.Bits, bits)
+	}
+	if header.Time <= MedianTime(height-1, branch) {
+		return ErrTimeTooOld
+	}
+	if int64(header.Time) > now.Add(MaxFutureDrift).Unix() {
+		return ErrTimeTooNew
+	}
+	return nil
+}
+
+// MedianTime returns the median time past of the header at height.
+func MedianTime(height int, branch HeaderFunc) uint64 {
+	times := make([]uint64, 0, MedianTimespan)
+	for i := 0; i < MedianTimespan; i++ {
+		header := branch(height - i)
+		if header == nil {
+			break
+		}
+		times = append(times, header.Time)
+	}
+	if len(times) == 0 {
+		return 0
+	}
+	sort.Slice(times, func(i, j int) bool {
+		return times[i] < times[j]
+	})
+	return times[len(times)/2]
+}
+
+// NextBits returns the difficulty required of a block with the given time
+// built on the header at prevHeight.
+func (p *Params) NextBits(prevHeight int, blockTime uint64, branch HeaderFunc) uint32 {
+	prev := branch(prevHeight)
+	if prev == nil {
+		return p.PowBits
+	}
+	if p.TargetReset && blockTime > prev.Time+uint64(p.TargetSpacing*2) {
+		return p.PowBits
+	}
+	if p.NoRetargeting {
+		return p.PowBits
+	}
+	firstHeight := prevHeight - p.TargetWindow
+	if firstHeight < 0 || branch(firstHeight) == nil {
+		return p.PowBits
+	}
+
+	total := new(big.Int)
+	for i := 0; i < p.TargetWindow; i++ {
+		total.Add(total, primitives.CompactToBig(branch(prevHeight-i).Bits))
+	}
+	target := total.Div(total, big.NewInt(int64(p.TargetWindow)))
+
+	start := int64(MedianTime(firstHeight, branch))
+	end := int64(MedianTime(prevHeight, branch))
+	timespan := end - start
+	timespan = p.TargetTimespan + (timespan-p.TargetTimespan)/4
+	if timespan < p.MinActual {
+		timespan = p.MinActual
+	}
+	if timespan > p.MaxActual {
+		timespan = p.MaxActual
+	}
+
+	target.Mul(target, big.NewInt(timespan))
+	target.Div(target, big.NewInt(p.TargetTimespan))
+	if target.Cmp(p.PowLimit) > 0 {
+		return p.PowBits
+	}
+	return primitives.BigToCompact(target)
+}
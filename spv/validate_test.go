@@ -0,0 +1,37 @@
+package spv
+
+import (
+	"github.com/mslipper/handshake/primitives"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// TestParams_NextBits checks a full mainnet window against a retarget
+// computed independently of this package. The window is synthetic: heights
+// 0 to 144 with timestamps about 560 seconds apart and three alternating
+// targets, so that the averaging, median time past, dampening and compact
+// rounding all take effect without hitting the clamps.
+func TestParams_NextBits(t *testing.T) {
+	bits := []uint32{0x1a2f4a1c, 0x1a2e8b10, 0x1a30c1f2}
+	var headers []*primitives.Block
+	for i := 0; i <= MainnetParams.TargetWindow; i++ {
+		headers = append(headers, &primitives.Block{
+			Time: uint64(1580745078 + i*560 + (i*37)%300 - 150),
+			Bits: bits[i%len(bits)],
+		})
+	}
+	branch := func(height int) *primitives.Block {
+		if height < 0 || height >= len(headers) {
+			return nil
+		}
+		return headers[height]
+	}
+	prev := len(headers) - 1
+	tip := headers[prev]
+	require.EqualValues(t, 0x1a2e5be0, MainnetParams.NextBits(prev, tip.Time+600, branch))
+
+	// One block short of a full window keeps the minimum difficulty.
+	require.Equal(t, MainnetParams.PowBits, MainnetParams.NextBits(prev-1, tip.Time, branch))
+	// Testnet drops to the minimum difficulty after two missed spacings.
+	require.Equal(t, TestnetParams.PowBits, TestnetParams.NextBits(prev, tip.Time+1201, branch))
+}
@@ -0,0 +1,108 @@
+package urkel
+
+import (
+	"errors"
+	"github.com/mslipper/handshake/encoding"
+	"io"
+	"strings"
+)
+
+type Bits struct {
+	Size int
+	Data []byte
+}
+
+func NewBitsFromString(str string) (*Bits, error) {
+	if len(str) > KeyBits {
+		return nil, errors.New("bit string too long")
+	}
+	b := &Bits{
+		Size: len(str),
+		Data: make([]byte, (len(str)+7)/8),
+	}
+	for i, ch := range str {
+		switch ch {
+		case '0':
+		case '1':
+			b.Data[i>>3] |= 1 << (7 - uint(i&7))
+		default:
+			return nil, errors.New("invalid bit string")
+		}
+	}
+	return b, nil
+}
+
+func (b *Bits) Get(index int) bool {
+	return hasBit(b.Data, index)
+}
+
+func (b *Bits) Has(key []byte, depth int) bool {
+	if depth+b.Size > len(key)*8 {
+		return false
+	}
+	for i := 0; i < b.Size; i++ {
+		if b.Get(i) != hasBit(key, depth+i) {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *Bits) String() string {
+	var sb strings.Builder
+	for i := 0; i < b.Size; i++ {
+		if b.Get(i) {
+			sb.WriteByte('1')
+		} else {
+			sb.WriteByte('0')
+		}
+	}
+	return sb.String()
+}
+
+func (b *Bits) Encode(w io.Writer) error {
+	if b.Size > 0x7fff {
+		return errors.New("bits too long")
+	}
+	if b.Size >= 0x80 {
+		if err := encoding.WriteUint8(w, uint8(0x80|(b.Size>>8))); err != nil {
+			return err
+		}
+	}
+	if err := encoding.WriteUint8(w, uint8(b.Size&0xff)); err != nil {
+		return err
+	}
+	if _, err := w.Write(b.Data[:(b.Size+7)/8]); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (b *Bits) Decode(r io.Reader) error {
+	size, err := encoding.ReadUint8(r)
+	if err != nil {
+		return err
+	}
+	bitSize := int(size)
+	if size&0x80 != 0 {
+		lo, err := encoding.ReadUint8(r)
+		if err != nil {
+			return err
+		}
+		bitSize = int(size&0x7f)<<8 | int(lo)
+	}
+	if bitSize > KeyBits {
+		return errors.New("bits too long")
+	}
+	data, err := encoding.ReadBytes(r, (bitSize+7)/8)
+	if err != nil {
+		return err
+	}
+	b.Size = bitSize
+	b.Data = data
+	return nil
+}
+
+func hasBit(buf []byte, index int) bool {
+	return (buf[index>>3]>>(7-uint(index&7)))&1 == 1
+}
@@ -0,0 +1,436 @@
+package urkel
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/mslipper/handshake/encoding"
+	"golang.org/x/crypto/blake2b"
+	"io"
+)
+
+const (
+	ProofTypeDeadEnd uint8 = iota
+	ProofTypeShort
+	ProofTypeCollision
+	ProofTypeExists
+	ProofTypeUnknown
+)
+
+const (
+	HashSize     = 32
+	KeySize      = 32
+	KeyBits      = KeySize * 8
+	MaxValueSize = 0x3ff
+)
+
+var (
+	ErrSameKey      = errors.New("urkel: proof key matches requested key")
+	ErrSamePath     = errors.New("urkel: proof prefix matches requested key")
+	ErrNegDepth     = errors.New("urkel: proof depth went negative")
+	ErrPathMismatch = errors.New("urkel: proof path does not match key")
+	ErrTooDeep      = errors.New("urkel: proof is too deep")
+	ErrHashMismatch = errors.New("urkel: proof does not hash to root")
+	ErrMalformed    = errors.New("urkel: malformed proof")
+)
+
+var (
+	leafPrefix     = []byte{0x00}
+	internalPrefix = []byte{0x01}
+	zeroHash       = make([]byte, HashSize)
+)
+
+var proofTypeNames = map[uint8]string{
+	ProofTypeDeadEnd:   "TYPE_DEADEND",
+	ProofTypeShort:     "TYPE_SHORT",
+	ProofTypeCollision: "TYPE_COLLISION",
+	ProofTypeExists:    "TYPE_EXISTS",
+	ProofTypeUnknown:   "TYPE_UNKNOWN",
+}
+
+type ProofNode struct {
+	Prefix *Bits
+	Hash   []byte
+}
+
+type Proof struct {
+	Type  uint8
+	Depth int
+	Nodes []*ProofNode
+
+	// Set for TYPE_SHORT proofs.
+	Prefix *Bits
+	Left   []byte
+	Right  []byte
+
+	// Set for TYPE_COLLISION proofs.
+	Key  []byte
+	Hash []byte
+
+	// Set for TYPE_EXISTS proofs.
+	Value []byte
+}
+
+// Verify checks the proof against root for key. It returns the stored value
+// for existence proofs, and a nil value for valid proofs of non-existence.
+func (p *Proof) Verify(root []byte, key []byte) ([]byte, error) {
+	if len(key) != KeySize || !p.isSane() {
+		return nil, ErrMalformed
+	}
+
+	var next []byte
+	switch p.Type {
+	case ProofTypeDeadEnd:
+		next = zeroHash
+	case ProofTypeShort:
+		if p.Prefix.Has(key, p.Depth) {
+			return nil, ErrSamePath
+		}
+		next = hashInternal(p.Prefix, p.Left, p.Right)
+	case ProofTypeCollision:
+		if bytes.Equal(p.Key, key) {
+			return nil, ErrSameKey
+		}
+		next = hashLeaf(p.Key, p.Hash)
+	case ProofTypeExists:
+		next = hashValue(key, p.Value)
+	default:
+		return nil, ErrMalformed
+	}
+
+	depth := p.Depth
+	for i := len(p.Nodes) - 1; i >= 0; i-- {
+		node := p.Nodes[i]
+		if depth < node.Prefix.Size+1 {
+			return nil, ErrNegDepth
+		}
+		depth--
+		if hasBit(key, depth) {
+			next = hashInternal(node.Prefix, node.Hash, next)
+		} else {
+			next = hashInternal(node.Prefix, next, node.Hash)
+		}
+		depth -= node.Prefix.Size
+		if !node.Prefix.Has(key, depth) {
+			return nil, ErrPathMismatch
+		}
+	}
+	if depth != 0 {
+		return nil, ErrTooDeep
+	}
+	if !bytes.Equal(next, root) {
+		return nil, ErrHashMismatch
+	}
+	if p.Type == ProofTypeExists {
+		return p.Value, nil
+	}
+	return nil, nil
+}
+
+func (p *Proof) isSane() bool {
+	if p.Depth < 0 || p.Depth > KeyBits {
+		return false
+	}
+	if len(p.Nodes) > KeyBits {
+		return false
+	}
+	for _, node := range p.Nodes {
+		if node.Prefix == nil || node.Prefix.Size > KeyBits || len(node.Hash) != HashSize {
+			return false
+		}
+	}
+	switch p.Type {
+	case ProofTypeDeadEnd:
+		return p.Prefix == nil && p.Left == nil && p.Right == nil && p.Key == nil && p.Hash == nil && p.Value == nil
+	case ProofTypeShort:
+		if p.Prefix == nil || p.Prefix.Size == 0 || p.Prefix.Size > KeyBits {
+			return false
+		}
+		return len(p.Left) == HashSize && len(p.Right) == HashSize
+	case ProofTypeCollision:
+		return len(p.Key) == KeySize && len(p.Hash) == HashSize
+	case ProofTypeExists:
+		return p.Value != nil && len(p.Value) <= MaxValueSize
+	default:
+		return false
+	}
+}
+
+func (p *Proof) Encode(w io.Writer) error {
+	if !p.isSane() {
+		return ErrMalformed
+	}
+	if err := encoding.WriteUint16(w, uint16(p.Type)<<14|uint16(p.Depth)); err != nil {
+		return err
+	}
+	if err := encoding.WriteUint16(w, uint16(len(p.Nodes))); err != nil {
+		return err
+	}
+	bitmap := make([]byte, (len(p.Nodes)+7)/8)
+	for i, node := range p.Nodes {
+		if node.Prefix.Size > 0 {
+			bitmap[i>>3] |= 1 << (7 - uint(i&7))
+		}
+	}
+	if _, err := w.Write(bitmap); err != nil {
+		return err
+	}
+	for _, node := range p.Nodes {
+		if node.Prefix.Size > 0 {
+			if err := node.Prefix.Encode(w); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(node.Hash); err != nil {
+			return err
+		}
+	}
+	switch p.Type {
+	case ProofTypeShort:
+		if err := p.Prefix.Encode(w); err != nil {
+			return err
+		}
+		if _, err := w.Write(p.Left); err != nil {
+			return err
+		}
+		if _, err := w.Write(p.Right); err != nil {
+			return err
+		}
+	case ProofTypeCollision:
+		if _, err := w.Write(p.Key); err != nil {
+			return err
+		}
+		if _, err := w.Write(p.Hash); err != nil {
+			return err
+		}
+	case ProofTypeExists:
+		if err := encoding.WriteUint16(w, uint16(len(p.Value))); err != nil {
+			return err
+		}
+		if _, err := w.Write(p.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Proof) Decode(r io.Reader) error {
+	field, err := encoding.ReadUint16(r)
+	if err != nil {
+		return err
+	}
+	typ := uint8(field >> 14)
+	depth := int(field &^ (3 << 14))
+	if depth > KeyBits {
+		return ErrMalformed
+	}
+	count, err := encoding.ReadUint16(r)
+	if err != nil {
+		return err
+	}
+	if int(count) > KeyBits {
+		return ErrMalformed
+	}
+	bitmap, err := encoding.ReadBytes(r, (int(count)+7)/8)
+	if err != nil {
+		return err
+	}
+	var nodes []*ProofNode
+	for i := 0; i < int(count); i++ {
+		prefix := new(Bits)
+		if hasBit(bitmap, i) {
+			if err := prefix.Decode(r); err != nil {
+				return err
+			}
+		}
+		hash, err := encoding.ReadBytes(r, HashSize)
+		if err != nil {
+			return err
+		}
+		nodes = append(nodes, &ProofNode{
+			Prefix: prefix,
+			Hash:   hash,
+		})
+	}
+
+	decoded := &Proof{
+		Type:  typ,
+		Depth: depth,
+		Nodes: nodes,
+	}
+	switch typ {
+	case ProofTypeDeadEnd:
+	case ProofTypeShort:
+		prefix := new(Bits)
+		if err := prefix.Decode(r); err != nil {
+			return err
+		}
+		left, err := encoding.ReadBytes(r, HashSize)
+		if err != nil {
+			return err
+		}
+		right, err := encoding.ReadBytes(r, HashSize)
+		if err != nil {
+			return err
+		}
+		decoded.Prefix = prefix
+		decoded.Left = left
+		decoded.Right = right
+	case ProofTypeCollision:
+		key, err := encoding.ReadBytes(r, KeySize)
+		if err != nil {
+			return err
+		}
+		hash, err := encoding.ReadBytes(r, HashSize)
+		if err != nil {
+			return err
+		}
+		decoded.Key = key
+		decoded.Hash = hash
+	case ProofTypeExists:
+		size, err := encoding.ReadUint16(r)
+		if err != nil {
+			return err
+		}
+		if size > MaxValueSize {
+			return ErrMalformed
+		}
+		value, err := encoding.ReadBytes(r, int(size))
+		if err != nil {
+			return err
+		}
+		decoded.Value = value
+	default:
+		return ErrMalformed
+	}
+	*p = *decoded
+	return nil
+}
+
+type jsonProof struct {
+	Type   string      `json:"type"`
+	Depth  int         `json:"depth"`
+	Nodes  [][2]string `json:"nodes"`
+	Prefix string      `json:"prefix,omitempty"`
+	Left   string      `json:"left,omitempty"`
+	Right  string      `json:"right,omitempty"`
+	Key    string      `json:"key,omitempty"`
+	Hash   string      `json:"hash,omitempty"`
+	Value  string      `json:"value,omitempty"`
+}
+
+// UnmarshalJSON decodes the JSON representation of a proof returned by hsd's
+// getnameproof RPC.
+func (p *Proof) UnmarshalJSON(data []byte) error {
+	var jp jsonProof
+	if err := json.Unmarshal(data, &jp); err != nil {
+		return err
+	}
+	typ, ok := proofTypeFromName(jp.Type)
+	if !ok {
+		return errors.New("urkel: invalid proof type")
+	}
+	decoded := &Proof{
+		Type:  typ,
+		Depth: jp.Depth,
+	}
+	for _, node := range jp.Nodes {
+		prefix, err := NewBitsFromString(node[0])
+		if err != nil {
+			return err
+		}
+		hash, err := hex.DecodeString(node[1])
+		if err != nil {
+			return err
+		}
+		decoded.Nodes = append(decoded.Nodes, &ProofNode{
+			Prefix: prefix,
+			Hash:   hash,
+		})
+	}
+
+	var err error
+	switch typ {
+	case ProofTypeShort:
+		if decoded.Prefix, err = NewBitsFromString(jp.Prefix); err != nil {
+			return err
+		}
+		if decoded.Left, err = hex.DecodeString(jp.Left); err != nil {
+			return err
+		}
+		if decoded.Right, err = hex.DecodeString(jp.Right); err != nil {
+			return err
+		}
+	case ProofTypeCollision:
+		if decoded.Key, err = hex.DecodeString(jp.Key); err != nil {
+			return err
+		}
+		if decoded.Hash, err = hex.DecodeString(jp.Hash); err != nil {
+			return err
+		}
+	case ProofTypeExists:
+		if decoded.Value, err = hex.DecodeString(jp.Value); err != nil {
+			return err
+		}
+	}
+	*p = *decoded
+	return nil
+}
+
+func (p *Proof) MarshalJSON() ([]byte, error) {
+	jp := jsonProof{
+		Type:  proofTypeNames[p.Type],
+		Depth: p.Depth,
+		Nodes: make([][2]string, 0, len(p.Nodes)),
+	}
+	for _, node := range p.Nodes {
+		jp.Nodes = append(jp.Nodes, [2]string{node.Prefix.String(), hex.EncodeToString(node.Hash)})
+	}
+	switch p.Type {
+	case ProofTypeShort:
+		jp.Prefix = p.Prefix.String()
+		jp.Left = hex.EncodeToString(p.Left)
+		jp.Right = hex.EncodeToString(p.Right)
+	case ProofTypeCollision:
+		jp.Key = hex.EncodeToString(p.Key)
+		jp.Hash = hex.EncodeToString(p.Hash)
+	case ProofTypeExists:
+		jp.Value = hex.EncodeToString(p.Value)
+	}
+	return json.Marshal(jp)
+}
+
+func proofTypeFromName(name string) (uint8, bool) {
+	for typ, typName := range proofTypeNames {
+		if typName == name {
+			return typ, true
+		}
+	}
+	return 0, false
+}
+
+func hashInternal(prefix *Bits, left []byte, right []byte) []byte {
+	h, _ := blake2b.New256(nil)
+	h.Write(internalPrefix)
+	if prefix.Size > 0 {
+		_ = prefix.Encode(h)
+	}
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+func hashLeaf(key []byte, valueHash []byte) []byte {
+	h, _ := blake2b.New256(nil)
+	h.Write(leafPrefix)
+	h.Write(key)
+	h.Write(valueHash)
+	return h.Sum(nil)
+}
+
+func hashValue(key []byte, value []byte) []byte {
+	h, _ := blake2b.New256(nil)
+	h.Write(value)
+	return hashLeaf(key, h.Sum(nil))
+}
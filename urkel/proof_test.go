@@ -0,0 +1,214 @@
+package urkel
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/blake2b"
+	"testing"
+)
+
+func key(first byte) []byte {
+	k := make([]byte, KeySize)
+	k[0] = first
+	return k
+}
+
+func TestProof_Verify(t *testing.T) {
+	k1 := key(0x00)
+	k2 := key(0x01)
+	k3 := key(0x80)
+	v1 := []byte("value one")
+	v2 := []byte("value two")
+	leaf1 := hashValue(k1, v1)
+	leaf2 := hashValue(k2, v2)
+
+	// k1 and k2 share their first seven bits, so the root is an internal
+	// node with a seven bit prefix.
+	rootPrefix := &Bits{Size: 7, Data: []byte{0x00}}
+	root := hashInternal(rootPrefix, leaf1, leaf2)
+
+	tests := []struct {
+		name   string
+		proof  *Proof
+		root   []byte
+		key    []byte
+		value  []byte
+		expErr error
+	}{
+		{
+			"existence of first key",
+			&Proof{
+				Type:  ProofTypeExists,
+				Depth: 8,
+				Nodes: []*ProofNode{{Prefix: rootPrefix, Hash: leaf2}},
+				Value: v1,
+			},
+			root,
+			k1,
+			v1,
+			nil,
+		},
+		{
+			"existence of second key",
+			&Proof{
+				Type:  ProofTypeExists,
+				Depth: 8,
+				Nodes: []*ProofNode{{Prefix: rootPrefix, Hash: leaf1}},
+				Value: v2,
+			},
+			root,
+			k2,
+			v2,
+			nil,
+		},
+		{
+			"existence against the wrong root",
+			&Proof{
+				Type:  ProofTypeExists,
+				Depth: 8,
+				Nodes: []*ProofNode{{Prefix: rootPrefix, Hash: leaf2}},
+				Value: v1,
+			},
+			leaf1,
+			k1,
+			nil,
+			ErrHashMismatch,
+		},
+		{
+			"existence with a tampered value",
+			&Proof{
+				Type:  ProofTypeExists,
+				Depth: 8,
+				Nodes: []*ProofNode{{Prefix: rootPrefix, Hash: leaf2}},
+				Value: []byte("forged"),
+			},
+			root,
+			k1,
+			nil,
+			ErrHashMismatch,
+		},
+		{
+			"non-existence via diverging prefix",
+			&Proof{
+				Type:   ProofTypeShort,
+				Depth:  0,
+				Prefix: rootPrefix,
+				Left:   leaf1,
+				Right:  leaf2,
+			},
+			root,
+			k3,
+			nil,
+			nil,
+		},
+		{
+			"short proof for a key on the same path",
+			&Proof{
+				Type:   ProofTypeShort,
+				Depth:  0,
+				Prefix: rootPrefix,
+				Left:   leaf1,
+				Right:  leaf2,
+			},
+			root,
+			k1,
+			nil,
+			ErrSamePath,
+		},
+		{
+			"non-existence via collision",
+			&Proof{
+				Type:  ProofTypeCollision,
+				Depth: 0,
+				Key:   k1,
+				Hash:  blake2bSum(v1),
+			},
+			leaf1,
+			k2,
+			nil,
+			nil,
+		},
+		{
+			"collision proof for the same key",
+			&Proof{
+				Type:  ProofTypeCollision,
+				Depth: 0,
+				Key:   k1,
+				Hash:  blake2bSum(v1),
+			},
+			leaf1,
+			k1,
+			nil,
+			ErrSameKey,
+		},
+		{
+			"non-existence in an empty tree",
+			&Proof{
+				Type:  ProofTypeDeadEnd,
+				Depth: 0,
+			},
+			zeroHash,
+			k1,
+			nil,
+			nil,
+		},
+		{
+			"proof that is too deep",
+			&Proof{
+				Type:  ProofTypeExists,
+				Depth: 9,
+				Nodes: []*ProofNode{{Prefix: rootPrefix, Hash: leaf2}},
+				Value: v1,
+			},
+			root,
+			k1,
+			nil,
+			ErrTooDeep,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := tt.proof.Verify(tt.root, tt.key)
+			if tt.expErr != nil {
+				require.Equal(t, tt.expErr, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.value, value)
+
+			buf := new(bytes.Buffer)
+			require.NoError(t, tt.proof.Encode(buf))
+			decoded := new(Proof)
+			require.NoError(t, decoded.Decode(bytes.NewReader(buf.Bytes())))
+			value, err = decoded.Verify(tt.root, tt.key)
+			require.NoError(t, err)
+			require.Equal(t, tt.value, value)
+
+			jsonB, err := json.Marshal(tt.proof)
+			require.NoError(t, err)
+			fromJSON := new(Proof)
+			require.NoError(t, json.Unmarshal(jsonB, fromJSON))
+			value, err = fromJSON.Verify(tt.root, tt.key)
+			require.NoError(t, err)
+			require.Equal(t, tt.value, value)
+		})
+	}
+}
+
+func TestBits_Encoding(t *testing.T) {
+	bits, err := NewBitsFromString("1011000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000001")
+	require.NoError(t, err)
+	require.Equal(t, 133, bits.Size)
+	buf := new(bytes.Buffer)
+	require.NoError(t, bits.Encode(buf))
+	require.Equal(t, []byte{0x80, 0x85, 0xb0}, buf.Bytes()[:3])
+	decoded := new(Bits)
+	require.NoError(t, decoded.Decode(bytes.NewReader(buf.Bytes())))
+	require.Equal(t, bits.String(), decoded.String())
+}
+
+func blake2bSum(data []byte) []byte {
+	h := blake2b.Sum256(data)
+	return h[:]
+}
@@ -0,0 +1,347 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/mslipper/handshake/encoding"
+	"github.com/mslipper/handshake/primitives"
+	"golang.org/x/crypto/blake2b"
+	"io"
+)
+
+const (
+	MaxShortID    = 1<<48 - 1
+	MaxCmpctIndex = 0xffff
+)
+
+var ErrInvalidIndex = errors.New("wire: invalid transaction index")
+
+type SendCmpctMessage struct {
+	Mode    uint8
+	Version uint64
+}
+
+func (s *SendCmpctMessage) Type() MessageType {
+	return MessageTypeSendCmpct
+}
+
+func (s *SendCmpctMessage) Encode(w io.Writer) error {
+	if err := encoding.WriteUint8(w, s.Mode); err != nil {
+		return err
+	}
+	return encoding.WriteUint64(w, s.Version)
+}
+
+func (s *SendCmpctMessage) Decode(r io.Reader) error {
+	mode, err := encoding.ReadUint8(r)
+	if err != nil {
+		return err
+	}
+	version, err := encoding.ReadUint64(r)
+	if err != nil {
+		return err
+	}
+	s.Mode = mode
+	s.Version = version
+	return nil
+}
+
+// PrefilledTx is a transaction sent in full inside a compact block. Index
+// is its absolute position in the block; the wire encoding is differential.
+type PrefilledTx struct {
+	Index int
+	Tx    *primitives.Transaction
+}
+
+type CmpctBlockMessage struct {
+	Header    *primitives.Block
+	KeyNonce  [8]byte
+	IDs       []uint64
+	Prefilled []*PrefilledTx
+}
+
+// NewCmpctBlockMessage builds a compact block for block. The coinbase is
+// prefilled; every other transaction is sent as the short ID of its witness
+// hash.
+func NewCmpctBlockMessage(block *primitives.Block, keyNonce [8]byte) *CmpctBlockMessage {
+	header := *block
+	header.Transactions = nil
+	c := &CmpctBlockMessage{
+		Header:   &header,
+		KeyNonce: keyNonce,
+	}
+	key := c.SipKey()
+	for i, tx := range block.Transactions {
+		if i == 0 {
+			c.Prefilled = append(c.Prefilled, &PrefilledTx{
+				Index: i,
+				Tx:    tx,
+			})
+			continue
+		}
+		c.IDs = append(c.IDs, ShortID(key, tx.WitnessHash()))
+	}
+	return c
+}
+
+// SipKey returns the SipHash key for the block's short IDs: the first 16
+// bytes of the blake2b hash of the header and key nonce.
+func (c *CmpctBlockMessage) SipKey() [16]byte {
+	buf := new(bytes.Buffer)
+	if err := c.Header.EncodeHeader(buf); err != nil {
+		panic(err)
+	}
+	buf.Write(c.KeyNonce[:])
+	hash := blake2b.Sum256(buf.Bytes())
+	var key [16]byte
+	copy(key[:], hash[:16])
+	return key
+}
+
+// ShortID returns the 48-bit short ID of a transaction's witness hash under
+// a compact block's SipHash key.
+func ShortID(key [16]byte, hash []byte) uint64 {
+	k0 := binary.LittleEndian.Uint64(key[:8])
+	k1 := binary.LittleEndian.Uint64(key[8:])
+	return sipHash24(k0, k1, hash) & MaxShortID
+}
+
+func (c *CmpctBlockMessage) Type() MessageType {
+	return MessageTypeCmpctBlock
+}
+
+func (c *CmpctBlockMessage) Encode(w io.Writer) error {
+	if err := c.Header.EncodeHeader(w); err != nil {
+		return err
+	}
+	if _, err := w.Write(c.KeyNonce[:]); err != nil {
+		return err
+	}
+	if err := encoding.WriteVarint(w, uint64(len(c.IDs))); err != nil {
+		return err
+	}
+	for _, id := range c.IDs {
+		if id > MaxShortID {
+			return errors.New("short id too large")
+		}
+		if err := encoding.WriteUint32(w, uint32(id)); err != nil {
+			return err
+		}
+		if err := encoding.WriteUint16(w, uint16(id>>32)); err != nil {
+			return err
+		}
+	}
+	if err := encoding.WriteVarint(w, uint64(len(c.Prefilled))); err != nil {
+		return err
+	}
+	indexes := make([]int, len(c.Prefilled))
+	for i, ptx := range c.Prefilled {
+		indexes[i] = ptx.Index
+	}
+	diffs, err := diffIndexes(indexes)
+	if err != nil {
+		return err
+	}
+	for i, ptx := range c.Prefilled {
+		if err := encoding.WriteVarint(w, uint64(diffs[i])); err != nil {
+			return err
+		}
+		if err := ptx.Tx.Encode(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *CmpctBlockMessage) Decode(r io.Reader) error {
+	header := new(primitives.Block)
+	if err := header.DecodeHeader(r); err != nil {
+		return err
+	}
+	var keyNonce [8]byte
+	if _, err := io.ReadFull(r, keyNonce[:]); err != nil {
+		return err
+	}
+	idCount, err := readCount(r, MaxBlockTx)
+	if err != nil {
+		return err
+	}
+	var ids []uint64
+	for i := 0; i < idCount; i++ {
+		lo, err := encoding.ReadUint32(r)
+		if err != nil {
+			return err
+		}
+		hi, err := encoding.ReadUint16(r)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, uint64(hi)<<32|uint64(lo))
+	}
+	ptxCount, err := readCount(r, MaxBlockTx)
+	if err != nil {
+		return err
+	}
+	var prefilled []*PrefilledTx
+	last := -1
+	for i := 0; i < ptxCount; i++ {
+		diff, err := encoding.ReadVarint(r)
+		if err != nil {
+			return err
+		}
+		if diff > MaxCmpctIndex {
+			return ErrInvalidIndex
+		}
+		last += int(diff) + 1
+		if last > MaxCmpctIndex || last >= idCount+ptxCount {
+			return ErrInvalidIndex
+		}
+		tx := new(primitives.Transaction)
+		if err := tx.Decode(r); err != nil {
+			return err
+		}
+		prefilled = append(prefilled, &PrefilledTx{
+			Index: last,
+			Tx:    tx,
+		})
+	}
+	c.Header = header
+	c.KeyNonce = keyNonce
+	c.IDs = ids
+	c.Prefilled = prefilled
+	return nil
+}
+
+// GetBlockTxnMessage requests the transactions a compact block could not
+// be filled in with. Indexes are absolute and must be strictly increasing.
+type GetBlockTxnMessage struct {
+	Hash    [32]byte
+	Indexes []int
+}
+
+func (g *GetBlockTxnMessage) Type() MessageType {
+	return MessageTypeGetBlockTxn
+}
+
+func (g *GetBlockTxnMessage) Encode(w io.Writer) error {
+	diffs, err := diffIndexes(g.Indexes)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(g.Hash[:]); err != nil {
+		return err
+	}
+	if err := encoding.WriteVarint(w, uint64(len(diffs))); err != nil {
+		return err
+	}
+	for _, diff := range diffs {
+		if err := encoding.WriteVarint(w, uint64(diff)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *GetBlockTxnMessage) Decode(r io.Reader) error {
+	hash, err := readHash(r)
+	if err != nil {
+		return err
+	}
+	count, err := readCount(r, MaxBlockTx)
+	if err != nil {
+		return err
+	}
+	var indexes []int
+	last := -1
+	for i := 0; i < count; i++ {
+		diff, err := encoding.ReadVarint(r)
+		if err != nil {
+			return err
+		}
+		if diff > MaxCmpctIndex {
+			return ErrInvalidIndex
+		}
+		last += int(diff) + 1
+		if last > MaxCmpctIndex {
+			return ErrInvalidIndex
+		}
+		indexes = append(indexes, last)
+	}
+	g.Hash = hash
+	g.Indexes = indexes
+	return nil
+}
+
+type BlockTxnMessage struct {
+	Hash [32]byte
+	Txs  []*primitives.Transaction
+}
+
+// NewBlockTxnMessage answers a getblocktxn request for block.
+func NewBlockTxnMessage(block *primitives.Block, req *GetBlockTxnMessage) (*BlockTxnMessage, error) {
+	res := &BlockTxnMessage{Hash: req.Hash}
+	for _, index := range req.Indexes {
+		if index < 0 || index >= len(block.Transactions) {
+			return nil, ErrInvalidIndex
+		}
+		res.Txs = append(res.Txs, block.Transactions[index])
+	}
+	return res, nil
+}
+
+func (b *BlockTxnMessage) Type() MessageType {
+	return MessageTypeBlockTxn
+}
+
+func (b *BlockTxnMessage) Encode(w io.Writer) error {
+	if _, err := w.Write(b.Hash[:]); err != nil {
+		return err
+	}
+	if err := encoding.WriteVarint(w, uint64(len(b.Txs))); err != nil {
+		return err
+	}
+	for _, tx := range b.Txs {
+		if err := tx.Encode(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *BlockTxnMessage) Decode(r io.Reader) error {
+	hash, err := readHash(r)
+	if err != nil {
+		return err
+	}
+	count, err := readCount(r, MaxBlockTx)
+	if err != nil {
+		return err
+	}
+	var txs []*primitives.Transaction
+	for i := 0; i < count; i++ {
+		tx := new(primitives.Transaction)
+		if err := tx.Decode(r); err != nil {
+			return err
+		}
+		txs = append(txs, tx)
+	}
+	b.Hash = hash
+	b.Txs = txs
+	return nil
+}
+
+// diffIndexes converts strictly increasing absolute indexes into the
+// differential form used on the wire.
+func diffIndexes(indexes []int) ([]int, error) {
+	diffs := make([]int, len(indexes))
+	last := -1
+	for i, index := range indexes {
+		if index <= last || index > MaxCmpctIndex {
+			return nil, ErrInvalidIndex
+		}
+		diffs[i] = index - last - 1
+		last = index
+	}
+	return diffs, nil
+}
@@ -0,0 +1,369 @@
+package wire
+
+import (
+	"errors"
+	"github.com/mslipper/handshake/encoding"
+	"io"
+)
+
+const (
+	ProtocolVersion    = 3
+	MinProtocolVersion = 1
+	MaxAgentSize       = 255
+	MaxAddrs           = 1000
+	MaxRejectReason    = 255
+)
+
+const (
+	RejectMalformed       uint8 = 0x01
+	RejectInvalid         uint8 = 0x10
+	RejectObsolete        uint8 = 0x11
+	RejectDuplicate       uint8 = 0x12
+	RejectNonstandard     uint8 = 0x40
+	RejectDust            uint8 = 0x41
+	RejectInsufficientFee uint8 = 0x42
+	RejectCheckpoint      uint8 = 0x43
+)
+
+type VersionMessage struct {
+	Version  uint32
+	Services uint32
+	Time     uint64
+	Remote   *NetAddress
+	Nonce    [8]byte
+	Agent    string
+	Height   uint32
+	NoRelay  bool
+}
+
+func (v *VersionMessage) Type() MessageType {
+	return MessageTypeVersion
+}
+
+func (v *VersionMessage) Encode(w io.Writer) error {
+	if len(v.Agent) > MaxAgentSize {
+		return errors.New("agent too long")
+	}
+	if err := encoding.WriteUint32(w, v.Version); err != nil {
+		return err
+	}
+	if err := encoding.WriteUint32(w, v.Services); err != nil {
+		return err
+	}
+	if err := encoding.WriteUint32(w, 0); err != nil {
+		return err
+	}
+	if err := encoding.WriteUint64(w, v.Time); err != nil {
+		return err
+	}
+	remote := v.Remote
+	if remote == nil {
+		remote = new(NetAddress)
+	}
+	if err := remote.Encode(w); err != nil {
+		return err
+	}
+	if _, err := w.Write(v.Nonce[:]); err != nil {
+		return err
+	}
+	if err := encoding.WriteUint8(w, uint8(len(v.Agent))); err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(v.Agent)); err != nil {
+		return err
+	}
+	if err := encoding.WriteUint32(w, v.Height); err != nil {
+		return err
+	}
+	var noRelay uint8
+	if v.NoRelay {
+		noRelay = 1
+	}
+	return encoding.WriteUint8(w, noRelay)
+}
+
+func (v *VersionMessage) Decode(r io.Reader) error {
+	version, err := encoding.ReadUint32(r)
+	if err != nil {
+		return err
+	}
+	services, err := encoding.ReadUint32(r)
+	if err != nil {
+		return err
+	}
+	if _, err := encoding.ReadUint32(r); err != nil {
+		return err
+	}
+	ts, err := encoding.ReadUint64(r)
+	if err != nil {
+		return err
+	}
+	remote := new(NetAddress)
+	if err := remote.Decode(r); err != nil {
+		return err
+	}
+	var nonce [8]byte
+	if _, err := io.ReadFull(r, nonce[:]); err != nil {
+		return err
+	}
+	agentLen, err := encoding.ReadUint8(r)
+	if err != nil {
+		return err
+	}
+	agent, err := encoding.ReadString(r, int(agentLen))
+	if err != nil {
+		return err
+	}
+	height, err := encoding.ReadUint32(r)
+	if err != nil {
+		return err
+	}
+	noRelay, err := encoding.ReadUint8(r)
+	if err != nil {
+		return err
+	}
+	v.Version = version
+	v.Services = services
+	v.Time = ts
+	v.Remote = remote
+	v.Nonce = nonce
+	v.Agent = agent
+	v.Height = height
+	v.NoRelay = noRelay == 1
+	return nil
+}
+
+type VerackMessage struct{}
+
+func (v *VerackMessage) Type() MessageType {
+	return MessageTypeVerack
+}
+
+func (v *VerackMessage) Encode(w io.Writer) error {
+	return nil
+}
+
+func (v *VerackMessage) Decode(r io.Reader) error {
+	return nil
+}
+
+type PingMessage struct {
+	Nonce [8]byte
+}
+
+func (p *PingMessage) Type() MessageType {
+	return MessageTypePing
+}
+
+func (p *PingMessage) Encode(w io.Writer) error {
+	_, err := w.Write(p.Nonce[:])
+	return err
+}
+
+func (p *PingMessage) Decode(r io.Reader) error {
+	_, err := io.ReadFull(r, p.Nonce[:])
+	return err
+}
+
+type PongMessage struct {
+	Nonce [8]byte
+}
+
+func (p *PongMessage) Type() MessageType {
+	return MessageTypePong
+}
+
+func (p *PongMessage) Encode(w io.Writer) error {
+	_, err := w.Write(p.Nonce[:])
+	return err
+}
+
+func (p *PongMessage) Decode(r io.Reader) error {
+	_, err := io.ReadFull(r, p.Nonce[:])
+	return err
+}
+
+type GetAddrMessage struct{}
+
+func (g *GetAddrMessage) Type() MessageType {
+	return MessageTypeGetAddr
+}
+
+func (g *GetAddrMessage) Encode(w io.Writer) error {
+	return nil
+}
+
+func (g *GetAddrMessage) Decode(r io.Reader) error {
+	return nil
+}
+
+type AddrMessage struct {
+	Addrs []*NetAddress
+}
+
+func (a *AddrMessage) Type() MessageType {
+	return MessageTypeAddr
+}
+
+func (a *AddrMessage) Encode(w io.Writer) error {
+	if len(a.Addrs) > MaxAddrs {
+		return ErrTooManyItems
+	}
+	if err := encoding.WriteVarint(w, uint64(len(a.Addrs))); err != nil {
+		return err
+	}
+	for _, addr := range a.Addrs {
+		if err := addr.Encode(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *AddrMessage) Decode(r io.Reader) error {
+	count, err := readCount(r, MaxAddrs)
+	if err != nil {
+		return err
+	}
+	addrs := make([]*NetAddress, 0, count)
+	for i := 0; i < count; i++ {
+		addr := new(NetAddress)
+		if err := addr.Decode(r); err != nil {
+			return err
+		}
+		addrs = append(addrs, addr)
+	}
+	a.Addrs = addrs
+	return nil
+}
+
+type SendHeadersMessage struct{}
+
+func (s *SendHeadersMessage) Type() MessageType {
+	return MessageTypeSendHeaders
+}
+
+func (s *SendHeadersMessage) Encode(w io.Writer) error {
+	return nil
+}
+
+func (s *SendHeadersMessage) Decode(r io.Reader) error {
+	return nil
+}
+
+type MempoolMessage struct{}
+
+func (m *MempoolMessage) Type() MessageType {
+	return MessageTypeMempool
+}
+
+func (m *MempoolMessage) Encode(w io.Writer) error {
+	return nil
+}
+
+func (m *MempoolMessage) Decode(r io.Reader) error {
+	return nil
+}
+
+// RejectMessage reports why a peer refused a message. Hash is only sent
+// when the rejected message refers to a block, transaction, claim or
+// airdrop.
+type RejectMessage struct {
+	Message MessageType
+	Code    uint8
+	Reason  string
+	Hash    [32]byte
+}
+
+func (r *RejectMessage) Type() MessageType {
+	return MessageTypeReject
+}
+
+func (r *RejectMessage) HasHash() bool {
+	switch r.Message {
+	case MessageTypeBlock, MessageTypeTx, MessageTypeClaim, MessageTypeAirdrop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *RejectMessage) Encode(w io.Writer) error {
+	if len(r.Reason) > MaxRejectReason {
+		return errors.New("reject reason too long")
+	}
+	if err := encoding.WriteUint8(w, uint8(r.Message)); err != nil {
+		return err
+	}
+	if err := encoding.WriteUint8(w, r.Code); err != nil {
+		return err
+	}
+	if err := encoding.WriteUint8(w, uint8(len(r.Reason))); err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(r.Reason)); err != nil {
+		return err
+	}
+	if r.HasHash() {
+		if _, err := w.Write(r.Hash[:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *RejectMessage) Decode(rd io.Reader) error {
+	msgType, err := encoding.ReadUint8(rd)
+	if err != nil {
+		return err
+	}
+	code, err := encoding.ReadUint8(rd)
+	if err != nil {
+		return err
+	}
+	reasonLen, err := encoding.ReadUint8(rd)
+	if err != nil {
+		return err
+	}
+	reason, err := encoding.ReadString(rd, int(reasonLen))
+	if err != nil {
+		return err
+	}
+	decoded := &RejectMessage{
+		Message: MessageType(msgType),
+		Code:    code,
+		Reason:  reason,
+	}
+	if decoded.HasHash() {
+		hash, err := readHash(rd)
+		if err != nil {
+			return err
+		}
+		decoded.Hash = hash
+	}
+	*r = *decoded
+	return nil
+}
+
+// FeeFilterMessage asks the peer not to relay transactions paying less
+// than Rate dollarydoos per kB.
+type FeeFilterMessage struct {
+	Rate int64
+}
+
+func (f *FeeFilterMessage) Type() MessageType {
+	return MessageTypeFeeFilter
+}
+
+func (f *FeeFilterMessage) Encode(w io.Writer) error {
+	return encoding.WriteUint64(w, uint64(f.Rate))
+}
+
+func (f *FeeFilterMessage) Decode(r io.Reader) error {
+	rate, err := encoding.ReadUint64(r)
+	if err != nil {
+		return err
+	}
+	f.Rate = int64(rate)
+	return nil
+}
@@ -0,0 +1,172 @@
+package wire
+
+import (
+	"github.com/mslipper/handshake/encoding"
+	"github.com/mslipper/handshake/primitives"
+	"io"
+)
+
+const (
+	MaxFilterSize     = 36000
+	MaxFilterHashFns  = 50
+	MaxFilterAddSize  = 520
+	MaxMerkleBlockTxs = MaxBlockTx
+)
+
+const (
+	FilterUpdateNone uint8 = iota
+	FilterUpdateAll
+	FilterUpdateP2PKOnly
+)
+
+type FilterLoadMessage struct {
+	Filter  []byte
+	HashFns uint32
+	Tweak   uint32
+	Update  uint8
+}
+
+func (f *FilterLoadMessage) Type() MessageType {
+	return MessageTypeFilterLoad
+}
+
+func (f *FilterLoadMessage) Encode(w io.Writer) error {
+	if len(f.Filter) > MaxFilterSize {
+		return ErrMessageTooLarge
+	}
+	if err := encoding.WriteVarBytes(w, f.Filter); err != nil {
+		return err
+	}
+	if err := encoding.WriteUint32(w, f.HashFns); err != nil {
+		return err
+	}
+	if err := encoding.WriteUint32(w, f.Tweak); err != nil {
+		return err
+	}
+	return encoding.WriteUint8(w, f.Update)
+}
+
+func (f *FilterLoadMessage) Decode(r io.Reader) error {
+	filter, err := readVarBytes(r, MaxFilterSize)
+	if err != nil {
+		return err
+	}
+	hashFns, err := encoding.ReadUint32(r)
+	if err != nil {
+		return err
+	}
+	tweak, err := encoding.ReadUint32(r)
+	if err != nil {
+		return err
+	}
+	update, err := encoding.ReadUint8(r)
+	if err != nil {
+		return err
+	}
+	f.Filter = filter
+	f.HashFns = hashFns
+	f.Tweak = tweak
+	f.Update = update
+	return nil
+}
+
+type FilterAddMessage struct {
+	Data []byte
+}
+
+func (f *FilterAddMessage) Type() MessageType {
+	return MessageTypeFilterAdd
+}
+
+func (f *FilterAddMessage) Encode(w io.Writer) error {
+	if len(f.Data) > MaxFilterAddSize {
+		return ErrMessageTooLarge
+	}
+	return encoding.WriteVarBytes(w, f.Data)
+}
+
+func (f *FilterAddMessage) Decode(r io.Reader) error {
+	data, err := readVarBytes(r, MaxFilterAddSize)
+	if err != nil {
+		return err
+	}
+	f.Data = data
+	return nil
+}
+
+type FilterClearMessage struct{}
+
+func (f *FilterClearMessage) Type() MessageType {
+	return MessageTypeFilterClear
+}
+
+func (f *FilterClearMessage) Encode(w io.Writer) error {
+	return nil
+}
+
+func (f *FilterClearMessage) Decode(r io.Reader) error {
+	return nil
+}
+
+// MerkleBlockMessage is a block header with a partial merkle tree proving
+// which of its transactions matched the peer's filter.
+type MerkleBlockMessage struct {
+	Header  *primitives.Block
+	TotalTx uint32
+	Hashes  [][32]byte
+	Flags   []byte
+}
+
+func (m *MerkleBlockMessage) Type() MessageType {
+	return MessageTypeMerkleBlock
+}
+
+func (m *MerkleBlockMessage) Encode(w io.Writer) error {
+	if err := m.Header.EncodeHeader(w); err != nil {
+		return err
+	}
+	if err := encoding.WriteUint32(w, m.TotalTx); err != nil {
+		return err
+	}
+	if err := encoding.WriteVarint(w, uint64(len(m.Hashes))); err != nil {
+		return err
+	}
+	for _, hash := range m.Hashes {
+		if _, err := w.Write(hash[:]); err != nil {
+			return err
+		}
+	}
+	return encoding.WriteVarBytes(w, m.Flags)
+}
+
+func (m *MerkleBlockMessage) Decode(r io.Reader) error {
+	header := new(primitives.Block)
+	if err := header.DecodeHeader(r); err != nil {
+		return err
+	}
+	totalTx, err := encoding.ReadUint32(r)
+	if err != nil {
+		return err
+	}
+	count, err := readCount(r, MaxMerkleBlockTxs)
+	if err != nil {
+		return err
+	}
+	var hashes [][32]byte
+	for i := 0; i < count; i++ {
+		hash, err := readHash(r)
+		if err != nil {
+			return err
+		}
+		hashes = append(hashes, hash)
+	}
+	flags, err := readVarBytes(r, MaxMessageSize)
+	if err != nil {
+		return err
+	}
+	m.Header = header
+	m.TotalTx = totalTx
+	m.Hashes = hashes
+	m.Flags = flags
+	return nil
+}
@@ -0,0 +1,308 @@
+package wire
+
+import (
+	"github.com/mslipper/handshake/encoding"
+	"github.com/mslipper/handshake/primitives"
+	"io"
+)
+
+const (
+	MaxInv     = 50000
+	MaxHeaders = 2000
+	MaxBlockTx = 1000000
+)
+
+type InvType uint32
+
+const (
+	InvTypeTx InvType = iota + 1
+	InvTypeBlock
+	InvTypeFilteredBlock
+	InvTypeCmpctBlock
+	InvTypeClaim
+	InvTypeAirdrop
+)
+
+type InvItem struct {
+	Type InvType
+	Hash [32]byte
+}
+
+func (i *InvItem) Encode(w io.Writer) error {
+	if err := encoding.WriteUint32(w, uint32(i.Type)); err != nil {
+		return err
+	}
+	_, err := w.Write(i.Hash[:])
+	return err
+}
+
+func (i *InvItem) Decode(r io.Reader) error {
+	typ, err := encoding.ReadUint32(r)
+	if err != nil {
+		return err
+	}
+	hash, err := readHash(r)
+	if err != nil {
+		return err
+	}
+	i.Type = InvType(typ)
+	i.Hash = hash
+	return nil
+}
+
+func encodeInvItems(w io.Writer, items []*InvItem) error {
+	if len(items) > MaxInv {
+		return ErrTooManyItems
+	}
+	if err := encoding.WriteVarint(w, uint64(len(items))); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := item.Encode(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeInvItems(r io.Reader) ([]*InvItem, error) {
+	count, err := readCount(r, MaxInv)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*InvItem, 0, count)
+	for i := 0; i < count; i++ {
+		item := new(InvItem)
+		if err := item.Decode(r); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+type InvMessage struct {
+	Items []*InvItem
+}
+
+func (i *InvMessage) Type() MessageType {
+	return MessageTypeInv
+}
+
+func (i *InvMessage) Encode(w io.Writer) error {
+	return encodeInvItems(w, i.Items)
+}
+
+func (i *InvMessage) Decode(r io.Reader) error {
+	items, err := decodeInvItems(r)
+	if err != nil {
+		return err
+	}
+	i.Items = items
+	return nil
+}
+
+type GetDataMessage struct {
+	Items []*InvItem
+}
+
+func (g *GetDataMessage) Type() MessageType {
+	return MessageTypeGetData
+}
+
+func (g *GetDataMessage) Encode(w io.Writer) error {
+	return encodeInvItems(w, g.Items)
+}
+
+func (g *GetDataMessage) Decode(r io.Reader) error {
+	items, err := decodeInvItems(r)
+	if err != nil {
+		return err
+	}
+	g.Items = items
+	return nil
+}
+
+type NotFoundMessage struct {
+	Items []*InvItem
+}
+
+func (n *NotFoundMessage) Type() MessageType {
+	return MessageTypeNotFound
+}
+
+func (n *NotFoundMessage) Encode(w io.Writer) error {
+	return encodeInvItems(w, n.Items)
+}
+
+func (n *NotFoundMessage) Decode(r io.Reader) error {
+	items, err := decodeInvItems(r)
+	if err != nil {
+		return err
+	}
+	n.Items = items
+	return nil
+}
+
+// locator is the shared payload of getblocks and getheaders. A zero stop
+// hash means "as many as you'll send".
+type locator struct {
+	Locator [][32]byte
+	Stop    [32]byte
+}
+
+func (l *locator) encode(w io.Writer) error {
+	if len(l.Locator) > MaxInv {
+		return ErrTooManyItems
+	}
+	if err := encoding.WriteVarint(w, uint64(len(l.Locator))); err != nil {
+		return err
+	}
+	for _, hash := range l.Locator {
+		if _, err := w.Write(hash[:]); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(l.Stop[:])
+	return err
+}
+
+func (l *locator) decode(r io.Reader) error {
+	count, err := readCount(r, MaxInv)
+	if err != nil {
+		return err
+	}
+	hashes := make([][32]byte, 0, count)
+	for i := 0; i < count; i++ {
+		hash, err := readHash(r)
+		if err != nil {
+			return err
+		}
+		hashes = append(hashes, hash)
+	}
+	stop, err := readHash(r)
+	if err != nil {
+		return err
+	}
+	l.Locator = hashes
+	l.Stop = stop
+	return nil
+}
+
+type GetBlocksMessage struct {
+	Locator [][32]byte
+	Stop    [32]byte
+}
+
+func (g *GetBlocksMessage) Type() MessageType {
+	return MessageTypeGetBlocks
+}
+
+func (g *GetBlocksMessage) Encode(w io.Writer) error {
+	return (*locator)(g).encode(w)
+}
+
+func (g *GetBlocksMessage) Decode(r io.Reader) error {
+	return (*locator)(g).decode(r)
+}
+
+type GetHeadersMessage struct {
+	Locator [][32]byte
+	Stop    [32]byte
+}
+
+func (g *GetHeadersMessage) Type() MessageType {
+	return MessageTypeGetHeaders
+}
+
+func (g *GetHeadersMessage) Encode(w io.Writer) error {
+	return (*locator)(g).encode(w)
+}
+
+func (g *GetHeadersMessage) Decode(r io.Reader) error {
+	return (*locator)(g).decode(r)
+}
+
+// HeadersMessage carries block headers only. Transactions on the blocks
+// are ignored when encoding.
+type HeadersMessage struct {
+	Headers []*primitives.Block
+}
+
+func (h *HeadersMessage) Type() MessageType {
+	return MessageTypeHeaders
+}
+
+func (h *HeadersMessage) Encode(w io.Writer) error {
+	if len(h.Headers) > MaxHeaders {
+		return ErrTooManyItems
+	}
+	if err := encoding.WriteVarint(w, uint64(len(h.Headers))); err != nil {
+		return err
+	}
+	for _, header := range h.Headers {
+		if err := header.EncodeHeader(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *HeadersMessage) Decode(r io.Reader) error {
+	count, err := readCount(r, MaxHeaders)
+	if err != nil {
+		return err
+	}
+	headers := make([]*primitives.Block, 0, count)
+	for i := 0; i < count; i++ {
+		header := new(primitives.Block)
+		if err := header.DecodeHeader(r); err != nil {
+			return err
+		}
+		headers = append(headers, header)
+	}
+	h.Headers = headers
+	return nil
+}
+
+type BlockMessage struct {
+	Block *primitives.Block
+}
+
+func (b *BlockMessage) Type() MessageType {
+	return MessageTypeBlock
+}
+
+func (b *BlockMessage) Encode(w io.Writer) error {
+	return b.Block.Encode(w)
+}
+
+func (b *BlockMessage) Decode(r io.Reader) error {
+	block := new(primitives.Block)
+	if err := block.Decode(r); err != nil {
+		return err
+	}
+	b.Block = block
+	return nil
+}
+
+type TxMessage struct {
+	Tx *primitives.Transaction
+}
+
+func (t *TxMessage) Type() MessageType {
+	return MessageTypeTx
+}
+
+func (t *TxMessage) Encode(w io.Writer) error {
+	return t.Tx.Encode(w)
+}
+
+func (t *TxMessage) Decode(r io.Reader) error {
+	tx := new(primitives.Transaction)
+	if err := tx.Decode(r); err != nil {
+		return err
+	}
+	t.Tx = tx
+	return nil
+}
@@ -0,0 +1,324 @@
+package wire
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/mslipper/handshake/encoding"
+	"github.com/mslipper/handshake/primitives"
+	"io"
+)
+
+type MessageType uint8
+
+const (
+	MessageTypeVersion MessageType = iota
+	MessageTypeVerack
+	MessageTypePing
+	MessageTypePong
+	MessageTypeGetAddr
+	MessageTypeAddr
+	MessageTypeInv
+	MessageTypeGetData
+	MessageTypeNotFound
+	MessageTypeGetBlocks
+	MessageTypeGetHeaders
+	MessageTypeHeaders
+	MessageTypeSendHeaders
+	MessageTypeBlock
+	MessageTypeTx
+	MessageTypeReject
+	MessageTypeMempool
+	MessageTypeFilterLoad
+	MessageTypeFilterAdd
+	MessageTypeFilterClear
+	MessageTypeMerkleBlock
+	MessageTypeFeeFilter
+	MessageTypeSendCmpct
+	MessageTypeCmpctBlock
+	MessageTypeGetBlockTxn
+	MessageTypeBlockTxn
+	MessageTypeGetProof
+	MessageTypeProof
+	MessageTypeClaim
+	MessageTypeAirdrop
+	MessageTypeUnknown
+)
+
+const (
+	HeaderSize     = 9
+	MaxMessageSize = 8000000
+)
+
+var (
+	ErrInvalidMagic    = errors.New("wire: invalid magic")
+	ErrMessageTooLarge = errors.New("wire: message too large")
+	ErrTrailingData    = errors.New("wire: trailing data after message")
+	ErrTooManyItems    = errors.New("wire: too many items")
+)
+
+// DecodeError is returned by ReadMessage when a message was framed
+// correctly but its payload could not be decoded. The stream itself is
+// still usable.
+type DecodeError struct {
+	Type MessageType
+	Err  error
+}
+
+func (d *DecodeError) Error() string {
+	return fmt.Sprintf("wire: error decoding %s: %v", d.Type, d.Err)
+}
+
+func (d *DecodeError) Unwrap() error {
+	return d.Err
+}
+
+var messageTypeNames = map[MessageType]string{
+	MessageTypeVersion:     "VERSION",
+	MessageTypeVerack:      "VERACK",
+	MessageTypePing:        "PING",
+	MessageTypePong:        "PONG",
+	MessageTypeGetAddr:     "GETADDR",
+	MessageTypeAddr:        "ADDR",
+	MessageTypeInv:         "INV",
+	MessageTypeGetData:     "GETDATA",
+	MessageTypeNotFound:    "NOTFOUND",
+	MessageTypeGetBlocks:   "GETBLOCKS",
+	MessageTypeGetHeaders:  "GETHEADERS",
+	MessageTypeHeaders:     "HEADERS",
+	MessageTypeSendHeaders: "SENDHEADERS",
+	MessageTypeBlock:       "BLOCK",
+	MessageTypeTx:          "TX",
+	MessageTypeReject:      "REJECT",
+	MessageTypeMempool:     "MEMPOOL",
+	MessageTypeFilterLoad:  "FILTERLOAD",
+	MessageTypeFilterAdd:   "FILTERADD",
+	MessageTypeFilterClear: "FILTERCLEAR",
+	MessageTypeMerkleBlock: "MERKLEBLOCK",
+	MessageTypeFeeFilter:   "FEEFILTER",
+	MessageTypeSendCmpct:   "SENDCMPCT",
+	MessageTypeCmpctBlock:  "CMPCTBLOCK",
+	MessageTypeGetBlockTxn: "GETBLOCKTXN",
+	MessageTypeBlockTxn:    "BLOCKTXN",
+	MessageTypeGetProof:    "GETPROOF",
+	MessageTypeProof:       "PROOF",
+	MessageTypeClaim:       "CLAIM",
+	MessageTypeAirdrop:     "AIRDROP",
+	MessageTypeUnknown:     "UNKNOWN",
+}
+
+func (t MessageType) String() string {
+	name, ok := messageTypeNames[t]
+	if !ok {
+		return fmt.Sprintf("UNKNOWN(%d)", uint8(t))
+	}
+	return name
+}
+
+type Message interface {
+	encoding.Encoder
+	encoding.Decoder
+	Type() MessageType
+}
+
+// MaxPayloadSize returns the largest payload a well-formed message of the
+// given type can have.
+func MaxPayloadSize(t MessageType) int {
+	switch t {
+	case MessageTypeVerack, MessageTypeGetAddr, MessageTypeSendHeaders,
+		MessageTypeMempool, MessageTypeFilterClear:
+		return 0
+	case MessageTypePing, MessageTypePong, MessageTypeFeeFilter:
+		return 8
+	case MessageTypeSendCmpct:
+		return 9
+	case MessageTypeGetProof:
+		return 64
+	case MessageTypeVersion:
+		return 4 + 4 + 4 + 8 + NetAddressSize + 8 + 1 + MaxAgentSize + 4 + 1
+	case MessageTypeAddr:
+		return 9 + MaxAddrs*NetAddressSize
+	case MessageTypeInv, MessageTypeGetData, MessageTypeNotFound:
+		return 9 + MaxInv*36
+	case MessageTypeGetBlocks, MessageTypeGetHeaders:
+		return 9 + MaxInv*32 + 32
+	case MessageTypeHeaders:
+		return 9 + MaxHeaders*primitives.HeaderSize
+	case MessageTypeFilterLoad:
+		return 9 + MaxFilterSize + 4 + 4 + 1
+	case MessageTypeFilterAdd:
+		return 9 + MaxFilterAddSize
+	case MessageTypeReject:
+		return 1 + 1 + 1 + MaxRejectReason + 32
+	default:
+		return MaxMessageSize
+	}
+}
+
+// NewMessage returns an empty message of the given type, ready to be
+// decoded into. Types without a known payload become an UnknownMessage.
+func NewMessage(t MessageType) Message {
+	switch t {
+	case MessageTypeVersion:
+		return new(VersionMessage)
+	case MessageTypeVerack:
+		return new(VerackMessage)
+	case MessageTypePing:
+		return new(PingMessage)
+	case MessageTypePong:
+		return new(PongMessage)
+	case MessageTypeGetAddr:
+		return new(GetAddrMessage)
+	case MessageTypeAddr:
+		return new(AddrMessage)
+	case MessageTypeInv:
+		return new(InvMessage)
+	case MessageTypeGetData:
+		return new(GetDataMessage)
+	case MessageTypeNotFound:
+		return new(NotFoundMessage)
+	case MessageTypeGetBlocks:
+		return new(GetBlocksMessage)
+	case MessageTypeGetHeaders:
+		return new(GetHeadersMessage)
+	case MessageTypeHeaders:
+		return new(HeadersMessage)
+	case MessageTypeSendHeaders:
+		return new(SendHeadersMessage)
+	case MessageTypeBlock:
+		return new(BlockMessage)
+	case MessageTypeTx:
+		return new(TxMessage)
+	case MessageTypeReject:
+		return new(RejectMessage)
+	case MessageTypeMempool:
+		return new(MempoolMessage)
+	case MessageTypeFilterLoad:
+		return new(FilterLoadMessage)
+	case MessageTypeFilterAdd:
+		return new(FilterAddMessage)
+	case MessageTypeFilterClear:
+		return new(FilterClearMessage)
+	case MessageTypeMerkleBlock:
+		return new(MerkleBlockMessage)
+	case MessageTypeFeeFilter:
+		return new(FeeFilterMessage)
+	case MessageTypeSendCmpct:
+		return new(SendCmpctMessage)
+	case MessageTypeCmpctBlock:
+		return new(CmpctBlockMessage)
+	case MessageTypeGetBlockTxn:
+		return new(GetBlockTxnMessage)
+	case MessageTypeBlockTxn:
+		return new(BlockTxnMessage)
+	case MessageTypeGetProof:
+		return new(GetProofMessage)
+	case MessageTypeProof:
+		return new(ProofMessage)
+	case MessageTypeClaim:
+		return new(ClaimMessage)
+	case MessageTypeAirdrop:
+		return new(AirdropMessage)
+	default:
+		return &UnknownMessage{
+			MessageType: t,
+		}
+	}
+}
+
+// WriteMessage frames msg with the network magic, its type and its payload
+// length and writes it to w in a single call.
+func WriteMessage(w io.Writer, magic uint32, msg Message) error {
+	payload := new(bytes.Buffer)
+	if err := msg.Encode(payload); err != nil {
+		return err
+	}
+	if payload.Len() > MaxMessageSize {
+		return ErrMessageTooLarge
+	}
+	buf := new(bytes.Buffer)
+	buf.Grow(HeaderSize + payload.Len())
+	if err := encoding.WriteUint32(buf, magic); err != nil {
+		return err
+	}
+	if err := encoding.WriteUint8(buf, uint8(msg.Type())); err != nil {
+		return err
+	}
+	if err := encoding.WriteUint32(buf, uint32(payload.Len())); err != nil {
+		return err
+	}
+	buf.Write(payload.Bytes())
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// ReadMessage reads a single framed message from r. Payloads larger than
+// the type allows are rejected before they are read, and the whole payload
+// must be consumed by the message's decoder.
+func ReadMessage(r io.Reader, magic uint32) (Message, error) {
+	actMagic, err := encoding.ReadUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	if actMagic != magic {
+		return nil, ErrInvalidMagic
+	}
+	typ, err := encoding.ReadUint8(r)
+	if err != nil {
+		return nil, err
+	}
+	size, err := encoding.ReadUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	if int(size) > MaxPayloadSize(MessageType(typ)) {
+		return nil, ErrMessageTooLarge
+	}
+	payload, err := encoding.ReadBytes(r, int(size))
+	if err != nil {
+		return nil, err
+	}
+	msg := NewMessage(MessageType(typ))
+	pr := bytes.NewReader(payload)
+	if err := msg.Decode(pr); err != nil {
+		return nil, &DecodeError{
+			Type: MessageType(typ),
+			Err:  err,
+		}
+	}
+	if pr.Len() != 0 {
+		return nil, &DecodeError{
+			Type: MessageType(typ),
+			Err:  ErrTrailingData,
+		}
+	}
+	return msg, nil
+}
+
+func readCount(r io.Reader, max int) (int, error) {
+	count, err := encoding.ReadVarint(r)
+	if err != nil {
+		return 0, err
+	}
+	if count > uint64(max) {
+		return 0, ErrTooManyItems
+	}
+	return int(count), nil
+}
+
+func readVarBytes(r io.Reader, max int) ([]byte, error) {
+	l, err := encoding.ReadVarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if l > uint64(max) {
+		return nil, ErrMessageTooLarge
+	}
+	return encoding.ReadBytes(r, int(l))
+}
+
+func readHash(r io.Reader) ([32]byte, error) {
+	var hash [32]byte
+	_, err := io.ReadFull(r, hash[:])
+	return hash, err
+}
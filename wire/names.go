@@ -0,0 +1,163 @@
+package wire
+
+import (
+	"errors"
+	"github.com/mslipper/handshake/encoding"
+	"github.com/mslipper/handshake/urkel"
+	"io"
+	"io/ioutil"
+	"math"
+)
+
+type GetProofMessage struct {
+	Root [32]byte
+	Key  [32]byte
+}
+
+func (g *GetProofMessage) Type() MessageType {
+	return MessageTypeGetProof
+}
+
+func (g *GetProofMessage) Encode(w io.Writer) error {
+	if _, err := w.Write(g.Root[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(g.Key[:])
+	return err
+}
+
+func (g *GetProofMessage) Decode(r io.Reader) error {
+	root, err := readHash(r)
+	if err != nil {
+		return err
+	}
+	key, err := readHash(r)
+	if err != nil {
+		return err
+	}
+	g.Root = root
+	g.Key = key
+	return nil
+}
+
+type ProofMessage struct {
+	Root  [32]byte
+	Key   [32]byte
+	Proof *urkel.Proof
+}
+
+func (p *ProofMessage) Type() MessageType {
+	return MessageTypeProof
+}
+
+func (p *ProofMessage) Encode(w io.Writer) error {
+	if _, err := w.Write(p.Root[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(p.Key[:]); err != nil {
+		return err
+	}
+	return p.Proof.Encode(w)
+}
+
+func (p *ProofMessage) Decode(r io.Reader) error {
+	root, err := readHash(r)
+	if err != nil {
+		return err
+	}
+	key, err := readHash(r)
+	if err != nil {
+		return err
+	}
+	proof := new(urkel.Proof)
+	if err := proof.Decode(r); err != nil {
+		return err
+	}
+	p.Root = root
+	p.Key = key
+	p.Proof = proof
+	return nil
+}
+
+// ClaimMessage relays a DNSSEC ownership proof for a reserved name. The
+// blob is passed through undecoded.
+type ClaimMessage struct {
+	Blob []byte
+}
+
+func (c *ClaimMessage) Type() MessageType {
+	return MessageTypeClaim
+}
+
+func (c *ClaimMessage) Encode(w io.Writer) error {
+	if len(c.Blob) > math.MaxUint16 {
+		return errors.New("claim too large")
+	}
+	if err := encoding.WriteUint16(w, uint16(len(c.Blob))); err != nil {
+		return err
+	}
+	_, err := w.Write(c.Blob)
+	return err
+}
+
+func (c *ClaimMessage) Decode(r io.Reader) error {
+	size, err := encoding.ReadUint16(r)
+	if err != nil {
+		return err
+	}
+	blob, err := encoding.ReadBytes(r, int(size))
+	if err != nil {
+		return err
+	}
+	c.Blob = blob
+	return nil
+}
+
+// AirdropMessage relays an airdrop proof. Its payload has no length prefix
+// and takes up the rest of the message.
+type AirdropMessage struct {
+	Data []byte
+}
+
+func (a *AirdropMessage) Type() MessageType {
+	return MessageTypeAirdrop
+}
+
+func (a *AirdropMessage) Encode(w io.Writer) error {
+	_, err := w.Write(a.Data)
+	return err
+}
+
+func (a *AirdropMessage) Decode(r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	a.Data = data
+	return nil
+}
+
+// UnknownMessage holds the raw payload of any message type this package
+// does not understand, so that it can be relayed or logged.
+type UnknownMessage struct {
+	MessageType MessageType
+	Data        []byte
+}
+
+func (u *UnknownMessage) Type() MessageType {
+	return u.MessageType
+}
+
+func (u *UnknownMessage) Encode(w io.Writer) error {
+	_, err := w.Write(u.Data)
+	return err
+}
+
+func (u *UnknownMessage) Decode(r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	u.Data = data
+	return nil
+}
@@ -0,0 +1,118 @@
+package wire
+
+import (
+	"github.com/mslipper/handshake/encoding"
+	"io"
+	"net"
+	"strconv"
+)
+
+const (
+	NetAddressSize = 88
+	KeySize        = 33
+)
+
+const (
+	ServiceNetwork uint32 = 1 << 0
+	ServiceBloom   uint32 = 1 << 2
+)
+
+// NetAddress is a peer's address as advertised on the wire. Key holds the
+// peer's brontide identity key and is all zeroes if unknown.
+type NetAddress struct {
+	Time     uint64
+	Services uint32
+	IP       net.IP
+	Port     uint16
+	Key      [KeySize]byte
+}
+
+func (n *NetAddress) HostPort() string {
+	ip := n.IP
+	if ip == nil {
+		ip = net.IPv4zero
+	}
+	return net.JoinHostPort(ip.String(), strconv.Itoa(int(n.Port)))
+}
+
+func (n *NetAddress) HasKey() bool {
+	return n.Key != [KeySize]byte{}
+}
+
+func (n *NetAddress) Encode(w io.Writer) error {
+	if err := encoding.WriteUint64(w, n.Time); err != nil {
+		return err
+	}
+	if err := encoding.WriteUint32(w, n.Services); err != nil {
+		return err
+	}
+	if err := encoding.WriteUint32(w, 0); err != nil {
+		return err
+	}
+	if err := encoding.WriteUint8(w, 0); err != nil {
+		return err
+	}
+	ip := n.IP
+	if ip == nil {
+		ip = net.IPv4zero
+	}
+	if err := encoding.WriteIP6(w, ip); err != nil {
+		return err
+	}
+	if _, err := w.Write(make([]byte, 20)); err != nil {
+		return err
+	}
+	if err := encoding.WriteUint16(w, n.Port); err != nil {
+		return err
+	}
+	if _, err := w.Write(n.Key[:]); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (n *NetAddress) Decode(r io.Reader) error {
+	ts, err := encoding.ReadUint64(r)
+	if err != nil {
+		return err
+	}
+	services, err := encoding.ReadUint32(r)
+	if err != nil {
+		return err
+	}
+	if _, err := encoding.ReadUint32(r); err != nil {
+		return err
+	}
+	addrType, err := encoding.ReadUint8(r)
+	if err != nil {
+		return err
+	}
+	// Non-IP address types are reserved. hsd skips them entirely, so an
+	// address of an unknown type is left without an IP.
+	var ip net.IP
+	if addrType == 0 {
+		ip, err = encoding.ReadIP6(r)
+		if err != nil {
+			return err
+		}
+		if _, err := encoding.ReadBytes(r, 20); err != nil {
+			return err
+		}
+	} else if _, err := encoding.ReadBytes(r, 36); err != nil {
+		return err
+	}
+	port, err := encoding.ReadUint16(r)
+	if err != nil {
+		return err
+	}
+	var key [KeySize]byte
+	if _, err := io.ReadFull(r, key[:]); err != nil {
+		return err
+	}
+	n.Time = ts
+	n.Services = services
+	n.IP = ip
+	n.Port = port
+	n.Key = key
+	return nil
+}
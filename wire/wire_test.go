@@ -0,0 +1,240 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"github.com/mslipper/handshake/primitives"
+	"github.com/mslipper/handshake/primitives/primitivestest"
+	"github.com/mslipper/handshake/urkel"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+)
+
+func hash(b byte) [32]byte {
+	var h [32]byte
+	h[0] = b
+	h[31] = b
+	return h
+}
+
+func TestMessages_RoundTrip(t *testing.T) {
+	block := primitivestest.LoadBlock(t, primitivestest.GoldenBlock)
+	tx := block.Transactions[0]
+	addr := &NetAddress{
+		Time:     1580745078,
+		Services: ServiceNetwork | ServiceBloom,
+		IP:       net.ParseIP("10.0.0.1"),
+		Port:     12038,
+	}
+	addr.Key[0] = 0x02
+
+	msgs := []Message{
+		&VersionMessage{
+			Version:  ProtocolVersion,
+			Services: ServiceNetwork,
+			Time:     1580745078,
+			Remote:   addr,
+			Nonce:    [8]byte{1, 2, 3, 4, 5, 6, 7, 8},
+			Agent:    "/hsd:2.0.0/",
+			Height:   1234,
+			NoRelay:  true,
+		},
+		new(VerackMessage),
+		&PingMessage{Nonce: [8]byte{0xff}},
+		&PongMessage{Nonce: [8]byte{0xff}},
+		new(GetAddrMessage),
+		&AddrMessage{Addrs: []*NetAddress{addr, addr}},
+		&InvMessage{Items: []*InvItem{{Type: InvTypeBlock, Hash: hash(1)}, {Type: InvTypeTx, Hash: hash(2)}}},
+		&GetDataMessage{Items: []*InvItem{{Type: InvTypeCmpctBlock, Hash: hash(3)}}},
+		&NotFoundMessage{Items: []*InvItem{{Type: InvTypeTx, Hash: hash(4)}}},
+		&GetBlocksMessage{Locator: [][32]byte{hash(1), hash(2)}, Stop: hash(3)},
+		&GetHeadersMessage{Locator: [][32]byte{hash(1)}},
+		&HeadersMessage{Headers: []*primitives.Block{block, block}},
+		new(SendHeadersMessage),
+		&BlockMessage{Block: block},
+		&TxMessage{Tx: tx},
+		&RejectMessage{Message: MessageTypeTx, Code: RejectDust, Reason: "dust", Hash: hash(5)},
+		&RejectMessage{Message: MessageTypeVersion, Code: RejectObsolete, Reason: "obsolete"},
+		new(MempoolMessage),
+		&FilterLoadMessage{Filter: []byte{0x01, 0x02, 0x03}, HashFns: 11, Tweak: 0xdeadbeef, Update: FilterUpdateAll},
+		&FilterAddMessage{Data: []byte("data")},
+		new(FilterClearMessage),
+		&MerkleBlockMessage{Header: block, TotalTx: 7, Hashes: [][32]byte{hash(1), hash(2)}, Flags: []byte{0x1d}},
+		&FeeFilterMessage{Rate: 100000},
+		&SendCmpctMessage{Mode: 1, Version: 1},
+		&CmpctBlockMessage{
+			Header:    block,
+			KeyNonce:  [8]byte{9},
+			IDs:       []uint64{0x0000ffffffffffff, 0x000012345678},
+			Prefilled: []*PrefilledTx{{Index: 0, Tx: tx}, {Index: 3, Tx: tx}},
+		},
+		&GetBlockTxnMessage{Hash: hash(6), Indexes: []int{1, 2, 10}},
+		&BlockTxnMessage{Hash: hash(6), Txs: []*primitives.Transaction{tx, tx}},
+		&GetProofMessage{Root: hash(7), Key: hash(8)},
+		&ProofMessage{Root: hash(7), Key: hash(8), Proof: &urkel.Proof{Type: urkel.ProofTypeExists, Value: []byte("value")}},
+		&ClaimMessage{Blob: []byte("claim")},
+		&AirdropMessage{Data: []byte("airdrop")},
+		&UnknownMessage{MessageType: 99, Data: []byte("unknown")},
+	}
+	magic := primitives.NetworkMainnet.Magic()
+	for _, msg := range msgs {
+		t.Run(msg.Type().String(), func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			require.NoError(t, WriteMessage(buf, magic, msg))
+			raw := append([]byte(nil), buf.Bytes()...)
+
+			decoded, err := ReadMessage(buf, magic)
+			require.NoError(t, err)
+			require.Equal(t, 0, buf.Len())
+			require.Equal(t, msg.Type(), decoded.Type())
+			require.IsType(t, msg, decoded)
+
+			reencoded := new(bytes.Buffer)
+			require.NoError(t, WriteMessage(reencoded, magic, decoded))
+			require.Equal(t, raw, reencoded.Bytes())
+		})
+	}
+}
+
+func TestWriteMessage_Framing(t *testing.T) {
+	buf := new(bytes.Buffer)
+	require.NoError(t, WriteMessage(buf, primitives.NetworkMainnet.Magic(), &PingMessage{Nonce: [8]byte{1}}))
+	require.Equal(t, "d3f26e5b"+"02"+"08000000"+"0100000000000000", hex.EncodeToString(buf.Bytes()))
+
+	buf.Reset()
+	require.NoError(t, WriteMessage(buf, 0, &VersionMessage{}))
+	require.Equal(t, HeaderSize+4+4+4+8+NetAddressSize+8+1+4+1, buf.Len())
+}
+
+func TestReadMessage_Errors(t *testing.T) {
+	magic := primitives.NetworkRegtest.Magic()
+	frame := func(msg Message) []byte {
+		buf := new(bytes.Buffer)
+		require.NoError(t, WriteMessage(buf, magic, msg))
+		return buf.Bytes()
+	}
+
+	_, err := ReadMessage(bytes.NewReader(frame(new(VerackMessage))), primitives.NetworkMainnet.Magic())
+	require.Equal(t, ErrInvalidMagic, err)
+
+	oversize := frame(new(VerackMessage))
+	oversize[5] = 0xff
+	oversize[6] = 0xff
+	oversize[7] = 0xff
+	_, err = ReadMessage(bytes.NewReader(oversize), magic)
+	require.Equal(t, ErrMessageTooLarge, err)
+
+	trailing := frame(new(InvMessage))
+	trailing[5]++
+	trailing = append(trailing, 0x00)
+	_, err = ReadMessage(bytes.NewReader(trailing), magic)
+	var decodeErr *DecodeError
+	require.True(t, errors.As(err, &decodeErr))
+	require.Equal(t, MessageTypeInv, decodeErr.Type)
+	require.True(t, errors.Is(err, ErrTrailingData))
+
+	perType := frame(&PingMessage{})
+	perType[5] = 9
+	perType = append(perType, 0x00)
+	_, err = ReadMessage(bytes.NewReader(perType), magic)
+	require.Equal(t, ErrMessageTooLarge, err)
+
+	truncated := frame(&PingMessage{})
+	_, err = ReadMessage(bytes.NewReader(truncated[:len(truncated)-1]), magic)
+	require.Error(t, err)
+
+	tooMany := frame(new(VerackMessage))
+	tooMany[4] = uint8(MessageTypeHeaders)
+	tooMany[5] = 3
+	tooMany = append(tooMany, 0xfd, 0xd1, 0x07)
+	_, err = ReadMessage(bytes.NewReader(tooMany), magic)
+	require.True(t, errors.Is(err, ErrTooManyItems))
+}
+
+func TestGetBlockTxnMessage_DifferentialIndexes(t *testing.T) {
+	msg := &GetBlockTxnMessage{Indexes: []int{1, 2, 10}}
+	buf := new(bytes.Buffer)
+	require.NoError(t, msg.Encode(buf))
+	require.Equal(t, []byte{0x03, 0x01, 0x00, 0x07}, buf.Bytes()[32:])
+
+	require.Equal(t, ErrInvalidIndex, (&GetBlockTxnMessage{Indexes: []int{2, 2}}).Encode(new(bytes.Buffer)))
+}
+
+func TestSipHash24(t *testing.T) {
+	// Reference vectors from the SipHash paper: key 00..0f, message
+	// 00..(n-1).
+	tests := []struct {
+		len      int
+		expected uint64
+	}{
+		{0, 0x726fdb47dd0e0e31},
+		{1, 0x74f839c593dc67fd},
+		{8, 0x93f5f5799a932462},
+		{15, 0xa129ca6149be45e5},
+	}
+	msg := make([]byte, 64)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, sipHash24(0x0706050403020100, 0x0f0e0d0c0b0a0908, msg[:tt.len]))
+	}
+}
+
+func TestCmpctBlockMessage_ShortIDs(t *testing.T) {
+	block := primitivestest.LoadBlock(t, primitivestest.GoldenBlock)
+	msg := NewCmpctBlockMessage(block, [8]byte{1, 2, 3, 4, 5, 6, 7, 8})
+	require.Len(t, msg.Prefilled, 1)
+	require.Equal(t, 0, msg.Prefilled[0].Index)
+	require.Len(t, msg.IDs, len(block.Transactions)-1)
+	require.Nil(t, msg.Header.Transactions)
+	require.Equal(t, block.Hash(), msg.Header.Hash())
+
+	key := msg.SipKey()
+	for i, id := range msg.IDs {
+		require.True(t, id <= MaxShortID)
+		require.Equal(t, ShortID(key, block.Transactions[i+1].WitnessHash()), id)
+	}
+	other := NewCmpctBlockMessage(block, [8]byte{})
+	require.NotEqual(t, key, other.SipKey())
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, msg.Encode(buf))
+	decoded := new(CmpctBlockMessage)
+	require.NoError(t, decoded.Decode(buf))
+	require.Equal(t, msg.IDs, decoded.IDs)
+	require.Equal(t, key, decoded.SipKey())
+
+	res, err := NewBlockTxnMessage(block, &GetBlockTxnMessage{Indexes: []int{2, 5}})
+	require.NoError(t, err)
+	require.Equal(t, []*primitives.Transaction{block.Transactions[2], block.Transactions[5]}, res.Txs)
+	_, err = NewBlockTxnMessage(block, &GetBlockTxnMessage{Indexes: []int{len(block.Transactions)}})
+	require.Equal(t, ErrInvalidIndex, err)
+}
+
+func TestNetAddress_Encoding(t *testing.T) {
+	addr := &NetAddress{
+		Time:     1,
+		Services: ServiceNetwork,
+		IP:       net.ParseIP("2001:db8::1"),
+		Port:     44806,
+	}
+	buf := new(bytes.Buffer)
+	require.NoError(t, addr.Encode(buf))
+	require.Equal(t, NetAddressSize, buf.Len())
+	require.False(t, addr.HasKey())
+
+	decoded := new(NetAddress)
+	require.NoError(t, decoded.Decode(bytes.NewReader(buf.Bytes())))
+	require.True(t, addr.IP.Equal(decoded.IP))
+	require.Equal(t, "[2001:db8::1]:44806", decoded.HostPort())
+
+	raw := buf.Bytes()
+	raw[16] = 1
+	decoded = new(NetAddress)
+	require.NoError(t, decoded.Decode(bytes.NewReader(raw)))
+	require.Nil(t, decoded.IP)
+	require.EqualValues(t, 44806, decoded.Port)
+}